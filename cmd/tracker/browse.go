@@ -1,9 +1,6 @@
 package main
 
 import (
-	"github.com/ValGrace/command-history-tracker/internal/browser"
-	"github.com/ValGrace/command-history-tracker/internal/config"
-	"github.com/ValGrace/command-history-tracker/internal/storage"
 	"fmt"
 	"os"
 	"github.com/spf13/cobra"
@@ -13,13 +10,23 @@ var browseFlags struct {
 	dir    string
 	search string
 	tree   bool
+	inline bool
 }
 
 var browseCmd = &cobra.Command{
 	Use:   "browse",
 	Short: "Browse command history interactively",
 	Long: `Launch an interactive terminal UI to browse command history. 
-Navigate through commands, search, and execute selected commands.`,
+Navigate through commands, search, and execute selected commands.
+
+Enter runs the selected command in the directory it was recorded in (or the
+current directory if that no longer exists) and x runs it in the current
+directory. The browser is suspended while the command runs, its result is
+shown afterwards and the run is recorded in the history.
+
+With --inline the browser is drawn below the prompt on stderr instead of the
+full screen, and the chosen command is printed to stdout rather than executed.
+This is what the Ctrl-R bindings from 'tracker widget' run.`,
 	RunE: runBrowse,
 }
 
@@ -27,27 +34,24 @@ func init() {
 	browseCmd.Flags().StringVarP(&browseFlags.dir, "dir", "d", "", "Browse history for specific directory")
 	browseCmd.Flags().StringVarP(&browseFlags.search, "search", "s", "", "Start with search filter")
 	browseCmd.Flags().BoolVarP(&browseFlags.tree, "tree", "t", false, "Show directory tree view")
+	browseCmd.Flags().BoolVar(&browseFlags.inline, "inline", false, "Draw below the prompt and print the chosen command instead of executing it")
 
 	rootCmd.AddCommand(browseCmd)
 }
 
 func runBrowse(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	cfg := config.Global()
+	// The application's browser runs selected commands through its executor,
+	// which validates them against the security policy, confirms them when
+	// needed and records them
+	b := globalApp.GetBrowser()
 
-	// Initialize storage
-	storageEngine, err := storage.NewStorageEngine("sqlite", cfg.StoragePath)
-	if err != nil {
-		return fmt.Errorf("failed to create storage engine: %w", err)
-	}
-
-	if err := storageEngine.Initialize(); err != nil {
-		return fmt.Errorf("failed to initialize storage: %w", err)
+	// Follow edits to the configuration file while the browser is open, so
+	// changes to blocked_commands apply to the next command run
+	if !browseFlags.inline {
+		if err := globalApp.WatchConfig(); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "⚠ Configuration changes will not be reloaded: %v\n", err)
+		}
 	}
-	defer storageEngine.Close()
-
-	// Create browser
-	b := browser.NewBrowser(storageEngine)
 
 	// Determine directory to browse
 	dir := browseFlags.dir
@@ -69,7 +73,16 @@ func runBrowse(cmd *cobra.Command, args []string) error {
 	}
 
 	// Launch appropriate view
-	if browseFlags.tree {
+	if browseFlags.inline {
+		selected, err := b.PickCommand(browseFlags.search, cmd.ErrOrStderr())
+		if err != nil {
+			return err
+		}
+		if selected != nil {
+			fmt.Fprint(cmd.OutOrStdout(), selected.Command)
+		}
+		return nil
+	} else if browseFlags.tree {
 		return b.ShowDirectoryTree()
 	} else if browseFlags.search != "" {
 		return b.FilterCommands(browseFlags.search)
@@ -2,14 +2,20 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/importer"
+	"github.com/ValGrace/command-history-tracker/internal/interceptor"
 	"github.com/ValGrace/command-history-tracker/internal/storage"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/spf13/cobra"
 )
 
 // TestBrowseCommand tests the browse command functionality
@@ -180,6 +186,99 @@ func TestHistoryCommand(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("HistoryPagedListing", func(t *testing.T) {
+		historyFlags.shell = ""
+		historyFlags.since = ""
+		historyFlags.limit = 3
+		defer func() {
+			historyFlags.limit = 50
+			historyFlags.cursor = ""
+		}()
+
+		filters := storage.CommandFilters{Directory: testDir}
+		load := func() ([]history.CommandRecord, error) { return store.GetCommandsByDirectory(testDir) }
+
+		first, next, err := listHistory(store, filters, load)
+		if err != nil {
+			t.Fatalf("listHistory failed: %v", err)
+		}
+		if len(first) != 3 || next == "" {
+			t.Fatalf("Expected 3 commands and a cursor, got %d (cursor %q)", len(first), next)
+		}
+
+		historyFlags.cursor = next
+		rest, next, err := listHistory(store, filters, load)
+		if err != nil {
+			t.Fatalf("listHistory with cursor failed: %v", err)
+		}
+		if len(rest) != 1 || next != "" {
+			t.Fatalf("Expected the last command and no cursor, got %d (cursor %q)", len(rest), next)
+		}
+		if rest[0].Command != "very old command" {
+			t.Errorf("Expected oldest command on the last page, got %q", rest[0].Command)
+		}
+
+		historyFlags.cursor = ""
+		historyFlags.limit = 0
+		all, _, err := listHistory(store, filters, load)
+		if err != nil {
+			t.Fatalf("listHistory without limit failed: %v", err)
+		}
+		if len(all) != 4 {
+			t.Errorf("Expected all 4 commands without a limit, got %d", len(all))
+		}
+
+		historyFlags.limit = 10
+		historyFlags.shell = "powershell"
+		filtered, _, err := listHistory(store, historyFilters(), load)
+		if err != nil {
+			t.Fatalf("listHistory with shell filter failed: %v", err)
+		}
+		historyFlags.shell = ""
+		if len(filtered) != 1 || filtered[0].Shell != history.PowerShell {
+			t.Errorf("Expected only the PowerShell command, got %d", len(filtered))
+		}
+	})
+
+	t.Run("HistoryWithSession", func(t *testing.T) {
+		historyFlags.shell = ""
+		historyFlags.since = ""
+		historyFlags.noInteractive = true
+		defer func() { historyFlags.noInteractive = false }()
+
+		record := history.CommandRecord{
+			ID:        generateCommandID(),
+			Command:   "session command",
+			Directory: "/test/elsewhere",
+			Timestamp: now,
+			Shell:     history.Zsh,
+			SessionID: "sess-cli",
+			ShellPID:  999,
+		}
+		if err := store.SaveCommand(record); err != nil {
+			t.Fatalf("Failed to save command: %v", err)
+		}
+
+		t.Setenv("CHT_SESSION_ID", "sess-cli")
+		sessionID, err := resolveSessionID("current")
+		if err != nil || sessionID != "sess-cli" {
+			t.Errorf("Expected current session sess-cli, got %q (err: %v)", sessionID, err)
+		}
+
+		if err := runSessionHistory(store, "current"); err != nil {
+			t.Errorf("runSessionHistory failed: %v", err)
+		}
+
+		t.Setenv("CHT_SESSION_ID", "")
+		if _, err := resolveSessionID("current"); err == nil {
+			t.Error("Expected error when no current session is set")
+		}
+
+		if sessionID, _ := resolveSessionID("abc123"); sessionID != "abc123" {
+			t.Errorf("Expected explicit session ID to pass through, got %q", sessionID)
+		}
+	})
 }
 
 // TestSearchCommand tests the search command functionality
@@ -581,6 +680,10 @@ func TestShellTypeConversion(t *testing.T) {
 		{"bash", history.Bash},
 		{"zsh", history.Zsh},
 		{"cmd", history.Cmd},
+		{"fish", history.Fish},
+		{"nushell", history.Nushell},
+		{"nu", history.Nushell},
+		{"elvish", history.Elvish},
 		{"unknown", history.Unknown},
 		{"", history.Unknown},
 	}
@@ -603,6 +706,9 @@ func TestShellTypeConversion(t *testing.T) {
 		{history.Bash, "bash"},
 		{history.Zsh, "zsh"},
 		{history.Cmd, "cmd"},
+		{history.Fish, "fish"},
+		{history.Nushell, "nushell"},
+		{history.Elvish, "elvish"},
 		{history.Unknown, "unknown"},
 	}
 
@@ -808,6 +914,8 @@ func TestCLICommandParameters(t *testing.T) {
 			limit         int
 			since         string
 			shell         string
+			session       string
+			cursor        string
 			noInteractive bool
 		}{}
 	})
@@ -842,6 +950,7 @@ func TestCLICommandParameters(t *testing.T) {
 			caseSensitive bool
 			limit         int
 			noInteractive bool
+			fts           bool
 		}{}
 	})
 
@@ -863,6 +972,7 @@ func TestCLICommandParameters(t *testing.T) {
 			dir    string
 			search string
 			tree   bool
+			inline bool
 		}{}
 	})
 }
@@ -1227,6 +1337,8 @@ func TestConfigurationFlows(t *testing.T) {
 			func(c *config.Config) { c.MaxCommands = 100000 },
 			func(c *config.Config) { c.AutoCleanup = true },
 			func(c *config.Config) { c.AutoCleanup = false },
+			func(c *config.Config) { c.EnabledShells = []history.ShellType{history.Fish} },
+			func(c *config.Config) { c.EnabledShells = []history.ShellType{history.Nushell, history.Elvish} },
 		}
 
 		for i, change := range validChanges {
@@ -1243,6 +1355,8 @@ func TestConfigurationFlows(t *testing.T) {
 			func(c *config.Config) { c.RetentionDays = -1 },
 			func(c *config.Config) { c.MaxCommands = -1 },
 			func(c *config.Config) { c.StoragePath = "" },
+			func(c *config.Config) { c.EnabledShells = []history.ShellType{history.Elvish + 1} },
+			func(c *config.Config) { c.RedactPatterns = []string{"("} },
 		}
 
 		for i, change := range invalidChanges {
@@ -1255,3 +1369,804 @@ func TestConfigurationFlows(t *testing.T) {
 		}
 	})
 }
+
+// TestImportCommand tests importing a shell history file
+func TestImportCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.StoragePath = filepath.Join(tmpDir, "commands.db")
+	config.SetGlobal(cfg)
+
+	histFile := filepath.Join(tmpDir, "fish_history")
+	content := "- cmd: git status\n  when: 1700000000\n- cmd: make test\n  when: 1700000030\n"
+	if err := os.WriteFile(histFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write history file: %v", err)
+	}
+
+	importFlags.from = "fish"
+	importFlags.dir = "/test/imported"
+	defer func() { importFlags = struct{ from, dir string }{} }()
+
+	var buf bytes.Buffer
+	importCmd.SetOut(&buf)
+	defer importCmd.SetOut(nil)
+
+	if err := runImport(importCmd, []string{histFile}); err != nil {
+		t.Fatalf("runImport failed: %v", err)
+	}
+	if err := runImport(importCmd, []string{histFile}); err != nil {
+		t.Fatalf("Second runImport failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Imported 0 commands (2 already present)") {
+		t.Errorf("Expected re-import to skip duplicates, got:\n%s", buf.String())
+	}
+
+	store, err := storage.NewStorageEngine("sqlite", cfg.StoragePath)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	commands, err := store.GetCommandsByDirectory("/test/imported")
+	if err != nil {
+		t.Fatalf("Failed to get commands: %v", err)
+	}
+	if len(commands) != 2 || commands[0].Command != "make test" || commands[0].Shell != history.Fish {
+		t.Errorf("Unexpected imported commands: %+v", commands)
+	}
+
+	importFlags.from = "tcsh"
+	if err := runImport(importCmd, []string{histFile}); err == nil {
+		t.Error("Expected error for unsupported history format")
+	}
+
+	// Imported history is masked and filtered like captured commands
+	bashFile := filepath.Join(tmpDir, "bash_history")
+	if err := os.WriteFile(bashFile, []byte("mysql -u root -phunter2 app\nls\nmake build\n"), 0644); err != nil {
+		t.Fatalf("Failed to write history file: %v", err)
+	}
+
+	importFlags.from = "bash"
+	importFlags.dir = "/test/secrets"
+	for i := 0; i < 2; i++ {
+		buf.Reset()
+		if err := runImport(importCmd, []string{bashFile}); err != nil {
+			t.Fatalf("runImport of bash history failed: %v", err)
+		}
+	}
+	if !strings.Contains(buf.String(), "Imported 0 commands (2 already present)") || !strings.Contains(buf.String(), "1 commands were left out") {
+		t.Errorf("Expected redacted commands to be found again on re-import, got:\n%s", buf.String())
+	}
+
+	commands, err = store.GetCommandsByDirectory("/test/secrets")
+	if err != nil {
+		t.Fatalf("Failed to get commands: %v", err)
+	}
+	if len(commands) != 2 {
+		t.Fatalf("Expected the excluded ls to be left out, got %+v", commands)
+	}
+	for _, record := range commands {
+		if record.Command == "make build" {
+			continue
+		}
+		if strings.Contains(record.Command, "hunter2") || !record.HasTag(interceptor.RedactedTag) || !record.HasTag(importer.ImportedTag) {
+			t.Errorf("Expected the imported secret to be masked and tagged, got %+v", record)
+		}
+	}
+}
+
+// TestExportCommand tests exporting history and re-importing the JSON Lines output
+func TestExportCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.StoragePath = filepath.Join(tmpDir, "commands.db")
+	config.SetGlobal(cfg)
+
+	store := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	now := time.Now()
+	records := []history.CommandRecord{
+		{ID: "exp-1", Command: "go build ./...", Directory: "/test/export", Timestamp: now.Add(-2 * time.Hour), Shell: history.Bash, Tags: []string{"go"}},
+		{ID: "exp-2", Command: "go test ./...", Directory: "/test/export", Timestamp: now.Add(-time.Hour), Shell: history.Zsh, ExitCode: 1, Duration: 4 * time.Second, SessionID: "sess-exp", ShellPID: 77,
+			Hostname: "ci-runner", Username: "bob", SSH: true, KubeContext: "staging"},
+		{ID: "exp-3", Command: "make lint", Directory: "/test/other", Timestamp: now.Add(-10 * 24 * time.Hour), Shell: history.PowerShell},
+	}
+	for _, record := range records {
+		if err := store.SaveCommand(record); err != nil {
+			t.Fatalf("Failed to save command: %v", err)
+		}
+	}
+	store.Close()
+
+	defer func() {
+		exportFlags.format, exportFlags.output, exportFlags.dir, exportFlags.since = "jsonl", "", "", ""
+		importFlags = struct{ from, dir string }{}
+	}()
+
+	t.Run("FiltersAndStdout", func(t *testing.T) {
+		exportFlags.format = "bash"
+		exportFlags.dir = "/test/export"
+		exportFlags.since = "1w"
+		defer func() { exportFlags.dir, exportFlags.since = "", "" }()
+
+		var buf bytes.Buffer
+		exportCmd.SetOut(&buf)
+		defer exportCmd.SetOut(nil)
+
+		if err := runExport(exportCmd, nil); err != nil {
+			t.Fatalf("runExport failed: %v", err)
+		}
+
+		output := buf.String()
+		if strings.Index(output, "go build") > strings.Index(output, "go test") || strings.Contains(output, "lint") {
+			t.Errorf("Expected only /test/export commands, oldest first, got:\n%s", output)
+		}
+	})
+
+	t.Run("HostAndUser", func(t *testing.T) {
+		exportFlags.format = "bash"
+		exportFlags.host, exportFlags.user = "ci-runner", "bob"
+		defer func() { exportFlags.host, exportFlags.user = "", "" }()
+
+		var buf bytes.Buffer
+		exportCmd.SetOut(&buf)
+		defer exportCmd.SetOut(nil)
+
+		if err := runExport(exportCmd, nil); err != nil {
+			t.Fatalf("runExport failed: %v", err)
+		}
+		if output := buf.String(); !strings.Contains(output, "go test") || strings.Contains(output, "go build") {
+			t.Errorf("Expected only the command run by bob on ci-runner, got:\n%s", output)
+		}
+	})
+
+	t.Run("JSONLRoundTrip", func(t *testing.T) {
+		exportFile := filepath.Join(tmpDir, "history.jsonl")
+		exportFlags.format = "jsonl"
+		exportFlags.output = exportFile
+
+		var buf bytes.Buffer
+		exportCmd.SetOut(&buf)
+		defer exportCmd.SetOut(nil)
+
+		if err := runExport(exportCmd, nil); err != nil {
+			t.Fatalf("runExport failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), "Exported 3 commands") {
+			t.Errorf("Unexpected export summary: %s", buf.String())
+		}
+
+		original := storage.NewSQLiteStorage(cfg.StoragePath)
+		if err := original.Initialize(); err != nil {
+			t.Fatalf("Failed to initialize storage: %v", err)
+		}
+		want, err := original.FilterCommands(storage.CommandFilters{})
+		original.Close()
+		if err != nil {
+			t.Fatalf("FilterCommands failed: %v", err)
+		}
+
+		// Import into a fresh database
+		cfg.StoragePath = filepath.Join(tmpDir, "restored.db")
+		importFlags.from = "jsonl"
+		importCmd.SetOut(&buf)
+		defer importCmd.SetOut(nil)
+
+		if err := runImport(importCmd, []string{exportFile}); err != nil {
+			t.Fatalf("runImport failed: %v", err)
+		}
+
+		restored := storage.NewSQLiteStorage(cfg.StoragePath)
+		if err := restored.Initialize(); err != nil {
+			t.Fatalf("Failed to initialize storage: %v", err)
+		}
+		defer restored.Close()
+
+		got, err := restored.FilterCommands(storage.CommandFilters{})
+		if err != nil {
+			t.Fatalf("FilterCommands failed: %v", err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("Expected %d restored commands, got %d", len(want), len(got))
+		}
+		for i := range want {
+			if got[i].ID != want[i].ID || got[i].Shell != want[i].Shell || got[i].Duration != want[i].Duration ||
+				got[i].SessionID != want[i].SessionID || got[i].ShellPID != want[i].ShellPID ||
+				got[i].Hostname != want[i].Hostname || got[i].Username != want[i].Username ||
+				got[i].SSH != want[i].SSH || got[i].KubeContext != want[i].KubeContext ||
+				!got[i].Timestamp.Equal(want[i].Timestamp) || strings.Join(got[i].Tags, ",") != strings.Join(want[i].Tags, ",") {
+				t.Errorf("Record %d not restored losslessly:\n got  %+v\n want %+v", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("InvalidFlags", func(t *testing.T) {
+		exportFlags.format = "xml"
+		if err := runExport(exportCmd, nil); err == nil {
+			t.Error("Expected error for unsupported format")
+		}
+
+		exportFlags.format = "jsonl"
+		exportFlags.since = "yesterday"
+		if err := runExport(exportCmd, nil); err == nil {
+			t.Error("Expected error for invalid --since value")
+		}
+	})
+}
+
+// TestRedactCommand tests masking secrets in stored history
+func TestRedactCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.StoragePath = filepath.Join(tmpDir, "commands.db")
+	config.SetGlobal(cfg)
+
+	store := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+
+	for _, record := range []history.CommandRecord{
+		{ID: "secret", Command: "curl -u admin --password hunter2 https://example.com", Directory: "/test/redact", Timestamp: time.Now(), Shell: history.Bash, Tags: []string{}},
+		{ID: "clean", Command: "ls -la", Directory: "/test/redact", Timestamp: time.Now(), Shell: history.Bash, Tags: []string{}},
+	} {
+		if err := store.SaveCommand(record); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	redactCmd.SetOut(&buf)
+	defer redactCmd.SetOut(nil)
+	defer func() { redactFlags.dryRun, redactFlags.dir = false, "" }()
+
+	redactFlags.dryRun = true
+	if err := runRedact(redactCmd, nil); err != nil {
+		t.Fatalf("runRedact --dry-run failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "1 of 2 commands contain secrets") {
+		t.Errorf("Unexpected dry-run output:\n%s", buf.String())
+	}
+
+	commands, err := store.GetCommandsByDirectory("/test/redact")
+	if err != nil {
+		t.Fatalf("Failed to get commands: %v", err)
+	}
+	for _, record := range commands {
+		if record.ID == "secret" && !strings.Contains(record.Command, "hunter2") {
+			t.Error("Dry run should not change the database")
+		}
+	}
+
+	redactFlags.dryRun = false
+	if err := runRedact(redactCmd, nil); err != nil {
+		t.Fatalf("runRedact failed: %v", err)
+	}
+
+	commands, err = store.GetCommandsByDirectory("/test/redact")
+	if err != nil {
+		t.Fatalf("Failed to get commands: %v", err)
+	}
+	for _, record := range commands {
+		switch record.ID {
+		case "secret":
+			if strings.Contains(record.Command, "hunter2") || !record.HasTag(interceptor.RedactedTag) {
+				t.Errorf("Secret not masked: %+v", record)
+			}
+		case "clean":
+			if record.Command != "ls -la" || record.HasTag(interceptor.RedactedTag) {
+				t.Errorf("Clean command changed: %+v", record)
+			}
+		}
+	}
+
+	// Masked commands are not reported or rewritten again
+	buf.Reset()
+	redactFlags.dryRun = true
+	if err := runRedact(redactCmd, nil); err != nil {
+		t.Fatalf("runRedact --dry-run failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "0 of 2 commands contain secrets") {
+		t.Errorf("Expected masked commands not to be reported again:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	redactFlags.dryRun = false
+	if err := runRedact(redactCmd, nil); err != nil {
+		t.Fatalf("runRedact failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "No secrets found in 2 commands") {
+		t.Errorf("Expected a second run to change nothing:\n%s", buf.String())
+	}
+	// Project redact_patterns apply to commands run in the project
+	projectDir := filepath.Join(tmpDir, "project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, ".tracker.json"), []byte(`{"redact_patterns": ["vault-[0-9]+"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+	for _, record := range []history.CommandRecord{
+		{ID: "project", Command: "unlock vault-1234", Directory: filepath.ToSlash(projectDir), Timestamp: time.Now(), Shell: history.Bash, Tags: []string{}},
+		{ID: "outside", Command: "unlock vault-1234", Directory: "/test/redact", Timestamp: time.Now(), Shell: history.Bash, Tags: []string{}},
+	} {
+		if err := store.SaveCommand(record); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+
+	buf.Reset()
+	if err := runRedact(redactCmd, nil); err != nil {
+		t.Fatalf("runRedact failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Masked secrets in 1 of 4 commands") {
+		t.Errorf("Expected only the project command to be masked:\n%s", buf.String())
+	}
+
+	project, err := store.GetCommandsByDirectory(filepath.ToSlash(projectDir))
+	if err != nil {
+		t.Fatalf("Failed to get commands: %v", err)
+	}
+	if len(project) != 1 || project[0].Command != "unlock ****" {
+		t.Errorf("Project pattern not applied: %+v", project)
+	}
+}
+
+func TestRmCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.StoragePath = filepath.Join(tmpDir, "commands.db")
+	config.SetGlobal(cfg)
+
+	store := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+
+	for _, record := range []history.CommandRecord{
+		{ID: "pw1", Command: "mysql -u root -phunter2", Directory: "/test/rm", Timestamp: time.Now(), Shell: history.Bash, Tags: []string{}},
+		{ID: "pw2", Command: "mysql -u app -phunter2 app", Directory: "/test/rm", Timestamp: time.Now(), Shell: history.Bash, Tags: []string{}},
+		{ID: "keep", Command: "ls -la", Directory: "/test/rm", Timestamp: time.Now(), Shell: history.Bash, Tags: []string{}},
+	} {
+		if err := store.SaveCommand(record); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	rmCmd.SetOut(&buf)
+	defer rmCmd.SetOut(nil)
+	defer rmCmd.SetIn(nil)
+	defer func() { rmFlags.pattern, rmFlags.dir, rmFlags.dryRun, rmFlags.yes = "", "", false, false }()
+
+	remaining := func() int {
+		commands, err := store.GetCommandsByDirectory("/test/rm")
+		if err != nil {
+			t.Fatalf("Failed to get commands: %v", err)
+		}
+		return len(commands)
+	}
+
+	if err := runRm(rmCmd, nil); err == nil {
+		t.Error("Expected an error without IDs or --pattern")
+	}
+	if err := runRm(rmCmd, []string{"missing"}); err == nil || !strings.Contains(err.Error(), "command not found") {
+		t.Errorf("Expected command not found, got %v", err)
+	}
+
+	// Pattern matches are listed and need confirmation
+	rmFlags.pattern = "hunter2"
+	rmFlags.dryRun = true
+	if err := runRm(rmCmd, nil); err != nil {
+		t.Fatalf("runRm --dry-run failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "2 command(s) match") || remaining() != 3 {
+		t.Errorf("Unexpected dry-run output:\n%s", buf.String())
+	}
+
+	rmFlags.dryRun = false
+	rmCmd.SetIn(strings.NewReader("n\n"))
+	if err := runRm(rmCmd, nil); err != nil {
+		t.Fatalf("runRm failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Deletion cancelled.") || remaining() != 3 {
+		t.Error("Expected n to cancel the deletion")
+	}
+
+	buf.Reset()
+	rmCmd.SetIn(strings.NewReader("y\n"))
+	if err := runRm(rmCmd, nil); err != nil {
+		t.Fatalf("runRm failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "✓ Deleted 2 command(s)") || remaining() != 1 {
+		t.Errorf("Expected pattern matches deleted, got:\n%s", buf.String())
+	}
+
+	// By ID, --dry-run lists the commands and --dir is rejected
+	rmFlags.pattern = ""
+	buf.Reset()
+	rmFlags.dryRun = true
+	if err := runRm(rmCmd, []string{"keep"}); err != nil {
+		t.Fatalf("runRm by ID --dry-run failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "ls -la") || !strings.Contains(buf.String(), "1 command(s) would be deleted") || remaining() != 1 {
+		t.Errorf("Expected dry run by ID to delete nothing, got:\n%s", buf.String())
+	}
+	rmFlags.dryRun = false
+
+	rmFlags.dir = "/test/rm"
+	if err := runRm(rmCmd, []string{"keep"}); err == nil || remaining() != 1 {
+		t.Errorf("Expected --dir with IDs to be rejected, got %v", err)
+	}
+	rmFlags.dir = ""
+
+	// By ID, counting a repeated ID once
+	buf.Reset()
+	if err := runRm(rmCmd, []string{"keep", "keep"}); err != nil {
+		t.Fatalf("runRm by ID failed: %v", err)
+	}
+	if remaining() != 0 || !strings.Contains(buf.String(), "✓ Deleted 1 command(s)") {
+		t.Errorf("Expected the command deleted by ID, got:\n%s", buf.String())
+	}
+	dirs, err := store.GetDirectoriesWithHistory()
+	if err != nil {
+		t.Fatalf("Failed to get directories: %v", err)
+	}
+	for _, dir := range dirs {
+		if dir == "/test/rm" {
+			t.Error("Expected the emptied directory removed from directory stats")
+		}
+	}
+}
+
+// TestConfigTestExcludeCommand tests explaining exclude rule matches
+func TestConfigTestExcludeCommand(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ExcludeRules = []config.ExcludeRule{{Pattern: "deploy*", Match: config.MatchGlob, Shells: []history.ShellType{history.Zsh}}}
+	config.SetGlobal(cfg)
+
+	configTestExcludeFlags.dir = t.TempDir()
+	configTestExcludeFlags.shell = "zsh"
+	defer func() { configTestExcludeFlags.dir, configTestExcludeFlags.shell = "", "" }()
+
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"ls -la", `exclude_patterns[1]: prefix "ls"`},
+		{" echo private", "leading space"},
+		{"deploy --prod", `exclude_rules[0]: glob "deploy*" for zsh`},
+		{"make build", "✓ Recorded"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		configTestExcludeCmd.SetOut(&buf)
+
+		if err := runConfigTestExclude(configTestExcludeCmd, []string{tt.command}); err != nil {
+			t.Fatalf("runConfigTestExclude(%q) failed: %v", tt.command, err)
+		}
+		if !strings.Contains(buf.String(), tt.expected) {
+			t.Errorf("Expected %q in output for %q, got:\n%s", tt.expected, tt.command, buf.String())
+		}
+	}
+	configTestExcludeCmd.SetOut(nil)
+
+	configTestExcludeFlags.shell = "tcsh"
+	if err := runConfigTestExclude(configTestExcludeCmd, []string{"ls"}); err == nil {
+		t.Error("Expected error for unknown shell")
+	}
+}
+
+// TestConfigEffectiveCommand tests showing the merged project configuration
+func TestConfigEffectiveCommand(t *testing.T) {
+	config.SetGlobal(config.DefaultConfig())
+
+	projectDir := t.TempDir()
+	nested := filepath.Join(projectDir, "pkg")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	projectFile := filepath.Join(projectDir, ".tracker.toml")
+	if err := os.WriteFile(projectFile, []byte("retention_days = 7\nauto_tags = [\"monorepo\"]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+
+	var buf bytes.Buffer
+	configEffectiveCmd.SetOut(&buf)
+	defer configEffectiveCmd.SetOut(nil)
+
+	if err := runConfigEffective(configEffectiveCmd, []string{nested}); err != nil {
+		t.Fatalf("runConfigEffective failed: %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{
+		"Project config: " + projectFile,
+		"retention_days         7  [" + projectFile + "]",
+		"auto_tags              monorepo  [" + projectFile + "]",
+		"max_commands           10000  [global]",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, output)
+		}
+	}
+}
+
+// TestStatsCommand tests usage statistics as text and JSON
+func TestStatsCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.StoragePath = filepath.Join(tmpDir, "commands.db")
+	config.SetGlobal(cfg)
+
+	store := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	now := time.Now()
+	records := []history.CommandRecord{
+		{ID: "stats-1", Command: "make test", Directory: "/test/stats", Timestamp: now.Add(-2 * time.Hour), Shell: history.Bash, Duration: 2 * time.Second},
+		{ID: "stats-2", Command: "make test", Directory: "/test/stats/sub", Timestamp: now.Add(-time.Hour), Shell: history.Bash, ExitCode: 2, Duration: 4 * time.Second},
+		{ID: "stats-3", Command: "make lint", Directory: "/test/stats", Timestamp: now.Add(-time.Hour), Shell: history.Bash},
+		{ID: "stats-4", Command: "ls", Directory: "/test/other", Timestamp: now.Add(-10 * 24 * time.Hour), Shell: history.Zsh},
+	}
+	for _, record := range records {
+		if err := store.SaveCommand(record); err != nil {
+			t.Fatalf("Failed to save command: %v", err)
+		}
+	}
+	store.Close()
+
+	defer func() {
+		statsFlags.dir, statsFlags.since, statsFlags.json = "", "", false
+	}()
+
+	t.Run("Text", func(t *testing.T) {
+		statsFlags.dir = "/test/stats"
+
+		var buf bytes.Buffer
+		statsCmd.SetOut(&buf)
+		defer statsCmd.SetOut(nil)
+
+		if err := runStats(statsCmd, nil); err != nil {
+			t.Fatalf("runStats failed: %v", err)
+		}
+
+		output := buf.String()
+		for _, expected := range []string{
+			"Commands:    3 in 2 directories",
+			"Failures:    1 (33.3%)",
+			"       2   50.0%         3s         4s  make test",
+			"       1    0.0%          -          -  make lint",
+			"       3   33.3%         3s         4s  make\n",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected %q in output:\n%s", expected, output)
+			}
+		}
+		if strings.Contains(output, "/test/other") {
+			t.Errorf("Expected only the /test/stats subtree, got:\n%s", output)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		statsFlags.dir = ""
+		statsFlags.since = "1w"
+		statsFlags.json = true
+
+		var buf bytes.Buffer
+		statsCmd.SetOut(&buf)
+		defer statsCmd.SetOut(nil)
+
+		if err := runStats(statsCmd, nil); err != nil {
+			t.Fatalf("runStats failed: %v", err)
+		}
+
+		var stats storage.UsageStats
+		if err := json.Unmarshal(buf.Bytes(), &stats); err != nil {
+			t.Fatalf("Invalid JSON output: %v\n%s", err, buf.String())
+		}
+		if stats.TotalCommands != 3 || len(stats.TopDirectories) != 2 {
+			t.Errorf("Expected 3 commands from the last week in 2 directories, got %+v", stats)
+		}
+	})
+
+	t.Run("InvalidRange", func(t *testing.T) {
+		statsFlags.since = "yesterday"
+		if err := runStats(statsCmd, nil); err == nil {
+			t.Error("Expected error for invalid --since value")
+		}
+	})
+}
+
+func TestWidgetCommand(t *testing.T) {
+	defer func() { widgetFlags.shell = "" }()
+
+	widgetFlags.shell = "zsh"
+	var buf bytes.Buffer
+	widgetCmd.SetOut(&buf)
+	defer widgetCmd.SetOut(nil)
+
+	if err := runWidget(widgetCmd, nil); err != nil {
+		t.Fatalf("runWidget failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "bindkey -M emacs '^R' __cht_widget") {
+		t.Errorf("Expected zsh Ctrl-R binding, got:\n%s", buf.String())
+	}
+
+	for _, name := range []string{"cmd", "ksh"} {
+		widgetFlags.shell = name
+		if err := runWidget(widgetCmd, nil); err == nil {
+			t.Errorf("Expected error for --shell %s", name)
+		}
+	}
+}
+
+// TestDBEncryptCommands tests encrypting, rekeying and decrypting the database
+func TestDBEncryptCommands(t *testing.T) {
+	t.Setenv(storage.PassphraseEnv, "")
+	t.Setenv(storage.KeyFileEnv, "")
+	tmpDir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.StoragePath = filepath.Join(tmpDir, "commands.db")
+	config.SetGlobal(cfg)
+
+	store := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	record := history.CommandRecord{ID: "secret", Command: "vault login -method=userpass", Directory: "/test/db", Timestamp: time.Now(), Shell: history.Bash, Tags: []string{}}
+	if err := store.SaveCommand(record); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+	store.Close()
+
+	// A migration backup written before encrypting keeps the plaintext
+	data, err := os.ReadFile(cfg.StoragePath)
+	if err != nil {
+		t.Fatalf("Failed to read database: %v", err)
+	}
+	backup := cfg.StoragePath + ".v4-20260101-120000.bak"
+	if err := os.WriteFile(backup, data, 0644); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+
+	defer func() { dbKeyFlags.keyFile, dbKeyFlags.passphrase, dbKeyFlags.removeBackups = "", false, false }()
+	var buf bytes.Buffer
+	for _, cmd := range []*cobra.Command{dbEncryptCmd, dbDecryptCmd, dbRekeyCmd} {
+		cmd.SetOut(&buf)
+		defer cmd.SetOut(nil)
+		defer cmd.SetIn(nil)
+	}
+
+	readBack := func() string {
+		store := storage.NewSQLiteStorage(cfg.StoragePath)
+		defer store.Close()
+		if err := store.Initialize(); err != nil {
+			t.Fatalf("Failed to reopen storage: %v", err)
+		}
+		commands, err := store.GetCommandsByDirectory("/test/db")
+		if err != nil || len(commands) != 1 {
+			t.Fatalf("Failed to read commands: %v", err)
+		}
+		return commands[0].Command
+	}
+
+	dbKeyFlags.keyFile = filepath.Join(tmpDir, "db.key")
+	if err := runDBEncrypt(dbEncryptCmd, nil); err != nil {
+		t.Fatalf("runDBEncrypt failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Encrypted 1 command(s)") || !strings.Contains(buf.String(), "db.key") {
+		t.Errorf("Unexpected encrypt output:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "still hold plaintext commands:\n  "+backup) {
+		t.Errorf("Expected the plaintext backup to be listed, got:\n%s", buf.String())
+	}
+	if readBack() != record.Command {
+		t.Error("Expected the recorded key file to decrypt the history")
+	}
+
+	// Rekey to a passphrase piped on stdin, deleting the plaintext backup
+	dbKeyFlags.keyFile, dbKeyFlags.passphrase, dbKeyFlags.removeBackups = "", true, true
+	dbRekeyCmd.SetIn(strings.NewReader("correct horse\n"))
+	if err := runDBRekey(dbRekeyCmd, nil); err != nil {
+		t.Fatalf("runDBRekey failed: %v", err)
+	}
+	if _, err := os.Stat(backup); !os.IsNotExist(err) || !strings.Contains(buf.String(), "Removed plaintext migration backup") {
+		t.Errorf("Expected the plaintext backup to be removed, got:\n%s", buf.String())
+	}
+
+	store = storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := store.Initialize(); err == nil {
+		t.Error("Expected opening without the passphrase to fail")
+	}
+	store.Close()
+
+	dbDecryptCmd.SetIn(strings.NewReader("wrong\n"))
+	if err := runDBDecrypt(dbDecryptCmd, nil); err == nil {
+		t.Error("Expected decrypt to fail with a wrong passphrase")
+	}
+	buf.Reset()
+	dbDecryptCmd.SetIn(strings.NewReader("correct horse\n"))
+	if err := runDBDecrypt(dbDecryptCmd, nil); err != nil {
+		t.Fatalf("runDBDecrypt failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Decrypted 1 command(s)") {
+		t.Errorf("Unexpected decrypt output:\n%s", buf.String())
+	}
+	if readBack() != record.Command {
+		t.Error("Expected plaintext history after decrypting")
+	}
+	if err := runDBDecrypt(dbDecryptCmd, nil); err == nil {
+		t.Error("Expected decrypt to fail on an unencrypted database")
+	}
+}
+
+func TestSyncCommand(t *testing.T) {
+	shared := t.TempDir()
+	newConfig := func(id, command string) *config.Config {
+		cfg := config.DefaultConfig()
+		cfg.StoragePath = filepath.Join(t.TempDir(), "commands.db")
+		cfg.SyncPath = shared
+
+		store := storage.NewSQLiteStorage(cfg.StoragePath)
+		if err := store.Initialize(); err != nil {
+			t.Fatalf("Failed to initialize storage: %v", err)
+		}
+		defer store.Close()
+		record := history.CommandRecord{ID: id, Command: command, Directory: "/test/sync", Timestamp: time.Now(), Shell: history.Bash, Tags: []string{}}
+		if err := store.SaveCommand(record); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+		return cfg
+	}
+	laptop, desktop := newConfig("a", "git pull"), newConfig("b", "go test ./...")
+
+	var buf bytes.Buffer
+	syncCmd.SetOut(&buf)
+	defer syncCmd.SetOut(nil)
+
+	for _, cfg := range []*config.Config{laptop, desktop, laptop} {
+		config.SetGlobal(cfg)
+		if err := runSync(syncCmd, nil); err != nil {
+			t.Fatalf("runSync failed: %v", err)
+		}
+	}
+	if !strings.Contains(buf.String(), "2 machine(s)") || !strings.Contains(buf.String(), "added 1") {
+		t.Errorf("Unexpected sync output:\n%s", buf.String())
+	}
+
+	for name, cfg := range map[string]*config.Config{"laptop": laptop, "desktop": desktop} {
+		store := storage.NewSQLiteStorage(cfg.StoragePath)
+		if err := store.Initialize(); err != nil {
+			t.Fatalf("Failed to reopen storage: %v", err)
+		}
+		commands, err := store.GetCommandsByDirectory("/test/sync")
+		store.Close()
+		if err != nil || len(commands) != 2 {
+			t.Errorf("%s: expected both commands after syncing, got %d (%v)", name, len(commands), err)
+		}
+	}
+
+	desktop.SyncPath = ""
+	config.SetGlobal(desktop)
+	if err := runSync(syncCmd, nil); err == nil {
+		t.Error("Expected an error without a sync path")
+	}
+}
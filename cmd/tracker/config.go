@@ -3,11 +3,16 @@ package main
 import (
 	"bufio"
 	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/daemon"
+	"github.com/ValGrace/command-history-tracker/internal/interceptor"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
+	"github.com/ValGrace/command-history-tracker/pkg/shell"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -21,6 +26,11 @@ var configFlags struct {
 	showPath bool
 }
 
+var configTestExcludeFlags struct {
+	dir   string
+	shell string
+}
+
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Manage tracker configuration",
@@ -29,6 +39,37 @@ Use subcommands or flags to get, set, or list configuration values.`,
 	RunE: runConfig,
 }
 
+var configTestExcludeCmd = &cobra.Command{
+	Use:   "test-exclude <command>",
+	Short: "Check whether a command would be recorded",
+	Long: `Check a command against the exclude settings and explain which rule, if
+any, stops it from being recorded. Rules scoped to directories or shells are
+checked against --dir and --shell, which default to the current directory
+and shell.
+
+Examples:
+  tracker config test-exclude "ls -la"
+  tracker config test-exclude " export TOKEN=abc"
+  tracker config test-exclude --dir ~/work/secret --shell zsh "git push"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigTestExclude,
+}
+
+var configEffectiveCmd = &cobra.Command{
+	Use:   "effective [dir]",
+	Short: "Show the configuration that applies in a directory",
+	Long: `Show the global configuration merged with the nearest .tracker.json or
+.tracker.toml found by walking up from the directory, and where each value
+came from. Project files can set do_not_record and retention_days, and add
+exclude_patterns, exclude_rules, auto_tags and redact_patterns.
+
+Examples:
+  tracker config effective
+  tracker config effective ~/src/monorepo/services/api`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigEffective,
+}
+
 func init() {
 	configCmd.Flags().StringVar(&configFlags.get, "get", "", "Get configuration value (e.g., 'retention_days')")
 	configCmd.Flags().StringVar(&configFlags.set, "set", "", "Set configuration value (format: 'key=value')")
@@ -37,9 +78,112 @@ func init() {
 	configCmd.Flags().BoolVarP(&configFlags.edit, "edit", "e", false, "Edit configuration interactively")
 	configCmd.Flags().BoolVar(&configFlags.showPath, "path", false, "Show configuration file path")
 
+	configTestExcludeCmd.Flags().StringVarP(&configTestExcludeFlags.dir, "dir", "d", "", "Directory the command runs in (default: current directory)")
+	configTestExcludeCmd.Flags().StringVarP(&configTestExcludeFlags.shell, "shell", "s", "", "Shell the command runs in (default: detected shell)")
+
+	configCmd.AddCommand(configTestExcludeCmd)
+	configCmd.AddCommand(configEffectiveCmd)
 	rootCmd.AddCommand(configCmd)
 }
 
+func runConfigTestExclude(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+
+	dir := configTestExcludeFlags.dir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		dir = wd
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	shellType := history.Unknown
+	if configTestExcludeFlags.shell != "" {
+		shellType = parseShellType(configTestExcludeFlags.shell)
+		if shellType == history.Unknown {
+			return fmt.Errorf("unknown shell: %s", configTestExcludeFlags.shell)
+		}
+	} else if detected, err := shell.NewDetector().DetectShell(); err == nil {
+		shellType = detected
+	}
+
+	record := &history.CommandRecord{
+		Command:   args[0],
+		Directory: normalizeDirectoryPath(dir),
+		Shell:     shellType,
+	}
+
+	cfg, project, err := config.Global().ForDirectory(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Command:   %q\n", record.Command)
+	fmt.Fprintf(out, "Directory: %s\n", record.Directory)
+	fmt.Fprintf(out, "Shell:     %s\n", shellTypeToString(record.Shell))
+	if project != nil {
+		fmt.Fprintf(out, "Project:   %s\n", project.Path)
+	}
+	fmt.Fprintln(out)
+
+	if cfg.DoNotRecord {
+		fmt.Fprintln(out, "✗ Not recorded: do_not_record is set")
+		return nil
+	}
+
+	reason := interceptor.NewCommandCapture(nil, cfg).SkipReason(record)
+	if reason == "" {
+		fmt.Fprintln(out, "✓ Recorded: no exclude rule matches")
+		return nil
+	}
+
+	fmt.Fprintf(out, "✗ Not recorded: %s\n", reason)
+	return nil
+}
+
+func runConfigEffective(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	global := config.Global()
+	_, project, err := global.ForDirectory(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Directory:      %s\n", normalizeDirectoryPath(dir))
+	fmt.Fprintf(out, "Global config:  %s\n", config.GetConfigPath())
+	if project != nil {
+		fmt.Fprintf(out, "Project config: %s\n", project.Path)
+	} else {
+		fmt.Fprintln(out, "Project config: (none)")
+	}
+	fmt.Fprintln(out)
+
+	for _, setting := range global.EffectiveSettings(project) {
+		value := setting.Value
+		if value == "" {
+			value = "(none)"
+		}
+		fmt.Fprintf(out, "%-22s %s  [%s]\n", setting.Key, value, setting.Source)
+	}
+
+	return nil
+}
+
 func runConfig(cmd *cobra.Command, args []string) error {
 	// Show config path
 	if configFlags.showPath {
@@ -48,8 +192,12 @@ func runConfig(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Load configuration
-	cfg, err := config.Load()
+	// Load configuration; changes are saved without environment overrides
+	load := config.Load
+	if configFlags.set != "" || configFlags.edit {
+		load = config.LoadFile
+	}
+	cfg, err := load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
@@ -92,6 +240,20 @@ func listConfig(cfg *config.Config) error {
 	fmt.Printf("Cleanup Interval:   %s\n", cfg.CleanupInterval)
 	fmt.Printf("Database Timeout:   %s\n", cfg.DatabaseTimeout)
 	fmt.Printf("UI Theme:           %s\n", cfg.UITheme)
+	fmt.Printf("Daemon Socket:      %s\n", daemon.Address(cfg.DaemonSocket))
+	if cfg.APIToken != "" {
+		fmt.Println("API Token:          (set)")
+	} else {
+		fmt.Println("API Token:          (not set)")
+	}
+	if cfg.SyncPath != "" {
+		fmt.Printf("Sync Path:          %s\n", cfg.SyncPath)
+	}
+	if cfg.RecordLeadingSpace {
+		fmt.Println("Leading Space:      recorded")
+	} else {
+		fmt.Println("Leading Space:      skipped")
+	}
 
 	fmt.Printf("\nEnabled Shells:     ")
 	for i, shell := range cfg.EnabledShells {
@@ -103,7 +265,7 @@ func listConfig(cfg *config.Config) error {
 	fmt.Println()
 
 	fmt.Printf("\nExclude Patterns:   ")
-	if len(cfg.ExcludePatterns) == 0 {
+	if len(cfg.ExcludePatterns) == 0 && len(cfg.ExcludeRules) == 0 {
 		fmt.Println("(none)")
 	} else {
 		fmt.Println()
@@ -111,8 +273,43 @@ func listConfig(cfg *config.Config) error {
 			fmt.Printf("  - %s\n", pattern)
 		}
 	}
+	for i, rule := range cfg.ExcludeRules {
+		match := config.ExcludeMatch{Source: fmt.Sprintf("rule %d", i), Rule: rule}
+		if match.Rule.Match == "" {
+			match.Rule.Match = config.MatchPrefix
+		}
+		fmt.Printf("  - %s\n", match.String())
+	}
+
+	fmt.Printf("\nRedact Patterns:    ")
+	if len(cfg.RedactPatterns) == 0 {
+		fmt.Println("(built-in only)")
+	} else {
+		fmt.Println()
+		for _, pattern := range cfg.RedactPatterns {
+			fmt.Printf("  - %s\n", pattern)
+		}
+	}
+
+	fmt.Printf("\nBlocked Commands:   ")
+	if len(cfg.BlockedCommands) == 0 {
+		fmt.Println("(built-in only)")
+	} else {
+		fmt.Println()
+		for _, command := range cfg.BlockedCommands {
+			fmt.Printf("  - %s\n", command)
+		}
+	}
 
 	fmt.Printf("\nConfiguration file: %s\n", config.GetConfigPath())
+	for _, name := range config.EnvVars() {
+		if value := os.Getenv(name); value != "" {
+			if name == config.EnvVar("api_token") {
+				value = "(set)"
+			}
+			fmt.Printf("Overridden by %s=%s\n", name, value)
+		}
+	}
 
 	return nil
 }
@@ -129,12 +326,20 @@ func getConfigValue(cfg *config.Config, key string) error {
 		fmt.Println(cfg.MaxCommands)
 	case "auto_cleanup", "autocleanup":
 		fmt.Println(cfg.AutoCleanup)
+	case "record_leading_space", "recordleadingspace":
+		fmt.Println(cfg.RecordLeadingSpace)
 	case "cleanup_interval", "cleanupinterval":
 		fmt.Println(cfg.CleanupInterval)
 	case "database_timeout", "databasetimeout":
 		fmt.Println(cfg.DatabaseTimeout)
 	case "ui_theme", "uitheme":
 		fmt.Println(cfg.UITheme)
+	case "daemon_socket", "daemonsocket":
+		fmt.Println(daemon.Address(cfg.DaemonSocket))
+	case "api_token", "apitoken":
+		fmt.Println(cfg.APIToken)
+	case "sync_path", "syncpath":
+		fmt.Println(cfg.SyncPath)
 	case "enabled_shells", "enabledshells":
 		for i, shell := range cfg.EnabledShells {
 			if i > 0 {
@@ -147,6 +352,14 @@ func getConfigValue(cfg *config.Config, key string) error {
 		for _, pattern := range cfg.ExcludePatterns {
 			fmt.Println(pattern)
 		}
+	case "redact_patterns", "redactpatterns":
+		for _, pattern := range cfg.RedactPatterns {
+			fmt.Println(pattern)
+		}
+	case "blocked_commands", "blockedcommands":
+		for _, command := range cfg.BlockedCommands {
+			fmt.Println(command)
+		}
 	default:
 		return fmt.Errorf("unknown configuration key: %s", key)
 	}
@@ -184,8 +397,32 @@ func setConfigValue(cfg *config.Config, keyValue string) error {
 			return fmt.Errorf("invalid auto_cleanup value: %w", err)
 		}
 		cfg.AutoCleanup = autoCleanup
+	case "record_leading_space", "recordleadingspace":
+		recordLeadingSpace, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid record_leading_space value: %w", err)
+		}
+		cfg.RecordLeadingSpace = recordLeadingSpace
+	case "cleanup_interval", "cleanupinterval":
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid cleanup_interval value: %w", err)
+		}
+		cfg.CleanupInterval = interval
+	case "database_timeout", "databasetimeout":
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid database_timeout value: %w", err)
+		}
+		cfg.DatabaseTimeout = timeout
 	case "ui_theme", "uitheme":
 		cfg.UITheme = value
+	case "daemon_socket", "daemonsocket":
+		cfg.DaemonSocket = value
+	case "api_token", "apitoken":
+		cfg.APIToken = value
+	case "sync_path", "syncpath":
+		cfg.SyncPath = value
 	case "exclude_patterns", "excludepatterns":
 		patterns := strings.Split(value, ",")
 		for i := range patterns {
@@ -322,6 +559,12 @@ func shellTypeToString(shell history.ShellType) string {
 		return "zsh"
 	case history.Cmd:
 		return "cmd"
+	case history.Fish:
+		return "fish"
+	case history.Nushell:
+		return "nushell"
+	case history.Elvish:
+		return "elvish"
 	default:
 		return "unknown"
 	}
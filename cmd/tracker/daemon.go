@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/daemon"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var daemonFlags struct {
+	socket        string
+	batchSize     int
+	flushInterval time.Duration
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the background recorder",
+	Long: `Run a long-lived recorder that keeps the history database open and accepts
+commands from the shell hooks over a Unix domain socket (a named pipe on
+Windows). Commands are written in batches, so recording no longer opens the
+database on every prompt.
+
+The daemon runs in the foreground until interrupted or until
+"tracker daemon stop" is run; start it from your login scripts or a service
+manager. While it is not running, "tracker record" writes directly to the
+database as before.
+
+Examples:
+  tracker daemon &
+  tracker daemon status
+  tracker daemon stop`,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: initializeDBCommand,
+	RunE:              runDaemon,
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the recorder daemon is running",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStatus,
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the recorder daemon after writing queued commands",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStop,
+}
+
+func init() {
+	daemonCmd.PersistentFlags().StringVar(&daemonFlags.socket, "socket", "", "Socket path or pipe name (default from config)")
+	daemonCmd.Flags().IntVar(&daemonFlags.batchSize, "batch-size", daemon.DefaultBatchSize, "Maximum commands written per transaction")
+	daemonCmd.Flags().DurationVar(&daemonFlags.flushInterval, "flush-interval", daemon.DefaultFlushInterval, "Longest time a command waits before being written")
+
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
+
+// daemonAddress returns the socket given on the command line or in the config
+func daemonAddress() string {
+	if daemonFlags.socket != "" {
+		return daemonFlags.socket
+	}
+	return daemon.Address(config.Global().DaemonSocket)
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	cfg := config.Global()
+
+	storagePath, err := filepath.Abs(cfg.StoragePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve storage path: %w", err)
+	}
+
+	// The daemon runs the application so automatic cleanup and the cache
+	// follow edits to the configuration file; main shuts it down on return
+	if err := initializeApp(cmd, args); err != nil {
+		return err
+	}
+
+	store, ok := globalApp.GetStorage().(storage.BatchStorageEngine)
+	if !ok {
+		return fmt.Errorf("storage does not support batch saves")
+	}
+
+	address := daemonAddress()
+	listener, err := daemon.Listen(address)
+	if err != nil {
+		return err
+	}
+
+	server := daemon.NewServer(store, daemon.Options{
+		BatchSize:     daemonFlags.batchSize,
+		FlushInterval: daemonFlags.flushInterval,
+	})
+
+	// Take over interrupt handling from main so queued commands are written
+	// before the process exits
+	signal.Reset(os.Interrupt, syscall.SIGTERM)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		if _, ok := <-sigChan; ok {
+			server.Close()
+		}
+	}()
+
+	if err := globalApp.Start(); err != nil {
+		return err
+	}
+	if err := globalApp.WatchConfig(); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "⚠ Configuration changes will not be reloaded: %v\n", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Recorder daemon listening on %s\n", address)
+	fmt.Fprintf(cmd.OutOrStdout(), "Writing to %s\n", storagePath)
+	if storagePath != cfg.StoragePath {
+		fmt.Fprintf(cmd.ErrOrStderr(), "⚠ storage_path %q is relative; hooks that fall back to direct writes will not use the same database\n", cfg.StoragePath)
+	}
+
+	if err := server.Serve(listener); err != nil {
+		return err
+	}
+
+	stats := server.Stats()
+	fmt.Fprintf(cmd.OutOrStdout(), "✓ Daemon stopped: %d commands saved, %d failed\n", stats.Saved, stats.Failed)
+	return nil
+}
+
+func runDaemonStatus(cmd *cobra.Command, args []string) error {
+	address := daemonAddress()
+
+	stats, err := daemon.NewClient(address).Ping()
+	if err != nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Daemon is not running (%s)\n", address)
+		return nil
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Daemon is running on %s\n", address)
+	fmt.Fprintf(cmd.OutOrStdout(), "  Uptime:   %s\n", time.Since(stats.Started).Round(time.Second))
+	fmt.Fprintf(cmd.OutOrStdout(), "  Received: %d\n", stats.Received)
+	fmt.Fprintf(cmd.OutOrStdout(), "  Saved:    %d\n", stats.Saved)
+	fmt.Fprintf(cmd.OutOrStdout(), "  Failed:   %d\n", stats.Failed)
+	fmt.Fprintf(cmd.OutOrStdout(), "  Queued:   %d\n", stats.Queued)
+	return nil
+}
+
+func runDaemonStop(cmd *cobra.Command, args []string) error {
+	address := daemonAddress()
+
+	if err := daemon.NewClient(address).Shutdown(); err != nil {
+		return fmt.Errorf("failed to stop daemon on %s: %w", address, err)
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "✓ Daemon is shutting down")
+	return nil
+}
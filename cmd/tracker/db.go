@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var dbMigrateFlags struct {
+	status bool
+	to     int
+}
+
+var dbKeyFlags struct {
+	keyFile       string
+	passphrase    bool
+	removeBackups bool
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the history database",
+	Long: `Maintenance commands for the command history database.
+
+These commands open the database directly and do not apply pending schema
+migrations on startup, so the schema can be inspected before upgrading.`,
+	PersistentPreRunE: initializeDBCommand,
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Show or change the database schema version",
+	Long: `Upgrade the database schema to the latest version, or to a specific
+version with --to. Passing a version lower than the current one reverts the
+newer migrations. A backup of the database is written next to it before any
+upgrade of a database that already holds history.
+
+Examples:
+  tracker db migrate --status
+  tracker db migrate
+  tracker db migrate --to 1`,
+	RunE: runDBMigrate,
+}
+
+var dbEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt stored command text",
+	Long: `Seal the text of every stored command with a new key, and every command
+recorded afterwards. The key is read from a key file, created with a random
+key when missing (default: db.key in the user configuration directory), or
+derived from a passphrase with --passphrase.
+
+A passphrase must then be supplied in CHT_DB_PASSPHRASE to every tracker
+process that opens the database, including the shell hooks; a key file is
+found through the database itself, or CHT_DB_KEY_FILE.
+
+Examples:
+  tracker db encrypt
+  tracker db encrypt --key-file ~/.secrets/history.key
+  tracker db encrypt --passphrase`,
+	RunE: runDBEncrypt,
+}
+
+var dbDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Store command text unencrypted again",
+	Long: `Decrypt every stored command and stop encrypting new ones. The current
+key is read from CHT_DB_PASSPHRASE or CHT_DB_KEY_FILE, the recorded key file,
+or a passphrase prompt.`,
+	RunE: runDBDecrypt,
+}
+
+var dbRekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Encrypt stored command text with a new key",
+	Long: `Re-seal every stored command with a new key from a key file or, with
+--passphrase, a new passphrase. The current key is read as for decrypt.
+
+Examples:
+  tracker db rekey --key-file ~/.secrets/history-2.key
+  tracker db rekey --passphrase`,
+	RunE: runDBRekey,
+}
+
+func init() {
+	dbMigrateCmd.Flags().BoolVar(&dbMigrateFlags.status, "status", false, "Show applied and pending migrations")
+	dbMigrateCmd.Flags().IntVar(&dbMigrateFlags.to, "to", -1, "Migrate up or down to this schema version")
+
+	for _, cmd := range []*cobra.Command{dbEncryptCmd, dbRekeyCmd} {
+		cmd.Flags().StringVar(&dbKeyFlags.keyFile, "key-file", "", "Key file to encrypt with, created when missing")
+		cmd.Flags().BoolVar(&dbKeyFlags.passphrase, "passphrase", false, "Derive the key from a passphrase read from the terminal or stdin")
+		cmd.Flags().BoolVar(&dbKeyFlags.removeBackups, "remove-backups", false, "Delete migration backups that still hold plaintext commands")
+	}
+
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbEncryptCmd)
+	dbCmd.AddCommand(dbDecryptCmd)
+	dbCmd.AddCommand(dbRekeyCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+// initializeDBCommand loads configuration without opening the application,
+// which would otherwise migrate the database to the latest schema
+func initializeDBCommand(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	config.SetGlobal(cfg)
+	return nil
+}
+
+// openDatabase opens the configured SQLite database without migrating it
+func openDatabase() (*storage.SQLiteStorage, error) {
+	cfg := config.Global()
+
+	sqliteStorage := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := sqliteStorage.Open(); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return sqliteStorage, nil
+}
+
+func runDBMigrate(cmd *cobra.Command, args []string) error {
+	sqliteStorage, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer sqliteStorage.Close()
+
+	if dbMigrateFlags.status {
+		return printMigrationStatus(sqliteStorage)
+	}
+
+	current, err := sqliteStorage.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	target := storage.LatestSchemaVersion()
+	if cmd.Flags().Changed("to") {
+		target = dbMigrateFlags.to
+	}
+
+	if target == current {
+		fmt.Printf("Database is already at schema version %d\n", current)
+		return nil
+	}
+
+	if err := sqliteStorage.MigrateTo(target); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	if backup := sqliteStorage.LastBackupPath(); backup != "" {
+		fmt.Printf("Backup written to %s\n", backup)
+	}
+	fmt.Printf("✓ Migrated database from schema version %d to %d\n", current, target)
+
+	return nil
+}
+
+func printMigrationStatus(sqliteStorage *storage.SQLiteStorage) error {
+	current, err := sqliteStorage.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	statuses, err := sqliteStorage.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Database: %s\n", config.Global().StoragePath)
+	fmt.Printf("Schema version: %d (latest: %d)\n\n", current, storage.LatestSchemaVersion())
+
+	for _, status := range statuses {
+		if status.Applied {
+			fmt.Printf("  [x] %3d  %-40s applied %s\n", status.Version, status.Description, status.AppliedAt.Local().Format("2006-01-02 15:04:05"))
+		} else {
+			fmt.Printf("  [ ] %3d  %-40s pending\n", status.Version, status.Description)
+		}
+	}
+
+	if current > storage.LatestSchemaVersion() {
+		fmt.Printf("\nWarning: database was written by a newer release of tracker\n")
+	}
+
+	return nil
+}
+
+func runDBEncrypt(cmd *cobra.Command, args []string) error {
+	sqliteStorage, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer sqliteStorage.Close()
+
+	keys, err := newKeySource(cmd)
+	if err != nil {
+		return err
+	}
+
+	count, err := sqliteStorage.EnableEncryption(keys)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt database: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "✓ Encrypted %d command(s)\n", count)
+	if keys.Passphrase != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "Set %s for every tracker process, including the shell hooks\n", storage.PassphraseEnv)
+	} else if info, err := sqliteStorage.EncryptionInfo(); err == nil {
+		fmt.Fprintf(cmd.OutOrStdout(), "Key file: %s (keep a copy: the history cannot be read without it)\n", info.KeyFile)
+	}
+	printEncryptionNotes(cmd)
+
+	return nil
+}
+
+func runDBDecrypt(cmd *cobra.Command, args []string) error {
+	sqliteStorage, err := openEncryptedDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer sqliteStorage.Close()
+
+	count, err := sqliteStorage.DisableEncryption()
+	if err != nil {
+		return fmt.Errorf("failed to decrypt database: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "✓ Decrypted %d command(s)\n", count)
+
+	return nil
+}
+
+func runDBRekey(cmd *cobra.Command, args []string) error {
+	sqliteStorage, err := openEncryptedDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer sqliteStorage.Close()
+
+	keys, err := newKeySource(cmd)
+	if err != nil {
+		return err
+	}
+
+	count, err := sqliteStorage.Rekey(keys)
+	if err != nil {
+		return fmt.Errorf("failed to rekey database: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "✓ Re-encrypted %d command(s) with the new key\n", count)
+	printEncryptionNotes(cmd)
+
+	return nil
+}
+
+// openEncryptedDatabase opens the database without migrating it and loads
+// its current key, prompting for the passphrase when none is set
+func openEncryptedDatabase(cmd *cobra.Command) (*storage.SQLiteStorage, error) {
+	sqliteStorage, err := openDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := sqliteStorage.EncryptionInfo()
+	if err != nil {
+		sqliteStorage.Close()
+		return nil, err
+	}
+	if !info.Enabled {
+		sqliteStorage.Close()
+		return nil, fmt.Errorf("database is not encrypted")
+	}
+
+	keys := storage.KeySourceFromEnv()
+	if info.KDF == storage.KDFArgon2id && keys.Passphrase == "" {
+		if keys.Passphrase, err = readPassphrase(cmd, "Current passphrase: ", false); err != nil {
+			sqliteStorage.Close()
+			return nil, err
+		}
+	}
+
+	sqliteStorage.SetKeySource(keys)
+	if err := sqliteStorage.Unlock(); err != nil {
+		sqliteStorage.Close()
+		return nil, err
+	}
+
+	return sqliteStorage, nil
+}
+
+// newKeySource returns the key to encrypt with from the --key-file and
+// --passphrase flags
+func newKeySource(cmd *cobra.Command) (storage.KeySource, error) {
+	if dbKeyFlags.passphrase {
+		if dbKeyFlags.keyFile != "" {
+			return storage.KeySource{}, fmt.Errorf("--passphrase and --key-file cannot be used together")
+		}
+		passphrase, err := readPassphrase(cmd, "New passphrase: ", true)
+		if err != nil {
+			return storage.KeySource{}, err
+		}
+		return storage.KeySource{Passphrase: passphrase}, nil
+	}
+
+	return storage.KeySource{KeyFile: dbKeyFlags.keyFile}, nil
+}
+
+// readPassphrase prompts for a passphrase without echo on a terminal, asking
+// twice when confirm is set; otherwise it reads the first line of stdin
+func readPassphrase(cmd *cobra.Command, prompt string, confirm bool) (string, error) {
+	in := cmd.InOrStdin()
+
+	file, ok := in.(*os.File)
+	if !ok || !term.IsTerminal(file.Fd()) {
+		line, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		return checkPassphrase(strings.TrimRight(line, "\r\n"))
+	}
+
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(cmd.ErrOrStderr(), prompt)
+		data, err := term.ReadPassword(file.Fd())
+		fmt.Fprintln(cmd.ErrOrStderr())
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		return string(data), nil
+	}
+
+	passphrase, err := read(prompt)
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		again, err := read("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return checkPassphrase(passphrase)
+}
+
+// checkPassphrase rejects an empty passphrase
+func checkPassphrase(passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase cannot be empty")
+	}
+	return passphrase, nil
+}
+
+// printEncryptionNotes warns about copies of the history that the key does
+// not protect
+func printEncryptionNotes(cmd *cobra.Command) {
+	out := cmd.OutOrStdout()
+
+	backups, err := storage.PlaintextBackups(config.Global().StoragePath)
+	if err != nil {
+		fmt.Fprintf(out, "\nWarning: %v\n", err)
+	}
+	if dbKeyFlags.removeBackups {
+		for _, backup := range backups {
+			if err := os.Remove(backup); err != nil {
+				fmt.Fprintf(out, "Warning: failed to remove %s: %v\n", backup, err)
+				continue
+			}
+			fmt.Fprintf(out, "Removed plaintext migration backup %s\n", backup)
+		}
+	} else if len(backups) > 0 {
+		fmt.Fprintf(out, "\nWarning: these migration backups still hold plaintext commands:\n")
+		for _, backup := range backups {
+			fmt.Fprintf(out, "  %s\n", backup)
+		}
+		fmt.Fprintf(out, "Delete them once they are no longer needed, or pass --remove-backups.\n")
+	}
+
+	fmt.Fprintf(out, "Restart a running tracker serve so it reads with the new key\n")
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/exporter"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+
+	"github.com/spf13/cobra"
+)
+
+var exportFlags struct {
+	format   string
+	output   string
+	dir      string
+	pattern  string
+	shell    string
+	since    string
+	until    string
+	exitCode int
+	host     string
+	user     string
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export --format jsonl|csv|bash|zsh|script",
+	Short: "Export command history",
+	Long: `Export command history, oldest first, to standard output or a file.
+
+Formats:
+  jsonl  one JSON record per line; re-import with "tracker import --from jsonl"
+  csv    spreadsheet-friendly columns with a header row
+  bash   bash history with "#<unix time>" lines (HISTTIMEFORMAT layout)
+  zsh    zsh extended history (": <start>:<elapsed>;<command>")
+  script shell script that replays the commands, changing to each directory
+
+--since and --until accept a duration ago ("6h", "2d", "1w") or a date
+("2006-01-02" or RFC 3339).
+
+Examples:
+  tracker export --format jsonl -o history.jsonl
+  tracker export --format csv --dir ~/work/api --since 1w
+  tracker export --format zsh --exit-code 0 >> ~/.zsh_history`,
+	Args: cobra.NoArgs,
+	RunE: runExport,
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportFlags.format, "format", "f", "jsonl", "Output format (jsonl, csv, bash, zsh, script)")
+	exportCmd.Flags().StringVarP(&exportFlags.output, "output", "o", "", "Write to file instead of standard output")
+	exportCmd.Flags().StringVarP(&exportFlags.dir, "dir", "d", "", "Only export commands run in this directory")
+	exportCmd.Flags().StringVarP(&exportFlags.pattern, "pattern", "p", "", "Only export commands containing this text")
+	exportCmd.Flags().StringVar(&exportFlags.shell, "shell", "", "Only export commands from this shell (bash, zsh, powershell, cmd, fish, nushell, elvish)")
+	exportCmd.Flags().StringVar(&exportFlags.since, "since", "", "Only export commands run at or after this time")
+	exportCmd.Flags().StringVar(&exportFlags.until, "until", "", "Only export commands run at or before this time")
+	exportCmd.Flags().IntVar(&exportFlags.exitCode, "exit-code", 0, "Only export commands that exited with this code")
+	exportCmd.Flags().StringVar(&exportFlags.host, "host", "", "Only export commands run on this host")
+	exportCmd.Flags().StringVar(&exportFlags.user, "user", "", "Only export commands run by this user")
+
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	format, err := exporter.ParseFormat(exportFlags.format)
+	if err != nil {
+		return err
+	}
+
+	filters, err := buildExportFilters(cmd, time.Now())
+	if err != nil {
+		return err
+	}
+
+	cfg := config.Global()
+	sqliteStorage := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := sqliteStorage.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer sqliteStorage.Close()
+
+	var out io.Writer = cmd.OutOrStdout()
+	if exportFlags.output != "" {
+		file, err := os.Create(exportFlags.output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	count, err := exportCommands(sqliteStorage, filters, format, out)
+	if err != nil {
+		return err
+	}
+
+	if exportFlags.output != "" {
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Exported %d commands to %s\n", count, exportFlags.output)
+	}
+	return nil
+}
+
+// exportCommands streams matching commands to out and returns how many were written
+func exportCommands(store storage.StreamingStorageEngine, filters storage.CommandFilters, format exporter.Format, out io.Writer) (int, error) {
+	writer, err := exporter.NewWriter(format, out)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = store.StreamCommands(filters, func(record history.CommandRecord) error {
+		count++
+		return writer.Write(record)
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to export commands: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return count, fmt.Errorf("failed to write export: %w", err)
+	}
+
+	return count, nil
+}
+
+// buildExportFilters converts the export flags into storage filters
+func buildExportFilters(cmd *cobra.Command, now time.Time) (storage.CommandFilters, error) {
+	filters := storage.CommandFilters{
+		Pattern:  exportFlags.pattern,
+		Hostname: exportFlags.host,
+		Username: exportFlags.user,
+	}
+
+	if exportFlags.dir != "" {
+		filters.Directory = normalizeDirectoryPath(exportFlags.dir)
+	}
+
+	if exportFlags.shell != "" {
+		filters.ShellType = parseShellType(exportFlags.shell)
+		if filters.ShellType == history.Unknown {
+			return filters, fmt.Errorf("unknown shell type: %s", exportFlags.shell)
+		}
+	}
+
+	var err error
+	if exportFlags.since != "" {
+		if filters.StartTime, err = parseTimeBound(exportFlags.since, now); err != nil {
+			return filters, fmt.Errorf("invalid --since value: %w", err)
+		}
+	}
+	if exportFlags.until != "" {
+		if filters.EndTime, err = parseTimeBound(exportFlags.until, now); err != nil {
+			return filters, fmt.Errorf("invalid --until value: %w", err)
+		}
+	}
+
+	if cmd.Flags().Changed("exit-code") {
+		exitCode := exportFlags.exitCode
+		filters.ExitCode = &exitCode
+	}
+
+	return filters, nil
+}
+
+// parseTimeBound accepts an absolute date or a duration before now
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, nil
+		}
+	}
+
+	duration, err := parseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a duration like 2d or a date like 2006-01-02: %s", value)
+	}
+
+	return now.Add(-duration), nil
+}
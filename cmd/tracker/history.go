@@ -17,6 +17,8 @@ var historyFlags struct {
 	limit         int
 	since         string
 	shell         string
+	session       string
+	cursor        string
 	noInteractive bool
 }
 
@@ -24,7 +26,14 @@ var historyCmd = &cobra.Command{
 	Use:   "history",
 	Short: "Show command history",
 	Long: `Display command history for the current or specified directory. 
-By default, launches an interactive browser. Use --no-interactive for simple list output.`,
+By default, launches an interactive browser. Use --no-interactive for simple list output.
+
+Use --session to show the commands typed in one terminal session across all
+directories: "current" selects the session of the calling shell, or pass a
+session ID as shown in the browser's session view (press S).
+
+With --no-interactive the newest --limit commands are listed (0 lists all). When
+more remain, the listing ends with a cursor; pass it to --cursor to continue.`,
 	RunE: runHistory,
 }
 
@@ -32,7 +41,9 @@ func init() {
 	historyCmd.Flags().StringVarP(&historyFlags.dir, "dir", "d", "", "Show history for specific directory")
 	historyCmd.Flags().IntVarP(&historyFlags.limit, "limit", "n", 50, "Limit number of commands to show")
 	historyCmd.Flags().StringVar(&historyFlags.since, "since", "", "Show commands since time (e.g., '24h', '7d')")
-	historyCmd.Flags().StringVar(&historyFlags.shell, "shell", "", "Filter by shell type (powershell, bash, zsh, cmd)")
+	historyCmd.Flags().StringVar(&historyFlags.shell, "shell", "", "Filter by shell type (powershell, bash, zsh, cmd, fish, nushell, elvish)")
+	historyCmd.Flags().StringVar(&historyFlags.session, "session", "", "Show history for a terminal session ('current' or a session ID)")
+	historyCmd.Flags().StringVar(&historyFlags.cursor, "cursor", "", "Continue a --no-interactive listing after the cursor it printed")
 	historyCmd.Flags().BoolVar(&historyFlags.noInteractive, "no-interactive", false, "Disable interactive mode, print list")
 
 	rootCmd.AddCommand(historyCmd)
@@ -53,6 +64,11 @@ func runHistory(cmd *cobra.Command, args []string) error {
 	}
 	defer storageEngine.Close()
 
+	// Session mode spans directories, so it bypasses the directory lookup
+	if historyFlags.session != "" {
+		return runSessionHistory(storageEngine, historyFlags.session)
+	}
+
 	// Determine directory
 	dir := historyFlags.dir
 	if dir == "" {
@@ -76,19 +92,15 @@ func runHistory(cmd *cobra.Command, args []string) error {
 	}
 
 	// Non-interactive mode: print list
-	commands, err := storageEngine.GetCommandsByDirectory(dir)
+	filters := historyFilters()
+	filters.Directory = dir
+	commands, nextCursor, err := listHistory(storageEngine, filters, func() ([]history.CommandRecord, error) {
+		return storageEngine.GetCommandsByDirectory(dir)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to get commands: %w", err)
 	}
 
-	// Apply filters
-	commands = applyHistoryFilters(commands)
-
-	// Apply limit
-	if historyFlags.limit > 0 && len(commands) > historyFlags.limit {
-		commands = commands[:historyFlags.limit]
-	}
-
 	// Print commands
 	if len(commands) == 0 {
 		fmt.Println("No commands found in history.")
@@ -101,10 +113,130 @@ func runHistory(cmd *cobra.Command, args []string) error {
 	for i, cmd := range commands {
 		fmt.Printf("%4d  %s  %s\n", i+1, cmd.Timestamp.Format("2006-01-02 15:04:05"), cmd.Command)
 	}
+	printNextCursor(nextCursor)
+
+	return nil
+}
+
+// runSessionHistory shows the commands recorded by a single terminal session
+func runSessionHistory(storageEngine storage.StorageEngine, session string) error {
+	sessionID, err := resolveSessionID(session)
+	if err != nil {
+		return err
+	}
+
+	sessionStorage, ok := storageEngine.(storage.SessionStorageEngine)
+	if !ok {
+		return fmt.Errorf("session history is not supported by this storage engine")
+	}
+
+	if !historyFlags.noInteractive {
+		b := browser.NewBrowser(storageEngine)
+		return b.ShowSessionHistory(sessionID)
+	}
+
+	filters := historyFilters()
+	filters.SessionID = sessionID
+	commands, nextCursor, err := listHistory(storageEngine, filters, func() ([]history.CommandRecord, error) {
+		return sessionStorage.GetCommandsBySession(sessionID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get session commands: %w", err)
+	}
+
+	if len(commands) == 0 {
+		fmt.Printf("No commands found for session %s.\n", sessionID)
+		return nil
+	}
+
+	fmt.Printf("Command history for session: %s\n", sessionID)
+	fmt.Printf("Found %d command(s)\n\n", len(commands))
+
+	for i, cmd := range commands {
+		fmt.Printf("%4d  %s  %s  %s\n", i+1, cmd.Timestamp.Format("2006-01-02 15:04:05"), cmd.Directory, cmd.Command)
+	}
+	printNextCursor(nextCursor)
 
 	return nil
 }
 
+// historyFilters converts the --since and --shell flags into storage filters
+func historyFilters() storage.CommandFilters {
+	var filters storage.CommandFilters
+	if historyFlags.since != "" {
+		if duration, err := parseDuration(historyFlags.since); err == nil {
+			filters.StartTime = time.Now().Add(-duration)
+		}
+	}
+	if historyFlags.shell != "" {
+		filters.ShellType = parseShellType(historyFlags.shell)
+	}
+	return filters
+}
+
+// listHistory returns the newest --limit commands matching the filters,
+// starting after --cursor, and the cursor of the following page. Paged
+// storage applies the filters and the limit in SQL; other engines fall back
+// to loading every command with load and filtering in memory.
+func listHistory(storageEngine storage.StorageEngine, filters storage.CommandFilters, load func() ([]history.CommandRecord, error)) ([]history.CommandRecord, string, error) {
+	pagedStorage, ok := storageEngine.(storage.PagedStorageEngine)
+	if !ok {
+		commands, err := load()
+		if err != nil {
+			return nil, "", err
+		}
+		commands = applyHistoryFilters(commands)
+		if historyFlags.limit > 0 && len(commands) > historyFlags.limit {
+			commands = commands[:historyFlags.limit]
+		}
+		return commands, "", nil
+	}
+
+	if historyFlags.limit > 0 {
+		page, err := pagedStorage.GetCommandsPage(filters, historyFlags.cursor, historyFlags.limit)
+		if err != nil {
+			return nil, "", err
+		}
+		return page.Commands, page.NextCursor, nil
+	}
+
+	// No limit: read page by page to the end
+	var commands []history.CommandRecord
+	cursor := historyFlags.cursor
+	for {
+		page, err := pagedStorage.GetCommandsPage(filters, cursor, storage.DefaultPageSize)
+		if err != nil {
+			return nil, "", err
+		}
+		commands = append(commands, page.Commands...)
+		if page.NextCursor == "" {
+			return commands, "", nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// printNextCursor tells the user how to continue a limited listing
+func printNextCursor(cursor string) {
+	if cursor != "" {
+		fmt.Printf("\nMore commands available; continue with --cursor %s\n", cursor)
+	}
+}
+
+// resolveSessionID maps "current" to the session of the calling shell
+func resolveSessionID(session string) (string, error) {
+	if session != "current" {
+		return session, nil
+	}
+
+	sessionID := os.Getenv("CHT_SESSION_ID")
+	if sessionID == "" {
+		return "", fmt.Errorf("no current session: CHT_SESSION_ID is not set (re-run 'tracker setup' to update the shell integration)")
+	}
+
+	return sessionID, nil
+}
+
 func applyHistoryFilters(commands []history.CommandRecord) []history.CommandRecord {
 	var filtered []history.CommandRecord
 
@@ -178,6 +310,12 @@ func parseShellType(s string) history.ShellType {
 		return history.Zsh
 	case "cmd":
 		return history.Cmd
+	case "fish":
+		return history.Fish
+	case "nushell", "nu":
+		return history.Nushell
+	case "elvish":
+		return history.Elvish
 	default:
 		return history.Unknown
 	}
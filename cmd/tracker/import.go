@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/importer"
+	"github.com/ValGrace/command-history-tracker/internal/interceptor"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+
+	"github.com/spf13/cobra"
+)
+
+var importFlags struct {
+	from string
+	dir  string
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import --from bash|zsh|powershell|fish|jsonl [file]",
+	Short: "Import an existing shell history file",
+	Long: `Import every command from a shell history file into the database.
+
+Without a file argument the shell's default history location is used:
+  bash        ~/.bash_history
+  zsh         $HISTFILE or ~/.zsh_history (plain or extended format)
+  powershell  PSReadLine ConsoleHost_history.txt
+  fish        ~/.local/share/fish/fish_history
+
+History files do not record where a command ran, so imported commands are
+filed under --dir (your home directory by default) and tagged "imported".
+Importing the same file again skips commands that are already stored.
+
+Imported commands go through the same rules as captured ones: secrets are
+masked and tagged "redacted", and commands matching exclude_patterns,
+exclude_rules or a project's do_not_record are left out.
+
+--from jsonl reads a "tracker export --format jsonl" file and restores every
+record as exported, including its ID, directory, shell and tags.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runImport,
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFlags.from, "from", "", "History format to read (bash, zsh, powershell, fish, jsonl)")
+	importCmd.Flags().StringVarP(&importFlags.dir, "dir", "d", "", "Directory to record imported commands under (default: home directory)")
+	_ = importCmd.MarkFlagRequired("from")
+
+	rootCmd.AddCommand(importCmd)
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	if strings.EqualFold(importFlags.from, "jsonl") {
+		return runJSONLinesImport(cmd, args)
+	}
+
+	shell, err := parseImportShell(importFlags.from)
+	if err != nil {
+		return err
+	}
+
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		path, err = importer.DefaultHistoryFile(shell)
+		if err != nil {
+			return err
+		}
+	}
+
+	dir := importFlags.dir
+	if dir == "" {
+		dir, err = os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home directory: %w", err)
+		}
+	}
+	dir = normalizeDirectoryPath(dir)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat history file: %w", err)
+	}
+
+	entries, err := importer.Parse(shell, file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s history: %w", shell, err)
+	}
+
+	sqliteStorage, err := openImportStorage()
+	if err != nil {
+		return err
+	}
+	defer sqliteStorage.Close()
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Importing %d %s commands from %s\n", len(entries), shell, path)
+
+	result, err := importer.NewImporter(sqliteStorage).Import(shell, entries, importer.Options{
+		Directory: dir,
+		Fallback:  info.ModTime(),
+		Filter:    importFilter(sqliteStorage),
+		Progress:  func(p importer.Progress) { printImportProgress(out, p) },
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "✓ Imported %d commands (%d already present) into %s\n", result.Imported, result.Duplicates, dir)
+	printImportExcluded(out, result)
+	return nil
+}
+
+// runJSONLinesImport restores records from a JSON Lines export
+func runJSONLinesImport(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("a file is required when importing jsonl")
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer file.Close()
+
+	records, err := importer.ParseJSONLines(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse jsonl export: %w", err)
+	}
+
+	sqliteStorage, err := openImportStorage()
+	if err != nil {
+		return err
+	}
+	defer sqliteStorage.Close()
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Importing %d records from %s\n", len(records), args[0])
+
+	result, err := importer.NewImporter(sqliteStorage).ImportRecords(records, importer.Options{
+		Filter:   importFilter(sqliteStorage),
+		Progress: func(p importer.Progress) { printImportProgress(out, p) },
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "✓ Imported %d commands (%d already present)\n", result.Imported, result.Duplicates)
+	printImportExcluded(out, result)
+	return nil
+}
+
+// openImportStorage opens the configured database for batch writes
+func openImportStorage() (*storage.SQLiteStorage, error) {
+	cfg := config.Global()
+
+	sqliteStorage := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := sqliteStorage.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	return sqliteStorage, nil
+}
+
+// importFilter masks secrets and applies the exclude rules and project
+// configuration to imported records, as capture does for new commands
+func importFilter(store *storage.SQLiteStorage) func(history.CommandRecord) (*history.CommandRecord, error) {
+	return interceptor.NewCommandCapture(store, config.Global()).FilterRecord
+}
+
+// printImportExcluded reports commands the configuration left out
+func printImportExcluded(out io.Writer, result *importer.Progress) {
+	if result.Excluded > 0 {
+		fmt.Fprintf(out, "  %d commands were left out by exclude rules or do_not_record\n", result.Excluded)
+	}
+}
+
+// printImportProgress reports a finished batch
+func printImportProgress(out io.Writer, p importer.Progress) {
+	percent := 100
+	if p.Total > 0 {
+		percent = p.Processed * 100 / p.Total
+	}
+	fmt.Fprintf(out, "  %3d%%  %d/%d processed, %d new, %d duplicates, %d excluded\n", percent, p.Processed, p.Total, p.Imported, p.Duplicates, p.Excluded)
+}
+
+// parseImportShell maps the --from value to a shell type with a supported history format
+func parseImportShell(s string) (history.ShellType, error) {
+	switch strings.ToLower(s) {
+	case "bash":
+		return history.Bash, nil
+	case "zsh":
+		return history.Zsh, nil
+	case "powershell", "pwsh":
+		return history.PowerShell, nil
+	case "fish":
+		return history.Fish, nil
+	default:
+		return history.Unknown, fmt.Errorf("unsupported history format %q (expected bash, zsh, powershell or fish)", s)
+	}
+}
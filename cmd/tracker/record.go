@@ -11,18 +11,25 @@ var recordCmd = &cobra.Command{
 	Use:   "record",
 	Short: "Record a command to history",
 	Long: `Record a command to the command history database. This command is typically 
-called automatically by shell hooks to capture executed commands.`,
-	RunE: runRecord,
+called automatically by shell hooks to capture executed commands.
+
+When "tracker daemon" is running, the command is handed to it over a local
+socket instead of opening the database, which keeps prompts fast. If the
+daemon is not running the command is written directly.`,
+	PersistentPreRunE: initializeRecordCommand,
+	RunE:              runRecord,
 }
 
 var recordFlags struct {
 	fromArgs bool
 	test     bool
+	noDaemon bool
 }
 
 func init() {
 	recordCmd.Flags().BoolVar(&recordFlags.fromArgs, "from-args", false, "Record command from command line arguments")
 	recordCmd.Flags().BoolVar(&recordFlags.test, "test", false, "Test command recording functionality")
+	recordCmd.Flags().BoolVar(&recordFlags.noDaemon, "no-daemon", false, "Write directly to the database even if the daemon is running")
 
 	rootCmd.AddCommand(recordCmd)
 }
@@ -42,7 +49,7 @@ func runRecord(cmd *cobra.Command, args []string) error {
 	}
 
 	// Default: record from environment variables
-	if err := interceptor.RecordCommand(); err != nil {
+	if err := interceptor.RecordCommand(!recordFlags.noDaemon); err != nil {
 		// Don't print error to stderr as it might interfere with shell output
 		// Instead, log to a file or silently fail
 		return nil
@@ -50,3 +57,10 @@ func runRecord(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// initializeRecordCommand skips application start-up, which opens and migrates
+// the database; the recorder loads its own configuration and only touches the
+// database when the daemon is unavailable
+func initializeRecordCommand(cmd *cobra.Command, args []string) error {
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/interceptor"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+
+	"github.com/spf13/cobra"
+)
+
+var redactFlags struct {
+	dryRun bool
+	dir    string
+}
+
+var redactCmd = &cobra.Command{
+	Use:   "redact",
+	Short: "Mask secrets in stored command history",
+	Long: `Scan stored commands for secrets and mask them, the same way new commands
+are masked before they are recorded. Detected secrets include API tokens,
+passwords in variables and flags, credentials in URLs, authorization headers,
+high-entropy strings and the patterns listed in redact_patterns, including
+those of the project configuration found from each command's directory.
+
+Masked commands are tagged "redacted". Afterwards the database is vacuumed so
+the original text is not left behind on disk.
+
+Examples:
+  tracker redact --dry-run
+  tracker redact
+  tracker redact --dir ~/work/api`,
+	Args: cobra.NoArgs,
+	RunE: runRedact,
+}
+
+func init() {
+	redactCmd.Flags().BoolVar(&redactFlags.dryRun, "dry-run", false, "Show what would be masked without changing the database")
+	redactCmd.Flags().StringVarP(&redactFlags.dir, "dir", "d", "", "Only scan commands run in this directory")
+
+	rootCmd.AddCommand(redactCmd)
+}
+
+func runRedact(cmd *cobra.Command, args []string) error {
+	cfg := config.Global()
+
+	sqliteStorage := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := sqliteStorage.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer sqliteStorage.Close()
+
+	var filters storage.CommandFilters
+	if redactFlags.dir != "" {
+		filters.Directory = normalizeDirectoryPath(redactFlags.dir)
+	}
+
+	_, err := redactHistory(sqliteStorage, projectRedactors(cfg), filters, redactFlags.dryRun, cmd.OutOrStdout())
+	return err
+}
+
+// projectRedactors returns a lookup of the redactor capture uses in each
+// directory, with the project's redact_patterns merged over cfg's
+func projectRedactors(cfg *config.Config) func(dir string) (*interceptor.Redactor, error) {
+	redactors := make(map[string]*interceptor.Redactor)
+
+	return func(dir string) (*interceptor.Redactor, error) {
+		if redactor, ok := redactors[dir]; ok {
+			return redactor, nil
+		}
+
+		merged, _, err := cfg.ForDirectory(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load project config for %s: %w", dir, err)
+		}
+		redactor, err := interceptor.NewRedactor(merged.RedactPatterns)
+		if err != nil {
+			return nil, err
+		}
+
+		redactors[dir] = redactor
+		return redactor, nil
+	}
+}
+
+// redactHistory masks secrets in every stored command matching filters and
+// returns how many commands contained secrets. redactorFor returns the
+// redactor for a command's directory. In a dry run nothing is written.
+func redactHistory(store storage.RewriteStorageEngine, redactorFor func(dir string) (*interceptor.Redactor, error), filters storage.CommandFilters, dryRun bool, out io.Writer) (int, error) {
+	var changed []history.CommandRecord
+	scanned := 0
+
+	err := store.StreamCommands(filters, func(record history.CommandRecord) error {
+		scanned++
+
+		redactor, err := redactorFor(record.Directory)
+		if err != nil {
+			return err
+		}
+
+		// Masks themselves still match some detectors, so only a changed
+		// command counts; masked rows are left alone on later runs
+		redacted, detectors := redactor.Redact(record.Command)
+		if len(detectors) == 0 || redacted == record.Command {
+			return nil
+		}
+
+		record.Command = redacted
+		record.AddTag(interceptor.RedactedTag)
+		changed = append(changed, record)
+
+		if dryRun {
+			fmt.Fprintf(out, "%s  [%s]\n    %s\n", record.ID, strings.Join(detectors, ", "), record.Command)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan commands: %w", err)
+	}
+
+	if dryRun {
+		fmt.Fprintf(out, "\n%d of %d commands contain secrets. Run without --dry-run to mask them.\n", len(changed), scanned)
+		return len(changed), nil
+	}
+
+	if len(changed) == 0 {
+		fmt.Fprintf(out, "No secrets found in %d commands\n", scanned)
+		return 0, nil
+	}
+
+	if err := store.RewriteCommands(changed); err != nil {
+		return 0, fmt.Errorf("failed to mask commands: %w", err)
+	}
+	if err := store.PurgeOldData(); err != nil {
+		return len(changed), fmt.Errorf("masked %d commands but failed to purge old data: %w", len(changed), err)
+	}
+
+	fmt.Fprintf(out, "✓ Masked secrets in %d of %d commands\n", len(changed), scanned)
+	return len(changed), nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+
+	"github.com/spf13/cobra"
+)
+
+var rmFlags struct {
+	pattern string
+	dir     string
+	dryRun  bool
+	yes     bool
+}
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <id>... | --pattern <text>",
+	Short: "Delete commands from history",
+	Long: `Delete individual commands from history, for example one that was recorded
+with a password in it.
+
+Commands are deleted by ID (shown in the browser preview and by --dry-run) or
+by a --pattern matched anywhere in the command text, optionally limited to one
+--dir. Pattern matches are listed and confirmed before they are deleted unless
+--yes is given; commands named by ID are deleted without asking. --dry-run
+lists what would be deleted either way. Afterwards the database is vacuumed so the deleted text is not
+left behind on disk.
+
+Examples:
+  tracker rm 1700000000123456789
+  tracker rm 1700000000123456789 --dry-run
+  tracker rm --pattern "mysql -p" --dry-run
+  tracker rm --pattern "export TOKEN=" --dir ~/work/api --yes`,
+	RunE: runRm,
+}
+
+func init() {
+	rmCmd.Flags().StringVarP(&rmFlags.pattern, "pattern", "p", "", "Delete commands containing this text")
+	rmCmd.Flags().StringVarP(&rmFlags.dir, "dir", "d", "", "Only match commands run in this directory")
+	rmCmd.Flags().BoolVar(&rmFlags.dryRun, "dry-run", false, "List the matching commands without deleting them")
+	rmCmd.Flags().BoolVarP(&rmFlags.yes, "yes", "y", false, "Delete pattern matches without asking")
+
+	rootCmd.AddCommand(rmCmd)
+}
+
+func runRm(cmd *cobra.Command, args []string) error {
+	if (len(args) == 0) == (rmFlags.pattern == "") {
+		return fmt.Errorf("give either command IDs or --pattern")
+	}
+	if len(args) > 0 && rmFlags.dir != "" {
+		return fmt.Errorf("--dir only applies to --pattern")
+	}
+
+	cfg := config.Global()
+	sqliteStorage := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := sqliteStorage.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer sqliteStorage.Close()
+
+	out := cmd.OutOrStdout()
+
+	var deleted int
+	var err error
+	if rmFlags.pattern != "" {
+		deleted, err = removeMatching(sqliteStorage, cmd.InOrStdin(), out)
+	} else {
+		deleted, err = removeByID(sqliteStorage, args, out)
+	}
+	if err != nil || deleted == 0 {
+		return err
+	}
+
+	if err := sqliteStorage.PurgeOldData(); err != nil {
+		return fmt.Errorf("deleted %d commands but failed to purge old data: %w", deleted, err)
+	}
+
+	fmt.Fprintf(out, "✓ Deleted %d command(s)\n", deleted)
+	return nil
+}
+
+// removeByID deletes the commands with the given IDs after checking they all
+// exist, or lists them in a dry run
+func removeByID(store *storage.SQLiteStorage, ids []string, out io.Writer) (int, error) {
+	commands, err := store.GetCommandsByIDs(ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up commands: %w", err)
+	}
+	existing := make(map[string]bool, len(commands))
+	for _, record := range commands {
+		existing[record.ID] = true
+	}
+	for _, id := range ids {
+		if !existing[id] {
+			return 0, fmt.Errorf("command not found: %s", id)
+		}
+	}
+
+	if rmFlags.dryRun {
+		printRemovals(out, commands)
+		fmt.Fprintf(out, "\n%d command(s) would be deleted. Run without --dry-run to delete them.\n", len(commands))
+		return 0, nil
+	}
+
+	deleted, err := store.DeleteCommands(ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete commands: %w", err)
+	}
+	return int(deleted), nil
+}
+
+// removeMatching lists the commands matching the pattern and deletes them
+// once confirmed
+func removeMatching(store *storage.SQLiteStorage, in io.Reader, out io.Writer) (int, error) {
+	filters := storage.CommandFilters{Pattern: rmFlags.pattern}
+	if rmFlags.dir != "" {
+		filters.Directory = normalizeDirectoryPath(rmFlags.dir)
+	}
+
+	matches, err := store.FilterCommands(filters)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find commands: %w", err)
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(out, "No commands match %q\n", rmFlags.pattern)
+		return 0, nil
+	}
+
+	printRemovals(out, matches)
+
+	if rmFlags.dryRun {
+		fmt.Fprintf(out, "\n%d command(s) match. Run without --dry-run to delete them.\n", len(matches))
+		return 0, nil
+	}
+
+	if !rmFlags.yes {
+		fmt.Fprintf(out, "\nDelete %d command(s)? (y/N): ", len(matches))
+		response, _ := bufio.NewReader(in).ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Fprintln(out, "Deletion cancelled.")
+			return 0, nil
+		}
+	}
+
+	ids := make([]string, len(matches))
+	for i, match := range matches {
+		ids[i] = match.ID
+	}
+	deleted, err := store.DeleteCommands(ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete commands: %w", err)
+	}
+	return int(deleted), nil
+}
+
+// printRemovals lists commands about to be deleted
+func printRemovals(out io.Writer, commands []history.CommandRecord) {
+	for _, record := range commands {
+		fmt.Fprintf(out, "%s  %s  %s\n    %s\n", record.ID, record.Timestamp.Format("2006-01-02 15:04"), record.Directory, record.Command)
+	}
+}
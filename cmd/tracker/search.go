@@ -19,13 +19,20 @@ var searchFlags struct {
 	caseSensitive bool
 	limit         int
 	noInteractive bool
+	fts           bool
 }
 
 var searchCmd = &cobra.Command{
 	Use:   "search [pattern]",
 	Short: "Search command history",
 	Long: `Search for commands matching a pattern in command history.
-By default, launches an interactive browser with search results.`,
+By default, launches an interactive browser with search results.
+
+With --fts the pattern is a full-text query and results are ranked by relevance:
+  tracker search --fts git push          # commands containing both tokens
+  tracker search --fts 'dock*'           # token prefix
+  tracker search --fts '"git commit"'    # exact phrase
+Full-text results are always printed as a list.`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runSearch,
 }
@@ -36,6 +43,7 @@ func init() {
 	searchCmd.Flags().BoolVarP(&searchFlags.caseSensitive, "case-sensitive", "c", false, "Case-sensitive search")
 	searchCmd.Flags().IntVarP(&searchFlags.limit, "limit", "n", 50, "Limit number of results")
 	searchCmd.Flags().BoolVar(&searchFlags.noInteractive, "no-interactive", false, "Disable interactive mode, print list")
+	searchCmd.Flags().BoolVar(&searchFlags.fts, "fts", false, "Treat pattern as a full-text query (tokens, prefix*, \"phrases\") ranked by relevance")
 
 	rootCmd.AddCommand(searchCmd)
 }
@@ -67,6 +75,11 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		dir = cwd
 	}
 
+	// Full-text search is ranked by relevance, so print it as a list
+	if searchFlags.fts {
+		return runFullTextSearch(storageEngine, pattern, dir)
+	}
+
 	// If interactive mode, launch browser with search
 	if !searchFlags.noInteractive {
 		b := browser.NewBrowser(storageEngine)
@@ -81,7 +94,19 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	// Non-interactive mode: search and print results
 	var commands []history.CommandRecord
 
-	if searchFlags.allDirs {
+	pagedStorage, paged := storageEngine.(storage.PagedStorageEngine)
+	if paged && searchFlags.limit > 0 {
+		// Let SQL pick the newest matches instead of loading them all
+		filters := storage.CommandFilters{Pattern: pattern}
+		if !searchFlags.allDirs {
+			filters.Directory = dir
+		}
+		page, err := pagedStorage.GetCommandsPage(filters, "", searchFlags.limit)
+		if err != nil {
+			return fmt.Errorf("failed to search commands: %w", err)
+		}
+		commands = page.Commands
+	} else if searchFlags.allDirs {
 		// Search across all directories
 		dirs, err := storageEngine.GetDirectoriesWithHistory()
 		if err != nil {
@@ -138,6 +163,48 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runFullTextSearch runs an FTS5 query and prints the ranked results
+func runFullTextSearch(storageEngine storage.StorageEngine, query, dir string) error {
+	ftsStorage, ok := storageEngine.(storage.FullTextStorageEngine)
+	if !ok {
+		return fmt.Errorf("full-text search is not supported by this storage engine")
+	}
+
+	if searchFlags.allDirs {
+		dir = ""
+	} else {
+		dir = normalizeDirectoryPath(dir)
+	}
+
+	commands, err := ftsStorage.SearchCommandsFTS(query, dir, searchFlags.limit)
+	if err != nil {
+		return fmt.Errorf("failed to search commands: %w", err)
+	}
+
+	if len(commands) == 0 {
+		fmt.Printf("No commands found matching: %s\n", query)
+		return nil
+	}
+
+	fmt.Printf("Full-text results for: %s\n", query)
+	if dir == "" {
+		fmt.Println("Searching across all directories")
+	} else {
+		fmt.Printf("Directory: %s\n", dir)
+	}
+	fmt.Printf("Found %d command(s), best match first\n\n", len(commands))
+
+	for i, cmd := range commands {
+		if dir == "" {
+			fmt.Printf("%4d  %s  [%s]  %s\n", i+1, cmd.Timestamp.Format("2006-01-02 15:04:05"), cmd.Directory, cmd.Command)
+		} else {
+			fmt.Printf("%4d  %s  %s\n", i+1, cmd.Timestamp.Format("2006-01-02 15:04:05"), cmd.Command)
+		}
+	}
+
+	return nil
+}
+
 func filterCaseInsensitive(commands []history.CommandRecord, pattern string) []history.CommandRecord {
 	lowerPattern := strings.ToLower(pattern)
 	var filtered []history.CommandRecord
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/api"
+	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/interceptor"
+	"github.com/ValGrace/command-history-tracker/internal/logging"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+
+	"github.com/spf13/cobra"
+)
+
+var serveFlags struct {
+	listen string
+	token  string
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve command history over a local HTTP/JSON API",
+	Long: `Serve the history database over HTTP with JSON responses, for editor plugins
+and dashboards. Read endpoints list directories, commands by directory, search
+and filter results and usage statistics; write endpoints record commands and
+change tags. Results are paged with limit and offset.
+
+--listen takes a TCP address such as 127.0.0.1:7420 or a Unix socket as
+unix:/path/to/socket. Every request must send "Authorization: Bearer <token>".
+The token is --token, or api_token from the configuration, which is reloaded
+when the file changes. Without either a random token is generated and printed.
+
+Examples:
+  tracker serve
+  tracker serve --listen unix:$HOME/.command-history-tracker/api.sock
+  curl -H "Authorization: Bearer $TOKEN" "http://127.0.0.1:7420/v1/search?q=git"`,
+	Args:              cobra.NoArgs,
+	PersistentPreRunE: initializeDBCommand,
+	RunE:              runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveFlags.listen, "listen", api.DefaultListenAddress, "TCP address or unix:<path> to listen on")
+	serveCmd.Flags().StringVar(&serveFlags.token, "token", "", "Bearer token clients must send (default api_token from config)")
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	cfg := config.Global()
+
+	token := serveFlags.token
+	if token == "" {
+		token = cfg.APIToken
+	}
+	generated := token == ""
+	if generated {
+		var err error
+		if token, err = generateToken(); err != nil {
+			return err
+		}
+	} else if len(token) < config.MinAPITokenLength {
+		return fmt.Errorf("token must be at least %d characters", config.MinAPITokenLength)
+	}
+
+	sqliteStorage := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := sqliteStorage.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer sqliteStorage.Close()
+
+	// Posted commands go through the same redaction and exclude rules as
+	// commands recorded by the shell hooks
+	var capture atomic.Pointer[interceptor.CommandCapture]
+	capture.Store(interceptor.NewCommandCapture(sqliteStorage, cfg))
+
+	server := api.NewServer(sqliteStorage, api.Options{
+		Token: token,
+		Prepare: func(record history.CommandRecord) (*history.CommandRecord, error) {
+			return capture.Load().PrepareRecord(record)
+		},
+	})
+
+	// Follow edits to the configuration file: new rules apply to posted
+	// commands and a changed api_token replaces the token unless --token is set
+	unsubscribe := config.Subscribe(func(old, new *config.Config) {
+		capture.Store(interceptor.NewCommandCapture(sqliteStorage, new))
+		if serveFlags.token == "" && new.APIToken != "" && new.APIToken != old.APIToken {
+			server.SetToken(new.APIToken)
+		}
+	})
+	defer unsubscribe()
+
+	if path, err := config.ConfigPath(); err == nil {
+		watcher, err := config.NewWatcher(path, logging.New(cmd.ErrOrStderr(), logging.InfoLevel))
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "⚠ Configuration changes will not be reloaded: %v\n", err)
+		} else {
+			watcher.Start()
+			defer watcher.Close()
+		}
+	}
+
+	listener, err := api.Listen(serveFlags.listen)
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Take over interrupt handling from main so requests in flight finish
+	signal.Reset(os.Interrupt, syscall.SIGTERM)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		if _, ok := <-sigChan; ok {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			httpServer.Shutdown(ctx)
+		}
+	}()
+
+	fmt.Fprintf(cmd.OutOrStdout(), "API server listening on %s\n", serveFlags.listen)
+	if generated {
+		fmt.Fprintf(cmd.OutOrStdout(), "API token: %s\n", token)
+		fmt.Fprintln(cmd.OutOrStdout(), "Set api_token in the configuration to keep the token across restarts")
+	}
+	if !api.IsLoopback(serveFlags.listen) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "⚠ %s accepts connections from other machines; history is sent unencrypted\n", serveFlags.listen)
+	}
+
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("API server failed: %w", err)
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "✓ API server stopped")
+	return nil
+}
+
+// generateToken returns a random bearer token
+func generateToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
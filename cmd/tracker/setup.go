@@ -282,6 +282,12 @@ func ensureShellConfigExists(shellType history.ShellType, configPath string) err
 		header = "# Zsh Configuration\n"
 	case history.Cmd:
 		header = "REM Command Prompt Configuration\n"
+	case history.Fish:
+		header = "# Fish Configuration\n"
+	case history.Nushell:
+		header = "# Nushell Configuration\n"
+	case history.Elvish:
+		header = "# Elvish Configuration\n"
 	default:
 		header = "# Shell Configuration\n"
 	}
@@ -659,6 +665,12 @@ func printShellRestartInstructions(shellType history.ShellType) {
 		fmt.Println("     source ~/.zshrc")
 	case history.Cmd:
 		fmt.Println("     Restart your Command Prompt")
+	case history.Fish:
+		fmt.Println("     exec fish")
+	case history.Nushell:
+		fmt.Println("     exec nu")
+	case history.Elvish:
+		fmt.Println("     exec elvish")
 	default:
 		fmt.Println("     Restart your shell")
 	}
@@ -758,9 +770,9 @@ func runInteractiveSetup() error {
 	}
 
 	// Load or create config
-	cfg, err := config.LoadConfig()
+	cfg, err := config.LoadFile()
 	if err != nil {
-		fmt.Println("\nCreating new configuration with defaults...")
+		fmt.Printf("\n%v\nCreating new configuration with defaults...\n", err)
 		cfg = config.DefaultConfig()
 	}
 
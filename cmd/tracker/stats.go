@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var statsFlags struct {
+	dir   string
+	since string
+	until string
+	limit int
+	json  bool
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show command usage statistics",
+	Long: `Show the most-used commands and base commands with their failure rate and
+average and 95th percentile duration, the commands that fail most often, the
+slowest commands, activity by hour and weekday, and the busiest directories.
+
+--dir limits the statistics to a directory and everything below it. --since and
+--until accept a duration ago ("6h", "2d", "1w") or a date ("2006-01-02" or
+RFC 3339). Durations only count runs whose duration was recorded.
+
+Examples:
+  tracker stats
+  tracker stats --dir . --since 1w
+  tracker stats --json | jq '.top_commands[0]'`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	statsCmd.Flags().StringVarP(&statsFlags.dir, "dir", "d", "", "Only include commands run in this directory and its subdirectories")
+	statsCmd.Flags().StringVar(&statsFlags.since, "since", "", "Only include commands run at or after this time")
+	statsCmd.Flags().StringVar(&statsFlags.until, "until", "", "Only include commands run at or before this time")
+	statsCmd.Flags().IntVarP(&statsFlags.limit, "limit", "n", storage.DefaultStatsLimit, "Number of entries in each ranking")
+	statsCmd.Flags().BoolVar(&statsFlags.json, "json", false, "Print the statistics as JSON")
+
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	query, err := buildStatsQuery(time.Now())
+	if err != nil {
+		return err
+	}
+
+	cfg := config.Global()
+	sqliteStorage := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := sqliteStorage.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer sqliteStorage.Close()
+
+	stats, err := sqliteStorage.GetUsageStats(query)
+	if err != nil {
+		return fmt.Errorf("failed to compute statistics: %w", err)
+	}
+
+	if statsFlags.json {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+
+	printStats(cmd.OutOrStdout(), query, stats)
+	return nil
+}
+
+// buildStatsQuery converts the stats flags into a storage query
+func buildStatsQuery(now time.Time) (storage.StatsQuery, error) {
+	query := storage.StatsQuery{Limit: statsFlags.limit}
+
+	if statsFlags.dir != "" {
+		dir, err := filepath.Abs(statsFlags.dir)
+		if err != nil {
+			return query, fmt.Errorf("failed to resolve directory: %w", err)
+		}
+		query.Directory = normalizeDirectoryPath(dir)
+	}
+
+	var err error
+	if statsFlags.since != "" {
+		if query.StartTime, err = parseTimeBound(statsFlags.since, now); err != nil {
+			return query, fmt.Errorf("invalid --since value: %w", err)
+		}
+	}
+	if statsFlags.until != "" {
+		if query.EndTime, err = parseTimeBound(statsFlags.until, now); err != nil {
+			return query, fmt.Errorf("invalid --until value: %w", err)
+		}
+	}
+
+	return query, nil
+}
+
+// printStats writes the statistics as text
+func printStats(out io.Writer, query storage.StatsQuery, stats *storage.UsageStats) {
+	fmt.Fprintln(out, "=== Command Statistics ===")
+	if query.Directory != "" {
+		fmt.Fprintf(out, "Directory:   %s (and subdirectories)\n", query.Directory)
+	}
+
+	if stats.TotalCommands == 0 {
+		fmt.Fprintln(out, "No commands recorded in this range")
+		return
+	}
+
+	fmt.Fprintf(out, "Range:       %s to %s\n", stats.FirstCommand.Format("2006-01-02 15:04"), stats.LastCommand.Format("2006-01-02 15:04"))
+	fmt.Fprintf(out, "Commands:    %d in %d directories\n", stats.TotalCommands, stats.DirectoryCount)
+	fmt.Fprintf(out, "Failures:    %d (%.1f%%)\n", stats.FailedCommands, stats.FailureRate*100)
+
+	printCommandUsage(out, "Top Commands", stats.TopCommands)
+	printCommandUsage(out, "Top Base Commands", stats.TopBaseCommands)
+	printCommandUsage(out, "Failure Hotspots", stats.FailingCommands)
+	printCommandUsage(out, "Slowest Commands", stats.SlowestCommands)
+
+	fmt.Fprintf(out, "\nBusiest Directories:\n")
+	fmt.Fprintf(out, "  %6s  %6s  %-16s  %s\n", "COUNT", "FAILED", "LAST USED", "DIRECTORY")
+	for _, dir := range stats.TopDirectories {
+		fmt.Fprintf(out, "  %6d  %6d  %-16s  %s\n", dir.Count, dir.Failures, dir.LastUsed.Format("2006-01-02 15:04"), dir.Directory)
+	}
+
+	fmt.Fprintf(out, "\nActivity by Hour:\n")
+	hours := make([]string, len(stats.ByHour))
+	for i := range hours {
+		hours[i] = fmt.Sprintf("%02d", i)
+	}
+	printHistogram(out, hours, stats.ByHour[:])
+
+	fmt.Fprintf(out, "\nActivity by Weekday:\n")
+	days := make([]string, len(stats.ByWeekday))
+	for i := range days {
+		days[i] = time.Weekday(i).String()[:3]
+	}
+	printHistogram(out, days, stats.ByWeekday[:])
+}
+
+// printCommandUsage writes one ranking of commands
+func printCommandUsage(out io.Writer, title string, usage []storage.CommandUsage) {
+	fmt.Fprintf(out, "\n%s:\n", title)
+	fmt.Fprintf(out, "  %6s  %6s  %9s  %9s  %s\n", "COUNT", "FAIL%", "AVG", "P95", "COMMAND")
+	for _, entry := range usage {
+		fmt.Fprintf(out, "  %6d  %5.1f%%  %9s  %9s  %s\n",
+			entry.Count, entry.FailureRate*100, formatStatsDuration(entry.AvgDuration), formatStatsDuration(entry.P95Duration), entry.Command)
+	}
+}
+
+// printHistogram writes a bar per bucket scaled to the largest count
+func printHistogram(out io.Writer, labels []string, counts []int) {
+	const width = 40
+
+	max := 0
+	for _, count := range counts {
+		if count > max {
+			max = count
+		}
+	}
+
+	for i, count := range counts {
+		bar := 0
+		if max > 0 {
+			bar = (count*width + max - 1) / max
+		}
+		fmt.Fprintf(out, "  %s %-*s %d\n", labels[i], width, strings.Repeat("█", bar), count)
+	}
+}
+
+// formatStatsDuration shortens a duration for the rankings, "-" when unknown
+func formatStatsDuration(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "-"
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(100 * time.Millisecond).String()
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/internal/syncer"
+
+	"github.com/spf13/cobra"
+)
+
+var syncFlags struct {
+	git            bool
+	allowPlaintext bool
+}
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [path]",
+	Short: "Exchange history with other machines",
+	Long: `Exchange command history with other machines through a shared folder,
+such as a Syncthing or Dropbox directory or a network drive, or through a git
+repository such as a bare repository on a shared drive or server.
+
+Each machine appends its changes - new, edited and deleted commands and tag
+changes - to its own change log in the folder and applies the logs of the
+others. A deleted command is deleted everywhere, even if another machine
+changed it meanwhile; tag changes to the same command from different machines
+are merged, the later one winning for the same tag.
+
+The path defaults to sync_path from the configuration. Paths ending in .git
+and bare repositories are synced with git; --git forces it for other remotes.
+
+Examples:
+  tracker sync ~/Sync/history
+  tracker sync /mnt/share/history.git
+  tracker config --set sync_path=$HOME/Sync/history && tracker sync`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSync,
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncFlags.git, "git", false, "Treat the path as a git remote")
+	syncCmd.Flags().BoolVar(&syncFlags.allowPlaintext, "allow-plaintext", false, "Sync an encrypted database, writing command text to the change logs unencrypted")
+
+	rootCmd.AddCommand(syncCmd)
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	cfg := config.Global()
+
+	path := cfg.SyncPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("no sync path: pass a folder or git remote, or set sync_path")
+	}
+	path = expandHome(path)
+
+	sqliteStorage := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := sqliteStorage.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer sqliteStorage.Close()
+
+	if sqliteStorage.Encrypted() && !syncFlags.allowPlaintext {
+		return fmt.Errorf("the database is encrypted but change logs hold command text unencrypted: pass --allow-plaintext to sync anyway")
+	}
+
+	opts := syncer.Options{RetentionDays: cfg.RetentionDays}
+
+	var result *syncer.Result
+	var err error
+	if syncFlags.git || syncer.IsGitRemote(path) {
+		result, err = syncer.SyncGit(sqliteStorage, path, syncCloneDir(cfg.StoragePath, path), opts)
+	} else {
+		result, err = syncer.Sync(sqliteStorage, path, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("sync failed: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "✓ Synced with %s (%d machine(s))\n", path, result.Machines)
+	fmt.Fprintf(out, "  Sent %d change(s); added %d, updated %d and deleted %d command(s)\n",
+		result.Exported, result.Added, result.Updated, result.Deleted)
+	if result.Skipped > 0 {
+		fmt.Fprintf(out, "  Skipped %d unreadable change log line(s); they may still be copying\n", result.Skipped)
+	}
+
+	return nil
+}
+
+// syncCloneDir returns where the clone of a git sync remote is kept: next to
+// the database, one per remote
+func syncCloneDir(storagePath, remote string) string {
+	sum := sha256.Sum256([]byte(remote))
+	return filepath.Join(filepath.Dir(storagePath), "sync", hex.EncodeToString(sum[:6]))
+}
+
+// expandHome replaces a leading ~ with the home directory, for paths read
+// from the configuration rather than the shell
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+	"github.com/ValGrace/command-history-tracker/pkg/shell"
+
+	"github.com/spf13/cobra"
+)
+
+var widgetFlags struct {
+	shell string
+}
+
+var widgetCmd = &cobra.Command{
+	Use:   "widget",
+	Short: "Print shell key bindings that replace Ctrl-R",
+	Long: `Print key bindings that replace Ctrl-R with the history browser. Pressing
+Ctrl-R opens the browser below the prompt, searching for the text already typed.
+The chosen command is put on the command line for editing instead of being run.
+
+Load the bindings from your shell configuration:
+  bash:        eval "$(tracker widget --shell bash)"
+  zsh:         eval "$(tracker widget --shell zsh)"
+  fish:        tracker widget --shell fish | source
+  powershell:  tracker widget --shell powershell | Out-String | Invoke-Expression`,
+	Args: cobra.NoArgs,
+	RunE: runWidget,
+}
+
+func init() {
+	widgetCmd.Flags().StringVar(&widgetFlags.shell, "shell", "", "Shell to print bindings for (bash, zsh, fish, powershell)")
+	widgetCmd.MarkFlagRequired("shell")
+
+	rootCmd.AddCommand(widgetCmd)
+}
+
+func runWidget(cmd *cobra.Command, args []string) error {
+	shellType := parseShellType(widgetFlags.shell)
+	if shellType == history.Unknown {
+		return fmt.Errorf("unknown shell %q (expected bash, zsh, fish or powershell)", widgetFlags.shell)
+	}
+
+	script, err := shell.NewIntegrator().GetWidgetScript(shellType)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), script)
+	return nil
+}
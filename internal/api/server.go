@@ -0,0 +1,507 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+const (
+	// DefaultPageSize is the number of results returned when no limit is given
+	DefaultPageSize = 100
+
+	// MaxPageSize is the largest limit a client may ask for
+	MaxPageSize = 1000
+
+	// maxBodySize bounds request bodies
+	maxBodySize = 1 << 20
+)
+
+// Options configures the API server
+type Options struct {
+	// Token is the bearer token every request must carry
+	Token string
+
+	// Prepare turns a posted record into the record to store, applying
+	// redaction, metadata and exclude rules. It returns nil for commands that
+	// must not be recorded. Records are stored as posted when Prepare is nil.
+	Prepare func(history.CommandRecord) (*history.CommandRecord, error)
+}
+
+// PageInfo describes which part of the results a response holds. NextOffset
+// is set while more results follow.
+type PageInfo struct {
+	Total      int  `json:"total"`
+	Offset     int  `json:"offset"`
+	Limit      int  `json:"limit"`
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+// CommandsResponse is a page of command records
+type CommandsResponse struct {
+	Commands []history.CommandRecord `json:"commands"`
+	PageInfo
+}
+
+// DirectoriesResponse is a page of directories with history
+type DirectoriesResponse struct {
+	Directories []history.DirectoryIndex `json:"directories"`
+	PageInfo
+}
+
+// RecordResponse reports whether a posted command was recorded and how it
+// was stored
+type RecordResponse struct {
+	Recorded bool                   `json:"recorded"`
+	Command  *history.CommandRecord `json:"command,omitempty"`
+}
+
+// TagsRequest adds and removes tags on a command
+type TagsRequest struct {
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+// TagsResponse reports whether the tags of a command changed
+type TagsResponse struct {
+	Changed bool `json:"changed"`
+}
+
+// ErrorResponse is the body of every failed request
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// directoryStatsStorage is implemented by storage engines that keep
+// per-directory statistics
+type directoryStatsStorage interface {
+	GetDirectoryStats() ([]history.DirectoryIndex, error)
+}
+
+// filterStorage is implemented by storage engines that filter commands in SQL
+type filterStorage interface {
+	FilterCommands(filters storage.CommandFilters) ([]history.CommandRecord, error)
+}
+
+// fullTextStorage is implemented by storage engines with a full-text index
+type fullTextStorage interface {
+	SearchCommandsFTS(query string, dir string, limit int) ([]history.CommandRecord, error)
+}
+
+// usageStatsStorage is implemented by storage engines that aggregate usage
+// statistics
+type usageStatsStorage interface {
+	GetUsageStats(query storage.StatsQuery) (*storage.UsageStats, error)
+}
+
+// tagStorage is implemented by storage engines that edit tags of stored commands
+type tagStorage interface {
+	ExistingCommandIDs(ids []string) (map[string]bool, error)
+	UpdateTags(ids []string, add []string, remove []string) (int64, error)
+}
+
+// Server answers API requests from a storage engine. It implements
+// http.Handler.
+type Server struct {
+	storage storage.StorageEngine
+	prepare func(history.CommandRecord) (*history.CommandRecord, error)
+	token   atomic.Pointer[string]
+	mux     *http.ServeMux
+}
+
+// NewServer creates an API server over the given storage
+func NewServer(store storage.StorageEngine, opts Options) *Server {
+	s := &Server{
+		storage: store,
+		prepare: opts.Prepare,
+		mux:     http.NewServeMux(),
+	}
+	s.SetToken(opts.Token)
+
+	s.mux.HandleFunc("GET /v1/directories", s.handleDirectories)
+	s.mux.HandleFunc("GET /v1/commands", s.handleCommands)
+	s.mux.HandleFunc("POST /v1/commands", s.handleRecord)
+	s.mux.HandleFunc("POST /v1/commands/{id}/tags", s.handleTags)
+	s.mux.HandleFunc("GET /v1/search", s.handleSearch)
+	s.mux.HandleFunc("GET /v1/filter", s.handleFilter)
+	s.mux.HandleFunc("GET /v1/stats", s.handleStats)
+
+	return s
+}
+
+// SetToken replaces the bearer token, for example after the configuration
+// was reloaded. Requests are refused while the token is empty.
+func (s *Server) SetToken(token string) {
+	s.token.Store(&token)
+}
+
+// ServeHTTP checks the bearer token and dispatches the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="tracker"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized reports whether the request carries the current token
+func (s *Server) authorized(r *http.Request) bool {
+	token := *s.token.Load()
+	if token == "" {
+		return false
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func (s *Server) handleDirectories(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var dirs []history.DirectoryIndex
+	if statsStore, ok := s.storage.(directoryStatsStorage); ok {
+		dirs, err = statsStore.GetDirectoryStats()
+	} else {
+		var paths []string
+		paths, err = s.storage.GetDirectoriesWithHistory()
+		for _, path := range paths {
+			dirs = append(dirs, history.DirectoryIndex{Path: path})
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get directories: %v", err))
+		return
+	}
+
+	page, info := paginate(dirs, p)
+	writeJSON(w, http.StatusOK, DirectoriesResponse{Directories: page, PageInfo: info})
+}
+
+func (s *Server) handleCommands(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dir := query.Get("dir")
+	if dir == "" {
+		writeError(w, http.StatusBadRequest, "dir is required")
+		return
+	}
+
+	commands, err := s.storage.GetCommandsByDirectory(dir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get commands: %v", err))
+		return
+	}
+	writeCommands(w, query, commands)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := query.Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	var commands []history.CommandRecord
+	var err error
+	if query.Get("fts") == "true" {
+		ftsStore, ok := s.storage.(fullTextStorage)
+		if !ok {
+			writeError(w, http.StatusNotImplemented, "full-text search is not supported by this storage")
+			return
+		}
+		commands, err = ftsStore.SearchCommandsFTS(q, query.Get("dir"), 0)
+	} else {
+		commands, err = s.storage.SearchCommands(q, query.Get("dir"))
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to search commands: %v", err))
+		return
+	}
+	writeCommands(w, query, commands)
+}
+
+func (s *Server) handleFilter(w http.ResponseWriter, r *http.Request) {
+	filterStore, ok := s.storage.(filterStorage)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "filtering is not supported by this storage")
+		return
+	}
+
+	query := r.URL.Query()
+	filters, err := parseFilters(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	commands, err := filterStore.FilterCommands(filters)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to filter commands: %v", err))
+		return
+	}
+	writeCommands(w, query, commands)
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	statsStore, ok := s.storage.(usageStatsStorage)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "usage statistics are not supported by this storage")
+		return
+	}
+
+	query := r.URL.Query()
+	statsQuery := storage.StatsQuery{Directory: query.Get("dir"), Limit: storage.DefaultStatsLimit}
+
+	var err error
+	if statsQuery.StartTime, err = parseTime(query, "since"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if statsQuery.EndTime, err = parseTime(query, "until"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		if statsQuery.Limit, err = strconv.Atoi(v); err != nil || statsQuery.Limit < 1 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", v))
+			return
+		}
+	}
+
+	stats, err := statsStore.GetUsageStats(statsQuery)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to compute statistics: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) handleRecord(w http.ResponseWriter, r *http.Request) {
+	var record history.CommandRecord
+	if err := decodeBody(w, r, &record); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(record.Command) == "" || record.Directory == "" {
+		writeError(w, http.StatusBadRequest, "command and directory are required")
+		return
+	}
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+	if record.Tags == nil {
+		record.Tags = []string{}
+	}
+
+	stored := &record
+	if s.prepare != nil {
+		var err error
+		if stored, err = s.prepare(record); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if stored == nil {
+			writeJSON(w, http.StatusOK, RecordResponse{Recorded: false})
+			return
+		}
+	}
+	if err := stored.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid command record: %v", err))
+		return
+	}
+
+	if err := s.storage.SaveCommand(*stored); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to save command: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, RecordResponse{Recorded: true, Command: stored})
+}
+
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	tagStore, ok := s.storage.(tagStorage)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "tagging is not supported by this storage")
+		return
+	}
+
+	var req TagsRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id := r.PathValue("id")
+	existing, err := tagStore.ExistingCommandIDs([]string{id})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to look up command: %v", err))
+		return
+	}
+	if !existing[id] {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("command not found: %s", id))
+		return
+	}
+
+	changed, err := tagStore.UpdateTags([]string{id}, req.Add, req.Remove)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, TagsResponse{Changed: changed > 0})
+}
+
+// page selects part of a result list
+type page struct {
+	offset int
+	limit  int
+}
+
+// parsePage reads the limit and offset parameters
+func parsePage(query url.Values) (page, error) {
+	p := page{limit: DefaultPageSize}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return p, fmt.Errorf("invalid limit %q", v)
+		}
+		p.limit = min(limit, MaxPageSize)
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return p, fmt.Errorf("invalid offset %q", v)
+		}
+		p.offset = offset
+	}
+
+	return p, nil
+}
+
+// paginate returns the requested page of items and where it lies
+func paginate[T any](items []T, p page) ([]T, PageInfo) {
+	info := PageInfo{Total: len(items), Offset: p.offset, Limit: p.limit}
+	if p.offset >= len(items) {
+		return []T{}, info
+	}
+
+	end := min(p.offset+p.limit, len(items))
+	if end < len(items) {
+		info.NextOffset = &end
+	}
+	return items[p.offset:end], info
+}
+
+// parseFilters converts filter parameters into storage filters
+func parseFilters(query url.Values) (storage.CommandFilters, error) {
+	filters := storage.CommandFilters{
+		Directory:   query.Get("dir"),
+		Command:     query.Get("command"),
+		Pattern:     query.Get("pattern"),
+		SessionID:   query.Get("session"),
+		Hostname:    query.Get("host"),
+		Username:    query.Get("user"),
+		TTY:         query.Get("tty"),
+		ContainerID: query.Get("container"),
+		PythonEnv:   query.Get("python_env"),
+		KubeContext: query.Get("kube_context"),
+	}
+
+	var err error
+	if v := query.Get("recursive"); v != "" {
+		if filters.Recursive, err = strconv.ParseBool(v); err != nil {
+			return filters, fmt.Errorf("invalid recursive %q", v)
+		}
+	}
+	if v := query.Get("shell"); v != "" {
+		if err := filters.ShellType.UnmarshalText([]byte(v)); err != nil || filters.ShellType == history.Unknown {
+			return filters, fmt.Errorf("unknown shell %q", v)
+		}
+	}
+	if v := query.Get("exit_code"); v != "" {
+		code, err := strconv.Atoi(v)
+		if err != nil {
+			return filters, fmt.Errorf("invalid exit_code %q", v)
+		}
+		filters.ExitCode = &code
+	}
+	if v := query.Get("ssh"); v != "" {
+		ssh, err := strconv.ParseBool(v)
+		if err != nil {
+			return filters, fmt.Errorf("invalid ssh %q", v)
+		}
+		filters.SSH = &ssh
+	}
+	if filters.StartTime, err = parseTime(query, "since"); err != nil {
+		return filters, err
+	}
+	if filters.EndTime, err = parseTime(query, "until"); err != nil {
+		return filters, err
+	}
+
+	return filters, nil
+}
+
+// parseTime reads an RFC 3339 time parameter, zero when absent
+func parseTime(query url.Values, name string) (time.Time, error) {
+	v := query.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: expected an RFC 3339 time", name, v)
+	}
+	return t, nil
+}
+
+// decodeBody reads a JSON request body into v
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return fmt.Errorf("request body is larger than %d bytes", maxBodySize)
+		}
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// writeCommands writes the requested page of commands
+func writeCommands(w http.ResponseWriter, query url.Values, commands []history.CommandRecord) {
+	p, err := parsePage(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, info := paginate(commands, p)
+	writeJSON(w, http.StatusOK, CommandsResponse{Commands: page, PageInfo: info})
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message})
+}
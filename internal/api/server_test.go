@@ -0,0 +1,318 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+const testToken = "test-token-0123456789"
+
+// startTestServer serves a fresh database holding three commands in
+// /home/user/project and one in /home/user
+func startTestServer(t *testing.T, opts Options) (*storage.SQLiteStorage, *Server, *httptest.Server) {
+	t.Helper()
+
+	store := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	now := time.Now().Truncate(time.Second)
+	for i, record := range []history.CommandRecord{
+		{ID: "1", Command: "git status", Directory: "/home/user/project", Shell: history.Bash},
+		{ID: "2", Command: "go test ./...", Directory: "/home/user/project", Shell: history.Zsh, ExitCode: 1},
+		{ID: "3", Command: "git push", Directory: "/home/user/project", Shell: history.Bash},
+		{ID: "4", Command: "ls -la", Directory: "/home/user", Shell: history.Bash},
+	} {
+		record.Timestamp = now.Add(time.Duration(i-4) * time.Minute)
+		record.Tags = []string{}
+		if err := store.SaveCommand(record); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+
+	if opts.Token == "" {
+		opts.Token = testToken
+	}
+	server := NewServer(store, opts)
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return store, server, ts
+}
+
+// call sends an authorized request and decodes the JSON response into out
+func call(t *testing.T, ts *httptest.Server, method, path string, body any, out any) int {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("Failed to decode %s %s response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServer_Auth(t *testing.T) {
+	_, server, ts := startTestServer(t, Options{})
+
+	for _, header := range []string{"", "Bearer wrong-token", testToken} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/directories", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var body ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized || body.Error == "" {
+			t.Errorf("Authorization %q: expected 401 with an error, got %d", header, resp.StatusCode)
+		}
+	}
+
+	if status := call(t, ts, http.MethodGet, "/v1/directories", nil, nil); status != http.StatusOK {
+		t.Errorf("Expected 200 with the token, got %d", status)
+	}
+
+	// A rotated token replaces the old one
+	server.SetToken("rotated-token-0123456789")
+	if status := call(t, ts, http.MethodGet, "/v1/directories", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected the old token rejected, got %d", status)
+	}
+}
+
+func TestServer_ReadEndpoints(t *testing.T) {
+	_, _, ts := startTestServer(t, Options{})
+
+	var dirs DirectoriesResponse
+	if status := call(t, ts, http.MethodGet, "/v1/directories", nil, &dirs); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if dirs.Total != 2 || len(dirs.Directories) != 2 {
+		t.Errorf("Expected 2 directories, got %+v", dirs)
+	}
+
+	// Commands by directory, two per page, newest first
+	var page CommandsResponse
+	path := "/v1/commands?dir=" + url.QueryEscape("/home/user/project") + "&limit=2"
+	if status := call(t, ts, http.MethodGet, path, nil, &page); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if page.Total != 3 || len(page.Commands) != 2 || page.NextOffset == nil || *page.NextOffset != 2 {
+		t.Fatalf("Unexpected first page: %+v", page)
+	}
+	if page.Commands[0].Command != "git push" || page.Commands[0].Shell != history.Bash {
+		t.Errorf("Expected git push first, got %+v", page.Commands[0])
+	}
+
+	page = CommandsResponse{}
+	call(t, ts, http.MethodGet, path+"&offset=2", nil, &page)
+	if len(page.Commands) != 1 || page.NextOffset != nil || page.Commands[0].Command != "git status" {
+		t.Errorf("Unexpected last page: %+v", page)
+	}
+
+	if status := call(t, ts, http.MethodGet, "/v1/commands", nil, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 without dir, got %d", status)
+	}
+	if status := call(t, ts, http.MethodGet, "/v1/commands?dir=%2Fhome%2Fuser&limit=0", nil, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for limit=0, got %d", status)
+	}
+
+	// Search, also with the full-text index
+	for _, path := range []string{"/v1/search?q=git", "/v1/search?q=git&fts=true"} {
+		page = CommandsResponse{}
+		if status := call(t, ts, http.MethodGet, path, nil, &page); status != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, status)
+		}
+		if page.Total != 2 {
+			t.Errorf("%s: expected 2 matches, got %+v", path, page.Commands)
+		}
+	}
+
+	// Filter with CommandFilters fields
+	page = CommandsResponse{}
+	path = "/v1/filter?dir=" + url.QueryEscape("/home/user") + "&recursive=true&shell=zsh&exit_code=1"
+	if status := call(t, ts, http.MethodGet, path, nil, &page); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if page.Total != 1 || page.Commands[0].ID != "2" {
+		t.Errorf("Expected only the failed go test, got %+v", page.Commands)
+	}
+	if status := call(t, ts, http.MethodGet, "/v1/filter?shell=sh", nil, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown shell, got %d", status)
+	}
+	if status := call(t, ts, http.MethodGet, "/v1/filter?since=yesterday", nil, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad time, got %d", status)
+	}
+	page = CommandsResponse{}
+	if status := call(t, ts, http.MethodGet, "/v1/filter?host=elsewhere&ssh=false", nil, &page); status != http.StatusOK || page.Total != 0 {
+		t.Errorf("Expected no commands from another host, got %d %+v", status, page.Commands)
+	}
+	if status := call(t, ts, http.MethodGet, "/v1/filter?ssh=maybe", nil, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad ssh flag, got %d", status)
+	}
+
+	// Statistics
+	var stats storage.UsageStats
+	since := url.QueryEscape(time.Now().Add(-time.Hour).Format(time.RFC3339))
+	if status := call(t, ts, http.MethodGet, "/v1/stats?since="+since, nil, &stats); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", status)
+	}
+	if stats.TotalCommands != 4 || stats.FailedCommands != 1 {
+		t.Errorf("Unexpected statistics: %+v", stats)
+	}
+}
+
+func TestServer_RecordAndTag(t *testing.T) {
+	_, _, ts := startTestServer(t, Options{})
+
+	record := map[string]any{
+		"id":        "5",
+		"command":   "make build",
+		"directory": "/home/user/project",
+		"shell":     "fish",
+		"exit_code": 0,
+	}
+	var recorded RecordResponse
+	if status := call(t, ts, http.MethodPost, "/v1/commands", record, &recorded); status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", status)
+	}
+	if !recorded.Recorded || recorded.Command.Timestamp.IsZero() || recorded.Command.Shell != history.Fish {
+		t.Errorf("Unexpected record response: %+v", recorded.Command)
+	}
+
+	var page CommandsResponse
+	call(t, ts, http.MethodGet, "/v1/search?q=make", nil, &page)
+	if page.Total != 1 || page.Commands[0].ID != "5" {
+		t.Errorf("Expected the recorded command to be stored, got %+v", page.Commands)
+	}
+
+	for _, body := range []any{
+		map[string]any{"command": "make", "id": "6"},
+		map[string]any{"command": "make", "directory": "/tmp", "id": "6", "unknown": 1},
+	} {
+		if status := call(t, ts, http.MethodPost, "/v1/commands", body, nil); status != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %v, got %d", body, status)
+		}
+	}
+
+	// Tags
+	var tags TagsResponse
+	if status := call(t, ts, http.MethodPost, "/v1/commands/5/tags", TagsRequest{Add: []string{"build", "ci"}}, &tags); status != http.StatusOK || !tags.Changed {
+		t.Fatalf("Expected tags to change, got %d %+v", status, tags)
+	}
+	call(t, ts, http.MethodPost, "/v1/commands/5/tags", TagsRequest{Remove: []string{"ci"}}, &tags)
+
+	page = CommandsResponse{}
+	call(t, ts, http.MethodGet, "/v1/search?q=make", nil, &page)
+	if got := page.Commands[0].Tags; len(got) != 1 || got[0] != "build" {
+		t.Errorf("Expected tags [build], got %v", got)
+	}
+
+	if status := call(t, ts, http.MethodPost, "/v1/commands/missing/tags", TagsRequest{Add: []string{"x"}}, nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown command, got %d", status)
+	}
+	if status := call(t, ts, http.MethodPost, "/v1/commands/5/tags", TagsRequest{Add: []string{"a,b"}}, nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a tag with a comma, got %d", status)
+	}
+}
+
+func TestServer_RecordPrepare(t *testing.T) {
+	var prepared []string
+	_, _, ts := startTestServer(t, Options{
+		Prepare: func(record history.CommandRecord) (*history.CommandRecord, error) {
+			prepared = append(prepared, record.Command)
+			if strings.HasPrefix(record.Command, "secret") {
+				return nil, nil
+			}
+			if record.ID == "" {
+				record.ID = fmt.Sprintf("prepared-%d", len(prepared))
+			}
+			record.AddTag("api")
+			return &record, nil
+		},
+	})
+
+	var recorded RecordResponse
+	body := map[string]any{"command": "secret stuff", "directory": "/home/user", "shell": "bash"}
+	if status := call(t, ts, http.MethodPost, "/v1/commands", body, &recorded); status != http.StatusOK || recorded.Recorded {
+		t.Errorf("Expected the excluded command not recorded, got %d %+v", status, recorded)
+	}
+
+	body = map[string]any{"command": "echo hi", "directory": "/home/user", "shell": "bash"}
+	if status := call(t, ts, http.MethodPost, "/v1/commands", body, &recorded); status != http.StatusCreated || !recorded.Recorded {
+		t.Fatalf("Expected the command recorded, got %d", status)
+	}
+	if recorded.Command.ID != "prepared-2" || !recorded.Command.HasTag("api") {
+		t.Errorf("Expected the prepared record stored, got %+v", recorded.Command)
+	}
+}
+
+func TestListen(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets are not used on Windows")
+	}
+
+	address := "unix:" + filepath.Join(t.TempDir(), "api.sock")
+	listener, err := Listen(address)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+
+	if _, err := Listen(address); err == nil {
+		t.Error("Expected an error while a server is listening")
+	}
+
+	for address, want := range map[string]bool{
+		"127.0.0.1:7420":   true,
+		"localhost:7420":   true,
+		"[::1]:7420":       true,
+		"unix:/tmp/a.sock": true,
+		"0.0.0.0:7420":     false,
+		":7420":            false,
+	} {
+		if got := IsLoopback(address); got != want {
+			t.Errorf("IsLoopback(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
@@ -27,6 +27,11 @@ type Application struct {
 	mu          sync.RWMutex
 	running     bool
 	cleanupDone chan struct{}
+
+	// Configuration reloads
+	watcher       *config.Watcher
+	unsubscribe   func()
+	configChanged chan struct{}
 }
 
 // New creates a new application instance
@@ -57,9 +62,10 @@ func New() (*Application, error) {
 	logging.SetDefault(logger)
 
 	app := &Application{
-		config:      cfg,
-		logger:      logger,
-		cleanupDone: make(chan struct{}),
+		config:        cfg,
+		logger:        logger,
+		cleanupDone:   make(chan struct{}),
+		configChanged: make(chan struct{}, 1),
 	}
 
 	return app, nil
@@ -156,8 +162,14 @@ func (a *Application) initializeBrowser() error {
 func (a *Application) initializeExecutor() error {
 	a.logger.Info("Initializing command executor...")
 
-	exec := executor.NewExecutor()
+	exec := executor.NewExecutorWithLogger(executionRecorder{app: a})
+	exec.GetValidator().SetPolicy(securityPolicy(a.config))
 	a.executor = exec
+
+	// Commands chosen in the browser run through the executor
+	if a.browser != nil {
+		a.browser.SetExecutor(exec)
+	}
 	a.logger.Info("✓ Executor initialized")
 	return nil
 }
@@ -173,12 +185,13 @@ func (a *Application) Start() error {
 
 	a.logger.Info("Starting Command History Tracker...")
 
-	// Start automatic cleanup if enabled
+	// Start automatic cleanup; it stays idle while disabled so a
+	// configuration reload can turn it on
 	if a.config.AutoCleanup {
 		a.logger.Info("Auto-cleanup enabled (interval: %v, retention: %d days)",
 			a.config.CleanupInterval, a.config.RetentionDays)
-		go a.runAutoCleanup()
 	}
+	go a.runAutoCleanup()
 
 	a.running = true
 	a.logger.Info("✓ Application started")
@@ -211,6 +224,9 @@ func (a *Application) Stop() error {
 func (a *Application) Shutdown() error {
 	a.logger.Info("Shutting down Command History Tracker...")
 
+	// Stop following configuration changes
+	a.stopWatchingConfig()
+
 	// Stop the application
 	if err := a.Stop(); err != nil {
 		a.logger.Error("Warning: error stopping application: %v", err)
@@ -248,7 +264,8 @@ func (a *Application) Shutdown() error {
 
 // runAutoCleanup runs automatic cleanup in the background
 func (a *Application) runAutoCleanup() {
-	ticker := time.NewTicker(a.config.CleanupInterval)
+	interval := a.GetConfig().CleanupInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	a.logger.Debug("Auto-cleanup goroutine started")
@@ -256,7 +273,15 @@ func (a *Application) runAutoCleanup() {
 	for {
 		select {
 		case <-ticker.C:
-			a.performCleanup()
+			if a.GetConfig().AutoCleanup {
+				a.performCleanup()
+			}
+		case <-a.configChanged:
+			if next := a.GetConfig().CleanupInterval; next != interval {
+				interval = next
+				ticker.Reset(interval)
+				a.logger.Info("Auto-cleanup interval changed to %v", interval)
+			}
 		case <-a.cleanupDone:
 			a.logger.Debug("Auto-cleanup goroutine stopped")
 			return
@@ -268,7 +293,17 @@ func (a *Application) runAutoCleanup() {
 func (a *Application) performCleanup() {
 	a.logger.Info("Running automatic cleanup...")
 
-	if err := a.storage.CleanupOldCommands(a.config.RetentionDays); err != nil {
+	retention, ok := a.storage.(storage.RetentionStorageEngine)
+	if !ok {
+		if err := a.storage.CleanupOldCommands(a.GetConfig().RetentionDays); err != nil {
+			a.logger.Error("Cleanup error: %v", err)
+			return
+		}
+		a.logger.Info("✓ Cleanup completed")
+		return
+	}
+
+	if err := a.cleanupByProject(retention); err != nil {
 		a.logger.Error("Cleanup error: %v", err)
 		return
 	}
@@ -276,6 +311,155 @@ func (a *Application) performCleanup() {
 	a.logger.Info("✓ Cleanup completed")
 }
 
+// cleanupByProject applies the retention period of each directory's project
+// config, falling back to the global retention period
+func (a *Application) cleanupByProject(retention storage.RetentionStorageEngine) error {
+	directories, err := retention.GetDirectoriesWithHistory()
+	if err != nil {
+		return fmt.Errorf("failed to list directories: %w", err)
+	}
+
+	globalDays := a.GetConfig().RetentionDays
+	projects := make(map[string]*config.ProjectConfig)
+	byRetention := make(map[int][]string)
+	for _, dir := range directories {
+		days := globalDays
+
+		path, err := config.FindProjectConfig(dir)
+		if err != nil {
+			a.logger.Error("Skipping cleanup of %s: %v", dir, err)
+			continue
+		}
+		if path != "" {
+			project, seen := projects[path]
+			if !seen {
+				project, err = config.LoadProjectConfig(path)
+				if err != nil {
+					a.logger.Error("Skipping cleanup of %s: %v", dir, err)
+				}
+				projects[path] = project
+			}
+			if project == nil {
+				continue
+			}
+			if project.RetentionDays != nil {
+				days = *project.RetentionDays
+			}
+		}
+		if days < 1 {
+			a.logger.Error("Skipping cleanup of %s: invalid retention period of %d days", dir, days)
+			continue
+		}
+
+		byRetention[days] = append(byRetention[days], dir)
+	}
+
+	for days, dirs := range byRetention {
+		removed, err := retention.CleanupDirectoryCommands(dirs, days)
+		if err != nil {
+			return err
+		}
+		if removed > 0 {
+			a.logger.Info("Removed %d commands older than %d days", removed, days)
+		}
+	}
+
+	return nil
+}
+
+// WatchConfig reloads the configuration file whenever it changes, for
+// long-running commands. Valid edits replace the global configuration and are
+// applied to the cleanup schedule, the cache and the executor policy.
+func (a *Application) WatchConfig() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.watcher != nil {
+		return nil
+	}
+
+	path, err := config.ConfigPath()
+	if err != nil {
+		return fmt.Errorf("failed to locate configuration: %w", err)
+	}
+
+	watcher, err := config.NewWatcher(path, a.logger)
+	if err != nil {
+		return err
+	}
+
+	a.unsubscribe = config.Subscribe(a.applyConfig)
+	a.watcher = watcher
+	watcher.Start()
+
+	a.logger.Info("Watching %s for changes", watcher.Path())
+	return nil
+}
+
+// stopWatchingConfig stops the watcher started by WatchConfig
+func (a *Application) stopWatchingConfig() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.watcher == nil {
+		return
+	}
+
+	if err := a.watcher.Close(); err != nil {
+		a.logger.Error("Failed to stop config watcher: %v", err)
+	}
+	a.unsubscribe()
+	a.watcher = nil
+	a.unsubscribe = nil
+}
+
+// applyConfig is notified when the global configuration is swapped
+func (a *Application) applyConfig(old, new *config.Config) {
+	a.mu.Lock()
+	a.config = new
+	a.mu.Unlock()
+
+	// Wake the cleanup loop so it picks up a new interval
+	select {
+	case a.configChanged <- struct{}{}:
+	default:
+	}
+
+	// Cached directory listings may hold commands the new retention or
+	// exclude settings would drop
+	if cached, ok := a.GetStorage().(*storage.CachedStorage); ok {
+		cached.InvalidateCache()
+	}
+
+	if exec := a.GetExecutor(); exec != nil {
+		exec.GetValidator().SetPolicy(securityPolicy(new))
+	}
+
+	if old.StoragePath != new.StoragePath {
+		a.logger.Info("storage_path changed to %s; restart to use the new database", new.StoragePath)
+	}
+}
+
+// executionRecorder records the commands the executor runs through the
+// capture pipeline of the current configuration, so they are redacted and
+// excluded like commands recorded by the shell hooks
+type executionRecorder struct {
+	app *Application
+}
+
+// SaveCommand prepares and stores a command run by the executor
+func (r executionRecorder) SaveCommand(record history.CommandRecord) error {
+	return interceptor.NewCommandCapture(r.app.GetStorage(), r.app.GetConfig()).SaveCommand(record)
+}
+
+// securityPolicy returns the default executor policy with the configured
+// blocked commands added
+func securityPolicy(cfg *config.Config) *executor.SecurityPolicy {
+	policy := executor.DefaultSecurityPolicy()
+	policy.BlacklistedCommands = append(policy.BlacklistedCommands, cfg.BlockedCommands...)
+	return policy
+}
+
 // GetStorage returns the storage engine
 func (a *Application) GetStorage() history.StorageEngine {
 	a.mu.RLock()
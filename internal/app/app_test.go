@@ -0,0 +1,105 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/logging"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// newTestApplication creates an application with a temporary database and a
+// logger writing to logs
+func newTestApplication(t *testing.T, cfg *config.Config, logs *bytes.Buffer) (*Application, *storage.SQLiteStorage) {
+	t.Helper()
+	store := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "commands.db"))
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return &Application{
+		config:        cfg,
+		storage:       store,
+		logger:        logging.New(logs, logging.InfoLevel),
+		cleanupDone:   make(chan struct{}),
+		configChanged: make(chan struct{}, 1),
+	}, store
+}
+
+func TestCleanupByProjectIgnoresZeroRetention(t *testing.T) {
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, ".tracker.json"), []byte(`{"retention_days": 0}`), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+
+	var logs bytes.Buffer
+	app, store := newTestApplication(t, config.DefaultConfig(), &logs)
+
+	record := history.CommandRecord{ID: "recent", Command: "make test", Directory: project, Timestamp: time.Now().Add(-time.Hour), Shell: history.Bash, Tags: []string{}}
+	if err := store.SaveCommand(record); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+
+	if err := app.cleanupByProject(store); err != nil {
+		t.Fatalf("cleanupByProject failed: %v", err)
+	}
+
+	commands, err := store.GetCommandsByDirectory(project)
+	if err != nil || len(commands) != 1 {
+		t.Errorf("Expected a retention_days of 0 to delete nothing, got %d command(s) (%v)", len(commands), err)
+	}
+	if !strings.Contains(logs.String(), "Retention days must be at least 1") {
+		t.Errorf("Expected the invalid project config to be logged, got:\n%s", logs.String())
+	}
+}
+
+func TestConfigReloadUpdatesComponents(t *testing.T) {
+	var logs bytes.Buffer
+	app, store := newTestApplication(t, config.DefaultConfig(), &logs)
+	cached := storage.NewCachedStorage(store, 10, time.Minute)
+	app.storage = cached
+	if err := app.initializeExecutor(); err != nil {
+		t.Fatalf("initializeExecutor failed: %v", err)
+	}
+
+	if _, err := cached.GetCommandsByDirectory("/project"); err != nil {
+		t.Fatalf("GetCommandsByDirectory failed: %v", err)
+	}
+	if entries := cached.(*storage.CachedStorage).GetCacheStats().Entries; entries != 1 {
+		t.Fatalf("Expected the listing to be cached, got %d entries", entries)
+	}
+
+	previous := config.Global()
+	unsubscribe := config.Subscribe(app.applyConfig)
+	defer func() {
+		unsubscribe()
+		config.SetGlobal(previous)
+	}()
+
+	next := config.DefaultConfig()
+	next.BlockedCommands = []string{"terraform"}
+	next.CleanupInterval = 6 * time.Hour
+	config.Swap(next)
+
+	if app.GetConfig() != next {
+		t.Error("Expected the application to use the swapped configuration")
+	}
+	if !app.GetExecutor().GetValidator().IsBlacklisted("terraform") {
+		t.Error("Expected blocked_commands to reach the executor policy")
+	}
+	if entries := cached.(*storage.CachedStorage).GetCacheStats().Entries; entries != 0 {
+		t.Errorf("Expected the cache to be cleared, got %d entries", entries)
+	}
+	select {
+	case <-app.configChanged:
+	default:
+		t.Error("Expected the cleanup loop to be woken")
+	}
+}
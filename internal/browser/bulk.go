@@ -0,0 +1,447 @@
+package browser
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ValGrace/command-history-tracker/internal/exporter"
+	"github.com/ValGrace/command-history-tracker/internal/interceptor"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// bulkAction identifies an action that needs input before it applies to the
+// marked commands
+type bulkAction int
+
+const (
+	bulkDelete bulkAction = iota
+	bulkAddTag
+	bulkRemoveTag
+	bulkExport
+	bulkEdit
+	bulkRun
+)
+
+// maxUndo is the number of changes that can be undone in a session
+const maxUndo = 50
+
+// defaultExportFile is the file name suggested when exporting a script
+const defaultExportFile = "runbook.sh"
+
+// bulkPrompt collects input for a bulk action
+type bulkPrompt struct {
+	action  bulkAction
+	label   string
+	input   string
+	records []history.CommandRecord
+	dir     string // where a bulkRun command runs
+}
+
+// undoEntry restores the commands changed by a browser action
+type undoEntry struct {
+	label    string
+	deleted  []history.CommandRecord // saved again
+	previous []history.CommandRecord // written back over their changes
+}
+
+// pluralCommands formats a command count, "1 command" or "3 commands"
+func pluralCommands(n int) string {
+	if n == 1 {
+		return "1 command"
+	}
+	return fmt.Sprintf("%d commands", n)
+}
+
+// visualRange returns the first and last index of the visual selection
+func (m UIModel) visualRange() (int, int) {
+	if m.visualAnchor < m.selectedIndex {
+		return m.visualAnchor, m.selectedIndex
+	}
+	return m.selectedIndex, m.visualAnchor
+}
+
+// isMarked reports whether the command at index i of the list is marked or
+// inside the visual selection
+func (m UIModel) isMarked(i int) bool {
+	if m.marked[m.filteredCmds[i].ID] {
+		return true
+	}
+	if m.visualMode {
+		lo, hi := m.visualRange()
+		return i >= lo && i <= hi
+	}
+	return false
+}
+
+// markedCount returns the number of marked commands in the list
+func (m UIModel) markedCount() int {
+	count := 0
+	for i := range m.filteredCmds {
+		if m.isMarked(i) {
+			count++
+		}
+	}
+	return count
+}
+
+// commitVisual marks every command in the visual selection and leaves visual mode
+func (m UIModel) commitVisual() UIModel {
+	lo, hi := m.visualRange()
+	for i := lo; i <= hi && i < len(m.filteredCmds); i++ {
+		m.marked[m.filteredCmds[i].ID] = true
+	}
+	m.visualMode = false
+	return m
+}
+
+// clearMarks unmarks everything and leaves visual mode
+func (m UIModel) clearMarks() UIModel {
+	m.marked = make(map[string]bool)
+	m.visualMode = false
+	return m
+}
+
+// bulkTargets returns the commands bulk actions apply to, oldest first: the
+// marked ones and the visual selection, or the selected command when nothing
+// is marked
+func (m UIModel) bulkTargets() []history.CommandRecord {
+	var targets []history.CommandRecord
+	for i, cmd := range m.filteredCmds {
+		if m.isMarked(i) {
+			targets = append(targets, cmd)
+		}
+	}
+
+	// Marks hidden by the current search still count
+	shown := make(map[string]bool, len(targets))
+	for _, cmd := range targets {
+		shown[cmd.ID] = true
+	}
+	for _, cmd := range m.commands {
+		if m.marked[cmd.ID] && !shown[cmd.ID] {
+			targets = append(targets, cmd)
+			shown[cmd.ID] = true
+		}
+	}
+
+	if len(targets) == 0 && m.selectedIndex < len(m.filteredCmds) {
+		targets = append(targets, m.filteredCmds[m.selectedIndex])
+	}
+
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].Timestamp.Before(targets[j].Timestamp)
+	})
+	return targets
+}
+
+// commandIDs returns the IDs of the given commands
+func commandIDs(commands []history.CommandRecord) []string {
+	ids := make([]string, len(commands))
+	for i, cmd := range commands {
+		ids[i] = cmd.ID
+	}
+	return ids
+}
+
+// handleBulkKey handles marking and bulk action keys in the command list. It
+// reports false for keys it does not use.
+func (m UIModel) handleBulkKey(msg tea.KeyMsg) (UIModel, tea.Cmd, bool) {
+	if len(m.filteredCmds) == 0 {
+		return m, nil, false
+	}
+
+	switch msg.String() {
+	case "m":
+		// Toggle the mark and move on, so runs of commands mark quickly
+		if m.visualMode {
+			return m.commitVisual(), nil, true
+		}
+		id := m.filteredCmds[m.selectedIndex].ID
+		if m.marked[id] {
+			delete(m.marked, id)
+		} else {
+			m.marked[id] = true
+		}
+		return m.moveDown(), nil, true
+
+	case "v", "V":
+		if m.visualMode {
+			return m.commitVisual(), nil, true
+		}
+		m.visualMode = true
+		m.visualAnchor = m.selectedIndex
+		return m, nil, true
+
+	case "u":
+		return m.clearMarks(), nil, true
+
+	case "U":
+		// Undo the most recent delete, edit or tag change of this session
+		if len(m.undoStack) == 0 {
+			m.bulkStatus, m.bulkErr = "Nothing to undo", nil
+			return m, nil, true
+		}
+		entry := m.undoStack[len(m.undoStack)-1]
+		m.undoStack = m.undoStack[:len(m.undoStack)-1]
+		return m, undoChange(m.storage, entry), true
+
+	case "esc":
+		if m.visualMode || len(m.marked) > 0 {
+			return m.clearMarks(), nil, true
+		}
+
+	case "D", "delete":
+		targets := m.bulkTargets()
+		m.prompt = &bulkPrompt{
+			action:  bulkDelete,
+			label:   fmt.Sprintf("Delete %s? (y/n)", pluralCommands(len(targets))),
+			records: targets,
+		}
+		return m, nil, true
+
+	case "e":
+		// Edit the selected command, e.g. to remove a secret from it
+		selected := m.filteredCmds[m.selectedIndex]
+		m.prompt = &bulkPrompt{
+			action:  bulkEdit,
+			label:   "Edit command",
+			input:   selected.Command,
+			records: []history.CommandRecord{selected},
+		}
+		return m, nil, true
+
+	case "+":
+		targets := m.bulkTargets()
+		m.prompt = &bulkPrompt{
+			action:  bulkAddTag,
+			label:   fmt.Sprintf("Add tags to %s", pluralCommands(len(targets))),
+			records: targets,
+		}
+		return m, nil, true
+
+	case "-":
+		targets := m.bulkTargets()
+		m.prompt = &bulkPrompt{
+			action:  bulkRemoveTag,
+			label:   fmt.Sprintf("Remove tags from %s", pluralCommands(len(targets))),
+			records: targets,
+		}
+		return m, nil, true
+
+	case "E":
+		targets := m.bulkTargets()
+		m.prompt = &bulkPrompt{
+			action:  bulkExport,
+			label:   fmt.Sprintf("Export %s to script", pluralCommands(len(targets))),
+			input:   defaultExportFile,
+			records: targets,
+		}
+		return m, nil, true
+
+	case "y":
+		targets := m.bulkTargets()
+		lines := make([]string, len(targets))
+		for i, cmd := range targets {
+			lines[i] = cmd.Command
+		}
+		status := fmt.Sprintf("Copied %s to the clipboard", pluralCommands(len(targets)))
+		return m, copyToClipboard(m.clipboard, strings.Join(lines, "\n"), status), true
+
+	case "R":
+		if m.runner == nil {
+			return m, nil, false
+		}
+		targets := m.bulkTargets()
+
+		// Masked commands only run once their secrets are filled in, which
+		// running them one at a time asks for
+		masked := 0
+		for _, cmd := range targets {
+			if cmd.HasTag(interceptor.RedactedTag) {
+				masked++
+			}
+		}
+		if masked > 0 {
+			m.bulkStatus, m.bulkErr = "", fmt.Errorf("sequence not run: %s with masked secrets marked; run them one at a time with enter to fill the secrets in", pluralCommands(masked))
+			return m, nil, true
+		}
+
+		steps := make([]executionStep, len(targets))
+		for i, cmd := range targets {
+			steps[i] = executionStep{record: cmd, dir: m.runDirectory(cmd, true)}
+		}
+		return m, runCommands(m.runner, steps), true
+	}
+
+	return m, nil, false
+}
+
+// handlePromptInput edits the bulk action prompt and applies the action on enter
+func (m UIModel) handlePromptInput(msg tea.KeyMsg) (UIModel, tea.Cmd) {
+	prompt := *m.prompt
+
+	if prompt.action == bulkDelete {
+		// Deleting only needs a yes or no
+		m.prompt = nil
+		switch msg.String() {
+		case "ctrl+c":
+			m.quitting = true
+			return m, tea.Quit
+		case "y", "Y":
+			return m, deleteCommands(m.storage, prompt.records)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+
+	case "esc":
+		m.prompt = nil
+		return m, nil
+
+	case "enter":
+		m.prompt = nil
+		return m.applyPrompt(prompt)
+
+	case "backspace":
+		if len(prompt.input) > 0 {
+			_, size := utf8.DecodeLastRuneInString(prompt.input)
+			prompt.input = prompt.input[:len(prompt.input)-size]
+		}
+
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			prompt.input += string(msg.Runes)
+		}
+	}
+
+	m.prompt = &prompt
+	return m, nil
+}
+
+// applyPrompt runs a bulk action with the entered input
+func (m UIModel) applyPrompt(prompt bulkPrompt) (UIModel, tea.Cmd) {
+	switch prompt.action {
+	case bulkAddTag, bulkRemoveTag:
+		tags := parseTags(prompt.input)
+		if len(tags) == 0 {
+			return m, nil
+		}
+		if prompt.action == bulkAddTag {
+			return m, updateTags(m.storage, prompt.records, tags, nil)
+		}
+		return m, updateTags(m.storage, prompt.records, nil, tags)
+
+	case bulkEdit:
+		previous := prompt.records[0]
+		command := strings.TrimSpace(prompt.input)
+		if command == "" || command == previous.Command {
+			return m, nil
+		}
+		edited := previous
+		edited.Command = command
+		return m, updateCommand(m.storage, previous, edited)
+
+	case bulkRun:
+		command := strings.TrimSpace(prompt.input)
+		if command == "" {
+			return m, nil
+		}
+		if strings.Contains(command, interceptor.RedactionMask) {
+			m.bulkStatus, m.bulkErr = "", fmt.Errorf("replace every %s with its secret before running the command", interceptor.RedactionMask)
+			return m, nil
+		}
+		record := prompt.records[0]
+		record.Command = command
+		m.selectedCmd = &record
+		return m, runCommands(m.runner, []executionStep{{record: record, dir: prompt.dir}})
+
+	case bulkExport:
+		path := strings.TrimSpace(prompt.input)
+		if path == "" {
+			return m, nil
+		}
+		if !filepath.IsAbs(path) && m.workDir != "" {
+			path = filepath.Join(m.workDir, path)
+		}
+		return m, exportScript(prompt.records, path)
+	}
+
+	return m, nil
+}
+
+// pushUndo records a change that can be undone, forgetting the oldest ones
+// beyond maxUndo
+func (m UIModel) pushUndo(entry undoEntry) UIModel {
+	stack := append(append([]undoEntry{}, m.undoStack...), entry)
+	if len(stack) > maxUndo {
+		stack = stack[len(stack)-maxUndo:]
+	}
+	m.undoStack = stack
+	return m
+}
+
+// parseTags splits tag input on commas and whitespace
+func parseTags(input string) []string {
+	return strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// exportScript writes the commands to a new executable shell script
+func exportScript(records []history.CommandRecord, path string) tea.Cmd {
+	return func() tea.Msg {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0755)
+		if err != nil {
+			return bulkResultMsg{err: fmt.Errorf("failed to create script: %w", err)}
+		}
+		defer file.Close()
+
+		writer, err := exporter.NewWriter(exporter.FormatScript, file)
+		if err != nil {
+			return bulkResultMsg{err: err}
+		}
+		for _, record := range records {
+			if err := writer.Write(record); err != nil {
+				return bulkResultMsg{err: fmt.Errorf("failed to write script: %w", err)}
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return bulkResultMsg{err: fmt.Errorf("failed to write script: %w", err)}
+		}
+
+		return bulkResultMsg{status: fmt.Sprintf("Exported %s to %s", pluralCommands(len(records)), path)}
+	}
+}
+
+// osc52 returns the escape sequence asking the terminal to put text on the
+// clipboard. Inside tmux it is wrapped so tmux passes it on.
+func osc52(text string, tmux bool) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if tmux {
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
+
+// copyToClipboard copies text to the clipboard of the terminal, which also
+// works over SSH, and reports status when done
+func copyToClipboard(out io.Writer, text, status string) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := io.WriteString(out, osc52(text, os.Getenv("TMUX") != "")); err != nil {
+			return bulkResultMsg{err: fmt.Errorf("failed to copy to clipboard: %w", err)}
+		}
+		return bulkResultMsg{status: status}
+	}
+}
@@ -0,0 +1,311 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/executor"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// setupBulkModel loads the /home/user history, newest first: ls -la,
+// Get-ChildItem, failed-command
+func setupBulkModel(t *testing.T) (UIModel, *MockStorage) {
+	t.Helper()
+	model, store := setupTestModel()
+	now := time.Now()
+	for i := range store.commands {
+		store.commands[i].Timestamp = now.Add(-time.Duration(i) * time.Minute)
+	}
+
+	next, _ := model.Update(model.loadHistory()())
+	updated := next.(UIModel)
+	if len(updated.filteredCmds) != 3 {
+		t.Fatalf("Expected 3 commands, got %d", len(updated.filteredCmds))
+	}
+	return updated, store
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	case "down":
+		return tea.KeyMsg{Type: tea.KeyDown}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// press sends keys to the model and returns the command of the last one
+func press(t *testing.T, m UIModel, keys ...string) (UIModel, tea.Cmd) {
+	t.Helper()
+	var cmd tea.Cmd
+	for _, k := range keys {
+		m, cmd = m.handleKeyPress(key(k))
+	}
+	return m, cmd
+}
+
+// apply runs a command and feeds its message, and a following reload, back
+func apply(t *testing.T, m UIModel, cmd tea.Cmd) UIModel {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("Expected a command")
+	}
+	next, reload := m.Update(cmd())
+	m = next.(UIModel)
+	if reload != nil {
+		next, _ = m.Update(reload())
+		m = next.(UIModel)
+	}
+	return m
+}
+
+func TestMarkAndVisualSelection(t *testing.T) {
+	model, _ := setupBulkModel(t)
+
+	// m marks and moves down, v selects a range
+	model, _ = press(t, model, "m", "v", "down")
+	if !model.visualMode || model.markedCount() != 3 {
+		t.Fatalf("Expected 3 commands marked in visual mode, got %d", model.markedCount())
+	}
+	if view := model.View(); !strings.Contains(view, "▶●") || !strings.Contains(view, "D: delete") {
+		t.Errorf("Expected marks and bulk keys in view, got:\n%s", view)
+	}
+
+	// Targets are oldest first
+	targets := model.bulkTargets()
+	if len(targets) != 3 || targets[0].Command != "failed-command" || targets[2].Command != "ls -la" {
+		t.Errorf("Expected targets oldest first, got %v", targets)
+	}
+
+	// Committing the range keeps the marks, unmarking clears them
+	model, _ = press(t, model, "v")
+	if model.visualMode || len(model.marked) != 3 {
+		t.Errorf("Expected the range to become 3 marks, got %d", len(model.marked))
+	}
+	model, _ = press(t, model, "m")
+	if len(model.marked) != 2 {
+		t.Errorf("Expected m to unmark the selected command, got %d marks", len(model.marked))
+	}
+	model, _ = press(t, model, "esc")
+	if len(model.marked) != 0 || model.quitting {
+		t.Error("Expected escape to clear the marks")
+	}
+
+	// Without marks actions apply to the selected command
+	model.selectedIndex = 1
+	if targets := model.bulkTargets(); len(targets) != 1 || targets[0].Command != "Get-ChildItem" {
+		t.Errorf("Expected the selected command as target, got %v", targets)
+	}
+}
+
+func TestBulkDeleteAndTags(t *testing.T) {
+	model, store := setupBulkModel(t)
+
+	// Deleting asks first; anything but y cancels
+	model, _ = press(t, model, "m", "m", "D")
+	if model.prompt == nil || !strings.Contains(model.View(), "Delete 2 commands? (y/n)") {
+		t.Fatalf("Expected delete confirmation, got:\n%s", model.View())
+	}
+	model, cmd := press(t, model, "n")
+	if cmd != nil || model.prompt != nil || len(store.commands) != 5 {
+		t.Fatal("Expected n to cancel the deletion")
+	}
+
+	model, cmd = press(t, model, "D", "y")
+	model = apply(t, model, cmd)
+	if len(store.commands) != 3 || len(model.filteredCmds) != 1 || model.filteredCmds[0].Command != "failed-command" {
+		t.Errorf("Expected ls -la and Get-ChildItem deleted, got %v", model.filteredCmds)
+	}
+	if model.bulkStatus != "Deleted 2 commands" || len(model.marked) != 0 {
+		t.Errorf("Expected status and cleared marks, got %q with %d marks", model.bulkStatus, len(model.marked))
+	}
+
+	// Tags are typed into a prompt
+	model, _ = press(t, model, "+", "r", "u", "n", " ", "o", "p", "s")
+	if !strings.Contains(model.View(), "Add tags to 1 command: run ops") {
+		t.Errorf("Expected tag prompt, got:\n%s", model.View())
+	}
+	model, cmd = press(t, model, "enter")
+	model = apply(t, model, cmd)
+	if tags := model.filteredCmds[0].Tags; len(tags) != 2 || tags[0] != "run" || tags[1] != "ops" {
+		t.Errorf("Expected tags run and ops, got %v", tags)
+	}
+
+	model, cmd = press(t, model, "-", "r", "u", "n", "enter")
+	model = apply(t, model, cmd)
+	if tags := model.filteredCmds[0].Tags; len(tags) != 1 || tags[0] != "ops" {
+		t.Errorf("Expected only ops left, got %v", tags)
+	}
+	if model.bulkStatus != "Untagged 1 command: run" {
+		t.Errorf("Unexpected status %q", model.bulkStatus)
+	}
+}
+
+func TestEditAndUndo(t *testing.T) {
+	model, store := setupBulkModel(t)
+
+	// e opens a prompt holding the command to edit
+	model, _ = press(t, model, "e")
+	if model.prompt == nil || model.prompt.input != "ls -la" {
+		t.Fatalf("Expected edit prompt with the command, got %+v", model.prompt)
+	}
+	for range "-la" {
+		model, _ = model.handleKeyPress(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	model, cmd := press(t, model, "-", "l", "enter")
+	model = apply(t, model, cmd)
+	if store.commands[0].Command != "ls -l" || model.filteredCmds[0].Command != "ls -l" {
+		t.Fatalf("Expected command edited to ls -l, got %q", store.commands[0].Command)
+	}
+	if model.bulkStatus != `Edited "ls -la"` || len(model.undoStack) != 1 {
+		t.Errorf("Expected edit status and one undo entry, got %q with %d", model.bulkStatus, len(model.undoStack))
+	}
+
+	model, cmd = press(t, model, "U")
+	model = apply(t, model, cmd)
+	if store.commands[0].Command != "ls -la" || len(model.undoStack) != 0 {
+		t.Errorf("Expected edit undone, got %q", store.commands[0].Command)
+	}
+
+	// Deleted commands come back with their tags
+	model, cmd = press(t, model, "+", "k", "e", "e", "p", "enter")
+	model = apply(t, model, cmd)
+	model, cmd = press(t, model, "D", "y")
+	model = apply(t, model, cmd)
+	if len(store.commands) != 4 || len(model.filteredCmds) != 2 {
+		t.Fatalf("Expected one command deleted, got %d left", len(store.commands))
+	}
+
+	model, cmd = press(t, model, "U")
+	model = apply(t, model, cmd)
+	if len(store.commands) != 5 || len(model.filteredCmds) != 3 || model.bulkStatus != "Undone: Deleted 1 command" {
+		t.Fatalf("Expected deletion undone, got %d commands and %q", len(store.commands), model.bulkStatus)
+	}
+	for _, cmd := range store.commands {
+		if cmd.ID == "1" && (len(cmd.Tags) != 1 || cmd.Tags[0] != "keep") {
+			t.Errorf("Expected restored command to keep its tags, got %v", cmd.Tags)
+		}
+	}
+
+	// Then the tag change is undone
+	model, cmd = press(t, model, "U")
+	model = apply(t, model, cmd)
+	for _, cmd := range store.commands {
+		if cmd.ID == "1" && len(cmd.Tags) != 0 {
+			t.Errorf("Expected tags removed by undo, got %v", cmd.Tags)
+		}
+	}
+
+	model, _ = press(t, model, "U")
+	if model.bulkStatus != "Nothing to undo" {
+		t.Errorf("Expected nothing to undo, got %q", model.bulkStatus)
+	}
+}
+
+func TestBulkCopyAndExport(t *testing.T) {
+	t.Setenv("TMUX", "")
+	model, _ := setupBulkModel(t)
+	var clipboard bytes.Buffer
+	model.clipboard = &clipboard
+	model.workDir = t.TempDir()
+
+	model, cmd := press(t, model, "m", "m", "y")
+	model = apply(t, model, cmd)
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("Get-ChildItem\nls -la")) + "\a"
+	if clipboard.String() != want {
+		t.Errorf("Expected OSC 52 sequence %q, got %q", want, clipboard.String())
+	}
+	if model.bulkStatus != "Copied 2 commands to the clipboard" || len(model.marked) != 2 {
+		t.Errorf("Expected status with marks kept, got %q", model.bulkStatus)
+	}
+
+	// Export suggests a file name relative to the working directory
+	model, cmd = press(t, model, "E", "enter")
+	model = apply(t, model, cmd)
+	path := filepath.Join(model.workDir, defaultExportFile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected script to be written: %v", err)
+	}
+	if string(content) != "#!/bin/sh\nset -e\n\ncd '/home/user'\nGet-ChildItem\nls -la\n" {
+		t.Errorf("Unexpected script:\n%s", content)
+	}
+	if info, _ := os.Stat(path); info.Mode()&0100 == 0 {
+		t.Errorf("Expected the script to be executable, got %v", info.Mode())
+	}
+
+	// Existing files are not overwritten
+	model, cmd = press(t, model, "E", "enter")
+	model = apply(t, model, cmd)
+	if model.bulkErr == nil || !strings.Contains(model.View(), "failed to create script") {
+		t.Errorf("Expected an error for an existing file, got %v", model.bulkErr)
+	}
+}
+
+func TestBulkRunInSequence(t *testing.T) {
+	model, store := setupBulkModel(t)
+	runner := &mockRunner{storage: store, result: &executor.ExecutionResult{Duration: time.Second}}
+	model.runner = runner
+	model.workDir = "/work"
+
+	model, _ = press(t, model, "m", "m", "m")
+	if _, cmd := press(t, model, "R"); cmd == nil {
+		t.Fatal("Expected R to run the marked commands")
+	}
+
+	steps := []executionStep{
+		{record: history.CommandRecord{Command: "make build"}, dir: "/work"},
+		{record: history.CommandRecord{Command: "make test"}, dir: "/work"},
+		{record: history.CommandRecord{Command: "make deploy"}, dir: "/work"},
+	}
+
+	var out bytes.Buffer
+	run := &execution{runner: runner, steps: steps, stdin: strings.NewReader("\n"), stdout: &out}
+	if err := run.Run(); err != nil || run.completed != 3 {
+		t.Fatalf("Expected all steps to run, got %d and %v", run.completed, err)
+	}
+	if !strings.Contains(out.String(), "[3/3] $ make deploy") {
+		t.Errorf("Expected numbered steps, got %q", out.String())
+	}
+
+	// A failing command stops the sequence
+	runner.result = &executor.ExecutionResult{ExitCode: 2}
+	out.Reset()
+	run = &execution{runner: runner, steps: steps, stdin: strings.NewReader("\n"), stdout: &out}
+	run.Run()
+	if run.completed != 0 || run.last != 0 || !strings.Contains(out.String(), "Stopped after 0 of 3 commands") {
+		t.Errorf("Expected to stop at the first failure, got %q", out.String())
+	}
+
+	next, _ := model.Update(executionMsg{command: "make build", dir: "/work", result: run.result, completed: 0, total: 3})
+	if view := next.(UIModel).View(); !strings.Contains(view, "Stopped at make build after 0 of 3 commands: it exited with 2") {
+		t.Errorf("Expected the sequence result in view, got:\n%s", view)
+	}
+
+	// A sequence with a masked command is not started
+	model.filteredCmds[1].Tags = []string{"redacted"}
+	model, cmd := press(t, model, "R")
+	if cmd != nil || model.bulkErr == nil || !strings.Contains(model.View(), "1 command with masked secrets") {
+		t.Errorf("Expected the sequence to be refused, got %v", model.bulkErr)
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	seq := osc52("hi", false)
+	if seq != "\x1b]52;c;aGk=\a" {
+		t.Errorf("Unexpected sequence %q", seq)
+	}
+	if wrapped := osc52("hi", true); wrapped != "\x1bPtmux;\x1b\x1b]52;c;aGk=\a\x1b\\" {
+		t.Errorf("Unexpected tmux sequence %q", wrapped)
+	}
+}
@@ -1,12 +1,15 @@
 package browser
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/ValGrace/command-history-tracker/internal/storage"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 // TestFilteringIntegration tests the complete filtering workflow
@@ -212,6 +215,9 @@ func TestFilteringIntegration(t *testing.T) {
 			history.Bash,
 			history.Zsh,
 			history.Cmd,
+			history.Fish,
+			history.Nushell,
+			history.Elvish,
 			history.Unknown,
 		}
 
@@ -354,3 +360,137 @@ func TestFilteringWithStorageBackend(t *testing.T) {
 		}
 	})
 }
+
+// TestLazyLoadingWithStorageBackend tests that pages load as the selection
+// nears the end and that a search loads the remaining pages
+func TestLazyLoadingWithStorageBackend(t *testing.T) {
+	store := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "commands.db"))
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+
+	total := historyPageSize*2 + 10
+	now := time.Now()
+	commands := make([]history.CommandRecord, total)
+	for i := range commands {
+		commands[i] = history.CommandRecord{
+			ID:        fmt.Sprintf("cmd-%04d", i),
+			Command:   fmt.Sprintf("echo %d", i),
+			Directory: "/big",
+			Timestamp: now.Add(-time.Duration(i) * time.Second),
+			Shell:     history.Bash,
+		}
+	}
+	if err := store.BatchSaveCommands(commands); err != nil {
+		t.Fatalf("Failed to save commands: %v", err)
+	}
+
+	model := *NewUIModel(store, "/big")
+	next, _ := model.Update(model.loadHistory()())
+	model = next.(UIModel)
+	if len(model.commands) != historyPageSize || model.nextCursor == "" {
+		t.Fatalf("Expected a first page of %d commands, got %d (cursor %q)", historyPageSize, len(model.commands), model.nextCursor)
+	}
+
+	// Scrolling towards the end of the page requests the next one
+	var cmd tea.Cmd
+	for !model.loadingPage {
+		next, cmd = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+		model = next.(UIModel)
+	}
+	selected := model.selectedIndex
+	if selected != historyPageSize-loadAheadRows {
+		t.Errorf("Expected the next page at index %d, requested at %d", historyPageSize-loadAheadRows, selected)
+	}
+	next, _ = model.Update(cmd())
+	model = next.(UIModel)
+	if len(model.commands) != historyPageSize*2 || model.selectedIndex != selected {
+		t.Fatalf("Expected %d commands with the selection kept at %d, got %d at %d",
+			historyPageSize*2, selected, len(model.commands), model.selectedIndex)
+	}
+	if model.commands[historyPageSize].Command != fmt.Sprintf("echo %d", historyPageSize) {
+		t.Errorf("Expected the second page to continue the first, got %q", model.commands[historyPageSize].Command)
+	}
+
+	// A search needs every command, so the last page loads right away
+	for _, k := range []string{"/", "e"} {
+		next, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		model = next.(UIModel)
+	}
+	if !model.loadingPage || cmd == nil {
+		t.Fatal("Expected a search to load the remaining commands")
+	}
+	next, _ = model.Update(cmd())
+	model = next.(UIModel)
+	if len(model.commands) != total || model.nextCursor != "" {
+		t.Fatalf("Expected all %d commands loaded, got %d (cursor %q)", total, len(model.commands), model.nextCursor)
+	}
+	for _, r := range "cho 1005" {
+		next, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		model = next.(UIModel)
+	}
+	if len(model.filteredCmds) == 0 || model.filteredCmds[0].Command != "echo 1005" {
+		t.Errorf("Expected the search to find a command from the last page, got %d matches", len(model.filteredCmds))
+	}
+
+	// A page requested before a reload is discarded
+	model.loadingPage = true
+	stale := directoryHistoryMsg{filters: storage.CommandFilters{Directory: "/other"}, more: true,
+		commands: []history.CommandRecord{{ID: "stale"}}}
+	next, _ = model.Update(stale)
+	if len(next.(UIModel).commands) != total {
+		t.Error("Expected a page for other filters to be ignored")
+	}
+}
+
+// TestCachedStorageBackend tests the session, statistics and directory views
+// against the cached storage the tracker commands open
+func TestCachedStorageBackend(t *testing.T) {
+	sqliteStorage := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "commands.db"))
+	if err := sqliteStorage.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	store := storage.NewCachedStorage(sqliteStorage, 10, time.Minute)
+	defer store.Close()
+
+	now := time.Now()
+	for i, command := range []string{"git status", "go test ./...", "git status"} {
+		record := history.CommandRecord{
+			ID:        fmt.Sprintf("cached-%d", i),
+			Command:   command,
+			Directory: "/project",
+			Timestamp: now.Add(time.Duration(i-3) * time.Minute),
+			Shell:     history.Bash,
+			SessionID: "sess-cached",
+			Tags:      []string{},
+		}
+		if err := store.SaveCommand(record); err != nil {
+			t.Fatalf("Failed to save command: %v", err)
+		}
+	}
+
+	if _, ok := store.(interface {
+		GetDirectoryStats() ([]history.DirectoryIndex, error)
+	}); !ok {
+		t.Error("Expected the directory tree to use the stored directory statistics")
+	}
+	if msg, ok := loadDirectoryTree(store)().(directoryTreeMsg); !ok || len(msg.directories) != 1 || msg.directories[0].CommandCount != 3 {
+		t.Errorf("Unexpected directory tree: %+v", msg)
+	}
+
+	sessions, ok := loadSessions(store)().(sessionsMsg)
+	if !ok || len(sessions.sessions) != 1 || sessions.sessions[0].CommandCount != 3 {
+		t.Fatalf("Expected the recorded session, got %+v", sessions)
+	}
+
+	stats, ok := loadStats(store, "", now.Add(-time.Hour), 10)().(statsMsg)
+	if !ok || stats.stats.TotalCommands != 3 {
+		t.Errorf("Expected usage statistics for 3 commands, got %+v", stats)
+	}
+
+	runs, ok := loadCommandRuns(store, "git status", "/project", now.Add(-time.Hour))().(directoryHistoryMsg)
+	if !ok || len(runs.commands) != 2 {
+		t.Errorf("Expected 2 runs of git status, got %+v", runs)
+	}
+}
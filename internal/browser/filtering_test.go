@@ -227,6 +227,9 @@ func TestShellFilterCycle(t *testing.T) {
 		history.Bash,
 		history.Zsh,
 		history.Cmd,
+		history.Fish,
+		history.Nushell,
+		history.Elvish,
 		history.Unknown,
 	}
 
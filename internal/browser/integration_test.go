@@ -266,6 +266,27 @@ func TestPreviewToggling(t *testing.T) {
 	}
 }
 
+func TestPreviewShowsContext(t *testing.T) {
+	model, _ := setupTestModel()
+	cmd := createTestCommand("1", "kubectl apply -f deploy.yaml", "/test", history.Bash, 0)
+
+	if preview := model.renderCommandPreview(cmd); strings.Contains(preview, "Host:") || strings.Contains(preview, "Kube Context:") {
+		t.Errorf("Expected no context lines for a record without context, got:\n%s", preview)
+	}
+
+	cmd.Hostname, cmd.Username, cmd.SSH = "build-01", "alice", true
+	cmd.ContainerID, cmd.PythonEnv, cmd.KubeContext = "0123456789ab", "/srv/app/.venv", "prod-eu"
+	preview := model.renderCommandPreview(cmd)
+	for _, want := range []string{"alice@build-01 (ssh)", "Container: ", "0123456789ab", "/srv/app/.venv", "Kube Context: ", "prod-eu"} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("Expected preview to contain %q, got:\n%s", want, preview)
+		}
+	}
+	if strings.Contains(preview, "TTY:") {
+		t.Error("Expected an unrecorded TTY to be left out")
+	}
+}
+
 func TestDirectoryNavigation(t *testing.T) {
 	model, _ := setupTestModel()
 	model.currentDir = "/home/user/project"
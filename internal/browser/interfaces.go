@@ -1,14 +1,20 @@
 package browser
 
 import (
+	"io"
+	"os"
+
+	"github.com/ValGrace/command-history-tracker/internal/executor"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 // Browser implements the HistoryBrowser interface
 type Browser struct {
 	currentDir string
 	storage    history.StorageEngine
+	executor   *executor.Executor
 }
 
 // NewBrowser creates a new history browser
@@ -18,12 +24,29 @@ func NewBrowser(storage history.StorageEngine) *Browser {
 	}
 }
 
+// SetExecutor makes the browser run selected commands with exec instead of
+// returning them
+func (b *Browser) SetExecutor(exec *executor.Executor) {
+	b.executor = exec
+}
+
+// newModel creates a UI model for dir that runs selected commands when an
+// executor is set
+func (b *Browser) newModel(dir string) *UIModel {
+	model := NewUIModel(b.storage, dir)
+	if b.executor != nil {
+		model.runner = b.executor
+		model.workDir, _ = os.Getwd()
+	}
+	return model
+}
+
 // ShowDirectoryHistory displays commands for a specific directory using terminal UI
 func (b *Browser) ShowDirectoryHistory(dir string) error {
 	b.currentDir = dir
 
 	// Create and run the terminal UI
-	model := NewUIModel(b.storage, dir)
+	model := b.newModel(dir)
 	program := tea.NewProgram(model, tea.WithAltScreen())
 
 	_, err := program.Run()
@@ -33,7 +56,7 @@ func (b *Browser) ShowDirectoryHistory(dir string) error {
 // ShowDirectoryTree displays directory tree with command counts using terminal UI
 func (b *Browser) ShowDirectoryTree() error {
 	// Create UI model in directory tree view mode
-	model := NewUIModel(b.storage, b.currentDir)
+	model := b.newModel(b.currentDir)
 	model.viewMode = DirectoryTreeView
 
 	program := tea.NewProgram(model, tea.WithAltScreen())
@@ -41,6 +64,26 @@ func (b *Browser) ShowDirectoryTree() error {
 	return err
 }
 
+// ShowSessionHistory displays the commands recorded by one shell session
+func (b *Browser) ShowSessionHistory(sessionID string) error {
+	model := b.newModel(b.currentDir)
+	model.currentSession = sessionID
+
+	program := tea.NewProgram(model, tea.WithAltScreen())
+	_, err := program.Run()
+	return err
+}
+
+// ShowSessions displays recorded shell sessions for selection
+func (b *Browser) ShowSessions() error {
+	model := b.newModel(b.currentDir)
+	model.viewMode = SessionView
+
+	program := tea.NewProgram(model, tea.WithAltScreen())
+	_, err := program.Run()
+	return err
+}
+
 // SelectCommand allows user to select a command interactively
 func (b *Browser) SelectCommand() (*history.CommandRecord, error) {
 	// Create UI model for command selection
@@ -60,10 +103,36 @@ func (b *Browser) SelectCommand() (*history.CommandRecord, error) {
 	return nil, nil
 }
 
+// PickCommand runs the browser inline below the prompt, drawing on output and
+// reading keys from the terminal, starting with query typed into the search.
+// It returns the chosen command without executing it, or nil when cancelled.
+func (b *Browser) PickCommand(query string, output io.Writer) (*history.CommandRecord, error) {
+	model := NewUIModel(b.storage, b.currentDir)
+	model.insertMode = true
+	model.searchMode = true
+	model.searchQuery = query
+	model.matchMode = FuzzyMatch
+
+	// Colors follow the terminal drawn on rather than the captured stdout
+	lipgloss.SetColorProfile(lipgloss.NewRenderer(output).ColorProfile())
+
+	program := tea.NewProgram(model, tea.WithOutput(output), tea.WithInputTTY())
+	finalModel, err := program.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	if uiModel, ok := finalModel.(UIModel); ok {
+		return uiModel.selectedCmd, nil
+	}
+
+	return nil, nil
+}
+
 // FilterCommands applies search filter to displayed commands
 func (b *Browser) FilterCommands(pattern string) error {
 	// Create UI model in search mode
-	model := NewUIModel(b.storage, b.currentDir)
+	model := b.newModel(b.currentDir)
 	model.searchMode = true
 	model.searchQuery = pattern
 	filteredModel := model.filterCommands()
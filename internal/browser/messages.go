@@ -1,6 +1,14 @@
 package browser
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/executor"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -10,6 +18,13 @@ import (
 // directoryHistoryMsg contains commands for a specific directory
 type directoryHistoryMsg struct {
 	commands []history.CommandRecord
+
+	// Paged loads: the filters the page was read with, the cursor of the
+	// next page ("" when every command is loaded) and whether the commands
+	// follow those already loaded instead of replacing them
+	filters    storage.CommandFilters
+	nextCursor string
+	more       bool
 }
 
 // directoryTreeMsg contains directory tree information
@@ -17,6 +32,35 @@ type directoryTreeMsg struct {
 	directories []history.DirectoryIndex
 }
 
+// sessionsMsg contains recorded shell sessions
+type sessionsMsg struct {
+	sessions []history.SessionIndex
+}
+
+// statsMsg contains usage statistics for the stats view
+type statsMsg struct {
+	stats *storage.UsageStats
+}
+
+// executionMsg reports commands run from the browser; command, dir and
+// result describe the last one run
+type executionMsg struct {
+	command   string
+	dir       string
+	result    *executor.ExecutionResult
+	err       error
+	completed int // commands that finished successfully
+	total     int // commands in the sequence
+}
+
+// bulkResultMsg reports a bulk action on the marked commands
+type bulkResultMsg struct {
+	status  string
+	err     error
+	changed bool       // stored commands changed and the list must be reloaded
+	undo    *undoEntry // reverts the change, nil when it cannot be undone
+}
+
 // errorMsg contains error information
 type errorMsg struct {
 	error error
@@ -24,10 +68,51 @@ type errorMsg struct {
 
 // Commands for loading data asynchronously
 
-// loadDirectoryHistory loads command history for a specific directory
-func loadDirectoryHistory(storage history.StorageEngine, dir string) tea.Cmd {
+// historyPageSize is the number of commands loaded at a time from storage
+// that supports paging
+const historyPageSize = 500
+
+// loadAheadRows is how close the selection gets to the last loaded command
+// before the next page is requested
+const loadAheadRows = 100
+
+// pagedStorage is implemented by storage engines that read commands a page
+// at a time
+type pagedStorage interface {
+	GetCommandsPage(filters storage.CommandFilters, cursor string, limit int) (*storage.CommandPage, error)
+}
+
+// loadHistoryPage loads the page of commands matching filters that follows
+// cursor, or the first page when cursor is empty
+func loadHistoryPage(store history.StorageEngine, filters storage.CommandFilters, cursor string) tea.Cmd {
+	return func() tea.Msg {
+		pageStore, ok := store.(pagedStorage)
+		if !ok {
+			return errorMsg{error: fmt.Errorf("paging is not supported by this storage")}
+		}
+
+		page, err := pageStore.GetCommandsPage(filters, cursor, historyPageSize)
+		if err != nil {
+			return errorMsg{error: err}
+		}
+		return directoryHistoryMsg{
+			commands:   page.Commands,
+			filters:    filters,
+			nextCursor: page.NextCursor,
+			more:       cursor != "",
+		}
+	}
+}
+
+// loadDirectoryHistory loads command history for a specific directory, the
+// first page of it when the storage supports paging
+func loadDirectoryHistory(store history.StorageEngine, dir string) tea.Cmd {
+	if _, ok := store.(pagedStorage); ok {
+		return loadHistoryPage(store, storage.CommandFilters{Directory: dir}, "")
+	}
+
 	return func() tea.Msg {
-		commands, err := storage.GetCommandsByDirectory(dir)
+		commands, err := store.GetCommandsByDirectory(dir)
 		if err != nil {
 			return errorMsg{error: err}
 		}
@@ -97,3 +182,283 @@ func searchCommands(storage history.StorageEngine, pattern, dir string) tea.Cmd
 		return directoryHistoryMsg{commands: commands}
 	}
 }
+
+// sessionStorage is implemented by storage engines that track shell sessions
+type sessionStorage interface {
+	GetCommandsBySession(sessionID string) ([]history.CommandRecord, error)
+	GetSessions(limit int) ([]history.SessionIndex, error)
+}
+
+// loadSessions loads recorded shell sessions, most recent first
+func loadSessions(storage history.StorageEngine) tea.Cmd {
+	return func() tea.Msg {
+		sessionStore, ok := storage.(sessionStorage)
+		if !ok {
+			return errorMsg{error: fmt.Errorf("session tracking is not supported by this storage")}
+		}
+
+		sessions, err := sessionStore.GetSessions(0)
+		if err != nil {
+			return errorMsg{error: err}
+		}
+		return sessionsMsg{sessions: sessions}
+	}
+}
+
+// loadSessionHistory loads the commands recorded by a single shell session,
+// the first page of them when the storage supports paging
+func loadSessionHistory(store history.StorageEngine, sessionID string) tea.Cmd {
+	if _, ok := store.(pagedStorage); ok {
+		return loadHistoryPage(store, storage.CommandFilters{SessionID: sessionID}, "")
+	}
+
+	return func() tea.Msg {
+		sessionStore, ok := store.(sessionStorage)
+		if !ok {
+			return errorMsg{error: fmt.Errorf("session tracking is not supported by this storage")}
+		}
+
+		commands, err := sessionStore.GetCommandsBySession(sessionID)
+		if err != nil {
+			return errorMsg{error: err}
+		}
+		return directoryHistoryMsg{commands: commands}
+	}
+}
+
+// usageStatsStorage is implemented by storage engines that aggregate usage
+// statistics and filter commands in SQL
+type usageStatsStorage interface {
+	GetUsageStats(query storage.StatsQuery) (*storage.UsageStats, error)
+	FilterCommands(filters storage.CommandFilters) ([]history.CommandRecord, error)
+}
+
+// loadStats loads usage statistics for a directory subtree ("" for all
+// directories) since the given time
+func loadStats(store history.StorageEngine, dir string, since time.Time, limit int) tea.Cmd {
+	return func() tea.Msg {
+		statsStore, ok := store.(usageStatsStorage)
+		if !ok {
+			return errorMsg{error: fmt.Errorf("usage statistics are not supported by this storage")}
+		}
+
+		stats, err := statsStore.GetUsageStats(storage.StatsQuery{Directory: dir, StartTime: since, Limit: limit})
+		if err != nil {
+			return errorMsg{error: err}
+		}
+		return statsMsg{stats: stats}
+	}
+}
+
+// loadCommandRuns loads the runs of one command in a directory subtree ("" for
+// all directories) since the given time
+func loadCommandRuns(store history.StorageEngine, command, dir string, since time.Time) tea.Cmd {
+	return func() tea.Msg {
+		statsStore, ok := store.(usageStatsStorage)
+		if !ok {
+			return errorMsg{error: fmt.Errorf("usage statistics are not supported by this storage")}
+		}
+
+		commands, err := statsStore.FilterCommands(storage.CommandFilters{
+			Directory: dir,
+			Recursive: true,
+			Command:   command,
+			StartTime: since,
+		})
+		if err != nil {
+			return errorMsg{error: err}
+		}
+		return directoryHistoryMsg{commands: commands}
+	}
+}
+
+// commandRunner runs commands chosen in the browser, validating them, asking
+// for confirmation when needed and recording the run
+type commandRunner interface {
+	Execute(cmd *history.CommandRecord, dir string) (*executor.ExecutionResult, error)
+}
+
+// executionStep is a command to run and the directory to run it in
+type executionStep struct {
+	record history.CommandRecord
+	dir    string
+}
+
+// execution runs commands one after another while the browser is suspended,
+// stopping at the first that fails, and waits for Enter afterwards so their
+// output can be read
+type execution struct {
+	runner    commandRunner
+	steps     []executionStep
+	stdin     io.Reader
+	stdout    io.Writer
+	result    *executor.ExecutionResult // result of the last command run
+	last      int                       // index of the last step attempted
+	completed int                       // steps that finished successfully
+}
+
+func (e *execution) SetStdin(r io.Reader)  { e.stdin = r }
+func (e *execution) SetStdout(w io.Writer) { e.stdout = w }
+func (e *execution) SetStderr(w io.Writer) {}
+
+// Run executes the commands and prints a summary of each result
+func (e *execution) Run() error {
+	var err error
+	for i, step := range e.steps {
+		e.last = i
+		if len(e.steps) > 1 {
+			fmt.Fprintf(e.stdout, "[%d/%d] ", i+1, len(e.steps))
+		}
+		fmt.Fprintf(e.stdout, "$ %s\n  (in %s)\n\n", step.record.Command, step.dir)
+
+		// Validate and preview the command against where it actually runs
+		record := step.record
+		record.Directory = step.dir
+		var result *executor.ExecutionResult
+		result, err = e.runner.Execute(&record, step.dir)
+		e.result = result
+
+		fmt.Fprintln(e.stdout)
+		switch {
+		case err != nil:
+			fmt.Fprintf(e.stdout, "✗ %v\n", err)
+		case result != nil && result.ExitCode != 0:
+			fmt.Fprintf(e.stdout, "✗ exit code %d after %s\n", result.ExitCode, result.Duration.Round(time.Millisecond))
+		case result != nil:
+			fmt.Fprintf(e.stdout, "✓ finished in %s\n", result.Duration.Round(time.Millisecond))
+		}
+
+		if err != nil || (result != nil && result.ExitCode != 0) {
+			break
+		}
+		e.completed++
+		if i < len(e.steps)-1 {
+			fmt.Fprintln(e.stdout)
+		}
+	}
+
+	if len(e.steps) > 1 && e.completed < len(e.steps) {
+		fmt.Fprintf(e.stdout, "Stopped after %d of %d commands\n", e.completed, len(e.steps))
+	}
+
+	fmt.Fprint(e.stdout, "Press Enter to return to the browser...")
+	bufio.NewReader(e.stdin).ReadString('\n')
+	return err
+}
+
+// runCommands suspends the browser, runs the steps in order and reports the
+// result of the last one run
+func runCommands(runner commandRunner, steps []executionStep) tea.Cmd {
+	run := &execution{runner: runner, steps: steps}
+	return tea.Exec(run, func(err error) tea.Msg {
+		step := steps[run.last]
+		return executionMsg{
+			command:   step.record.Command,
+			dir:       step.dir,
+			result:    run.result,
+			err:       err,
+			completed: run.completed,
+			total:     len(steps),
+		}
+	})
+}
+
+// editableStorage is implemented by storage engines that delete and edit
+// stored commands
+type editableStorage interface {
+	DeleteCommands(ids []string) (int64, error)
+	UpdateTags(ids []string, add []string, remove []string) (int64, error)
+	UpdateCommand(cmd history.CommandRecord) error
+}
+
+// deleteCommands deletes commands, keeping them so the deletion can be undone
+func deleteCommands(store history.StorageEngine, records []history.CommandRecord) tea.Cmd {
+	return func() tea.Msg {
+		editable, ok := store.(editableStorage)
+		if !ok {
+			return bulkResultMsg{err: fmt.Errorf("deleting commands is not supported by this storage")}
+		}
+
+		removed, err := editable.DeleteCommands(commandIDs(records))
+		if err != nil {
+			return bulkResultMsg{err: err, changed: removed > 0}
+		}
+
+		label := fmt.Sprintf("Deleted %s", pluralCommands(int(removed)))
+		return bulkResultMsg{
+			status:  label,
+			changed: true,
+			undo:    &undoEntry{label: label, deleted: records},
+		}
+	}
+}
+
+// updateTags adds and removes tags on commands
+func updateTags(store history.StorageEngine, records []history.CommandRecord, add, remove []string) tea.Cmd {
+	return func() tea.Msg {
+		editable, ok := store.(editableStorage)
+		if !ok {
+			return bulkResultMsg{err: fmt.Errorf("editing tags is not supported by this storage")}
+		}
+
+		changed, err := editable.UpdateTags(commandIDs(records), add, remove)
+		if err != nil {
+			return bulkResultMsg{err: err}
+		}
+
+		verb, tags := "Tagged", add
+		if len(remove) > 0 {
+			verb, tags = "Untagged", remove
+		}
+		label := fmt.Sprintf("%s %s: %s", verb, pluralCommands(int(changed)), strings.Join(tags, ", "))
+		return bulkResultMsg{
+			status:  label,
+			changed: true,
+			undo:    &undoEntry{label: label, previous: records},
+		}
+	}
+}
+
+// updateCommand replaces a stored command with an edited version
+func updateCommand(store history.StorageEngine, previous, edited history.CommandRecord) tea.Cmd {
+	return func() tea.Msg {
+		editable, ok := store.(editableStorage)
+		if !ok {
+			return bulkResultMsg{err: fmt.Errorf("editing commands is not supported by this storage")}
+		}
+
+		if err := editable.UpdateCommand(edited); err != nil {
+			return bulkResultMsg{err: err}
+		}
+
+		label := fmt.Sprintf("Edited %q", previous.Command)
+		return bulkResultMsg{
+			status:  label,
+			changed: true,
+			undo:    &undoEntry{label: label, previous: []history.CommandRecord{previous}},
+		}
+	}
+}
+
+// undoChange restores the commands an undo entry recorded
+func undoChange(store history.StorageEngine, entry undoEntry) tea.Cmd {
+	return func() tea.Msg {
+		editable, ok := store.(editableStorage)
+		if !ok {
+			return bulkResultMsg{err: fmt.Errorf("editing commands is not supported by this storage")}
+		}
+
+		for _, record := range entry.deleted {
+			if err := store.SaveCommand(record); err != nil {
+				return bulkResultMsg{err: fmt.Errorf("failed to restore command: %w", err), changed: true}
+			}
+		}
+		for _, record := range entry.previous {
+			if err := editable.UpdateCommand(record); err != nil {
+				return bulkResultMsg{err: fmt.Errorf("failed to restore command: %w", err), changed: true}
+			}
+		}
+
+		return bulkResultMsg{status: "Undone: " + entry.label, changed: true}
+	}
+}
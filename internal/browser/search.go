@@ -0,0 +1,353 @@
+package browser
+
+import (
+	"fmt"
+	"math/bits"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// MatchMode selects how the search query is matched against commands
+type MatchMode int
+
+const (
+	ExactMatch MatchMode = iota
+	FuzzyMatch
+	RegexMatch
+)
+
+// String returns the name shown in the search header
+func (m MatchMode) String() string {
+	switch m {
+	case FuzzyMatch:
+		return "fuzzy"
+	case RegexMatch:
+		return "regex"
+	default:
+		return "exact"
+	}
+}
+
+// next returns the mode after m in the exact, fuzzy, regex cycle
+func (m MatchMode) next() MatchMode {
+	return (m + 1) % 3
+}
+
+// asyncSearchThreshold is the number of commands above which searches run in
+// the background instead of inside Update
+const asyncSearchThreshold = 2000
+
+// Score weights for matched characters
+const (
+	scoreMatchChar   = 16
+	bonusConsecutive = 8
+	bonusWordStart   = 8
+	bonusPrefix      = 16
+	maxGapPenalty    = 8
+)
+
+// commandMatcher matches commands against a search query. Lower-case queries
+// match case-insensitively, queries with an upper-case letter match exactly.
+type commandMatcher struct {
+	mode          MatchMode
+	query         string
+	pattern       []rune
+	caseSensitive bool
+	re            *regexp.Regexp
+}
+
+// newCommandMatcher compiles a query for the given mode
+func newCommandMatcher(query string, mode MatchMode) (*commandMatcher, error) {
+	m := &commandMatcher{
+		mode:          mode,
+		query:         query,
+		caseSensitive: strings.IndexFunc(query, unicode.IsUpper) >= 0,
+	}
+
+	if mode == RegexMatch {
+		expr := query
+		if !m.caseSensitive {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		m.re = re
+		return m, nil
+	}
+
+	for _, r := range query {
+		m.pattern = append(m.pattern, m.fold(r))
+	}
+	return m, nil
+}
+
+// fold lower-cases r unless the match is case-sensitive
+func (m *commandMatcher) fold(r rune) rune {
+	if m.caseSensitive {
+		return r
+	}
+	return unicode.ToLower(r)
+}
+
+// match reports whether text matches, with a quality score and the byte
+// offsets of the matched characters
+func (m *commandMatcher) match(text string) (int, []int, bool) {
+	switch m.mode {
+	case FuzzyMatch:
+		return m.matchFuzzy(text)
+	case RegexMatch:
+		return m.matchRegex(text)
+	default:
+		return m.matchExact(text)
+	}
+}
+
+// matchExact finds the best-scoring occurrence of the query in text
+func (m *commandMatcher) matchExact(text string) (int, []int, bool) {
+	if len(m.pattern) == 0 {
+		return 0, nil, true
+	}
+
+	bestScore := -1
+	var best []int
+	for start := range text {
+		positions, ok := m.matchAt(text, start)
+		if !ok {
+			continue
+		}
+		if score := scoreMatch(text, positions); score > bestScore {
+			bestScore, best = score, positions
+		}
+	}
+
+	if best == nil {
+		return 0, nil, false
+	}
+	return bestScore, best, true
+}
+
+// matchAt returns the offsets of the query characters if they occur
+// contiguously at start
+func (m *commandMatcher) matchAt(text string, start int) ([]int, bool) {
+	positions := make([]int, 0, len(m.pattern))
+	offset := start
+	for _, want := range m.pattern {
+		if offset >= len(text) {
+			return nil, false
+		}
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		if m.fold(r) != want {
+			return nil, false
+		}
+		positions = append(positions, offset)
+		offset += size
+	}
+	return positions, true
+}
+
+// matchFuzzy matches the query characters in order with anything between
+// them. Like fzf, it finds the first complete match, then walks back from its
+// end to the shortest span that still contains the query.
+func (m *commandMatcher) matchFuzzy(text string) (int, []int, bool) {
+	if len(m.pattern) == 0 {
+		return 0, nil, true
+	}
+
+	end := -1
+	next := 0
+	for i, r := range text {
+		if m.fold(r) == m.pattern[next] {
+			next++
+			if next == len(m.pattern) {
+				end = i + utf8.RuneLen(r)
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	start := end
+	for next = len(m.pattern) - 1; next >= 0 && start > 0; {
+		r, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+		if m.fold(r) == m.pattern[next] {
+			next--
+		}
+	}
+
+	positions := make([]int, 0, len(m.pattern))
+	next = 0
+	for i, r := range text[start:end] {
+		if next < len(m.pattern) && m.fold(r) == m.pattern[next] {
+			positions = append(positions, start+i)
+			next++
+		}
+	}
+
+	return scoreMatch(text, positions), positions, true
+}
+
+// matchRegex matches the leftmost occurrence of the expression
+func (m *commandMatcher) matchRegex(text string) (int, []int, bool) {
+	loc := m.re.FindStringIndex(text)
+	if loc == nil {
+		return 0, nil, false
+	}
+
+	var positions []int
+	for i := range text[loc[0]:loc[1]] {
+		positions = append(positions, loc[0]+i)
+	}
+	return scoreMatch(text, positions), positions, true
+}
+
+// scoreMatch rates matched characters: every character scores, characters
+// that follow the previous match or start a word score more, and gaps between
+// matches cost a little
+func scoreMatch(text string, positions []int) int {
+	if len(positions) == 0 {
+		return 0
+	}
+
+	score := 0
+	prevEnd := -1
+	for _, pos := range positions {
+		score += scoreMatchChar
+		if isWordStart(text, pos) {
+			score += bonusWordStart
+		}
+		if prevEnd >= 0 {
+			if gap := pos - prevEnd; gap == 0 {
+				score += bonusConsecutive
+			} else if gap > maxGapPenalty {
+				score -= maxGapPenalty
+			} else {
+				score -= gap
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		prevEnd = pos + size
+	}
+
+	if positions[0] == 0 {
+		score += bonusPrefix
+	}
+	return score
+}
+
+// isWordStart reports whether the character at pos starts a word of a command
+func isWordStart(text string, pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	return strings.IndexByte(" /\\-_.=:|;&'\"", text[pos-1]) >= 0
+}
+
+// recencyBonus favours commands run recently
+func recencyBonus(age time.Duration) int {
+	switch {
+	case age < time.Hour:
+		return 24
+	case age < 24*time.Hour:
+		return 16
+	case age < 7*24*time.Hour:
+		return 8
+	case age < 30*24*time.Hour:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// frequencyBonus favours commands run often, growing with the logarithm of
+// the number of runs
+func frequencyBonus(count int) int {
+	if count <= 1 {
+		return 0
+	}
+	bonus := 6 * (bits.Len(uint(count)) - 1)
+	if bonus > 24 {
+		bonus = 24
+	}
+	return bonus
+}
+
+// countCommands counts the runs of each distinct command text
+func countCommands(commands []history.CommandRecord) map[string]int {
+	counts := make(map[string]int)
+	for _, cmd := range commands {
+		counts[cmd.Command]++
+	}
+	return counts
+}
+
+// rankCommands returns the commands matching the matcher, best first. The
+// score adds match quality, recency and how often the command was run;
+// commands with the same score keep their order. It returns nil when
+// cancelled reports true part way through.
+func rankCommands(commands []history.CommandRecord, matcher *commandMatcher, counts map[string]int, now time.Time, cancelled func() bool) []history.CommandRecord {
+	type scored struct {
+		cmd   history.CommandRecord
+		score int
+	}
+
+	var matches []scored
+	for i, cmd := range commands {
+		if cancelled != nil && i%1024 == 0 && cancelled() {
+			return nil
+		}
+		quality, _, ok := matcher.match(cmd.Command)
+		if !ok {
+			continue
+		}
+		score := quality + recencyBonus(now.Sub(cmd.Timestamp)) + frequencyBonus(counts[cmd.Command])
+		matches = append(matches, scored{cmd: cmd, score: score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	ranked := make([]history.CommandRecord, len(matches))
+	for i, match := range matches {
+		ranked[i] = match.cmd
+	}
+	return ranked
+}
+
+// searchResultsMsg carries the ranked matches of a background search
+type searchResultsMsg struct {
+	generation uint64
+	query      string
+	matches    []history.CommandRecord
+	counts     map[string]int
+}
+
+// runSearch ranks commands in the background. Searches superseded by a newer
+// generation stop early and deliver nothing.
+func runSearch(commands []history.CommandRecord, matcher *commandMatcher, counts map[string]int, generation uint64, latest *atomic.Uint64) tea.Cmd {
+	return func() tea.Msg {
+		cancelled := func() bool { return latest.Load() != generation }
+		if counts == nil {
+			counts = countCommands(commands)
+		}
+
+		matches := rankCommands(commands, matcher, counts, time.Now(), cancelled)
+		if cancelled() {
+			return nil
+		}
+		return searchResultsMsg{generation: generation, query: matcher.query, matches: matches, counts: counts}
+	}
+}
@@ -0,0 +1,177 @@
+package browser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCommandMatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      MatchMode
+		query     string
+		text      string
+		matches   bool
+		positions []int
+	}{
+		{"exact", ExactMatch, "stat", "git status", true, []int{4, 5, 6, 7}},
+		{"exact prefers word start", ExactMatch, "st", "test status", true, []int{5, 6}},
+		{"exact miss", ExactMatch, "gst", "git status", false, nil},
+		{"fuzzy", FuzzyMatch, "gst", "git status", true, []int{0, 4, 5}},
+		{"fuzzy shortest span", FuzzyMatch, "ls", "lx; ls", true, []int{4, 5}},
+		{"fuzzy order matters", FuzzyMatch, "tg", "git", false, nil},
+		{"smart case insensitive", FuzzyMatch, "gci", "Get-ChildItem", true, []int{0, 4, 6}},
+		{"smart case sensitive", ExactMatch, "Get", "get-thing", false, nil},
+		{"regex", RegexMatch, `^git (push|pull)`, "git pull --rebase", true, []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{"regex insensitive", RegexMatch, `make`, "MAKE all", true, []int{0, 1, 2, 3}},
+		{"unicode", FuzzyMatch, "éf", "echo café fin", true, []int{8, 11}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := newCommandMatcher(tt.query, tt.mode)
+			if err != nil {
+				t.Fatalf("newCommandMatcher failed: %v", err)
+			}
+			_, positions, ok := matcher.match(tt.text)
+			if ok != tt.matches {
+				t.Fatalf("Expected match=%v for %q, got %v", tt.matches, tt.text, ok)
+			}
+			if fmt.Sprint(positions) != fmt.Sprint(tt.positions) {
+				t.Errorf("Expected positions %v, got %v", tt.positions, positions)
+			}
+		})
+	}
+
+	if _, err := newCommandMatcher("(", RegexMatch); err == nil {
+		t.Error("Expected an invalid regular expression to be rejected")
+	}
+}
+
+func TestRankCommands(t *testing.T) {
+	now := time.Now()
+	commands := []history.CommandRecord{
+		{Command: "gradle assemble test", Timestamp: now.Add(-10 * 24 * time.Hour)},
+		{Command: "go build ./...", Timestamp: now.Add(-60 * 24 * time.Hour)},
+		{Command: "git status", Timestamp: now.Add(-2 * time.Hour)},
+		{Command: "git status", Timestamp: now.Add(-3 * time.Hour)},
+		{Command: "git stash", Timestamp: now.Add(-90 * 24 * time.Hour)},
+	}
+	counts := countCommands(commands)
+
+	matcher, _ := newCommandMatcher("gst", FuzzyMatch)
+	ranked := rankCommands(commands, matcher, counts, now, nil)
+	if len(ranked) != 4 {
+		t.Fatalf("Expected 4 matches, got %d", len(ranked))
+	}
+
+	// Word-start matches run often and recently rank first, equal scores keep
+	// the most recent run first and a scattered match comes last
+	want := []string{"git status", "git status", "git stash", "gradle assemble test"}
+	for i, cmd := range ranked {
+		if cmd.Command != want[i] {
+			t.Errorf("Rank %d: expected %q, got %q", i, want[i], cmd.Command)
+		}
+	}
+	if !ranked[0].Timestamp.Equal(now.Add(-2 * time.Hour)) {
+		t.Error("Expected equal scores to keep the most recent run first")
+	}
+}
+
+func TestBackgroundSearch(t *testing.T) {
+	model, _ := setupTestModel()
+	now := time.Now()
+	commands := make([]history.CommandRecord, 100000)
+	for i := range commands {
+		commands[i] = history.CommandRecord{
+			ID:        fmt.Sprint(i),
+			Command:   fmt.Sprintf("echo %d", i),
+			Timestamp: now.Add(-time.Duration(i) * time.Second),
+		}
+	}
+	commands[500].Command = "git status"
+	commands[900].Command = "git stash"
+
+	next, _ := model.Update(directoryHistoryMsg{commands: commands})
+	m := next.(UIModel)
+	m.searchMode = true
+	m.matchMode = FuzzyMatch
+
+	// Typing starts a background search instead of filtering in Update
+	m, first := m.handleSearchInput(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+	if first == nil || !m.searching {
+		t.Fatal("Expected typing to start a background search")
+	}
+	if len(m.filteredCmds) != len(commands) {
+		t.Errorf("Expected results to stay unchanged while searching, got %d", len(m.filteredCmds))
+	}
+
+	// A newer query supersedes the running search
+	m, second := m.handleSearchInput(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if msg := first(); msg != nil {
+		t.Errorf("Expected the superseded search to deliver nothing, got %T", msg)
+	}
+
+	next, _ = m.Update(second())
+	m = next.(UIModel)
+	if m.searching || len(m.filteredCmds) != 2 {
+		t.Fatalf("Expected 2 matches for \"gs\", got %d (searching=%v)", len(m.filteredCmds), m.searching)
+	}
+
+	// Extending the query only searches the previous matches
+	m.searchQuery += "t"
+	if candidates := m.searchCandidates(); len(candidates) != 2 {
+		t.Errorf("Expected the extended query to search 2 candidates, got %d", len(candidates))
+	}
+	m.searchQuery = "g"
+	if candidates := m.searchCandidates(); len(candidates) != len(commands) {
+		t.Errorf("Expected a shorter query to search every command, got %d", len(candidates))
+	}
+}
+
+func TestSearchHighlightAndModes(t *testing.T) {
+	model, _ := setupTestModel()
+	model.width = 60
+	model.commands = []history.CommandRecord{
+		createTestCommand("1", "git status", "/home/user", history.Bash, 0),
+		createTestCommand("2", "Get-ChildItem", "/home/user", history.PowerShell, 0),
+		createTestCommand("3", "kubectl describe pods --all-namespaces --output wide --sort-by name", "/home/user", history.Bash, 0),
+	}
+	model.searchQuery = "git"
+	*model = model.applyFilters()
+	if len(model.filteredCmds) != 1 {
+		t.Fatalf("Expected exact search to match 1 command, got %d", len(model.filteredCmds))
+	}
+
+	// ctrl+r switches to fuzzy matching
+	updated, _ := model.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if updated.matchMode != FuzzyMatch || len(updated.filteredCmds) != 2 {
+		t.Fatalf("Expected fuzzy search to match 2 commands, got mode %v with %d", updated.matchMode, len(updated.filteredCmds))
+	}
+
+	line := updated.formatDirectoryCommandLine(updated.filteredCmds[0], false, 0)
+	if !strings.Contains(line, "git status") {
+		t.Errorf("Expected highlighted line to keep the command text, got %q", line)
+	}
+
+	// Matches past the truncated end of a long command are not highlighted
+	updated.searchQuery = "name"
+	updated = updated.applyFilters()
+	full := updated.filteredCmds[0].Command
+	if positions := updated.visibleMatches(full, full[:17]+"..."); len(positions) != 0 {
+		t.Errorf("Expected no visible matches in the truncated text, got %v", positions)
+	}
+
+	// An invalid regular expression is reported without dropping the list
+	updated.matchMode = RegexMatch
+	updated.searchQuery = "(git"
+	updated = updated.applyFilters()
+	if updated.searchErr == nil || len(updated.filteredCmds) != 3 {
+		t.Errorf("Expected a regex error and the unfiltered list, got %v with %d", updated.searchErr, len(updated.filteredCmds))
+	}
+}
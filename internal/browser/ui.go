@@ -2,9 +2,15 @@ package browser
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
+	"github.com/ValGrace/command-history-tracker/internal/interceptor"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -18,8 +24,23 @@ const (
 	DirectoryHistoryView ViewMode = iota
 	DirectoryTreeView
 	SearchView
+	SessionView
+	StatsView
 )
 
+// maxInlineHeight is the number of lines the browser uses when it runs inline
+// below the prompt
+const maxInlineHeight = 20
+
+// statsDays is the number of days summarized by the stats view
+const statsDays = 30
+
+// statsEntry is a selectable command in the stats view
+type statsEntry struct {
+	section string
+	usage   storage.CommandUsage
+}
+
 // FilterMode represents different filtering modes
 type FilterMode int
 
@@ -60,6 +81,22 @@ type UIModel struct {
 	commands    []history.CommandRecord
 	directories []history.DirectoryIndex
 
+	// Lazy loading of paged command lists
+	pageFilters storage.CommandFilters // filters the loaded pages were read with
+	nextCursor  string                 // cursor of the next page, "" when every command is loaded
+	loadingPage bool
+
+	// Session grouping
+	sessions       []history.SessionIndex
+	currentSession string
+
+	// Usage statistics
+	stats         *storage.UsageStats
+	statsEntries  []statsEntry
+	statsAllDirs  bool   // summarize every directory instead of the current subtree
+	statsCommand  string // command whose runs are listed after selecting a stats entry
+	statsSelected int
+
 	// Selection and navigation
 	selectedIndex int
 	scrollOffset  int
@@ -72,6 +109,17 @@ type UIModel struct {
 	searchMode   bool
 	filteredCmds []history.CommandRecord
 
+	// Search matching and ranking
+	matchMode       MatchMode
+	matcher         *commandMatcher
+	searchErr       error
+	searchBase      []history.CommandRecord // ranked matches of searchBaseQuery, narrowed as the query grows
+	searchBaseQuery string
+	commandCounts   map[string]int
+	searchGen       uint64
+	latestSearch    *atomic.Uint64 // shared with background searches so stale ones stop early
+	searching       bool
+
 	// Advanced filtering
 	filterMode  FilterMode
 	dateFilter  DateFilterConfig
@@ -90,8 +138,24 @@ type UIModel struct {
 
 	// Dependencies
 	storage history.StorageEngine
+	runner  commandRunner // nil when selecting only returns the command
+	workDir string        // directory commands run in when not their own
+
+	// Last command run from the browser
+	lastRun *executionMsg
+
+	// Multi-select and bulk actions
+	marked       map[string]bool // IDs of marked commands
+	visualMode   bool            // selecting the range from visualAnchor to the selection
+	visualAnchor int
+	prompt       *bulkPrompt
+	bulkStatus   string
+	bulkErr      error
+	undoStack    []undoEntry // changes to stored commands, most recent last
+	clipboard    io.Writer   // receives OSC 52 clipboard sequences
 
 	// UI state
+	insertMode  bool // picking a command for the prompt: inline, enter chooses without executing
 	quitting    bool
 	error       error
 	selectedCmd *history.CommandRecord
@@ -105,6 +169,7 @@ func NewUIModel(storage history.StorageEngine, currentDir string) *UIModel {
 		currentDir:    currentDir,
 		commands:      []history.CommandRecord{},
 		directories:   []history.DirectoryIndex{},
+		sessions:      []history.SessionIndex{},
 		selectedIndex: 0,
 		scrollOffset:  0,
 		pageSize:      50, // Show 50 commands per page for better performance
@@ -113,6 +178,7 @@ func NewUIModel(storage history.StorageEngine, currentDir string) *UIModel {
 		searchQuery:   "",
 		searchMode:    false,
 		filteredCmds:  []history.CommandRecord{},
+		latestSearch:  new(atomic.Uint64),
 		filterMode:    NoFilter,
 		dateFilter:    DateFilterConfig{Enabled: false},
 		shellFilter:   history.Unknown,
@@ -121,6 +187,8 @@ func NewUIModel(storage history.StorageEngine, currentDir string) *UIModel {
 		parentDirs:    []string{},
 		directoryTree: []DirectoryTreeItem{},
 		treeExpanded:  make(map[string]bool),
+		marked:        make(map[string]bool),
+		clipboard:     os.Stdout,
 		width:         80,
 		height:        24,
 		storage:       storage,
@@ -133,30 +201,89 @@ func NewUIModel(storage history.StorageEngine, currentDir string) *UIModel {
 
 // Init implements tea.Model
 func (m UIModel) Init() tea.Cmd {
+	if m.viewMode == SessionView {
+		return loadSessions(m.storage)
+	}
+	if m.viewMode == StatsView {
+		return tea.Batch(m.loadStats(), loadDirectoryTree(m.storage))
+	}
+
 	return tea.Batch(
-		loadDirectoryHistory(m.storage, m.currentDir),
+		m.loadHistory(),
 		loadDirectoryTree(m.storage),
 	)
 }
 
+// loadHistory loads the command list for the active session or directory
+func (m UIModel) loadHistory() tea.Cmd {
+	if m.currentSession != "" {
+		return loadSessionHistory(m.storage, m.currentSession)
+	}
+	if m.statsCommand != "" {
+		return loadCommandRuns(m.storage, m.statsCommand, m.statsDirectory(), statsRangeStart(time.Now()))
+	}
+	return loadDirectoryHistory(m.storage, m.currentDir)
+}
+
+// loadStats loads usage statistics sized to fit the window
+func (m UIModel) loadStats() tea.Cmd {
+	// Three rankings share the space left by the header, sparkline and footer
+	limit := (m.height - 14) / 3
+	if limit < 1 {
+		limit = 1
+	} else if limit > storage.DefaultStatsLimit {
+		limit = storage.DefaultStatsLimit
+	}
+	return loadStats(m.storage, m.statsDirectory(), statsRangeStart(time.Now()), limit)
+}
+
+// statsDirectory returns the subtree summarized by the stats view, "" for all
+func (m UIModel) statsDirectory() string {
+	if m.statsAllDirs {
+		return ""
+	}
+	return m.currentDir
+}
+
+// statsRangeStart returns the start of the day statsDays-1 days before now
+func statsRangeStart(now time.Time) time.Time {
+	start := now.AddDate(0, 0, -(statsDays - 1))
+	return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+}
+
 // Update implements tea.Model
 func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		if m.insertMode && m.height > maxInlineHeight {
+			// Leave the rest of the terminal to the scrollback above the prompt
+			m.height = maxInlineHeight
+		}
 		return m, nil
 
 	case tea.KeyMsg:
-		return m.handleKeyPress(msg)
+		updated, cmd := m.handleKeyPress(msg)
+		return updated.loadMoreIfNeeded(cmd)
 
 	case directoryHistoryMsg:
+		if msg.more {
+			return m.appendPage(msg)
+		}
 		m.commands = msg.commands
+		m.pageFilters = msg.filters
+		m.nextCursor = msg.nextCursor
+		m.loadingPage = false
+		m = m.cancelSearch()
+		m.searchBase = nil
+		m.commandCounts = nil
 		// Initialize filtered commands for directory-based browsing
 		// Commands are already sorted chronologically (recent-first) from storage
+		var search tea.Cmd
 		if len(m.commands) > 0 {
 			// Apply existing filters to new commands
-			m = m.applyFilters()
+			m, search = m.updateSearch()
 		} else {
 			// No commands in this directory
 			m.filteredCmds = []history.CommandRecord{}
@@ -164,7 +291,8 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Reset selection when loading new directory
 		m.selectedIndex = 0
 		m.scrollOffset = 0
-		return m, nil
+		m.visualMode = false
+		return m.loadMoreIfNeeded(search)
 
 	case directoryTreeMsg:
 		m.directories = msg.directories
@@ -172,6 +300,47 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.directoryTree = m.organizeDirectoriesHierarchically()
 		return m, nil
 
+	case sessionsMsg:
+		m.sessions = msg.sessions
+		return m, nil
+
+	case searchResultsMsg:
+		if msg.generation != m.searchGen {
+			return m, nil // superseded by a newer query
+		}
+		m.searching = false
+		m.searchBase = msg.matches
+		m.searchBaseQuery = msg.query
+		m.commandCounts = msg.counts
+		m.filteredCmds = m.filterByDateAndShell(msg.matches)
+		m.selectedIndex = 0
+		m.scrollOffset = 0
+		return m, nil
+
+	case executionMsg:
+		// Show the outcome and pick up the newly recorded run
+		m.lastRun = &msg
+		return m, m.loadHistory()
+
+	case bulkResultMsg:
+		m.bulkStatus, m.bulkErr = msg.status, msg.err
+		if msg.undo != nil {
+			m = m.pushUndo(*msg.undo)
+		}
+		if msg.changed {
+			m = m.clearMarks()
+			return m, m.loadHistory()
+		}
+		return m, nil
+
+	case statsMsg:
+		m.stats = msg.stats
+		m.statsEntries = buildStatsEntries(msg.stats)
+		if m.viewMode == StatsView && m.selectedIndex > m.getMaxIndex() {
+			m.selectedIndex = m.getMaxIndex()
+		}
+		return m, nil
+
 	case errorMsg:
 		m.error = msg.error
 		return m, nil
@@ -183,6 +352,10 @@ func (m UIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 // View implements tea.Model
 func (m UIModel) View() string {
 	if m.quitting {
+		if m.insertMode {
+			// Clear the inline browser so only the prompt remains
+			return ""
+		}
 		return "Goodbye!\n"
 	}
 
@@ -197,6 +370,10 @@ func (m UIModel) View() string {
 		return m.renderDirectoryTreeView()
 	case SearchView:
 		return m.renderSearchView()
+	case SessionView:
+		return m.renderSessionView()
+	case StatsView:
+		return m.renderStatsView()
 	default:
 		return "Unknown view mode"
 	}
@@ -204,27 +381,52 @@ func (m UIModel) View() string {
 
 // handleKeyPress processes keyboard input
 func (m UIModel) handleKeyPress(msg tea.KeyMsg) (UIModel, tea.Cmd) {
+	// An open bulk action prompt takes every key
+	if m.prompt != nil {
+		return m.handlePromptInput(msg)
+	}
+
 	// Global key bindings
 	switch msg.String() {
 	case "q", "ctrl+c":
-		m.quitting = true
-		return m, tea.Quit
+		// q is part of the query while searching
+		if msg.String() == "ctrl+c" || !m.searchMode {
+			m.quitting = true
+			return m, tea.Quit
+		}
+
+	case "esc":
+		if m.insertMode && !m.searchMode {
+			m.quitting = true
+			return m, tea.Quit
+		}
 
 	case "tab":
 		return m.switchViewMode(), nil
 
 	case "/":
 		if !m.searchMode {
+			m.visualMode = false
 			m.searchMode = true
 			m.searchQuery = ""
 			m.filterMode = TextFilter
 			return m, nil
 		}
 
+	case "ctrl+r":
+		// Cycle between exact, fuzzy and regex matching
+		if m.searchMode || m.searchQuery != "" {
+			m.matchMode = m.matchMode.next()
+			m.searchBase = nil
+			return m.updateSearch()
+		}
+
 	case "f":
 		// Toggle filter panel
-		m.showFilters = !m.showFilters
-		return m, nil
+		if !m.searchMode {
+			m.showFilters = !m.showFilters
+			return m, nil
+		}
 
 	case "d":
 		// Toggle date filter
@@ -269,6 +471,13 @@ func (m UIModel) handleKeyPress(msg tea.KeyMsg) (UIModel, tea.Cmd) {
 		}
 	}
 
+	// Marking and bulk actions in the command list
+	if m.viewMode == DirectoryHistoryView && !m.insertMode {
+		if updated, cmd, ok := m.handleBulkKey(msg); ok {
+			return updated, cmd
+		}
+	}
+
 	// Navigation key bindings
 	switch msg.String() {
 	case "up", "k":
@@ -284,21 +493,55 @@ func (m UIModel) handleKeyPress(msg tea.KeyMsg) (UIModel, tea.Cmd) {
 		m.showPreview = !m.showPreview
 		return m, nil
 
+	case "x":
+		// Run the selected command in the directory the browser was opened in
+		if m.viewMode == DirectoryHistoryView && m.runner != nil && !m.insertMode {
+			return m.executeSelected(false)
+		}
+
 	case "t":
 		m.viewMode = DirectoryTreeView
 		return m, loadDirectoryTree(m.storage)
 
 	case "h":
 		m.viewMode = DirectoryHistoryView
+		m.currentSession = ""
+		m.statsCommand = ""
 		return m, loadDirectoryHistory(m.storage, m.currentDir)
 
+	case "S":
+		// Group history by terminal session
+		m.viewMode = SessionView
+		m.selectedIndex = 0
+		m.scrollOffset = 0
+		return m, loadSessions(m.storage)
+
+	case "a":
+		// Usage statistics for the current directory or the whole tree
+		m.viewMode = StatsView
+		m.selectedIndex = 0
+		m.scrollOffset = 0
+		return m, m.loadStats()
+
+	case "w":
+		if m.viewMode == StatsView {
+			// Switch between the current directory and every directory
+			m.statsAllDirs = !m.statsAllDirs
+			m.selectedIndex = 0
+			return m, m.loadStats()
+		}
+
 	case "r":
 		// Refresh current view
 		switch m.viewMode {
 		case DirectoryHistoryView:
-			return m, loadDirectoryHistory(m.storage, m.currentDir)
+			return m, m.loadHistory()
 		case DirectoryTreeView:
 			return m, loadDirectoryTree(m.storage)
+		case SessionView:
+			return m, loadSessions(m.storage)
+		case StatsView:
+			return m, m.loadStats()
 		}
 
 	case "e":
@@ -320,7 +563,21 @@ func (m UIModel) handleKeyPress(msg tea.KeyMsg) (UIModel, tea.Cmd) {
 		}
 
 	case "backspace", "left":
-		if m.viewMode == DirectoryHistoryView {
+		if m.viewMode == DirectoryHistoryView && m.statsCommand != "" {
+			// Return to the statistics, keeping the selected entry
+			m.statsCommand = ""
+			m.viewMode = StatsView
+			m.selectedIndex = m.statsSelected
+			m.scrollOffset = 0
+			return m, nil
+		} else if m.viewMode == DirectoryHistoryView && m.currentSession != "" {
+			// Return to the session list
+			m.currentSession = ""
+			m.viewMode = SessionView
+			m.selectedIndex = 0
+			m.scrollOffset = 0
+			return m, loadSessions(m.storage)
+		} else if m.viewMode == DirectoryHistoryView {
 			// Navigate to parent directory
 			parentDir := getParentDirectory(m.currentDir)
 			if parentDir != "" {
@@ -370,6 +627,7 @@ func (m UIModel) handleKeyPress(msg tea.KeyMsg) (UIModel, tea.Cmd) {
 					m.currentDir = selectedItem.Path
 					m.breadcrumbs = buildBreadcrumbs(selectedItem.Path)
 					m.viewMode = DirectoryHistoryView
+					m.statsCommand = ""
 					return m, loadDirectoryHistory(m.storage, selectedItem.Path)
 				}
 			}
@@ -382,27 +640,47 @@ func (m UIModel) handleKeyPress(msg tea.KeyMsg) (UIModel, tea.Cmd) {
 // handleSearchInput processes search mode input
 func (m UIModel) handleSearchInput(msg tea.KeyMsg) (UIModel, tea.Cmd) {
 	switch msg.String() {
-	case "escape":
+	case "escape", "esc":
 		m.searchMode = false
 		m.searchQuery = ""
+		m = m.cancelSearch()
 		m.filteredCmds = m.commands
 		m.selectedIndex = 0
 		return m, nil
 
 	case "enter":
 		m.searchMode = false
-		return m.filterCommands(), nil
+		if m.insertMode {
+			// Choose the best match right away, as Ctrl-R does
+			if m.searching {
+				m = m.cancelSearch()
+				m = m.applyFilters()
+			}
+			return m.selectItem()
+		}
+		if m.searching {
+			// The pending background search delivers the results
+			return m, nil
+		}
+		return m.updateSearch()
+
+	case "up", "ctrl+p":
+		return m.moveUp(), nil
+
+	case "down", "ctrl+n":
+		return m.moveDown(), nil
 
 	case "backspace":
 		if len(m.searchQuery) > 0 {
-			m.searchQuery = m.searchQuery[:len(m.searchQuery)-1]
-			return m.filterCommands(), nil
+			_, size := utf8.DecodeLastRuneInString(m.searchQuery)
+			m.searchQuery = m.searchQuery[:len(m.searchQuery)-size]
+			return m.updateSearch()
 		}
 
 	default:
-		if len(msg.String()) == 1 {
-			m.searchQuery += msg.String()
-			return m.filterCommands(), nil
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			m.searchQuery += string(msg.Runes)
+			return m.updateSearch()
 		}
 	}
 
@@ -416,10 +694,54 @@ func (m UIModel) switchViewMode() UIModel {
 		m.viewMode = DirectoryTreeView
 	case DirectoryTreeView:
 		m.viewMode = DirectoryHistoryView
+	case SessionView, StatsView:
+		m.viewMode = DirectoryHistoryView
 	}
 	return m
 }
 
+// loadMoreIfNeeded requests the next page of commands when the selection
+// nears the end of the loaded ones, or while filters are active so they see
+// every command
+func (m UIModel) loadMoreIfNeeded(cmd tea.Cmd) (UIModel, tea.Cmd) {
+	if m.quitting || m.loadingPage || m.nextCursor == "" || m.viewMode != DirectoryHistoryView {
+		return m, cmd
+	}
+
+	filtering := m.searchQuery != "" || m.dateFilter.Enabled || m.shellFilter != history.Unknown
+	if !filtering && m.selectedIndex+loadAheadRows < len(m.filteredCmds) {
+		return m, cmd
+	}
+
+	m.loadingPage = true
+	return m, tea.Batch(cmd, loadHistoryPage(m.storage, m.pageFilters, m.nextCursor))
+}
+
+// appendPage adds a lazily loaded page to the command list, keeping the
+// selection where it is
+func (m UIModel) appendPage(msg directoryHistoryMsg) (UIModel, tea.Cmd) {
+	if !m.loadingPage || msg.filters != m.pageFilters {
+		return m, nil // the list was reloaded since the page was requested
+	}
+
+	m.loadingPage = false
+	m.nextCursor = msg.nextCursor
+	m.commands = append(m.commands, msg.commands...)
+	m.commandCounts = nil
+	m.searchBase = nil
+
+	selected, offset := m.selectedIndex, m.scrollOffset
+	var search tea.Cmd
+	m, search = m.updateSearch()
+	if search == nil {
+		m.selectedIndex, m.scrollOffset = selected, offset
+		if maxIndex := m.getMaxIndex(); m.selectedIndex > maxIndex {
+			m.selectedIndex = maxIndex
+		}
+	}
+	return m.loadMoreIfNeeded(search)
+}
+
 // moveUp moves selection up
 func (m UIModel) moveUp() UIModel {
 	if m.selectedIndex > 0 {
@@ -461,6 +783,16 @@ func (m UIModel) getMaxIndex() int {
 			return 0
 		}
 		return len(m.directoryTree) - 1
+	case SessionView:
+		if len(m.sessions) == 0 {
+			return 0
+		}
+		return len(m.sessions) - 1
+	case StatsView:
+		if len(m.statsEntries) == 0 {
+			return 0
+		}
+		return len(m.statsEntries) - 1
 	default:
 		return 0
 	}
@@ -470,6 +802,9 @@ func (m UIModel) getMaxIndex() int {
 func (m UIModel) selectItem() (UIModel, tea.Cmd) {
 	switch m.viewMode {
 	case DirectoryHistoryView:
+		if m.runner != nil && !m.insertMode {
+			return m.executeSelected(true)
+		}
 		if len(m.filteredCmds) > 0 && m.selectedIndex < len(m.filteredCmds) {
 			// Store selected command for retrieval
 			m.selectedCmd = &m.filteredCmds[m.selectedIndex]
@@ -489,17 +824,155 @@ func (m UIModel) selectItem() (UIModel, tea.Cmd) {
 			m.searchQuery = ""
 			m.searchMode = false
 			m.filteredCmds = []history.CommandRecord{}
+			m.currentSession = ""
+			m.statsCommand = ""
 			return m, loadDirectoryHistory(m.storage, selectedItem.Path)
 		}
+	case SessionView:
+		if len(m.sessions) > 0 && m.selectedIndex < len(m.sessions) {
+			// Show every command typed in the selected terminal session
+			m.currentSession = m.sessions[m.selectedIndex].ID
+			m.viewMode = DirectoryHistoryView
+			m.selectedIndex = 0
+			m.scrollOffset = 0
+			m.searchQuery = ""
+			m.searchMode = false
+			m.filteredCmds = []history.CommandRecord{}
+			m.statsCommand = ""
+			return m, loadSessionHistory(m.storage, m.currentSession)
+		}
+	case StatsView:
+		if len(m.statsEntries) > 0 && m.selectedIndex < len(m.statsEntries) {
+			// List the runs of the selected command within the summarized scope
+			m.statsCommand = m.statsEntries[m.selectedIndex].usage.Command
+			m.statsSelected = m.selectedIndex
+			m.viewMode = DirectoryHistoryView
+			m.selectedIndex = 0
+			m.scrollOffset = 0
+			m.searchQuery = ""
+			m.searchMode = false
+			m.filteredCmds = []history.CommandRecord{}
+			m.currentSession = ""
+			return m, m.loadHistory()
+		}
 	}
 	return m, nil
 }
 
+// executeSelected suspends the browser and runs the selected command
+func (m UIModel) executeSelected(inOriginalDir bool) (UIModel, tea.Cmd) {
+	if len(m.filteredCmds) == 0 || m.selectedIndex >= len(m.filteredCmds) {
+		return m, nil
+	}
+
+	record := m.filteredCmds[m.selectedIndex]
+
+	// A masked command would run with **** in place of its secrets, so it
+	// is only run once they have been filled in. The edit is not saved.
+	if record.HasTag(interceptor.RedactedTag) {
+		m.prompt = &bulkPrompt{
+			action:  bulkRun,
+			label:   "Secrets are masked; fill them in to run",
+			input:   record.Command,
+			records: []history.CommandRecord{record},
+			dir:     m.runDirectory(record, inOriginalDir),
+		}
+		return m, nil
+	}
+
+	m.selectedCmd = &record
+	return m, runCommands(m.runner, []executionStep{{record: record, dir: m.runDirectory(record, inOriginalDir)}})
+}
+
+// runDirectory returns the directory a command runs in: the one it was
+// recorded in when inOriginalDir is set and it still exists, otherwise the
+// working directory
+func (m UIModel) runDirectory(record history.CommandRecord, inOriginalDir bool) string {
+	if inOriginalDir && record.Directory != "" {
+		if info, err := os.Stat(record.Directory); err == nil && info.IsDir() {
+			return record.Directory
+		}
+	}
+	if m.workDir == "" {
+		return "."
+	}
+	return m.workDir
+}
+
+// buildStatsEntries flattens the command rankings into the selectable list
+// shown by the stats view
+func buildStatsEntries(stats *storage.UsageStats) []statsEntry {
+	if stats == nil {
+		return nil
+	}
+
+	var entries []statsEntry
+	sections := []struct {
+		title string
+		usage []storage.CommandUsage
+	}{
+		{"Top Commands", stats.TopCommands},
+		{"Failure Hotspots", stats.FailingCommands},
+		{"Slowest Commands", stats.SlowestCommands},
+	}
+	for _, section := range sections {
+		for _, usage := range section.usage {
+			entries = append(entries, statsEntry{section: section.title, usage: usage})
+		}
+	}
+	return entries
+}
+
 // filterCommands applies search filter to commands
 func (m UIModel) filterCommands() UIModel {
 	return m.applyFilters()
 }
 
+// updateSearch re-runs the search after the query or match mode changed.
+// Small histories are filtered immediately; large ones are ranked in the
+// background, narrowing the previous matches when the query only grew.
+func (m UIModel) updateSearch() (UIModel, tea.Cmd) {
+	candidates := m.searchCandidates()
+	if m.searchQuery == "" || len(candidates) < asyncSearchThreshold || m.latestSearch == nil {
+		return m.applyFilters(), nil
+	}
+
+	matcher, err := newCommandMatcher(m.searchQuery, m.matchMode)
+	if err != nil {
+		// Keep the current results until the expression compiles
+		m = m.cancelSearch()
+		m.searchErr = err
+		return m, nil
+	}
+
+	m = m.cancelSearch()
+	m.matcher = matcher
+	m.searchErr = nil
+	m.searching = true
+	return m, runSearch(candidates, matcher, m.commandCounts, m.searchGen, m.latestSearch)
+}
+
+// searchCandidates returns the commands the current query has to be matched
+// against: the previous matches when the query extends the previous query,
+// otherwise every command
+func (m UIModel) searchCandidates() []history.CommandRecord {
+	if m.searchBase != nil && m.searchBaseQuery != "" && m.matchMode != RegexMatch &&
+		strings.HasPrefix(m.searchQuery, m.searchBaseQuery) {
+		return m.searchBase
+	}
+	return m.commands
+}
+
+// cancelSearch discards any background search in progress
+func (m UIModel) cancelSearch() UIModel {
+	m.searchGen++
+	if m.latestSearch != nil {
+		m.latestSearch.Store(m.searchGen)
+	}
+	m.searching = false
+	return m
+}
+
 // GetSelectedCommand returns the currently selected command
 func (m UIModel) GetSelectedCommand() *history.CommandRecord {
 	// Return stored selected command if available (from selectItem)
@@ -542,6 +1015,10 @@ var (
 			Foreground(lipgloss.Color("226")).
 			Background(lipgloss.Color("235"))
 
+	markedStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("229")).
+			Background(lipgloss.Color("238"))
+
 	breadcrumbStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("39")).
 			Bold(true)
@@ -618,6 +1095,12 @@ func (m UIModel) getNextShellFilter() history.ShellType {
 	case history.Zsh:
 		return history.Cmd
 	case history.Cmd:
+		return history.Fish
+	case history.Fish:
+		return history.Nushell
+	case history.Nushell:
+		return history.Elvish
+	case history.Elvish:
 		return history.Unknown // Back to no filter
 	default:
 		return history.Unknown
@@ -673,16 +1156,38 @@ func (m UIModel) applyDateFilter() UIModel {
 
 // applyFilters applies all active filters to the command list
 func (m UIModel) applyFilters() UIModel {
-	m.filteredCmds = []history.CommandRecord{}
-
-	for _, cmd := range m.commands {
-		// Apply text filter
-		if m.searchQuery != "" {
-			if !strings.Contains(strings.ToLower(cmd.Command), strings.ToLower(m.searchQuery)) {
-				continue
+	m = m.cancelSearch()
+	m.matcher = nil
+	m.searchErr = nil
+
+	matched := m.commands
+	if m.searchQuery != "" {
+		// Apply text filter, ranking the matches
+		matcher, err := newCommandMatcher(m.searchQuery, m.matchMode)
+		if err != nil {
+			m.searchErr = err
+		} else {
+			if m.commandCounts == nil {
+				m.commandCounts = countCommands(m.commands)
 			}
+			matched = rankCommands(m.searchCandidates(), matcher, m.commandCounts, time.Now(), nil)
+			m.matcher = matcher
+			m.searchBase = matched
+			m.searchBaseQuery = m.searchQuery
 		}
+	}
+
+	m.filteredCmds = m.filterByDateAndShell(matched)
+	m.selectedIndex = 0
+	m.scrollOffset = 0
+	return m
+}
+
+// filterByDateAndShell keeps the commands that pass the date and shell filters
+func (m UIModel) filterByDateAndShell(commands []history.CommandRecord) []history.CommandRecord {
+	filtered := []history.CommandRecord{}
 
+	for _, cmd := range commands {
 		// Apply date filter
 		if m.dateFilter.Enabled {
 			if cmd.Timestamp.Before(m.dateFilter.StartTime) || cmd.Timestamp.After(m.dateFilter.EndTime) {
@@ -697,18 +1202,19 @@ func (m UIModel) applyFilters() UIModel {
 			}
 		}
 
-		m.filteredCmds = append(m.filteredCmds, cmd)
+		filtered = append(filtered, cmd)
 	}
 
-	m.selectedIndex = 0
-	m.scrollOffset = 0
-	return m
+	return filtered
 }
 
 // clearFilters removes all active filters
 func (m UIModel) clearFilters() UIModel {
 	m.searchQuery = ""
 	m.searchMode = false
+	m = m.cancelSearch()
+	m.matcher = nil
+	m.searchErr = nil
 	m.dateFilter.Enabled = false
 	m.shellFilter = history.Unknown
 	m.filterMode = NoFilter
@@ -1,10 +1,17 @@
 package browser
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ValGrace/command-history-tracker/internal/executor"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 // MockStorage implements the StorageEngine interface for testing
@@ -12,6 +19,8 @@ type MockStorage struct {
 	commands    []history.CommandRecord
 	directories []string
 	dirStats    []history.DirectoryIndex
+	usageStats  *storage.UsageStats
+	statsQuery  storage.StatsQuery
 }
 
 func NewMockStorage() *MockStorage {
@@ -68,6 +77,115 @@ func (m *MockStorage) GetDirectoryStats() ([]history.DirectoryIndex, error) {
 	return m.dirStats, nil
 }
 
+func (m *MockStorage) GetUsageStats(query storage.StatsQuery) (*storage.UsageStats, error) {
+	m.statsQuery = query
+	return m.usageStats, nil
+}
+
+func (m *MockStorage) FilterCommands(filters storage.CommandFilters) ([]history.CommandRecord, error) {
+	var result []history.CommandRecord
+	for _, cmd := range m.commands {
+		if filters.Command != "" && cmd.Command != filters.Command {
+			continue
+		}
+		if filters.Directory != "" && cmd.Directory != filters.Directory &&
+			!strings.HasPrefix(cmd.Directory, filters.Directory+"/") {
+			continue
+		}
+		result = append(result, cmd)
+	}
+	return result, nil
+}
+
+func (m *MockStorage) GetCommandsBySession(sessionID string) ([]history.CommandRecord, error) {
+	var result []history.CommandRecord
+	for _, cmd := range m.commands {
+		if cmd.SessionID == sessionID {
+			result = append(result, cmd)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockStorage) GetSessions(limit int) ([]history.SessionIndex, error) {
+	var sessions []history.SessionIndex
+	seen := make(map[string]int)
+	for _, cmd := range m.commands {
+		if cmd.SessionID == "" {
+			continue
+		}
+		if idx, ok := seen[cmd.SessionID]; ok {
+			sessions[idx].CommandCount++
+			continue
+		}
+		seen[cmd.SessionID] = len(sessions)
+		sessions = append(sessions, history.SessionIndex{
+			ID:            cmd.SessionID,
+			Shell:         cmd.Shell,
+			ShellPID:      cmd.ShellPID,
+			CommandCount:  1,
+			FirstSeen:     cmd.Timestamp,
+			LastSeen:      cmd.Timestamp,
+			LastDirectory: cmd.Directory,
+		})
+	}
+	return sessions, nil
+}
+
+func (m *MockStorage) DeleteCommands(ids []string) (int64, error) {
+	var kept []history.CommandRecord
+	for _, cmd := range m.commands {
+		if !containsString(ids, cmd.ID) {
+			kept = append(kept, cmd)
+		}
+	}
+	removed := int64(len(m.commands) - len(kept))
+	m.commands = kept
+	return removed, nil
+}
+
+func (m *MockStorage) UpdateTags(ids []string, add []string, remove []string) (int64, error) {
+	var changed int64
+	for i, cmd := range m.commands {
+		if !containsString(ids, cmd.ID) {
+			continue
+		}
+		var tags []string
+		for _, tag := range cmd.Tags {
+			if !containsString(remove, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		for _, tag := range add {
+			if !containsString(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		m.commands[i].Tags = tags
+		changed++
+	}
+	return changed, nil
+}
+
+func (m *MockStorage) UpdateCommand(cmd history.CommandRecord) error {
+	for i := range m.commands {
+		if m.commands[i].ID == cmd.ID {
+			m.commands[i] = cmd
+			return nil
+		}
+	}
+	return fmt.Errorf("command not found: %s", cmd.ID)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // Test helper functions
 
 func createTestCommand(id, command, directory string, shell history.ShellType, exitCode int) history.CommandRecord {
@@ -440,7 +558,339 @@ func TestGetNextShellFilter(t *testing.T) {
 
 	model.shellFilter = history.Cmd
 	next = model.getNextShellFilter()
+	if next != history.Fish {
+		t.Errorf("Expected Fish after Cmd, got %v", next)
+	}
+
+	model.shellFilter = history.Fish
+	next = model.getNextShellFilter()
+	if next != history.Nushell {
+		t.Errorf("Expected Nushell after Fish, got %v", next)
+	}
+
+	model.shellFilter = history.Elvish
+	next = model.getNextShellFilter()
 	if next != history.Unknown {
-		t.Errorf("Expected Unknown after Cmd, got %v", next)
+		t.Errorf("Expected Unknown after Elvish, got %v", next)
+	}
+}
+
+// Test Session Grouping
+
+func TestSessionView(t *testing.T) {
+	model, storage := setupTestModel()
+
+	sessionCmd := createTestCommand("6", "make build", "/home/user/project", history.Zsh, 0)
+	sessionCmd.SessionID = "sess-1"
+	sessionCmd.ShellPID = 4321
+	otherDirCmd := createTestCommand("7", "cd /tmp", "/tmp", history.Zsh, 0)
+	otherDirCmd.SessionID = "sess-1"
+	otherDirCmd.ShellPID = 4321
+	storage.commands = append(storage.commands, sessionCmd, otherDirCmd)
+
+	// Open the session list
+	updated, cmd := model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("S")})
+	if updated.viewMode != SessionView {
+		t.Fatalf("Expected SessionView, got %v", updated.viewMode)
+	}
+	if cmd == nil {
+		t.Fatal("Expected command to load sessions")
+	}
+
+	next, _ := updated.Update(cmd())
+	updated = next.(UIModel)
+	if len(updated.sessions) != 1 || updated.sessions[0].CommandCount != 2 {
+		t.Fatalf("Expected one session with 2 commands, got %+v", updated.sessions)
+	}
+
+	view := updated.View()
+	if !strings.Contains(view, "sess-1") || !strings.Contains(view, "4321") {
+		t.Errorf("Expected session view to show session id and pid, got:\n%s", view)
+	}
+
+	// Selecting a session shows its commands across directories
+	updated, cmd = updated.selectItem()
+	if updated.currentSession != "sess-1" || updated.viewMode != DirectoryHistoryView {
+		t.Fatalf("Expected session history for sess-1, got session %q view %v", updated.currentSession, updated.viewMode)
+	}
+
+	next, _ = updated.Update(cmd())
+	updated = next.(UIModel)
+	if len(updated.filteredCmds) != 2 {
+		t.Errorf("Expected 2 session commands, got %d", len(updated.filteredCmds))
+	}
+	if !strings.Contains(updated.View(), "Session History - sess-1") {
+		t.Error("Expected session history header")
+	}
+
+	// Going back returns to the session list
+	updated, _ = updated.handleKeyPress(tea.KeyMsg{Type: tea.KeyLeft})
+	if updated.viewMode != SessionView || updated.currentSession != "" {
+		t.Errorf("Expected to return to session list, got view %v session %q", updated.viewMode, updated.currentSession)
+	}
+}
+
+// Test Usage Statistics
+
+func TestStatsView(t *testing.T) {
+	model, store := setupTestModel()
+	model.currentDir = "/home/user"
+	store.usageStats = &storage.UsageStats{
+		TotalCommands:  5,
+		FailedCommands: 1,
+		FailureRate:    0.2,
+		DirectoryCount: 2,
+		TopCommands: []storage.CommandUsage{
+			{Command: "git status", Count: 3, AvgDuration: time.Second},
+			{Command: "ls -la", Count: 2},
+		},
+		FailingCommands: []storage.CommandUsage{
+			{Command: "failed-command", Count: 1, Failures: 1, FailureRate: 1},
+		},
+		SlowestCommands: []storage.CommandUsage{
+			{Command: "npm install", Count: 1, AvgDuration: 40 * time.Second, P95Duration: 40 * time.Second},
+		},
+		Daily: []storage.DailyCount{{Date: time.Now().Format("2006-01-02"), Count: 5}},
+	}
+
+	// Open the statistics for the current directory
+	updated, cmd := model.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	if updated.viewMode != StatsView {
+		t.Fatalf("Expected StatsView, got %v", updated.viewMode)
+	}
+	next, _ := updated.Update(cmd())
+	updated = next.(UIModel)
+	if store.statsQuery.Directory != "/home/user" || store.statsQuery.StartTime.IsZero() {
+		t.Errorf("Expected a query for /home/user over the last %d days, got %+v", statsDays, store.statsQuery)
+	}
+	if len(updated.statsEntries) != 4 {
+		t.Fatalf("Expected 4 selectable entries, got %d", len(updated.statsEntries))
+	}
+
+	view := updated.View()
+	for _, want := range []string{"Usage Statistics - /home/user", "Top Commands", "Failure Hotspots", "Slowest Commands", "█", "npm install"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected stats view to contain %q, got:\n%s", want, view)
+		}
+	}
+
+	// The whole tree is one key away
+	toggled, cmd := updated.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")})
+	cmd()
+	if !toggled.statsAllDirs || store.statsQuery.Directory != "" {
+		t.Errorf("Expected statistics for all directories, got query %+v", store.statsQuery)
+	}
+
+	// Selecting the failure hotspot lists its runs
+	updated = updated.moveDown().moveDown()
+	updated, cmd = updated.selectItem()
+	if updated.viewMode != DirectoryHistoryView || updated.statsCommand != "failed-command" {
+		t.Fatalf("Expected runs of failed-command, got view %v command %q", updated.viewMode, updated.statsCommand)
+	}
+	next, _ = updated.Update(cmd())
+	updated = next.(UIModel)
+	if len(updated.filteredCmds) != 1 || updated.filteredCmds[0].Command != "failed-command" {
+		t.Errorf("Expected the failed-command run, got %+v", updated.filteredCmds)
+	}
+	if !strings.Contains(updated.View(), `Runs of "failed-command"`) {
+		t.Error("Expected command runs header")
+	}
+
+	// Going back returns to the statistics with the entry still selected
+	updated, _ = updated.handleKeyPress(tea.KeyMsg{Type: tea.KeyLeft})
+	if updated.viewMode != StatsView || updated.statsCommand != "" || updated.selectedIndex != 2 {
+		t.Errorf("Expected to return to stats entry 2, got view %v command %q index %d", updated.viewMode, updated.statsCommand, updated.selectedIndex)
+	}
+}
+
+// Test Inline Command Picking
+
+func TestInsertMode(t *testing.T) {
+	model, _ := setupTestModel()
+	model.currentDir = "/home/user/project"
+	model.insertMode = true
+	model.searchMode = true
+	model.matchMode = FuzzyMatch
+	model.searchQuery = "st"
+
+	next, _ := model.Update(tea.WindowSizeMsg{Width: 120, Height: 50})
+	updated := next.(UIModel)
+	if updated.height != maxInlineHeight {
+		t.Errorf("Expected inline height %d, got %d", maxInlineHeight, updated.height)
+	}
+
+	next, _ = updated.Update(updated.loadHistory()())
+	updated = next.(UIModel)
+
+	// q is typed into the query instead of quitting
+	updated, _ = updated.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if updated.quitting || updated.searchQuery != "stq" {
+		t.Fatalf("Expected q to extend the query, got %q (quitting=%v)", updated.searchQuery, updated.quitting)
+	}
+	updated, _ = updated.handleKeyPress(tea.KeyMsg{Type: tea.KeyBackspace})
+
+	// Enter chooses the best match immediately without executing it
+	updated, cmd := updated.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	if updated.selectedCmd == nil || updated.selectedCmd.Command != "git status" {
+		t.Fatalf("Expected git status to be chosen, got %+v", updated.selectedCmd)
+	}
+	if cmd == nil {
+		t.Fatal("Expected choosing a command to quit the browser")
+	}
+	updated.quitting = true
+	if view := updated.View(); view != "" {
+		t.Errorf("Expected the inline browser to clear itself, got %q", view)
+	}
+
+	// Escape leaves search first, then cancels
+	model.searchQuery = ""
+	updated, _ = model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	if updated.searchMode || updated.quitting {
+		t.Fatal("Expected the first escape to leave search mode")
+	}
+	updated, _ = updated.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	if !updated.quitting || updated.selectedCmd != nil {
+		t.Error("Expected the second escape to cancel without a command")
+	}
+}
+
+// Test Running Commands
+
+// mockRunner records the commands run from the browser
+type mockRunner struct {
+	storage *MockStorage
+	dirs    []string
+	result  *executor.ExecutionResult
+	err     error
+}
+
+func (r *mockRunner) Execute(cmd *history.CommandRecord, dir string) (*executor.ExecutionResult, error) {
+	r.dirs = append(r.dirs, dir)
+	if r.err != nil {
+		return r.result, r.err
+	}
+	r.storage.SaveCommand(history.CommandRecord{ID: "run", Command: cmd.Command, Directory: dir, Timestamp: time.Now()})
+	return r.result, nil
+}
+
+func TestExecuteFromBrowser(t *testing.T) {
+	model, store := setupTestModel()
+	recordedDir := t.TempDir()
+	store.commands[0].Directory = recordedDir
+	runner := &mockRunner{storage: store, result: &executor.ExecutionResult{ExitCode: 0, Duration: 1500 * time.Millisecond}}
+	model.runner = runner
+	model.workDir = "/work"
+	model.currentDir = recordedDir
+
+	next, _ := model.Update(model.loadHistory()())
+	updated := next.(UIModel)
+	if len(updated.filteredCmds) == 0 {
+		t.Fatal("Expected history to load")
+	}
+
+	// Enter runs the command instead of quitting the browser
+	updated.selectedIndex = 0
+	updated, cmd := updated.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || updated.quitting {
+		t.Fatal("Expected enter to start the command without quitting")
+	}
+	if updated.selectedCmd == nil || updated.selectedCmd.Command != "ls -la" {
+		t.Fatalf("Expected ls -la to be selected, got %+v", updated.selectedCmd)
+	}
+
+	// The command runs in its recorded directory when it still exists
+	if dir := updated.runDirectory(*updated.selectedCmd, true); dir != recordedDir {
+		t.Errorf("Expected to run in %s, got %s", recordedDir, dir)
+	}
+	run := &execution{runner: runner, steps: []executionStep{{record: *updated.selectedCmd, dir: recordedDir}}}
+	var out bytes.Buffer
+	run.SetStdin(strings.NewReader("\n"))
+	run.SetStdout(&out)
+	if err := run.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "$ ls -la") || !strings.Contains(out.String(), "finished in 1.5s") {
+		t.Errorf("Expected command and summary in output, got %q", out.String())
+	}
+
+	// The result is shown and the new run loaded
+	next, reload := updated.Update(executionMsg{command: "ls -la", dir: recordedDir, result: run.result})
+	updated = next.(UIModel)
+	if reload == nil {
+		t.Fatal("Expected history to reload after running a command")
+	}
+	next, _ = updated.Update(reload())
+	updated = next.(UIModel)
+	if len(updated.commands) != 2 {
+		t.Errorf("Expected the new run in the history, got %d commands", len(updated.commands))
+	}
+	if view := updated.View(); !strings.Contains(view, "✅ ls -la finished in 1.5s") {
+		t.Errorf("Expected success status in view, got:\n%s", view)
+	}
+
+	// x runs in the working directory, and missing directories fall back to it
+	if _, cmd := updated.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")}); cmd == nil {
+		t.Error("Expected x to run the command")
+	}
+	if dir := updated.runDirectory(store.commands[0], false); dir != "/work" {
+		t.Errorf("Expected x to run in the working directory, got %s", dir)
+	}
+	if dir := updated.runDirectory(store.commands[1], true); dir != "/work" {
+		t.Errorf("Expected a missing directory to fall back to the working directory, got %s", dir)
+	}
+
+	// Blocked commands report why they did not run
+	runner.err = errors.New("command blocked by policy")
+	run = &execution{runner: runner, steps: []executionStep{{record: history.CommandRecord{Command: "rm -rf /"}, dir: "/work"}}}
+	out.Reset()
+	run.SetStdin(strings.NewReader("\n"))
+	run.SetStdout(&out)
+	if err := run.Run(); err == nil || !strings.Contains(out.String(), "command blocked by policy") {
+		t.Errorf("Expected blocked command error, got %v and %q", err, out.String())
+	}
+	runner.result = nil
+	next, _ = updated.Update(executionMsg{command: "rm -rf /", dir: "/work", err: runner.err})
+	if view := next.(UIModel).View(); !strings.Contains(view, "Not run: command blocked by policy") {
+		t.Errorf("Expected blocked status in view, got:\n%s", view)
+	}
+
+	// Without a runner enter still returns the selection
+	model.runner = nil
+	model.filteredCmds = store.commands
+	if updated, cmd := model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || updated.selectedCmd == nil {
+		t.Error("Expected enter to select and quit without a runner")
+	}
+}
+
+func TestExecuteMaskedCommandFromBrowser(t *testing.T) {
+	model, store := setupTestModel()
+	masked := "curl -H 'Authorization: Bearer ****' https://example.com"
+	store.commands[0].Command = masked
+	store.commands[0].Tags = []string{"redacted"}
+	runner := &mockRunner{storage: store}
+	model.runner = runner
+	model.workDir = "/work"
+	model.filteredCmds = store.commands
+	model.selectedIndex = 0
+
+	// Enter asks for the secrets instead of running the masked command
+	updated, cmd := model.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || updated.prompt == nil || updated.prompt.action != bulkRun || updated.prompt.input != masked {
+		t.Fatalf("Expected a prompt to fill in the secrets, got %+v", updated.prompt)
+	}
+
+	// The command is not run while it is still masked
+	next, cmd := updated.handlePromptInput(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || next.bulkErr == nil || !strings.Contains(next.View(), "replace every ****") {
+		t.Fatalf("Expected the masked command to be refused, got %v", next.bulkErr)
+	}
+
+	updated.prompt.input = strings.Replace(masked, "****", "s3cr3t", 1)
+	next, cmd = updated.handlePromptInput(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || next.selectedCmd == nil || !strings.Contains(next.selectedCmd.Command, "s3cr3t") {
+		t.Fatalf("Expected the filled-in command to run, got %+v", next.selectedCmd)
+	}
+	if store.commands[0].Command != masked {
+		t.Errorf("Expected the stored command to stay masked, got %q", store.commands[0].Command)
 	}
 }
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/ValGrace/command-history-tracker/internal/storage"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -41,19 +43,55 @@ func (m UIModel) renderDirectoryHistoryView() string {
 	}
 
 	var header string
-	if cmdCount != totalCount {
+	if m.statsCommand != "" {
+		if cmdCount != totalCount {
+			header = fmt.Sprintf("📊 Runs of %q - %s (%d of %d commands)", m.statsCommand, m.statsScopeName(), cmdCount, totalCount)
+		} else {
+			header = fmt.Sprintf("📊 Runs of %q - %s (%d commands)", m.statsCommand, m.statsScopeName(), cmdCount)
+		}
+	} else if m.currentSession != "" {
+		if cmdCount != totalCount {
+			header = fmt.Sprintf("🖥️ Session History - %s (%d of %d commands)", m.currentSession, cmdCount, totalCount)
+		} else {
+			header = fmt.Sprintf("🖥️ Session History - %s (%d commands)", m.currentSession, cmdCount)
+		}
+	} else if cmdCount != totalCount {
 		header = fmt.Sprintf("📂 Current Directory History - %s (%d of %d commands)", dirName, cmdCount, totalCount)
 	} else {
 		header = fmt.Sprintf("📂 Current Directory History - %s (%d commands)", dirName, cmdCount)
 	}
 
 	if m.searchMode {
-		header += fmt.Sprintf(" 🔍 Search: %s", m.searchQuery)
+		header += fmt.Sprintf(" 🔍 Search: %s [%s]", m.searchQuery, m.matchMode)
+		if m.searching {
+			header += " …"
+		}
 	}
 
 	b.WriteString(headerStyle.Render(header))
 	b.WriteString("\n")
 
+	if m.searchErr != nil {
+		b.WriteString(errorStyle.Render(m.searchErr.Error()))
+		b.WriteString("\n")
+	}
+
+	if m.lastRun != nil {
+		b.WriteString(m.renderLastRun())
+		b.WriteString("\n")
+	}
+
+	if m.prompt != nil {
+		b.WriteString(searchStyle.Render(fmt.Sprintf("%s: %s█", m.prompt.label, m.prompt.input)))
+		b.WriteString("\n")
+	} else if m.bulkErr != nil {
+		b.WriteString(errorStyle.Render(m.bulkErr.Error()))
+		b.WriteString("\n")
+	} else if m.bulkStatus != "" {
+		b.WriteString(dimStyle.Render(m.bulkStatus))
+		b.WriteString("\n")
+	}
+
 	// Add directory context information
 	if totalCount > 0 {
 		// Show recent activity summary
@@ -139,7 +177,7 @@ func (m UIModel) renderDirectoryHistoryView() string {
 			cmd := m.filteredCmds[i]
 			isSelected := i == m.selectedIndex
 
-			line := m.formatDirectoryCommandLine(cmd, isSelected, i)
+			line := m.formatMarkableCommandLine(cmd, isSelected, m.isMarked(i), i)
 			b.WriteString(line)
 			b.WriteString("\n")
 		}
@@ -157,6 +195,9 @@ func (m UIModel) renderDirectoryHistoryView() string {
 			if end < len(m.filteredCmds) {
 				navHints = append(navHints, "↓ more below")
 			}
+			if m.nextCursor != "" {
+				navHints = append(navHints, "older commands load as you scroll")
+			}
 
 			if len(navHints) > 0 {
 				scrollInfo += fmt.Sprintf(" (%s)", strings.Join(navHints, ", "))
@@ -316,8 +357,248 @@ func (m UIModel) renderSearchView() string {
 	return b.String()
 }
 
+// renderSessionView renders recorded terminal sessions, most recent first
+func (m UIModel) renderSessionView() string {
+	var b strings.Builder
+
+	header := fmt.Sprintf("🖥️ Terminal Sessions - select a session to see its commands (%d sessions)", len(m.sessions))
+	b.WriteString(headerStyle.Render(header))
+	b.WriteString("\n\n")
+
+	if len(m.sessions) == 0 {
+		b.WriteString(dimStyle.Render("📭 No sessions recorded yet."))
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render("   Sessions are tracked once the shell integration is reinstalled with 'tracker setup'."))
+		b.WriteString("\n")
+	} else {
+		visibleLines := m.height - 6 // Account for header and footer
+		if visibleLines < 1 {
+			visibleLines = 1
+		}
+
+		start := m.scrollOffset
+		end := start + visibleLines
+		if end > len(m.sessions) {
+			end = len(m.sessions)
+		}
+
+		for i := start; i < end; i++ {
+			b.WriteString(m.formatSessionLine(m.sessions[i], i == m.selectedIndex))
+			b.WriteString("\n")
+		}
+
+		if len(m.sessions) > visibleLines {
+			b.WriteString(dimStyle.Render(fmt.Sprintf("📄 Showing %d-%d of %d sessions", start+1, end, len(m.sessions))))
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.renderFooter())
+
+	return b.String()
+}
+
+// renderStatsView renders daily activity and the command rankings for the
+// current directory subtree or every directory
+func (m UIModel) renderStatsView() string {
+	var b strings.Builder
+
+	header := fmt.Sprintf("📊 Usage Statistics - %s (last %d days)", m.statsScopeName(), statsDays)
+	b.WriteString(headerStyle.Render(header))
+	b.WriteString("\n")
+
+	if m.stats == nil {
+		b.WriteString(dimStyle.Render("Loading statistics..."))
+		b.WriteString("\n\n")
+		b.WriteString(m.renderFooter())
+		return b.String()
+	}
+
+	if m.stats.TotalCommands == 0 {
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render(fmt.Sprintf("📭 No commands recorded in the last %d days.", statsDays)))
+		b.WriteString("\n")
+		if !m.statsAllDirs {
+			b.WriteString(dimStyle.Render("   Press 'w' to include every directory."))
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+		b.WriteString(m.renderFooter())
+		return b.String()
+	}
+
+	summary := fmt.Sprintf("%d commands in %d directories • %d failed (%.1f%%)",
+		m.stats.TotalCommands, m.stats.DirectoryCount, m.stats.FailedCommands, m.stats.FailureRate*100)
+	b.WriteString(dimStyle.Render(summary))
+	b.WriteString("\n\n")
+
+	// Daily volume, one cell per day with today on the right
+	counts := dailySeries(m.stats.Daily, time.Now(), statsDays)
+	peak := 0
+	for _, count := range counts {
+		if count > peak {
+			peak = count
+		}
+	}
+	b.WriteString(fmt.Sprintf("Daily  %s  %s\n",
+		breadcrumbStyle.Render(sparkline(counts)), dimStyle.Render(fmt.Sprintf("peak %d/day", peak))))
+	start := statsRangeStart(time.Now()).Format("Jan 02")
+	b.WriteString(dimStyle.Render(fmt.Sprintf("       %-*s%s", statsDays-5, start, "today")))
+	b.WriteString("\n")
+
+	section := ""
+	for i, entry := range m.statsEntries {
+		if entry.section != section {
+			section = entry.section
+			b.WriteString("\n")
+			b.WriteString(headerStyle.Render(section))
+			b.WriteString("\n")
+		}
+		b.WriteString(m.formatStatsLine(entry, i == m.selectedIndex))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(m.renderFooter())
+
+	return b.String()
+}
+
+// formatStatsLine formats one ranked command of the stats view
+func (m UIModel) formatStatsLine(entry statsEntry, selected bool) string {
+	usage := entry.usage
+
+	maxCmdWidth := m.width - 50
+	if maxCmdWidth < 20 {
+		maxCmdWidth = 20
+	}
+	command := usage.Command
+	if len(command) > maxCmdWidth {
+		command = command[:maxCmdWidth-3] + "..."
+	}
+
+	line := fmt.Sprintf("%5d runs │ %4d failed │ avg %-7s │ p95 %-7s │ %s",
+		usage.Count, usage.Failures, formatRunDuration(usage.AvgDuration), formatRunDuration(usage.P95Duration), command)
+
+	if selected {
+		return selectedStyle.Render("▶ " + line)
+	} else if entry.section == "Failure Hotspots" {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("203")).Render("  " + line) // Light red
+	}
+
+	return normalStyle.Render("  " + line)
+}
+
+// statsScopeName describes the directories summarized by the stats view
+func (m UIModel) statsScopeName() string {
+	if m.statsAllDirs || m.currentDir == "" {
+		return "all directories"
+	}
+	return m.currentDir + " and subdirectories"
+}
+
+// sparklineLevels are the bar heights used by sparkline, lowest first
+var sparklineLevels = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws one bar per count scaled to the largest count; zero counts
+// are left blank
+func sparkline(counts []int) string {
+	peak := 0
+	for _, count := range counts {
+		if count > peak {
+			peak = count
+		}
+	}
+
+	var b strings.Builder
+	for _, count := range counts {
+		if count <= 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		level := (count*(len(sparklineLevels)-1) + peak/2) / peak
+		b.WriteRune(sparklineLevels[level])
+	}
+	return b.String()
+}
+
+// dailySeries returns the counts of the given number of days ending with the
+// day of now, filling days without commands with zero
+func dailySeries(daily []storage.DailyCount, now time.Time, days int) []int {
+	byDate := make(map[string]int, len(daily))
+	for _, day := range daily {
+		byDate[day.Date] = day.Count
+	}
+
+	counts := make([]int, days)
+	for i := range counts {
+		date := now.AddDate(0, 0, i-(days-1)).Format("2006-01-02")
+		counts[i] = byDate[date]
+	}
+	return counts
+}
+
+// formatRunDuration shortens a duration for the stats view, "-" when unknown
+func formatRunDuration(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "-"
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(100 * time.Millisecond).String()
+	}
+}
+
+// formatSessionLine formats a terminal session summary for display
+func (m UIModel) formatSessionLine(session history.SessionIndex, selected bool) string {
+	// Describe when the session was active
+	var active string
+	timeSince := time.Since(session.LastSeen)
+	if timeSince < time.Hour {
+		active = fmt.Sprintf("%s ago", formatDuration(timeSince))
+	} else if timeSince < 24*time.Hour {
+		active = session.LastSeen.Format("15:04 today")
+	} else {
+		active = session.LastSeen.Format("Jan 02 15:04")
+	}
+
+	pid := "-"
+	if session.ShellPID > 0 {
+		pid = fmt.Sprintf("%d", session.ShellPID)
+	}
+
+	// Truncate the last directory, keeping the end visible
+	maxDirWidth := m.width - 70
+	if maxDirWidth < 15 {
+		maxDirWidth = 15
+	}
+	dir := session.LastDirectory
+	if len(dir) > maxDirWidth {
+		dir = "..." + dir[len(dir)-maxDirWidth+3:]
+	}
+
+	line := fmt.Sprintf("%-20s │ %-12s │ pid %-7s │ %4d cmds │ %-14s │ %s",
+		session.ID, "["+session.Shell.String()+"]", pid, session.CommandCount, active, dir)
+
+	if selected {
+		return selectedStyle.Render("▶ " + line)
+	} else if timeSince < time.Hour {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("82")).Render("  " + line) // Light green
+	}
+
+	return normalStyle.Render("  " + line)
+}
+
 // formatDirectoryCommandLine formats a command record for directory-based browsing with enhanced selection
 func (m UIModel) formatDirectoryCommandLine(cmd history.CommandRecord, selected bool, index int) string {
+	return m.formatMarkableCommandLine(cmd, selected, false, index)
+}
+
+// formatMarkableCommandLine formats a command record for directory-based
+// browsing, showing whether it is marked for a bulk action
+func (m UIModel) formatMarkableCommandLine(cmd history.CommandRecord, selected, marked bool, index int) string {
 	// Calculate available width for command text
 	maxCmdWidth := m.width - 50 // Leave space for timestamp, shell, exit code, index, and indicators
 	if maxCmdWidth < 20 {
@@ -373,31 +654,104 @@ func (m UIModel) formatDirectoryCommandLine(cmd history.CommandRecord, selected
 	indexStr := fmt.Sprintf("%3d", index+1)
 
 	// Create line with enhanced columns for directory browsing
-	var line string
+	var details string
 	if durationIndicator != "" {
-		line = fmt.Sprintf("%s │ %-*s │ %s │ %s │ %s │ %s",
-			indexStr, maxCmdWidth, command, timestamp, shell, exitIndicator, durationIndicator)
+		details = fmt.Sprintf("%s │ %s │ %s │ %s", timestamp, shell, exitIndicator, durationIndicator)
 	} else {
-		line = fmt.Sprintf("%s │ %-*s │ %s │ %s │ %s",
-			indexStr, maxCmdWidth, command, timestamp, shell, exitIndicator)
+		details = fmt.Sprintf("%s │ %s │ %s", timestamp, shell, exitIndicator)
 	}
+	line := fmt.Sprintf("%s │ %-*s │ %s", indexStr, maxCmdWidth, command, details)
 
 	// Apply enhanced styling based on selection and command context
+	style, prefix := normalStyle, "  "
 	if selected {
 		// Enhanced selection highlighting with directory context
-		return selectedStyle.Render("▶ " + line)
+		style, prefix = selectedStyle, "▶ "
+		if marked {
+			prefix = "▶●"
+		}
+	} else if marked {
+		// Marked for a bulk action
+		style, prefix = markedStyle, " ●"
 	} else if cmd.ExitCode != 0 {
 		// Highlight failed commands with error styling
-		return errorStyle.Render("  " + line)
+		style = errorStyle
 	} else if timeSince < 5*time.Minute {
 		// Highlight very recent commands in current directory
-		return lipgloss.NewStyle().Foreground(lipgloss.Color("46")).Render("  " + line) // Bright green
+		style = lipgloss.NewStyle().Foreground(lipgloss.Color("46")) // Bright green
 	} else if timeSince < time.Hour {
 		// Highlight recent commands
-		return lipgloss.NewStyle().Foreground(lipgloss.Color("82")).Render("  " + line) // Light green
+		style = lipgloss.NewStyle().Foreground(lipgloss.Color("82")) // Light green
 	}
 
-	return normalStyle.Render("  " + line)
+	// Highlight the characters matched by the search query
+	if positions := m.visibleMatches(cmd.Command, command); len(positions) > 0 {
+		padding := strings.Repeat(" ", maxCmdWidth-utf8.RuneCountInString(command))
+		return style.Render(prefix+indexStr+" │ ") +
+			highlightMatches(command, positions, style) +
+			style.Render(padding+" │ "+details)
+	}
+
+	return style.Render(prefix + line)
+}
+
+// visibleMatches returns the offsets of the characters of full matched by the
+// search query that are visible in shown, its possibly truncated display form
+func (m UIModel) visibleMatches(full, shown string) []int {
+	if m.matcher == nil {
+		return nil
+	}
+
+	_, positions, ok := m.matcher.match(full)
+	if !ok {
+		return nil
+	}
+
+	visible := len(shown)
+	if shown != full {
+		visible -= len("...")
+	}
+	for i, pos := range positions {
+		if pos >= visible {
+			return positions[:i]
+		}
+	}
+	return positions
+}
+
+// highlightMatches renders text with the characters at the given offsets
+// emphasized on top of the line style
+func highlightMatches(text string, positions []int, style lipgloss.Style) string {
+	matchStyle := style.Foreground(lipgloss.Color("226")).Underline(true)
+
+	var b strings.Builder
+	var run strings.Builder
+	runMatched := false
+	next := 0
+	for i, r := range text {
+		matched := next < len(positions) && positions[next] == i
+		if matched {
+			next++
+		}
+		if matched != runMatched && run.Len() > 0 {
+			b.WriteString(renderRun(run.String(), runMatched, style, matchStyle))
+			run.Reset()
+		}
+		runMatched = matched
+		run.WriteRune(r)
+	}
+	if run.Len() > 0 {
+		b.WriteString(renderRun(run.String(), runMatched, style, matchStyle))
+	}
+	return b.String()
+}
+
+// renderRun renders a run of matched or unmatched characters
+func renderRun(text string, matched bool, style, matchStyle lipgloss.Style) string {
+	if matched {
+		return matchStyle.Render(text)
+	}
+	return style.Render(text)
 }
 
 // formatCommandLine formats a command record for display with enhanced current directory context
@@ -613,7 +967,7 @@ func (m UIModel) renderFilterPanel() string {
 
 	// Text filter status
 	if m.searchQuery != "" {
-		b.WriteString(fmt.Sprintf("Text: %s ", searchStyle.Render(m.searchQuery)))
+		b.WriteString(fmt.Sprintf("Text: %s (%s) ", searchStyle.Render(m.searchQuery), m.matchMode))
 	}
 
 	// Date filter status
@@ -684,6 +1038,9 @@ func (m UIModel) renderCommandPreview(cmd history.CommandRecord) string {
 	b.WriteString(fmt.Sprintf("Directory: %s\n", dimStyle.Render(cmd.Directory)))
 	b.WriteString(fmt.Sprintf("Executed: %s\n", dimStyle.Render(cmd.Timestamp.Format("2006-01-02 15:04:05"))))
 	b.WriteString(fmt.Sprintf("Shell: %s\n", dimStyle.Render(cmd.Shell.String())))
+	if cmd.ID != "" {
+		b.WriteString(fmt.Sprintf("ID: %s\n", dimStyle.Render(cmd.ID)))
+	}
 
 	if cmd.ExitCode != 0 {
 		b.WriteString(fmt.Sprintf("Exit Code: %s\n", errorStyle.Render(fmt.Sprintf("%d", cmd.ExitCode))))
@@ -699,16 +1056,108 @@ func (m UIModel) renderCommandPreview(cmd history.CommandRecord) string {
 		b.WriteString(fmt.Sprintf("Tags: %s\n", dimStyle.Render(strings.Join(cmd.Tags, ", "))))
 	}
 
+	b.WriteString(renderCommandContext(cmd))
+
 	return b.String()
 }
 
+// renderCommandContext renders the host, user and environment a command ran
+// in, leaving out what was not recorded
+func renderCommandContext(cmd history.CommandRecord) string {
+	var b strings.Builder
+
+	var host []string
+	switch {
+	case cmd.Username != "" && cmd.Hostname != "":
+		host = append(host, cmd.Username+"@"+cmd.Hostname)
+	case cmd.Username != "" || cmd.Hostname != "":
+		host = append(host, cmd.Username+cmd.Hostname)
+	}
+	if cmd.SSH {
+		host = append(host, "(ssh)")
+	}
+	if len(host) > 0 {
+		b.WriteString(fmt.Sprintf("Host: %s\n", dimStyle.Render(strings.Join(host, " "))))
+	}
+
+	for _, field := range []struct{ label, value string }{
+		{"TTY", cmd.TTY},
+		{"Container", cmd.ContainerID},
+		{"Python Env", cmd.PythonEnv},
+		{"Kube Context", cmd.KubeContext},
+	} {
+		if field.value != "" {
+			b.WriteString(fmt.Sprintf("%s: %s\n", field.label, dimStyle.Render(field.value)))
+		}
+	}
+
+	return b.String()
+}
+
+// renderLastRun summarises the last command run from the browser
+func (m UIModel) renderLastRun() string {
+	run := m.lastRun
+	if run.total > 1 {
+		if run.completed == run.total {
+			return dimStyle.Render(fmt.Sprintf("✅ Ran %s", pluralCommands(run.total)))
+		}
+		reason := "it failed"
+		if run.err != nil {
+			reason = run.err.Error()
+		} else if run.result != nil {
+			reason = fmt.Sprintf("it exited with %d", run.result.ExitCode)
+		}
+		return errorStyle.Render(fmt.Sprintf("❌ Stopped at %s after %d of %d commands: %s",
+			run.command, run.completed, run.total, reason))
+	}
+
+	switch {
+	case run.err != nil && run.result == nil:
+		return errorStyle.Render(fmt.Sprintf("⛔ Not run: %v", run.err))
+	case run.result == nil:
+		return dimStyle.Render(fmt.Sprintf("Ran %s", run.command))
+	case run.result.ExitCode != 0 || run.err != nil:
+		return errorStyle.Render(fmt.Sprintf("❌ %s exited with %d after %s in %s",
+			run.command, run.result.ExitCode, formatRunDuration(run.result.Duration), run.dir))
+	default:
+		return dimStyle.Render(fmt.Sprintf("✅ %s finished in %s in %s",
+			run.command, formatRunDuration(run.result.Duration), run.dir))
+	}
+}
+
 // renderFooter renders the help text footer
 func (m UIModel) renderFooter() string {
 	var help []string
 
 	switch m.viewMode {
 	case DirectoryHistoryView:
-		if m.showFilters {
+		if m.prompt != nil {
+			if m.prompt.action == bulkDelete {
+				help = []string{"y: delete", "any other key: cancel"}
+			} else if m.prompt.action == bulkRun {
+				help = []string{"type", "enter: run", "esc: cancel"}
+			} else {
+				help = []string{"type", "enter: apply", "esc: cancel"}
+			}
+		} else if !m.searchMode && (m.visualMode || len(m.marked) > 0) {
+			help = []string{
+				"m: mark", "v: range", "D: delete", "+/-: add/remove tag", "y: copy", "E: export script", "u/esc: unmark all",
+			}
+			if m.runner != nil {
+				help = append(help[:6:6], append([]string{"R: run in order"}, help[6:]...)...)
+			}
+			if len(m.undoStack) > 0 {
+				help = append(help, "U: undo")
+			}
+		} else if m.searchMode {
+			enter := "enter: apply"
+			if m.insertMode {
+				enter = "enter: insert"
+			}
+			help = []string{
+				"type to search", "↑/↓: select", "ctrl+r: exact/fuzzy/regex", enter, "esc: cancel",
+			}
+		} else if m.showFilters {
 			help = []string{
 				"↑/k: up", "↓/j: down", "enter: select", "d: date", "s: shell", "c: clear", "f: hide filters", "q: quit",
 			}
@@ -716,7 +1165,25 @@ func (m UIModel) renderFooter() string {
 			cmdCount := len(m.filteredCmds)
 			if cmdCount > 0 {
 				help = []string{
-					"↑/k: up", "↓/j: down", "enter: execute", "space: preview", "←: parent dir", "t: browse dirs", "/: search", "f: filters", "r: refresh", "q: quit",
+					"↑/k: up", "↓/j: down", "enter: execute", "space: preview", "←: parent dir", "t: browse dirs", "S: sessions", "a: stats", "/: search", "f: filters", "r: refresh", "q: quit",
+				}
+				if m.insertMode {
+					help[2] = "enter: insert"
+				}
+				if m.statsCommand != "" {
+					help[4] = "←: stats"
+				} else if m.currentSession != "" {
+					help[4] = "←: sessions"
+				}
+				if m.runner != nil && !m.insertMode {
+					help = append(help[:3:3], append([]string{"x: run here"}, help[3:]...)...)
+				}
+				if !m.insertMode {
+					edits := []string{"m/v: mark", "e: edit", "D: delete"}
+					if len(m.undoStack) > 0 {
+						edits = append(edits, "U: undo")
+					}
+					help = append(append(help[:len(help)-1:len(help)-1], edits...), help[len(help)-1])
 				}
 			} else {
 				help = []string{
@@ -734,6 +1201,30 @@ func (m UIModel) renderFooter() string {
 				"h: history view", "r: refresh", "q: quit",
 			}
 		}
+	case SessionView:
+		if len(m.sessions) > 0 {
+			help = []string{
+				"↑/k: up", "↓/j: down", "enter: show commands", "h: history", "t: browse dirs", "r: refresh", "q: quit",
+			}
+		} else {
+			help = []string{
+				"h: history view", "r: refresh", "q: quit",
+			}
+		}
+	case StatsView:
+		scope := "w: all dirs"
+		if m.statsAllDirs {
+			scope = "w: current dir"
+		}
+		if len(m.statsEntries) > 0 {
+			help = []string{
+				"↑/k: up", "↓/j: down", "enter: show runs", scope, "h: history", "t: browse dirs", "r: refresh", "q: quit",
+			}
+		} else {
+			help = []string{
+				scope, "h: history view", "r: refresh", "q: quit",
+			}
+		}
 	case SearchView:
 		if m.searchMode {
 			help = []string{
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ValGrace/command-history-tracker/internal/storage"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
 )
 
@@ -376,3 +377,19 @@ func TestViewRenderingWithLongPaths(t *testing.T) {
 		t.Error("Expected truncated path to show the end part")
 	}
 }
+
+func TestSparkline(t *testing.T) {
+	if got := sparkline([]int{0, 1, 2, 4, 8}); got != " ▂▃▅█" {
+		t.Errorf("Unexpected sparkline %q", got)
+	}
+	if got := sparkline([]int{0, 0}); got != "  " {
+		t.Errorf("Expected blank sparkline without commands, got %q", got)
+	}
+
+	now := time.Date(2024, 3, 2, 15, 0, 0, 0, time.Local)
+	daily := []storage.DailyCount{{Date: "2024-02-29", Count: 3}, {Date: "2024-03-02", Count: 1}}
+	counts := dailySeries(daily, now, 4)
+	if len(counts) != 4 || counts[0] != 0 || counts[1] != 3 || counts[2] != 0 || counts[3] != 1 {
+		t.Errorf("Unexpected daily series %v", counts)
+	}
+}
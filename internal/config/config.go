@@ -1,9 +1,11 @@
 package config
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sync/atomic"
 	"time"
 
 	"github.com/ValGrace/command-history-tracker/pkg/history"
@@ -11,17 +13,29 @@ import (
 
 // Config represents the application configuration
 type Config struct {
-	StoragePath     string              `json:"storage_path"`
-	RetentionDays   int                 `json:"retention_days"`
-	MaxCommands     int                 `json:"max_commands"`
-	EnabledShells   []history.ShellType `json:"enabled_shells"`
-	ExcludePatterns []string            `json:"exclude_patterns"`
-	AutoCleanup     bool                `json:"auto_cleanup"`
-	CleanupInterval time.Duration       `json:"cleanup_interval"`
-	DatabaseTimeout time.Duration       `json:"database_timeout"`
-	UITheme         string              `json:"ui_theme"`
+	StoragePath        string              `json:"storage_path" yaml:"storage_path" toml:"storage_path"`
+	RetentionDays      int                 `json:"retention_days" yaml:"retention_days" toml:"retention_days"`
+	MaxCommands        int                 `json:"max_commands" yaml:"max_commands" toml:"max_commands"`
+	EnabledShells      []history.ShellType `json:"enabled_shells" yaml:"enabled_shells" toml:"enabled_shells"`
+	ExcludePatterns    []string            `json:"exclude_patterns" yaml:"exclude_patterns" toml:"exclude_patterns"`
+	ExcludeRules       []ExcludeRule       `json:"exclude_rules,omitempty" yaml:"exclude_rules,omitempty" toml:"exclude_rules,omitempty"`
+	RecordLeadingSpace bool                `json:"record_leading_space" yaml:"record_leading_space" toml:"record_leading_space"`
+	AutoTags           []string            `json:"auto_tags" yaml:"auto_tags" toml:"auto_tags"`
+	DoNotRecord        bool                `json:"do_not_record" yaml:"do_not_record" toml:"do_not_record"`
+	AutoCleanup        bool                `json:"auto_cleanup" yaml:"auto_cleanup" toml:"auto_cleanup"`
+	CleanupInterval    time.Duration       `json:"cleanup_interval" yaml:"cleanup_interval" toml:"cleanup_interval"`
+	DatabaseTimeout    time.Duration       `json:"database_timeout" yaml:"database_timeout" toml:"database_timeout"`
+	UITheme            string              `json:"ui_theme" yaml:"ui_theme" toml:"ui_theme"`
+	DaemonSocket       string              `json:"daemon_socket" yaml:"daemon_socket" toml:"daemon_socket"`
+	RedactPatterns     []string            `json:"redact_patterns" yaml:"redact_patterns" toml:"redact_patterns"`
+	BlockedCommands    []string            `json:"blocked_commands" yaml:"blocked_commands" toml:"blocked_commands"`
+	APIToken           string              `json:"api_token,omitempty" yaml:"api_token,omitempty" toml:"api_token,omitempty"`
+	SyncPath           string              `json:"sync_path,omitempty" yaml:"sync_path,omitempty" toml:"sync_path,omitempty"`
 }
 
+// MinAPITokenLength is the shortest api_token accepted
+const MinAPITokenLength = 16
+
 // DefaultConfig returns a configuration with sensible defaults
 func DefaultConfig() *Config {
 	// Use current directory for database file
@@ -31,7 +45,7 @@ func DefaultConfig() *Config {
 		StoragePath:     storagePath,
 		RetentionDays:   90,
 		MaxCommands:     10000,
-		EnabledShells:   []history.ShellType{history.PowerShell, history.Bash, history.Zsh, history.Cmd},
+		EnabledShells:   []history.ShellType{history.PowerShell, history.Bash, history.Zsh, history.Cmd, history.Fish, history.Nushell, history.Elvish},
 		ExcludePatterns: []string{"cd", "ls", "dir", "pwd", "clear", "exit"},
 		AutoCleanup:     true,
 		CleanupInterval: 24 * time.Hour,
@@ -40,7 +54,12 @@ func DefaultConfig() *Config {
 	}
 }
 
-// ConfigPath returns the path to the configuration file
+// ConfigFileNames are the configuration file names looked for in the
+// configuration directory, in order. The first is created when none exists.
+var ConfigFileNames = []string{"config.json", "config.yaml", "config.yml", "config.toml"}
+
+// ConfigPath returns the path to the configuration file: the first of
+// ConfigFileNames that exists, or config.json
 func ConfigPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -52,7 +71,14 @@ func ConfigPath() (string, error) {
 		return "", err
 	}
 
-	return filepath.Join(configDir, "config.json"), nil
+	for _, name := range ConfigFileNames {
+		path := filepath.Join(configDir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return filepath.Join(configDir, ConfigFileNames[0]), nil
 }
 
 // GetConfigPath returns the path to the configuration file (ignoring errors)
@@ -66,8 +92,24 @@ func LoadConfig() (*Config, error) {
 	return Load()
 }
 
-// Load loads configuration from file or returns default if file doesn't exist
+// Load loads configuration from file, or the defaults if the file doesn't
+// exist, and applies CHT_* environment variable overrides
 func Load() (*Config, error) {
+	config, err := LoadFile()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.ApplyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// LoadFile loads configuration from file without environment overrides. Use
+// it when the configuration is going to be saved back.
+func LoadFile() (*Config, error) {
 	configPath, err := ConfigPath()
 	if err != nil {
 		return DefaultConfig(), nil
@@ -83,40 +125,51 @@ func Load() (*Config, error) {
 		return config, nil
 	}
 
-	data, err := os.ReadFile(configPath)
+	return LoadPath(configPath)
+}
+
+// LoadPath reads a JSON, YAML or TOML configuration file, chosen by extension
+func LoadPath(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return DefaultConfig(), nil
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
 	}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
-		return DefaultConfig(), nil
+	config := DefaultConfig()
+	if err := unmarshalConfig(path, data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
 	}
 
 	// Validate and set defaults for missing fields
 	config.validateAndSetDefaults()
 
-	return &config, nil
+	return config, nil
 }
 
-// Save saves the configuration to file
+// Save saves the configuration to file in the format of its extension
 func (c *Config) Save() error {
 	configPath, err := ConfigPath()
 	if err != nil {
 		return err
 	}
 
+	return c.SavePath(configPath)
+}
+
+// SavePath writes the configuration to path as JSON, YAML or TOML
+func (c *Config) SavePath(path string) error {
 	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return err
 	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	data, err := marshalConfig(path, c)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(configPath, data, 0644)
+	// The file can hold the API token, so only the owner may read it
+	return os.WriteFile(path, data, 0600)
 }
 
 // SaveConfig is an alias for Save for consistency
@@ -167,30 +220,30 @@ func (c *Config) IsShellEnabled(shell history.ShellType) bool {
 	return false
 }
 
-// ShouldExcludeCommand checks if a command should be excluded from recording
+// ShouldExcludeCommand checks if a command should be excluded from recording.
+// Rules scoped to directories or shells are ignored; use MatchExclude for those.
 func (c *Config) ShouldExcludeCommand(command string) bool {
-	for _, pattern := range c.ExcludePatterns {
-		if command == pattern {
-			return true
-		}
-	}
-	return false
+	return c.MatchExclude(command, "", history.Unknown) != nil
 }
 
-// Global configuration instance
-var globalConfig *Config
+// Global configuration instance. It is swapped atomically so a Watcher can
+// replace it while other goroutines read it.
+var globalConfig atomic.Pointer[Config]
 
-// SetGlobal sets the global configuration instance
+// SetGlobal sets the global configuration instance without notifying
+// subscribers
 func SetGlobal(config *Config) {
-	globalConfig = config
+	globalConfig.Store(config)
 }
 
-// Global returns the global configuration instance
+// Global returns the global configuration instance. Treat it as read-only:
+// reloads replace it rather than modifying it.
 func Global() *Config {
-	if globalConfig == nil {
-		globalConfig = DefaultConfig()
+	if config := globalConfig.Load(); config != nil {
+		return config
 	}
-	return globalConfig
+	globalConfig.CompareAndSwap(nil, DefaultConfig())
+	return globalConfig.Load()
 }
 
 // Validate checks if the configuration has valid values
@@ -211,9 +264,35 @@ func (c *Config) Validate() error {
 		return &ConfigValidationError{Field: "DatabaseTimeout", Message: "Database timeout cannot be negative"}
 	}
 
+	// Validate exclude patterns and rules
+	for _, pattern := range c.ExcludePatterns {
+		if pattern == "" {
+			continue
+		}
+		if err := ParseExcludePattern(pattern).Validate(); err != nil {
+			return &ConfigValidationError{Field: "ExcludePatterns", Message: err.Error()}
+		}
+	}
+	for i, rule := range c.ExcludeRules {
+		if err := rule.Validate(); err != nil {
+			return &ConfigValidationError{Field: "ExcludeRules", Message: fmt.Sprintf("rule %d: %s", i, err)}
+		}
+	}
+
+	// Validate redaction patterns
+	for _, pattern := range c.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return &ConfigValidationError{Field: "RedactPatterns", Message: "Invalid regular expression: " + pattern}
+		}
+	}
+
+	if c.APIToken != "" && len(c.APIToken) < MinAPITokenLength {
+		return &ConfigValidationError{Field: "APIToken", Message: fmt.Sprintf("API token must be at least %d characters", MinAPITokenLength)}
+	}
+
 	// Validate shell types
 	for _, shell := range c.EnabledShells {
-		if shell <= history.Unknown || shell > history.Cmd {
+		if !shell.IsValid() {
 			return &ConfigValidationError{Field: "EnabledShells", Message: "Invalid shell type in enabled shells"}
 		}
 	}
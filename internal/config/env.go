@@ -0,0 +1,139 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// EnvPrefix starts the environment variables that override configuration
+// fields: CHT_ followed by the upper-cased file key, e.g. CHT_RETENTION_DAYS
+const EnvPrefix = "CHT_"
+
+// EnvVar returns the environment variable that overrides a configuration key
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// EnvVars lists the override variable for every configuration field
+func EnvVars() []string {
+	var vars []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		vars = append(vars, EnvVar(fieldKey(t.Field(i))))
+	}
+	return vars
+}
+
+// fieldKey returns the file key of a Config field from its json tag
+func fieldKey(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+// ApplyEnv overrides fields with CHT_* variables looked up with getenv.
+// Lists are comma-separated, except CHT_EXCLUDE_RULES which is a JSON array.
+// Overrides get the same defaults and validation as the file, so e.g.
+// CHT_RETENTION_DAYS=0 falls back to the default instead of deleting every
+// command on the next cleanup.
+func (c *Config) ApplyEnv(getenv func(string) string) error {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := EnvVar(fieldKey(t.Field(i)))
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+
+		if err := setField(v.Field(i), value); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+
+	c.validateAndSetDefaults()
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("invalid environment override: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	durationType     = reflect.TypeOf(time.Duration(0))
+	shellTypesType   = reflect.TypeOf([]history.ShellType(nil))
+	excludeRulesType = reflect.TypeOf([]ExcludeRule(nil))
+)
+
+// setField parses value into field according to its type
+func setField(field reflect.Value, value string) error {
+	switch field.Type() {
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	case shellTypesType:
+		var shells []history.ShellType
+		for _, name := range splitList(value) {
+			var shell history.ShellType
+			if err := shell.UnmarshalText([]byte(name)); err != nil || !shell.IsValid() {
+				return fmt.Errorf("unknown shell %q", name)
+			}
+			shells = append(shells, shell)
+		}
+		field.Set(reflect.ValueOf(shells))
+		return nil
+	case excludeRulesType:
+		var rules []ExcludeRule
+		if err := json.Unmarshal([]byte(value), &rules); err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(rules))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", field.Type())
+		}
+		field.Set(reflect.ValueOf(splitList(value)))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma-separated value and trims each entry
+func splitList(value string) []string {
+	parts := strings.Split(value, ",")
+	list := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
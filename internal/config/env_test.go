@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+func TestConfig_ApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"CHT_STORAGE_PATH":         "/data/history.db",
+		"CHT_RETENTION_DAYS":       "7",
+		"CHT_ENABLED_SHELLS":       "bash, fish",
+		"CHT_EXCLUDE_PATTERNS":     "ls,make*",
+		"CHT_EXCLUDE_RULES":        `[{"pattern": "git push", "directories": ["~/secret"]}]`,
+		"CHT_RECORD_LEADING_SPACE": "true",
+		"CHT_AUTO_CLEANUP":         "false",
+		"CHT_CLEANUP_INTERVAL":     "90m",
+	}
+
+	cfg := DefaultConfig()
+	if err := cfg.ApplyEnv(func(name string) string { return env[name] }); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+
+	if cfg.StoragePath != "/data/history.db" || cfg.RetentionDays != 7 {
+		t.Errorf("Scalars not overridden: %+v", cfg)
+	}
+	if len(cfg.EnabledShells) != 2 || cfg.EnabledShells[1] != history.Fish {
+		t.Errorf("Shells not overridden: %v", cfg.EnabledShells)
+	}
+	if len(cfg.ExcludePatterns) != 2 || cfg.ExcludePatterns[1] != "make*" {
+		t.Errorf("Exclude patterns not overridden: %v", cfg.ExcludePatterns)
+	}
+	if len(cfg.ExcludeRules) != 1 || cfg.ExcludeRules[0].Directories[0] != "~/secret" {
+		t.Errorf("Exclude rules not overridden: %+v", cfg.ExcludeRules)
+	}
+	if !cfg.RecordLeadingSpace || cfg.AutoCleanup || cfg.CleanupInterval != 90*time.Minute {
+		t.Errorf("Booleans or durations not overridden: %+v", cfg)
+	}
+	// Unset variables leave fields alone
+	if cfg.UITheme != "default" || cfg.DatabaseTimeout != 30*time.Second {
+		t.Errorf("Unexpected change to unset fields: %+v", cfg)
+	}
+}
+
+func TestConfig_ApplyEnvErrors(t *testing.T) {
+	invalid := map[string]string{
+		"CHT_RETENTION_DAYS":   "a week",
+		"CHT_AUTO_CLEANUP":     "sometimes",
+		"CHT_CLEANUP_INTERVAL": "daily",
+		"CHT_ENABLED_SHELLS":   "bash,tcsh",
+		"CHT_EXCLUDE_RULES":    "git push",
+	}
+
+	for name, value := range invalid {
+		cfg := DefaultConfig()
+		err := cfg.ApplyEnv(func(n string) string {
+			if n == name {
+				return value
+			}
+			return ""
+		})
+		if err == nil {
+			t.Errorf("Expected error for %s=%q", name, value)
+		}
+	}
+}
+
+func TestConfig_ApplyEnvDefaultsAndValidates(t *testing.T) {
+	env := map[string]string{"CHT_RETENTION_DAYS": "0", "CHT_MAX_COMMANDS": "0"}
+	cfg := DefaultConfig()
+	if err := cfg.ApplyEnv(func(name string) string { return env[name] }); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+	defaults := DefaultConfig()
+	if cfg.RetentionDays != defaults.RetentionDays || cfg.MaxCommands != defaults.MaxCommands {
+		t.Errorf("Expected zero overrides to fall back to the defaults, got retention %d and max commands %d", cfg.RetentionDays, cfg.MaxCommands)
+	}
+
+	// Load applies the environment the same way
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	t.Setenv("CHT_RETENTION_DAYS", "0")
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.RetentionDays != defaults.RetentionDays {
+		t.Errorf("Expected CHT_RETENTION_DAYS=0 to load as %d days, got %d", defaults.RetentionDays, loaded.RetentionDays)
+	}
+
+	cfg = DefaultConfig()
+	err = cfg.ApplyEnv(func(name string) string {
+		if name == "CHT_CLEANUP_INTERVAL" {
+			return "-1h"
+		}
+		return ""
+	})
+	if err == nil {
+		t.Error("Expected a negative CHT_CLEANUP_INTERVAL to be rejected")
+	}
+}
+
+func TestEnvVars_CoverEveryField(t *testing.T) {
+	vars := EnvVars()
+	seen := make(map[string]bool)
+	for _, name := range vars {
+		seen[name] = true
+	}
+
+	for _, name := range []string{"CHT_STORAGE_PATH", "CHT_DAEMON_SOCKET", "CHT_REDACT_PATTERNS", "CHT_DATABASE_TIMEOUT"} {
+		if !seen[name] {
+			t.Errorf("Expected %s in %v", name, vars)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// Exclude rule match types
+const (
+	MatchPrefix = "prefix"
+	MatchGlob   = "glob"
+	MatchRegex  = "regex"
+)
+
+// ExcludeRule skips recording commands that match Pattern. Directories and
+// Shells narrow the rule; when empty the rule applies everywhere.
+type ExcludeRule struct {
+	Pattern     string              `json:"pattern" yaml:"pattern" toml:"pattern"`
+	Match       string              `json:"match,omitempty" yaml:"match,omitempty" toml:"match,omitempty"`
+	Directories []string            `json:"directories,omitempty" yaml:"directories,omitempty" toml:"directories,omitempty"`
+	Shells      []history.ShellType `json:"shells,omitempty" yaml:"shells,omitempty" toml:"shells,omitempty"`
+}
+
+// ExcludeMatch describes why a command is not recorded
+type ExcludeMatch struct {
+	// Source names the setting that matched, e.g. "exclude_rules[1]"
+	Source string
+	Rule   ExcludeRule
+}
+
+// String returns a one-line explanation of the match
+func (m *ExcludeMatch) String() string {
+	if m.Rule.Match == "" {
+		return fmt.Sprintf("%s: %s", m.Source, m.Rule.Pattern)
+	}
+
+	s := fmt.Sprintf("%s: %s %q", m.Source, m.Rule.Match, m.Rule.Pattern)
+	if len(m.Rule.Directories) > 0 {
+		s += fmt.Sprintf(" in %s", strings.Join(m.Rule.Directories, ", "))
+	}
+	if len(m.Rule.Shells) > 0 {
+		names := make([]string, len(m.Rule.Shells))
+		for i, shell := range m.Rule.Shells {
+			names[i] = shell.String()
+		}
+		s += fmt.Sprintf(" for %s", strings.Join(names, ", "))
+	}
+	return s
+}
+
+// ParseExcludePattern turns an exclude_patterns entry into a rule. Entries
+// prefixed with "re:" or "glob:" use that match type; entries containing
+// glob characters are globs; anything else matches the command name and the
+// command with arguments, so "ls" also excludes "ls -la".
+func ParseExcludePattern(pattern string) ExcludeRule {
+	switch {
+	case strings.HasPrefix(pattern, "re:"):
+		return ExcludeRule{Pattern: strings.TrimPrefix(pattern, "re:"), Match: MatchRegex}
+	case strings.HasPrefix(pattern, "glob:"):
+		return ExcludeRule{Pattern: strings.TrimPrefix(pattern, "glob:"), Match: MatchGlob}
+	case strings.ContainsAny(pattern, "*?["):
+		return ExcludeRule{Pattern: pattern, Match: MatchGlob}
+	default:
+		return ExcludeRule{Pattern: pattern, Match: MatchPrefix}
+	}
+}
+
+// Validate checks that the rule's patterns compile
+func (r ExcludeRule) Validate() error {
+	if r.Pattern == "" {
+		return fmt.Errorf("pattern cannot be empty")
+	}
+	switch r.Match {
+	case "", MatchPrefix, MatchGlob, MatchRegex:
+	default:
+		return fmt.Errorf("unknown match type %q (use prefix, glob or regex)", r.Match)
+	}
+	if _, err := r.compile(); err != nil {
+		return err
+	}
+	for _, dir := range r.Directories {
+		if _, err := filepath.Match(expandHome(dir), ""); err != nil {
+			return fmt.Errorf("invalid directory glob %q: %w", dir, err)
+		}
+	}
+	for _, shell := range r.Shells {
+		if !shell.IsValid() {
+			return fmt.Errorf("invalid shell type in shells")
+		}
+	}
+	return nil
+}
+
+// compile returns a regular expression equivalent to the rule's pattern
+func (r ExcludeRule) compile() (*regexp.Regexp, error) {
+	switch r.Match {
+	case MatchRegex:
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", r.Pattern, err)
+		}
+		return re, nil
+	case MatchGlob:
+		re, err := regexp.Compile(globToRegexp(r.Pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", r.Pattern, err)
+		}
+		return re, nil
+	default:
+		// A prefix matches whole words: "git" matches "git push" but not "gitk"
+		return regexp.MustCompile(`^` + regexp.QuoteMeta(r.Pattern) + `(?:\s|$)`), nil
+	}
+}
+
+// globToRegexp converts a shell glob to an anchored regular expression.
+// Unlike filepath.Match, "*" also matches "/" so globs work on command lines.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString(`^`)
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`$`)
+	return b.String()
+}
+
+// matches reports whether the rule applies to command run in directory
+// from shell. An empty directory or unknown shell never satisfies a scope.
+func (r ExcludeRule) matches(command, directory string, shell history.ShellType) bool {
+	if len(r.Shells) > 0 && !containsShell(r.Shells, shell) {
+		return false
+	}
+	if len(r.Directories) > 0 && !matchesDirectory(r.Directories, directory) {
+		return false
+	}
+
+	re, err := r.compile()
+	if err != nil {
+		return false
+	}
+	return re.MatchString(strings.TrimSpace(command))
+}
+
+func containsShell(shells []history.ShellType, shell history.ShellType) bool {
+	for _, s := range shells {
+		if s == shell {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesDirectory reports whether directory or one of its parents matches
+// any of the globs, so a rule for ~/work/secret also covers its subdirectories
+func matchesDirectory(globs []string, directory string) bool {
+	if directory == "" {
+		return false
+	}
+
+	dir := filepath.Clean(directory)
+	for {
+		for _, glob := range globs {
+			if matched, _ := filepath.Match(filepath.Clean(expandHome(glob)), dir); matched {
+				return true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// MatchExclude returns the first setting that stops command from being
+// recorded, or nil if it should be recorded
+func (c *Config) MatchExclude(command, directory string, shell history.ShellType) *ExcludeMatch {
+	// Like HISTCONTROL=ignorespace, a leading space keeps a command private
+	if !c.RecordLeadingSpace && (strings.HasPrefix(command, " ") || strings.HasPrefix(command, "\t")) {
+		return &ExcludeMatch{Source: "leading space", Rule: ExcludeRule{Pattern: "command starts with a space"}}
+	}
+
+	for i, pattern := range c.ExcludePatterns {
+		if pattern == "" {
+			continue
+		}
+		rule := ParseExcludePattern(pattern)
+		if rule.matches(command, directory, shell) {
+			return &ExcludeMatch{Source: fmt.Sprintf("exclude_patterns[%d]", i), Rule: rule}
+		}
+	}
+
+	for i, rule := range c.ExcludeRules {
+		if rule.Match == "" {
+			rule.Match = MatchPrefix
+		}
+		if rule.matches(command, directory, shell) {
+			return &ExcludeMatch{Source: fmt.Sprintf("exclude_rules[%d]", i), Rule: rule}
+		}
+	}
+
+	return nil
+}
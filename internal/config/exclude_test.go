@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+func TestMatchExclude(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("No home directory: %v", err)
+	}
+	secretDir := filepath.ToSlash(filepath.Join(home, "work", "secret"))
+
+	cfg := DefaultConfig()
+	cfg.ExcludePatterns = []string{"ls", "git st*", "re:^kubectl .*--token"}
+	cfg.ExcludeRules = []ExcludeRule{
+		{Pattern: "git push", Directories: []string{"~/work/secret"}},
+		{Pattern: `^Get-Secret\b`, Match: MatchRegex, Shells: []history.ShellType{history.PowerShell}},
+	}
+
+	tests := []struct {
+		name      string
+		command   string
+		directory string
+		shell     history.ShellType
+		source    string
+	}{
+		{"exact prefix", "ls", "/tmp", history.Bash, "exclude_patterns[0]"},
+		{"prefix with arguments", "ls -la", "/tmp", history.Bash, "exclude_patterns[0]"},
+		{"prefix needs whole word", "lsblk", "/tmp", history.Bash, ""},
+		{"glob", "git status --short", "/tmp", history.Bash, "exclude_patterns[1]"},
+		{"glob matches any continuation", "git stash", "/tmp", history.Bash, "exclude_patterns[1]"},
+		{"regex", "kubectl get pods --token abc", "/tmp", history.Bash, "exclude_patterns[2]"},
+		{"leading space", " curl https://example.com", "/tmp", history.Bash, "leading space"},
+		{"directory scope", "git push origin main", secretDir, history.Zsh, "exclude_rules[0]"},
+		{"directory scope covers subdirectories", "git push", secretDir + "/api", history.Zsh, "exclude_rules[0]"},
+		{"outside directory scope", "git push", "/tmp", history.Zsh, ""},
+		{"shell scope", "Get-Secret -Name db", "/tmp", history.PowerShell, "exclude_rules[1]"},
+		{"other shell", "Get-Secret -Name db", "/tmp", history.Bash, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := cfg.MatchExclude(tt.command, tt.directory, tt.shell)
+			switch {
+			case tt.source == "" && match != nil:
+				t.Errorf("Expected %q to be recorded, excluded by %s", tt.command, match)
+			case tt.source != "" && match == nil:
+				t.Errorf("Expected %q to be excluded by %s", tt.command, tt.source)
+			case tt.source != "" && match.Source != tt.source:
+				t.Errorf("Expected %q to be excluded by %s, got %s", tt.command, tt.source, match.Source)
+			}
+		})
+	}
+
+	cfg.RecordLeadingSpace = true
+	if match := cfg.MatchExclude(" make build", "/tmp", history.Bash); match != nil {
+		t.Errorf("Leading space should be recorded when enabled, excluded by %s", match)
+	}
+}
+
+func TestExcludeRule_Validate(t *testing.T) {
+	valid := []ExcludeRule{
+		{Pattern: "ls"},
+		{Pattern: "git *", Match: MatchGlob, Directories: []string{"~/src/*"}},
+		{Pattern: `^ssh\s`, Match: MatchRegex, Shells: []history.ShellType{history.Bash}},
+	}
+	for _, rule := range valid {
+		if err := rule.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid: %v", rule, err)
+		}
+	}
+
+	invalid := []ExcludeRule{
+		{Pattern: ""},
+		{Pattern: "ls", Match: "exact"},
+		{Pattern: "(", Match: MatchRegex},
+		{Pattern: "ls", Directories: []string{"[a-"}},
+		{Pattern: "ls", Shells: []history.ShellType{history.Unknown}},
+	}
+	for _, rule := range invalid {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", rule)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.ExcludePatterns = []string{"re:("}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected invalid exclude pattern to fail config validation")
+	}
+}
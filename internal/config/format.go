@@ -0,0 +1,105 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// unmarshalConfig decodes data into c using the format of path's extension
+func unmarshalConfig(path string, data []byte, c *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(data, c)
+	case ".toml":
+		_, err := toml.Decode(string(data), c)
+		return err
+	default:
+		return json.Unmarshal(data, c)
+	}
+}
+
+// marshalConfig encodes c in the format of path's extension
+func marshalConfig(path string, c *Config) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.Marshal(c)
+	case ".toml":
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return json.MarshalIndent(c, "", "  ")
+	}
+}
+
+// MarshalJSON writes durations as strings such as "24h0m0s" so the file can
+// be edited by hand
+func (c Config) MarshalJSON() ([]byte, error) {
+	type plain Config
+	return json.Marshal(struct {
+		plain
+		CleanupInterval string `json:"cleanup_interval"`
+		DatabaseTimeout string `json:"database_timeout"`
+	}{
+		plain:           plain(c),
+		CleanupInterval: c.CleanupInterval.String(),
+		DatabaseTimeout: c.DatabaseTimeout.String(),
+	})
+}
+
+// UnmarshalJSON accepts durations as strings such as "24h" or as integer
+// nanoseconds, as written by earlier versions
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	aux := struct {
+		*plain
+		CleanupInterval json.RawMessage `json:"cleanup_interval"`
+		DatabaseTimeout json.RawMessage `json:"database_timeout"`
+	}{plain: (*plain)(c)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if err := unmarshalDuration(aux.CleanupInterval, &c.CleanupInterval); err != nil {
+		return fmt.Errorf("cleanup_interval: %w", err)
+	}
+	if err := unmarshalDuration(aux.DatabaseTimeout, &c.DatabaseTimeout); err != nil {
+		return fmt.Errorf("database_timeout: %w", err)
+	}
+	return nil
+}
+
+// unmarshalDuration sets d from a JSON string or number, leaving it unchanged
+// when the value is absent
+func unmarshalDuration(raw json.RawMessage, d *time.Duration) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	}
+
+	var n int64
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return fmt.Errorf("expected a duration such as \"24h\", got %s", raw)
+	}
+	*d = time.Duration(n)
+	return nil
+}
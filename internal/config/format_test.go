@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+func TestConfig_SaveAndLoadFormats(t *testing.T) {
+	original := DefaultConfig()
+	original.RetentionDays = 14
+	original.CleanupInterval = 6 * time.Hour
+	original.DatabaseTimeout = 5 * time.Second
+	original.EnabledShells = []history.ShellType{history.Zsh, history.Fish}
+	original.ExcludeRules = []ExcludeRule{{Pattern: "^ssh", Match: MatchRegex, Shells: []history.ShellType{history.Bash}}}
+
+	for _, name := range []string{"config.json", "config.yaml", "config.toml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := original.SavePath(path); err != nil {
+				t.Fatalf("SavePath failed: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read saved config: %v", err)
+			}
+			if !strings.Contains(string(data), "6h0m0s") {
+				t.Errorf("Expected human-readable duration in %s:\n%s", name, data)
+			}
+
+			loaded, err := LoadPath(path)
+			if err != nil {
+				t.Fatalf("LoadPath failed: %v", err)
+			}
+			if loaded.RetentionDays != 14 || loaded.CleanupInterval != 6*time.Hour || loaded.DatabaseTimeout != 5*time.Second {
+				t.Errorf("Scalars not preserved: %+v", loaded)
+			}
+			if len(loaded.EnabledShells) != 2 || loaded.EnabledShells[1] != history.Fish {
+				t.Errorf("Shells not preserved: %v", loaded.EnabledShells)
+			}
+			if len(loaded.ExcludeRules) != 1 || loaded.ExcludeRules[0].Shells[0] != history.Bash {
+				t.Errorf("Exclude rules not preserved: %+v", loaded.ExcludeRules)
+			}
+		})
+	}
+}
+
+func TestLoadPath_HandWrittenFiles(t *testing.T) {
+	tests := map[string]string{
+		"config.yaml": "retention_days: 30\ncleanup_interval: 12h\nenabled_shells: [bash, zsh]\n",
+		"config.toml": "retention_days = 30\ncleanup_interval = \"12h\"\nenabled_shells = [\"bash\", \"zsh\"]\n",
+		"config.json": `{"retention_days": 30, "cleanup_interval": "12h", "enabled_shells": ["bash", "zsh"]}`,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			cfg, err := LoadPath(path)
+			if err != nil {
+				t.Fatalf("LoadPath failed: %v", err)
+			}
+			if cfg.RetentionDays != 30 || cfg.CleanupInterval != 12*time.Hour || len(cfg.EnabledShells) != 2 {
+				t.Errorf("Unexpected config: %+v", cfg)
+			}
+			// Unset fields keep their defaults
+			if cfg.DatabaseTimeout != 30*time.Second || cfg.UITheme != "default" {
+				t.Errorf("Defaults not kept: %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadPath_LegacyNanosecondDurations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"storage_path": "./commands.db", "cleanup_interval": 86400000000000, "database_timeout": 30000000000}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadPath(path)
+	if err != nil {
+		t.Fatalf("LoadPath failed: %v", err)
+	}
+	if cfg.CleanupInterval != 24*time.Hour || cfg.DatabaseTimeout != 30*time.Second {
+		t.Errorf("Legacy durations not read: %v %v", cfg.CleanupInterval, cfg.DatabaseTimeout)
+	}
+}
+
+func TestLoadPath_ReportsParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"config.json": `{"retention_days": 30,`,
+		"config.yaml": "retention_days: [30\n",
+		"config.toml": "retention_days = \n",
+	}
+	tests["bad_duration.json"] = `{"cleanup_interval": "one day"}`
+
+	for name, content := range tests {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		if _, err := LoadPath(path); err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("Expected parse error naming %s, got %v", name, err)
+		}
+	}
+}
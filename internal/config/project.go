@@ -0,0 +1,238 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProjectConfigNames are the per-project configuration files, in the order
+// they are looked for in each directory
+var ProjectConfigNames = []string{".tracker.json", ".tracker.toml"}
+
+// ProjectConfig holds the settings a project can override. Unset fields keep
+// the global value; lists are appended to the global lists.
+type ProjectConfig struct {
+	// Path is the file the settings were read from
+	Path string `json:"-" toml:"-"`
+
+	DoNotRecord     *bool         `json:"do_not_record" toml:"do_not_record"`
+	RetentionDays   *int          `json:"retention_days" toml:"retention_days"`
+	ExcludePatterns []string      `json:"exclude_patterns" toml:"exclude_patterns"`
+	ExcludeRules    []ExcludeRule `json:"exclude_rules" toml:"exclude_rules"`
+	AutoTags        []string      `json:"auto_tags" toml:"auto_tags"`
+	RedactPatterns  []string      `json:"redact_patterns" toml:"redact_patterns"`
+}
+
+// FindProjectConfig walks up from dir and returns the path of the nearest
+// project configuration file, or "" if there is none
+func FindProjectConfig(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	for {
+		for _, name := range ProjectConfigNames {
+			path := filepath.Join(current, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", nil
+		}
+		current = parent
+	}
+}
+
+// LoadProjectConfig reads a .tracker.json or .tracker.toml file. Unknown keys
+// are reported so typos do not silently leave commands recorded.
+func LoadProjectConfig(path string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project config: %w", err)
+	}
+
+	project := &ProjectConfig{Path: path}
+
+	switch filepath.Ext(path) {
+	case ".toml":
+		meta, err := toml.Decode(string(data), project)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("failed to parse %s: unknown setting %q", path, undecoded[0].String())
+		}
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(project); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	if err := project.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid project config %s: %w", path, err)
+	}
+
+	return project, nil
+}
+
+// Validate checks the project settings the same way Config.Validate does.
+// A retention period must be at least a day: unlike the global setting, a
+// project override is not replaced by the default, and 0 would delete every
+// command in the project on the next cleanup.
+func (p *ProjectConfig) Validate() error {
+	if p.RetentionDays != nil && *p.RetentionDays < 1 {
+		return &ConfigValidationError{Field: "RetentionDays", Message: "Retention days must be at least 1"}
+	}
+
+	// Reuse the global checks for patterns and rules
+	check := &Config{StoragePath: "-", ExcludePatterns: p.ExcludePatterns, ExcludeRules: p.ExcludeRules, RedactPatterns: p.RedactPatterns}
+	return check.Validate()
+}
+
+// Merge returns a copy of c with the project settings applied
+func (c *Config) Merge(project *ProjectConfig) *Config {
+	merged := *c
+	merged.EnabledShells = append([]history.ShellType(nil), c.EnabledShells...)
+	merged.ExcludePatterns = append([]string(nil), c.ExcludePatterns...)
+	merged.ExcludeRules = append([]ExcludeRule(nil), c.ExcludeRules...)
+	merged.AutoTags = append([]string(nil), c.AutoTags...)
+	merged.RedactPatterns = append([]string(nil), c.RedactPatterns...)
+	merged.BlockedCommands = append([]string(nil), c.BlockedCommands...)
+
+	if project == nil {
+		return &merged
+	}
+
+	if project.DoNotRecord != nil {
+		merged.DoNotRecord = *project.DoNotRecord
+	}
+	if project.RetentionDays != nil {
+		merged.RetentionDays = *project.RetentionDays
+	}
+	merged.ExcludePatterns = append(merged.ExcludePatterns, project.ExcludePatterns...)
+	merged.ExcludeRules = append(merged.ExcludeRules, project.ExcludeRules...)
+	merged.AutoTags = append(merged.AutoTags, project.AutoTags...)
+	merged.RedactPatterns = append(merged.RedactPatterns, project.RedactPatterns...)
+
+	return &merged
+}
+
+// ForDirectory returns the configuration that applies to commands run in dir:
+// c merged with the nearest project config. The project config is nil when
+// there is none.
+func (c *Config) ForDirectory(dir string) (*Config, *ProjectConfig, error) {
+	path, err := FindProjectConfig(dir)
+	if err != nil || path == "" {
+		return c, nil, err
+	}
+
+	project, err := LoadProjectConfig(path)
+	if err != nil {
+		return c, nil, err
+	}
+
+	return c.Merge(project), project, nil
+}
+
+// Setting is one effective configuration value and where it came from
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// EffectiveSettings lists every setting of c merged with project, naming the
+// file each value came from
+func (c *Config) EffectiveSettings(project *ProjectConfig) []Setting {
+	merged := c.Merge(project)
+
+	global := "global"
+	projectPath := ""
+	if project != nil {
+		projectPath = project.Path
+	}
+
+	// scalar reports a project override, list reports appended entries
+	scalar := func(overridden bool) string {
+		if overridden {
+			return projectPath
+		}
+		return global
+	}
+	list := func(globalLen, projectLen int) string {
+		switch {
+		case projectLen == 0:
+			return global
+		case globalLen == 0:
+			return projectPath
+		default:
+			return global + " + " + projectPath
+		}
+	}
+
+	var p ProjectConfig
+	if project != nil {
+		p = *project
+	}
+
+	rules := make([]string, len(merged.ExcludeRules))
+	for i, rule := range merged.ExcludeRules {
+		if rule.Match == "" {
+			rule.Match = MatchPrefix
+		}
+		rules[i] = (&ExcludeMatch{Source: strconv.Itoa(i), Rule: rule}).String()
+	}
+
+	shells := make([]string, len(merged.EnabledShells))
+	for i, shell := range merged.EnabledShells {
+		shells[i] = shell.String()
+	}
+
+	return []Setting{
+		{"do_not_record", strconv.FormatBool(merged.DoNotRecord), scalar(p.DoNotRecord != nil)},
+		{"storage_path", merged.StoragePath, global},
+		{"retention_days", strconv.Itoa(merged.RetentionDays), scalar(p.RetentionDays != nil)},
+		{"max_commands", strconv.Itoa(merged.MaxCommands), global},
+		{"enabled_shells", strings.Join(shells, ", "), global},
+		{"exclude_patterns", strings.Join(merged.ExcludePatterns, ", "), list(len(c.ExcludePatterns), len(p.ExcludePatterns))},
+		{"exclude_rules", strings.Join(rules, "; "), list(len(c.ExcludeRules), len(p.ExcludeRules))},
+		{"record_leading_space", strconv.FormatBool(merged.RecordLeadingSpace), global},
+		{"auto_tags", strings.Join(merged.AutoTags, ", "), list(len(c.AutoTags), len(p.AutoTags))},
+		{"redact_patterns", strings.Join(merged.RedactPatterns, ", "), list(len(c.RedactPatterns), len(p.RedactPatterns))},
+		{"blocked_commands", strings.Join(merged.BlockedCommands, ", "), global},
+		{"auto_cleanup", strconv.FormatBool(merged.AutoCleanup), global},
+		{"cleanup_interval", merged.CleanupInterval.String(), global},
+		{"database_timeout", merged.DatabaseTimeout.String(), global},
+		{"ui_theme", merged.UITheme, global},
+		{"daemon_socket", merged.DaemonSocket, global},
+		{"api_token", maskToken(merged.APIToken), global},
+		{"sync_path", merged.SyncPath, global},
+	}
+}
+
+// maskToken hides a secret setting, showing only whether it is set
+func maskToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	return "(set)"
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+func writeProjectFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+}
+
+func TestFindProjectConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "services", "api", "internal")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	path, err := FindProjectConfig(nested)
+	if err != nil || path != "" {
+		t.Fatalf("Expected no project config, got %q (%v)", path, err)
+	}
+
+	rootConfig := filepath.Join(root, ".tracker.toml")
+	writeProjectFile(t, rootConfig, "retention_days = 7\n")
+	if path, _ := FindProjectConfig(nested); path != rootConfig {
+		t.Errorf("Expected %s, got %q", rootConfig, path)
+	}
+
+	// The nearest file wins
+	apiConfig := filepath.Join(root, "services", "api", ".tracker.json")
+	writeProjectFile(t, apiConfig, `{"retention_days": 30}`)
+	if path, _ := FindProjectConfig(nested); path != apiConfig {
+		t.Errorf("Expected %s, got %q", apiConfig, path)
+	}
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	tomlPath := filepath.Join(dir, "toml", ".tracker.toml")
+	writeProjectFile(t, tomlPath, `
+do_not_record = false
+retention_days = 14
+exclude_patterns = ["make*"]
+auto_tags = ["monorepo"]
+redact_patterns = ["vault-[0-9]+"]
+
+[[exclude_rules]]
+pattern = "terraform apply"
+shells = ["zsh"]
+`)
+	project, err := LoadProjectConfig(tomlPath)
+	if err != nil {
+		t.Fatalf("LoadProjectConfig(toml) failed: %v", err)
+	}
+	if project.RetentionDays == nil || *project.RetentionDays != 14 || project.DoNotRecord == nil {
+		t.Errorf("Scalars not decoded: %+v", project)
+	}
+	if len(project.ExcludeRules) != 1 || project.ExcludeRules[0].Shells[0] != history.Zsh {
+		t.Errorf("Exclude rules not decoded: %+v", project.ExcludeRules)
+	}
+
+	jsonPath := filepath.Join(dir, "json", ".tracker.json")
+	writeProjectFile(t, jsonPath, `{"do_not_record": true, "exclude_rules": [{"pattern": "^ssh", "match": "regex"}]}`)
+	project, err = LoadProjectConfig(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadProjectConfig(json) failed: %v", err)
+	}
+	if project.DoNotRecord == nil || !*project.DoNotRecord || project.RetentionDays != nil {
+		t.Errorf("Unexpected JSON project config: %+v", project)
+	}
+
+	invalid := map[string]string{
+		".tracker.json": `{"retention_dayz": 3}`,
+		".tracker.toml": "retention_dayz = 3\n",
+	}
+	for name, content := range invalid {
+		path := filepath.Join(dir, "invalid", name)
+		writeProjectFile(t, path, content)
+		if _, err := LoadProjectConfig(path); err == nil || !strings.Contains(err.Error(), "retention_dayz") {
+			t.Errorf("Expected unknown key error for %s, got %v", name, err)
+		}
+	}
+
+	zeroRetention := filepath.Join(dir, "zero", ".tracker.json")
+	writeProjectFile(t, zeroRetention, `{"retention_days": 0}`)
+	if _, err := LoadProjectConfig(zeroRetention); err == nil {
+		t.Error("Expected a retention period of 0 days to be rejected")
+	}
+
+	badRule := filepath.Join(dir, "bad", ".tracker.json")
+	writeProjectFile(t, badRule, `{"exclude_patterns": ["re:("]}`)
+	if _, err := LoadProjectConfig(badRule); err == nil {
+		t.Error("Expected invalid exclude pattern to be rejected")
+	}
+}
+
+func TestConfig_MergeAndEffectiveSettings(t *testing.T) {
+	global := DefaultConfig()
+	global.AutoTags = []string{"work"}
+
+	doNotRecord := true
+	retention := 7
+	project := &ProjectConfig{
+		Path:            "/repo/.tracker.json",
+		DoNotRecord:     &doNotRecord,
+		RetentionDays:   &retention,
+		ExcludePatterns: []string{"make*"},
+		AutoTags:        []string{"monorepo"},
+	}
+
+	merged := global.Merge(project)
+	if !merged.DoNotRecord || merged.RetentionDays != 7 {
+		t.Errorf("Scalars not overridden: %+v", merged)
+	}
+	if len(merged.ExcludePatterns) != len(global.ExcludePatterns)+1 || len(merged.AutoTags) != 2 {
+		t.Errorf("Lists not appended: %v %v", merged.ExcludePatterns, merged.AutoTags)
+	}
+	if global.RetentionDays != 90 || len(global.AutoTags) != 1 {
+		t.Error("Merge modified the global config")
+	}
+
+	sources := make(map[string]string)
+	for _, setting := range global.EffectiveSettings(project) {
+		sources[setting.Key] = setting.Source
+	}
+	expected := map[string]string{
+		"do_not_record":    "/repo/.tracker.json",
+		"retention_days":   "/repo/.tracker.json",
+		"exclude_patterns": "global + /repo/.tracker.json",
+		"auto_tags":        "global + /repo/.tracker.json",
+		"redact_patterns":  "global",
+		"storage_path":     "global",
+	}
+	for key, source := range expected {
+		if sources[key] != source {
+			t.Errorf("Expected %s from %q, got %q", key, source, sources[key])
+		}
+	}
+}
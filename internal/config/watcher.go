@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/logging"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultReloadDelay is how long a Watcher waits after the last change to the
+// file before reloading it, so an editor's save is read once
+const DefaultReloadDelay = 200 * time.Millisecond
+
+// Subscriber is called after the global configuration has been replaced
+type Subscriber func(old, new *Config)
+
+type subscription struct {
+	id int
+	fn Subscriber
+}
+
+var (
+	subscribersMu  sync.Mutex
+	subscribers    []subscription
+	nextSubscriber int
+)
+
+// Subscribe registers fn to be called after every Swap, in the order the
+// subscribers were added. It returns a function that removes fn.
+func Subscribe(fn Subscriber) (unsubscribe func()) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	nextSubscriber++
+	id := nextSubscriber
+	subscribers = append(subscribers, subscription{id: id, fn: fn})
+
+	return func() {
+		subscribersMu.Lock()
+		defer subscribersMu.Unlock()
+
+		for i, sub := range subscribers {
+			if sub.id == id {
+				subscribers = append(subscribers[:i:i], subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Swap replaces the global configuration, notifies subscribers and returns the
+// previous configuration
+func Swap(config *Config) *Config {
+	old := globalConfig.Swap(config)
+	if old == nil {
+		old = DefaultConfig()
+	}
+
+	subscribersMu.Lock()
+	notify := append([]subscription(nil), subscribers...)
+	subscribersMu.Unlock()
+
+	for _, sub := range notify {
+		sub.fn(old, config)
+	}
+
+	return old
+}
+
+// Watcher reloads a configuration file when it changes. Each new version is
+// validated before it replaces Global(); invalid edits are logged and the
+// current configuration is kept.
+type Watcher struct {
+	path   string
+	delay  time.Duration
+	getenv func(string) string
+	logger *logging.Logger
+	fs     *fsnotify.Watcher
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWatcher creates a watcher for the configuration file at path. The
+// directory is watched rather than the file so editors that save by replacing
+// the file are followed. A nil logger uses the default logger.
+func NewWatcher(path string, logger *logging.Logger) (*Watcher, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config watcher: %w", err)
+	}
+
+	if err := fsWatcher.Add(filepath.Dir(absPath)); err != nil {
+		fsWatcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", filepath.Dir(absPath), err)
+	}
+
+	if logger == nil {
+		logger = logging.Default()
+	}
+
+	return &Watcher{
+		path:   absPath,
+		delay:  DefaultReloadDelay,
+		getenv: os.Getenv,
+		logger: logger,
+		fs:     fsWatcher,
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Path returns the configuration file being watched
+func (w *Watcher) Path() string {
+	return w.path
+}
+
+// SetDelay changes how long the watcher waits before reloading
+func (w *Watcher) SetDelay(delay time.Duration) {
+	w.delay = delay
+}
+
+// Start begins watching in the background until Close is called
+func (w *Watcher) Start() {
+	go w.run()
+}
+
+// Close stops watching the file
+func (w *Watcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.fs.Close()
+	})
+	return err
+}
+
+// run waits for changes to the file and reloads it once they settle
+func (w *Watcher) run() {
+	timer := time.NewTimer(w.delay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != w.path || event.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(w.delay)
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Error("Config watcher error: %v", err)
+		case <-timer.C:
+			w.reload()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// reload applies the file and logs the outcome
+func (w *Watcher) reload() {
+	changed, err := w.Reload()
+	if err != nil {
+		w.logger.Error("Rejected configuration change in %s, keeping the current configuration: %v", w.path, err)
+		return
+	}
+	if changed {
+		w.logger.Info("Reloaded configuration from %s", w.path)
+	}
+}
+
+// Reload reads and validates the file, applies CHT_* overrides and swaps it in
+// as the global configuration. It reports false when nothing changed.
+func (w *Watcher) Reload() (bool, error) {
+	config, err := LoadPath(w.path)
+	if err != nil {
+		return false, err
+	}
+
+	if err := config.ApplyEnv(w.getenv); err != nil {
+		return false, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return false, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if reflect.DeepEqual(config, Global()) {
+		return false, nil
+	}
+
+	Swap(config)
+	return true, nil
+}
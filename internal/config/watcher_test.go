@@ -0,0 +1,152 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/logging"
+)
+
+func TestSwap_NotifiesSubscribers(t *testing.T) {
+	previous := Global()
+	defer SetGlobal(previous)
+
+	var calls []int
+	unsubscribe := Subscribe(func(old, new *Config) {
+		if old != previous {
+			t.Errorf("Expected old config to be the previous global")
+		}
+		calls = append(calls, new.RetentionDays)
+	})
+
+	next := DefaultConfig()
+	next.RetentionDays = 7
+	if old := Swap(next); old != previous {
+		t.Errorf("Swap returned %p, expected previous config %p", old, previous)
+	}
+	if Global() != next {
+		t.Errorf("Expected Global() to return the swapped config")
+	}
+
+	unsubscribe()
+	Swap(DefaultConfig())
+
+	if len(calls) != 1 || calls[0] != 7 {
+		t.Errorf("Expected one notification with retention 7, got %v", calls)
+	}
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	previous := Global()
+	defer SetGlobal(previous)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "storage_path: ./test.db\nretention_days: 30\n")
+
+	watcher, err := NewWatcher(path, logging.New(io.Discard, logging.ErrorLevel))
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer watcher.Close()
+	watcher.getenv = func(string) string { return "" }
+
+	changed, err := watcher.Reload()
+	if err != nil || !changed {
+		t.Fatalf("Expected first reload to apply, got changed=%v err=%v", changed, err)
+	}
+	current := Global()
+	if current.RetentionDays != 30 {
+		t.Errorf("Expected retention 30, got %d", current.RetentionDays)
+	}
+
+	changed, err = watcher.Reload()
+	if err != nil || changed {
+		t.Errorf("Expected unchanged file to be ignored, got changed=%v err=%v", changed, err)
+	}
+
+	invalid := map[string]string{
+		"negative interval": "storage_path: ./test.db\ncleanup_interval: -1h\n",
+		"bad regex":         "storage_path: ./test.db\nexclude_patterns: [\"re:(\"]\n",
+		"syntax error":      "storage_path: [\n",
+	}
+	for name, content := range invalid {
+		t.Run(name, func(t *testing.T) {
+			writeFile(t, path, content)
+			if _, err := watcher.Reload(); err == nil {
+				t.Errorf("Expected invalid config to be rejected")
+			}
+			if Global() != current {
+				t.Errorf("Expected rejected config to leave Global() unchanged")
+			}
+		})
+	}
+}
+
+func TestWatcher_FollowsFileChanges(t *testing.T) {
+	previous := Global()
+	defer SetGlobal(previous)
+	SetGlobal(DefaultConfig())
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	writeFile(t, path, `{"storage_path": "./test.db", "retention_days": 90}`)
+
+	watcher, err := NewWatcher(path, logging.New(io.Discard, logging.ErrorLevel))
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer watcher.Close()
+	watcher.getenv = func(string) string { return "" }
+	watcher.SetDelay(20 * time.Millisecond)
+
+	reloaded := make(chan int, 10)
+	defer Subscribe(func(old, new *Config) { reloaded <- new.RetentionDays })()
+	watcher.Start()
+
+	// Changes to other files in the directory are ignored
+	writeFile(t, filepath.Join(dir, "other.json"), `{}`)
+
+	// An invalid edit is rejected, the following valid one applied
+	writeFile(t, path, `{"storage_path": "./test.db", "retention_days": 5, "cleanup_interval": "-1h"}`)
+	time.Sleep(100 * time.Millisecond)
+	writeFile(t, path, `{"storage_path": "./test.db", "retention_days": 14}`)
+
+	select {
+	case days := <-reloaded:
+		if days != 14 {
+			t.Errorf("Expected reload with retention 14, got %d", days)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for config reload")
+	}
+
+	if Global().RetentionDays != 14 {
+		t.Errorf("Expected Global() retention 14, got %d", Global().RetentionDays)
+	}
+
+	// Saving by replacing the file is followed too
+	tmp := filepath.Join(dir, "config.json.tmp")
+	writeFile(t, tmp, `{"storage_path": "./test.db", "retention_days": 21}`)
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Failed to replace config: %v", err)
+	}
+
+	select {
+	case days := <-reloaded:
+		if days != 21 {
+			t.Errorf("Expected reload with retention 21, got %d", days)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for config reload after rename")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
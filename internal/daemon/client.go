@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// DefaultTimeout bounds how long a shell hook waits for the daemon before
+// writing to the database itself
+const DefaultTimeout = 250 * time.Millisecond
+
+// Client sends requests to a running daemon
+type Client struct {
+	address string
+	timeout time.Duration
+}
+
+// NewClient creates a client for the daemon listening on address
+func NewClient(address string) *Client {
+	return &Client{
+		address: address,
+		timeout: DefaultTimeout,
+	}
+}
+
+// SetTimeout changes how long a request may take, including connecting
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// Send queues a command record in the daemon
+func (c *Client) Send(record history.CommandRecord) error {
+	_, err := c.do(Request{Op: OpRecord, Record: &record})
+	return err
+}
+
+// Ping checks that the daemon is running and returns its counters
+func (c *Client) Ping() (*Stats, error) {
+	resp, err := c.do(Request{Op: OpPing})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Stats == nil {
+		return &Stats{}, nil
+	}
+	return resp.Stats, nil
+}
+
+// Shutdown asks the daemon to write its queue and exit
+func (c *Client) Shutdown() error {
+	_, err := c.do(Request{Op: OpShutdown})
+	return err
+}
+
+// do sends one request on a fresh connection and waits for the response
+func (c *Client) do(req Request) (*Response, error) {
+	conn, err := dial(c.address, c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer conn.Close()
+
+	// Named pipes do not support deadlines; the daemon answers immediately
+	_ = conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if !resp.OK {
+		return &resp, fmt.Errorf("daemon rejected request: %s", resp.Error)
+	}
+
+	return &resp, nil
+}
+
+// Address returns the configured daemon address, or the platform default
+// when none is configured
+func Address(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	return DefaultAddress()
+}
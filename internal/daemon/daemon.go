@@ -0,0 +1,274 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/logging"
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// Request operations understood by the daemon
+const (
+	OpRecord   = "record"
+	OpPing     = "ping"
+	OpShutdown = "shutdown"
+)
+
+const (
+	// DefaultBatchSize is the most records written per BatchSaveCommands call
+	DefaultBatchSize = 100
+
+	// DefaultFlushInterval is how long a record may wait in the queue before
+	// a partial batch is written
+	DefaultFlushInterval = 500 * time.Millisecond
+
+	// DefaultQueueSize is how many records may wait to be written; clients are
+	// turned away once it is full and write to the database themselves
+	DefaultQueueSize = 1024
+
+	// connectionTimeout bounds how long a client connection may stay idle
+	connectionTimeout = 5 * time.Second
+)
+
+// Request is a newline-delimited JSON message sent by a client
+type Request struct {
+	Op     string                 `json:"op"`
+	Record *history.CommandRecord `json:"record,omitempty"`
+}
+
+// Response answers a single Request
+type Response struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	Stats *Stats `json:"stats,omitempty"`
+}
+
+// Stats reports what the daemon has done since it started
+type Stats struct {
+	Received int64     `json:"received"`
+	Saved    int64     `json:"saved"`
+	Failed   int64     `json:"failed"`
+	Queued   int       `json:"queued"`
+	Started  time.Time `json:"started"`
+}
+
+// Options controls batching in the daemon
+type Options struct {
+	BatchSize     int
+	FlushInterval time.Duration
+	QueueSize     int
+}
+
+// Server accepts command records from clients and writes them in batches
+// through a single storage connection
+type Server struct {
+	storage storage.BatchStorageEngine
+	opts    Options
+
+	records    chan history.CommandRecord
+	done       chan struct{}
+	writerDone chan struct{}
+	stopOnce   sync.Once
+	conns      sync.WaitGroup
+
+	started  time.Time
+	received atomic.Int64
+	saved    atomic.Int64
+	failed   atomic.Int64
+}
+
+// NewServer creates a daemon server that writes to the given storage
+func NewServer(store storage.BatchStorageEngine, opts Options) *Server {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+
+	return &Server{
+		storage:    store,
+		opts:       opts,
+		records:    make(chan history.CommandRecord, opts.QueueSize),
+		done:       make(chan struct{}),
+		writerDone: make(chan struct{}),
+		started:    time.Now(),
+	}
+}
+
+// Serve accepts connections on l until Close is called or a client requests
+// a shutdown. Queued records are written before it returns.
+func (s *Server) Serve(l net.Listener) error {
+	go s.writeLoop()
+	go func() {
+		<-s.done
+		l.Close()
+	}()
+
+	var acceptErr error
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				acceptErr = fmt.Errorf("failed to accept connection: %w", err)
+				s.stop()
+			}
+			break
+		}
+
+		s.conns.Add(1)
+		go s.handleConnection(conn)
+	}
+
+	// No handler may queue a record once the channel is closed
+	s.conns.Wait()
+	close(s.records)
+	<-s.writerDone
+
+	return acceptErr
+}
+
+// Close stops accepting connections; Serve returns once the queue is written
+func (s *Server) Close() error {
+	s.stop()
+	return nil
+}
+
+// Stats returns a snapshot of the daemon counters
+func (s *Server) Stats() Stats {
+	return Stats{
+		Received: s.received.Load(),
+		Saved:    s.saved.Load(),
+		Failed:   s.failed.Load(),
+		Queued:   len(s.records),
+		Started:  s.started,
+	}
+}
+
+func (s *Server) stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+// handleConnection answers requests on one connection until the client hangs up
+func (s *Server) handleConnection(conn net.Conn) {
+	defer s.conns.Done()
+	defer conn.Close()
+
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+
+	for {
+		// Named pipes do not support deadlines; the error is ignored there
+		_ = conn.SetDeadline(time.Now().Add(connectionTimeout))
+
+		var req Request
+		if err := decoder.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				logging.Debug("daemon: dropping connection: %v", err)
+			}
+			return
+		}
+
+		if err := encoder.Encode(s.handleRequest(req)); err != nil {
+			logging.Debug("daemon: failed to send response: %v", err)
+			return
+		}
+	}
+}
+
+// handleRequest performs a single request
+func (s *Server) handleRequest(req Request) Response {
+	switch req.Op {
+	case OpRecord:
+		if req.Record == nil {
+			return Response{Error: "record request without a record"}
+		}
+		if err := req.Record.Validate(); err != nil {
+			return Response{Error: err.Error()}
+		}
+
+		select {
+		case s.records <- *req.Record:
+			s.received.Add(1)
+			return Response{OK: true}
+		default:
+			return Response{Error: "queue is full"}
+		}
+	case OpPing:
+		stats := s.Stats()
+		return Response{OK: true, Stats: &stats}
+	case OpShutdown:
+		s.stop()
+		return Response{OK: true}
+	default:
+		return Response{Error: fmt.Sprintf("unknown operation %q", req.Op)}
+	}
+}
+
+// writeLoop drains the queue, writing a batch when it is full or when the
+// flush interval passes, until the queue is closed
+func (s *Server) writeLoop() {
+	defer close(s.writerDone)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]history.CommandRecord, 0, s.opts.BatchSize)
+	for {
+		select {
+		case record, ok := <-s.records:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= s.opts.BatchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush writes a batch in one transaction. If the batch is rejected, for
+// example because one record was already stored, the records are retried
+// one at a time so a single bad record does not lose the others.
+func (s *Server) flush(batch []history.CommandRecord) {
+	if len(batch) == 0 {
+		return
+	}
+
+	err := s.storage.BatchSaveCommands(batch)
+	if err == nil {
+		s.saved.Add(int64(len(batch)))
+		return
+	}
+	logging.Error("daemon: batch of %d commands failed, retrying individually: %v", len(batch), err)
+
+	for _, record := range batch {
+		if err := s.storage.SaveCommand(record); err != nil {
+			logging.Error("daemon: failed to save command %s: %v", record.ID, err)
+			s.failed.Add(1)
+			continue
+		}
+		s.saved.Add(1)
+	}
+}
@@ -0,0 +1,214 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// startTestDaemon serves a fresh database and returns a client for it and a
+// function that stops the daemon and waits for queued records to be written
+func startTestDaemon(t *testing.T, opts Options) (*storage.SQLiteStorage, *Client, func()) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	store := storage.NewSQLiteStorage(filepath.Join(tempDir, "test.db"))
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	address := filepath.Join(tempDir, "tracker.sock")
+	if runtime.GOOS == "windows" {
+		address = fmt.Sprintf(`\\.\pipe\cht-test-%d`, time.Now().UnixNano())
+	}
+
+	listener, err := Listen(address)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	server := NewServer(store, opts)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	client := NewClient(address)
+	client.SetTimeout(2 * time.Second)
+
+	stopped := false
+	stop := func() {
+		if stopped {
+			return
+		}
+		stopped = true
+		server.Close()
+		if err := <-served; err != nil {
+			t.Errorf("Serve returned error: %v", err)
+		}
+	}
+	t.Cleanup(stop)
+
+	return store, client, stop
+}
+
+func testRecord(id, command string) history.CommandRecord {
+	return history.CommandRecord{
+		ID:        id,
+		Command:   command,
+		Directory: "/test/daemon",
+		Timestamp: time.Now(),
+		Shell:     history.Bash,
+		Tags:      []string{},
+	}
+}
+
+func TestServer_WritesQueuedRecordsOnClose(t *testing.T) {
+	// A long interval means records are only written by a full batch or by Close
+	store, client, stop := startTestDaemon(t, Options{BatchSize: 2, FlushInterval: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		if err := client.Send(testRecord(fmt.Sprintf("cmd-%d", i), fmt.Sprintf("echo %d", i))); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	stop()
+
+	commands, err := store.GetCommandsByDirectory("/test/daemon")
+	if err != nil {
+		t.Fatalf("GetCommandsByDirectory failed: %v", err)
+	}
+	if len(commands) != 5 {
+		t.Errorf("Expected 5 commands after shutdown, got %d", len(commands))
+	}
+}
+
+func TestServer_FlushesPartialBatchAfterInterval(t *testing.T) {
+	store, client, _ := startTestDaemon(t, Options{BatchSize: 100, FlushInterval: 20 * time.Millisecond})
+
+	if err := client.Send(testRecord("cmd-1", "make build")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		commands, err := store.GetCommandsByDirectory("/test/daemon")
+		if err != nil {
+			t.Fatalf("GetCommandsByDirectory failed: %v", err)
+		}
+		if len(commands) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Record was not written within the flush interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServer_DuplicateDoesNotLoseBatch(t *testing.T) {
+	store, client, stop := startTestDaemon(t, Options{BatchSize: 10, FlushInterval: time.Hour})
+
+	if err := store.SaveCommand(testRecord("dup", "git status")); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+
+	for _, record := range []history.CommandRecord{
+		testRecord("fresh-1", "git pull"),
+		testRecord("dup", "git status"),
+		testRecord("fresh-2", "git push"),
+	} {
+		if err := client.Send(record); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	stop()
+
+	commands, err := store.GetCommandsByDirectory("/test/daemon")
+	if err != nil {
+		t.Fatalf("GetCommandsByDirectory failed: %v", err)
+	}
+	if len(commands) != 3 {
+		t.Errorf("Expected the duplicate to be skipped and both fresh records saved, got %d commands", len(commands))
+	}
+}
+
+func TestServer_RejectsInvalidRecord(t *testing.T) {
+	_, client, _ := startTestDaemon(t, Options{})
+
+	if err := client.Send(history.CommandRecord{ID: "bad"}); err == nil {
+		t.Error("Expected invalid record to be rejected")
+	}
+}
+
+func TestClient_PingAndShutdown(t *testing.T) {
+	_, client, _ := startTestDaemon(t, Options{})
+
+	if err := client.Send(testRecord("cmd-1", "ls -la")); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	stats, err := client.Ping()
+	if err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if stats.Received != 1 {
+		t.Errorf("Expected 1 received record, got %d", stats.Received)
+	}
+
+	if err := client.Shutdown(); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for client.Send(testRecord("cmd-2", "pwd")) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Daemon still accepting records after shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClient_NoDaemon(t *testing.T) {
+	address := filepath.Join(t.TempDir(), "missing.sock")
+	if runtime.GOOS == "windows" {
+		address = `\\.\pipe\cht-test-missing`
+	}
+
+	client := NewClient(address)
+	if err := client.Send(testRecord("cmd-1", "ls")); err == nil {
+		t.Error("Expected Send to fail without a daemon so callers can fall back")
+	}
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Named pipes leave no files behind")
+	}
+
+	address := filepath.Join(t.TempDir(), "tracker.sock")
+
+	first, err := Listen(address)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	if _, err := Listen(address); err == nil {
+		t.Error("Expected second Listen to fail while a daemon is listening")
+	}
+	// Leave the socket file behind as a crashed daemon would
+	first.(*net.UnixListener).SetUnlinkOnClose(false)
+	first.Close()
+
+	second, err := Listen(address)
+	if err != nil {
+		t.Fatalf("Listen after close failed: %v", err)
+	}
+	second.Close()
+}
@@ -0,0 +1,57 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DefaultAddress returns the Unix socket path used when none is configured
+func DefaultAddress() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "command-history-tracker-"+strconv.Itoa(os.Getuid())+".sock")
+	}
+	return filepath.Join(homeDir, ".command-history-tracker", "tracker.sock")
+}
+
+// Listen opens the daemon socket, replacing a stale socket file left by a
+// daemon that did not shut down cleanly
+func Listen(address string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(address), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	if _, err := os.Lstat(address); err == nil {
+		if conn, err := net.DialTimeout("unix", address, DefaultTimeout); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("a daemon is already listening on %s", address)
+		}
+		if err := os.Remove(address); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	// Commands can contain secrets, so only the owner may connect
+	if err := os.Chmod(address, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+
+	return listener, nil
+}
+
+// dial connects to the daemon socket
+func dial(address string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("unix", address, timeout)
+}
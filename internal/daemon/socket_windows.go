@@ -0,0 +1,161 @@
+//go:build windows
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+const pipeBufferSize = 4096
+
+// DefaultAddress returns the named pipe used when none is configured
+func DefaultAddress() string {
+	user := os.Getenv("USERNAME")
+	if user == "" {
+		user = "default"
+	}
+	return `\\.\pipe\command-history-tracker-` + user
+}
+
+// pipeAddr is the net.Addr of a named pipe
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+// pipeConn is one end of a named pipe connection. Deadlines come from
+// os.File and are not supported for synchronous pipe handles.
+type pipeConn struct {
+	*os.File
+	addr pipeAddr
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.addr }
+
+// pipeListener accepts clients on a named pipe, keeping one pipe instance
+// waiting for the next client
+type pipeListener struct {
+	name   string
+	mu     sync.Mutex
+	closed bool
+	handle windows.Handle
+}
+
+// Listen creates the daemon named pipe
+func Listen(address string) (net.Listener, error) {
+	listener := &pipeListener{name: address}
+
+	handle, err := listener.createInstance(true)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+			return nil, fmt.Errorf("a daemon is already listening on %s", address)
+		}
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	listener.handle = handle
+
+	return listener, nil
+}
+
+// createInstance creates a pipe instance; the first one fails if another
+// process already owns the pipe name
+func (l *pipeListener) createInstance(first bool) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(l.name)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+
+	flags := uint32(windows.PIPE_ACCESS_DUPLEX)
+	if first {
+		flags |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	mode := uint32(windows.PIPE_TYPE_BYTE | windows.PIPE_READMODE_BYTE | windows.PIPE_WAIT | windows.PIPE_REJECT_REMOTE_CLIENTS)
+
+	return windows.CreateNamedPipe(name, flags, mode, windows.PIPE_UNLIMITED_INSTANCES, pipeBufferSize, pipeBufferSize, 0, nil)
+}
+
+// Accept waits for a client to connect to the pipe
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	handle := l.handle
+	l.mu.Unlock()
+
+	err := windows.ConnectNamedPipe(handle, nil)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Close wakes a pending ConnectNamedPipe by connecting to it, and owns
+	// the handle from then on
+	if l.closed {
+		return nil, net.ErrClosed
+	}
+	if err != nil && !errors.Is(err, windows.ERROR_PIPE_CONNECTED) {
+		return nil, fmt.Errorf("failed to accept pipe client: %w", err)
+	}
+
+	next, err := l.createInstance(false)
+	if err != nil {
+		windows.CloseHandle(handle)
+		l.closed = true
+		return nil, fmt.Errorf("failed to create pipe instance: %w", err)
+	}
+	l.handle = next
+
+	return &pipeConn{File: os.NewFile(uintptr(handle), l.name), addr: pipeAddr(l.name)}, nil
+}
+
+// Close stops listening and releases the waiting pipe instance
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	handle := l.handle
+	l.mu.Unlock()
+
+	if conn, err := dial(l.name, DefaultTimeout); err == nil {
+		conn.Close()
+	}
+
+	return windows.CloseHandle(handle)
+}
+
+// Addr returns the pipe name
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr(l.name)
+}
+
+// dial connects to the daemon pipe, retrying while all instances are busy
+func dial(address string, timeout time.Duration) (net.Conn, error) {
+	name, err := windows.UTF16PtrFromString(address)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		handle, err := windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, 0, 0)
+		if err == nil {
+			return &pipeConn{File: os.NewFile(uintptr(handle), address), addr: pipeAddr(address)}, nil
+		}
+		if !errors.Is(err, windows.ERROR_PIPE_BUSY) || time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
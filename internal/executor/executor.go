@@ -252,28 +252,36 @@ func (e *Executor) RequiresConfirmation(command string) bool {
 
 // ExecuteCommand runs a command in the specified directory context
 func (e *Executor) ExecuteCommand(cmd *history.CommandRecord, currentDir string) error {
+	_, err := e.Execute(cmd, currentDir)
+	return err
+}
+
+// Execute validates a command, asks for confirmation when it needs it, runs it
+// in currentDir and records the run. A command that runs and exits non-zero is
+// reported through the result's ExitCode rather than an error.
+func (e *Executor) Execute(cmd *history.CommandRecord, currentDir string) (*ExecutionResult, error) {
 	if cmd == nil {
-		return errors.NewValidationError("command record cannot be nil", nil)
+		return nil, errors.NewValidationError("command record cannot be nil", nil)
 	}
 
 	// Validate command
 	if err := e.ValidateCommand(cmd); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Get confirmation if needed
 	confirmed, err := e.ConfirmExecution(cmd)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if !confirmed {
-		return errors.NewExecutionError("command execution cancelled by user", nil)
+		return nil, errors.NewExecutionError("command execution cancelled by user", nil)
 	}
 
 	// Execute the command
 	result, err := e.executeInContext(cmd.Command, currentDir, cmd.Shell)
 	if err != nil {
-		return errors.NewExecutionError("command execution failed", err).
+		return result, errors.NewExecutionError("command execution failed", err).
 			WithContext("command", cmd.Command).
 			WithContext("directory", currentDir).
 			WithContext("exit_code", result.ExitCode)
@@ -311,7 +319,7 @@ func (e *Executor) ExecuteCommand(cmd *history.CommandRecord, currentDir string)
 			}
 		}
 
-	return nil
+	return result, nil
 }
 
 // executeInContext executes a command in a specific directory with proper context
@@ -347,6 +355,12 @@ func (e *Executor) executeInContext(command string, directory string, shell hist
 		cmd = exec.Command("bash", "-c", command)
 	case history.Zsh:
 		cmd = exec.Command("zsh", "-c", command)
+	case history.Fish:
+		cmd = exec.Command("fish", "-c", command)
+	case history.Nushell:
+		cmd = exec.Command("nu", "-c", command)
+	case history.Elvish:
+		cmd = exec.Command("elvish", "-c", command)
 	default:
 		// Default to system shell
 		if isWindows() {
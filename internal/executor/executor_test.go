@@ -323,3 +323,29 @@ func TestExecutorClose(t *testing.T) {
 		t.Errorf("Close() with audit logger returned error: %v", err)
 	}
 }
+
+func TestExecute_ReturnsResult(t *testing.T) {
+	logger := &MockLogger{}
+	executor := NewExecutorWithLogger(logger)
+	tmpDir := t.TempDir()
+
+	cmd := &history.CommandRecord{Command: "echo test", Directory: tmpDir, Shell: history.Bash}
+	result, err := executor.Execute(cmd, tmpDir)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if result == nil || result.ExitCode != 0 {
+		t.Fatalf("expected successful result, got %+v", result)
+	}
+
+	recorded := logger.GetCommands()
+	if len(recorded) != 1 || recorded[0].Directory != tmpDir {
+		t.Errorf("expected the run to be recorded in %s, got %+v", tmpDir, recorded)
+	}
+
+	// Blocked commands return no result
+	result, err = executor.Execute(&history.CommandRecord{Command: "rm -rf /", Shell: history.Bash}, tmpDir)
+	if err == nil || result != nil {
+		t.Errorf("expected blocked command to fail without a result, got %+v, %v", result, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/ValGrace/command-history-tracker/internal/errors"
 )
@@ -127,6 +128,7 @@ func IsCommandSafe(command string) bool {
 
 // CommandValidator provides command validation functionality
 type CommandValidator struct {
+	mu     sync.RWMutex
 	policy *SecurityPolicy
 }
 
@@ -146,26 +148,36 @@ func NewCommandValidatorWithPolicy(policy *SecurityPolicy) *CommandValidator {
 
 // Validate validates a command against the security policy
 func (v *CommandValidator) Validate(command string, directory string) error {
-	return v.policy.ValidateWithPolicy(command, directory)
+	return v.GetPolicy().ValidateWithPolicy(command, directory)
 }
 
-// SetPolicy updates the security policy
+// SetPolicy updates the security policy. It is safe to call while commands
+// are being validated, e.g. from a configuration reload.
 func (v *CommandValidator) SetPolicy(policy *SecurityPolicy) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	v.policy = policy
 }
 
 // GetPolicy returns the current security policy
 func (v *CommandValidator) GetPolicy() *SecurityPolicy {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
 	return v.policy
 }
 
 // AddBlacklistedCommand adds a command to the blacklist
 func (v *CommandValidator) AddBlacklistedCommand(command string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	v.policy.BlacklistedCommands = append(v.policy.BlacklistedCommands, command)
 }
 
 // RemoveBlacklistedCommand removes a command from the blacklist
 func (v *CommandValidator) RemoveBlacklistedCommand(command string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
 	filtered := make([]string, 0)
 	for _, cmd := range v.policy.BlacklistedCommands {
 		if cmd != command {
@@ -177,6 +189,9 @@ func (v *CommandValidator) RemoveBlacklistedCommand(command string) {
 
 // IsBlacklisted checks if a command is blacklisted
 func (v *CommandValidator) IsBlacklisted(command string) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
 	for _, blacklisted := range v.policy.BlacklistedCommands {
 		if strings.TrimSpace(command) == blacklisted {
 			return true
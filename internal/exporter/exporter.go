@@ -0,0 +1,242 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// Format identifies an export file format
+type Format string
+
+const (
+	FormatJSONL  Format = "jsonl"
+	FormatCSV    Format = "csv"
+	FormatBash   Format = "bash"
+	FormatZsh    Format = "zsh"
+	FormatScript Format = "script"
+)
+
+// Formats lists every supported export format
+var Formats = []Format{FormatJSONL, FormatCSV, FormatBash, FormatZsh, FormatScript}
+
+// csvHeader is the first row of a CSV export
+var csvHeader = []string{"id", "command", "directory", "timestamp", "shell", "exit_code", "duration_ms", "tags", "session_id", "shell_pid"}
+
+// RecordWriter writes command records one at a time in an export format
+type RecordWriter interface {
+	// Write encodes a single record
+	Write(record history.CommandRecord) error
+
+	// Close flushes buffered output; it does not close the underlying writer
+	Close() error
+}
+
+// ParseFormat validates a format name
+func ParseFormat(s string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(s, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported export format %q (expected jsonl, csv, bash, zsh or script)", s)
+}
+
+// NewWriter creates a record writer for the given format
+func NewWriter(format Format, w io.Writer) (RecordWriter, error) {
+	buffered := bufio.NewWriter(w)
+
+	switch format {
+	case FormatJSONL:
+		encoder := json.NewEncoder(buffered)
+		encoder.SetEscapeHTML(false)
+		return &jsonlWriter{out: buffered, encoder: encoder}, nil
+	case FormatCSV:
+		return &csvWriter{out: buffered, csv: csv.NewWriter(buffered)}, nil
+	case FormatBash:
+		return &bashWriter{out: buffered}, nil
+	case FormatZsh:
+		return &zshWriter{out: buffered}, nil
+	case FormatScript:
+		return &scriptWriter{out: buffered}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// jsonlWriter writes one JSON object per line; shells are encoded by name
+// through ShellType.MarshalJSON so the output can be imported again
+type jsonlWriter struct {
+	out     *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (j *jsonlWriter) Write(record history.CommandRecord) error {
+	if record.Tags == nil {
+		record.Tags = []string{}
+	}
+	if err := j.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to encode command %s: %w", record.ID, err)
+	}
+	return nil
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.out.Flush()
+}
+
+// csvWriter writes a header row followed by one row per record
+type csvWriter struct {
+	out         *bufio.Writer
+	csv         *csv.Writer
+	wroteHeader bool
+}
+
+func (c *csvWriter) Write(record history.CommandRecord) error {
+	if !c.wroteHeader {
+		if err := c.csv.Write(csvHeader); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+		c.wroteHeader = true
+	}
+
+	row := []string{
+		record.ID,
+		record.Command,
+		record.Directory,
+		record.Timestamp.Format(time.RFC3339Nano),
+		record.Shell.String(),
+		strconv.Itoa(record.ExitCode),
+		strconv.FormatInt(record.Duration.Milliseconds(), 10),
+		strings.Join(record.Tags, ","),
+		record.SessionID,
+		strconv.Itoa(record.ShellPID),
+	}
+	if err := c.csv.Write(row); err != nil {
+		return fmt.Errorf("failed to write CSV row: %w", err)
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	// An empty export still gets a header
+	if !c.wroteHeader {
+		if err := c.csv.Write(csvHeader); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+	c.csv.Flush()
+	if err := c.csv.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return c.out.Flush()
+}
+
+// bashWriter writes the HISTTIMEFORMAT layout: a "#<unix time>" line before
+// each command, which lets bash keep multi-line commands together
+type bashWriter struct {
+	out *bufio.Writer
+}
+
+func (b *bashWriter) Write(record history.CommandRecord) error {
+	_, err := fmt.Fprintf(b.out, "#%d\n%s\n", record.Timestamp.Unix(), record.Command)
+	return err
+}
+
+func (b *bashWriter) Close() error {
+	return b.out.Flush()
+}
+
+// zshWriter writes zsh's EXTENDED_HISTORY layout, ": <start>:<elapsed>;<command>"
+type zshWriter struct {
+	out *bufio.Writer
+}
+
+func (z *zshWriter) Write(record history.CommandRecord) error {
+	command := strings.ReplaceAll(record.Command, "\n", "\\\n")
+	_, err := fmt.Fprintf(z.out, ": %d:%d;%s\n", record.Timestamp.Unix(), int64(record.Duration/time.Second), metafyZsh(command))
+	return err
+}
+
+func (z *zshWriter) Close() error {
+	return z.out.Flush()
+}
+
+// scriptWriter writes a POSIX shell script that replays the commands in order,
+// changing directory whenever the recorded directory changes and stopping at
+// the first failure
+type scriptWriter struct {
+	out         *bufio.Writer
+	wroteHeader bool
+	dir         string
+}
+
+func (s *scriptWriter) Write(record history.CommandRecord) error {
+	if err := s.writeHeader(); err != nil {
+		return err
+	}
+
+	if record.Directory != "" && record.Directory != s.dir {
+		if _, err := fmt.Fprintf(s.out, "\ncd %s\n", shellQuote(record.Directory)); err != nil {
+			return err
+		}
+		s.dir = record.Directory
+	}
+
+	_, err := fmt.Fprintf(s.out, "%s\n", record.Command)
+	return err
+}
+
+func (s *scriptWriter) Close() error {
+	if err := s.writeHeader(); err != nil {
+		return err
+	}
+	return s.out.Flush()
+}
+
+func (s *scriptWriter) writeHeader() error {
+	if s.wroteHeader {
+		return nil
+	}
+	s.wroteHeader = true
+	_, err := fmt.Fprint(s.out, "#!/bin/sh\nset -e\n")
+	return err
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// metafyZsh encodes bytes zsh treats as special as 0x83 followed by the byte XOR 32
+func metafyZsh(s string) string {
+	const meta, marker = 0x83, 0xa2
+
+	needsMeta := func(c byte) bool { return c == 0 || (c >= meta && c <= marker) }
+
+	var out []byte
+	for i := 0; i < len(s); i++ {
+		if needsMeta(s[i]) {
+			if out == nil {
+				out = append(make([]byte, 0, len(s)+8), s[:i]...)
+			}
+			out = append(out, meta, s[i]^32)
+			continue
+		}
+		if out != nil {
+			out = append(out, s[i])
+		}
+	}
+
+	if out == nil {
+		return s
+	}
+	return string(out)
+}
@@ -0,0 +1,186 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/importer"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+func testRecords() []history.CommandRecord {
+	return []history.CommandRecord{
+		{
+			ID:        "cmd-1",
+			Command:   "git status",
+			Directory: "/home/dev/project",
+			Timestamp: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+			Shell:     history.Zsh,
+			Duration:  3 * time.Second,
+			Tags:      []string{"git"},
+			SessionID: "sess-1",
+			ShellPID:  4242,
+		},
+		{
+			ID:        "cmd-2",
+			Command:   "for f in *; do\n  echo \"$f\"\ndone",
+			Directory: "/tmp",
+			Timestamp: time.Date(2024, 3, 1, 9, 5, 0, 0, time.UTC),
+			Shell:     history.Bash,
+			ExitCode:  1,
+			Duration:  1500 * time.Millisecond,
+			Tags:      []string{},
+		},
+		{
+			ID:        "cmd-3",
+			Command:   "Write-Host \"CAFÉ\"",
+			Directory: "C:/Users/dev",
+			Timestamp: time.Date(2024, 3, 1, 9, 10, 0, 0, time.UTC),
+			Shell:     history.PowerShell,
+			Tags:      []string{"ps", "files"},
+		},
+	}
+}
+
+func export(t *testing.T, format Format, records []history.CommandRecord) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"jsonl", "CSV", "bash", "zsh", "script"} {
+		if _, err := ParseFormat(name); err != nil {
+			t.Errorf("ParseFormat(%q) failed: %v", name, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestJSONLWriter_RoundTrip(t *testing.T) {
+	records := testRecords()
+	output := export(t, FormatJSONL, records)
+
+	if lines := strings.Count(output, "\n"); lines != len(records) {
+		t.Fatalf("Expected %d lines, got %d:\n%s", len(records), lines, output)
+	}
+	if !strings.Contains(output, `"shell":"zsh"`) {
+		t.Errorf("Expected shell to be encoded by name, got:\n%s", output)
+	}
+
+	decoded, err := importer.ParseJSONLines(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("ParseJSONLines failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, records) {
+		t.Errorf("Round trip mismatch:\nexported %+v\ndecoded  %+v", records, decoded)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	output := export(t, FormatCSV, testRecords())
+
+	rows, err := csv.NewReader(strings.NewReader(output)).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("Expected header and 3 rows, got %d", len(rows))
+	}
+	if !reflect.DeepEqual(rows[0], csvHeader) {
+		t.Errorf("Unexpected header %v", rows[0])
+	}
+
+	want := []string{"cmd-2", "for f in *; do\n  echo \"$f\"\ndone", "/tmp", "2024-03-01T09:05:00Z", "bash", "1", "1500", "", "", "0"}
+	if !reflect.DeepEqual(rows[2], want) {
+		t.Errorf("Unexpected row:\n got  %q\n want %q", rows[2], want)
+	}
+
+	if empty := export(t, FormatCSV, nil); strings.TrimSpace(empty) != strings.Join(csvHeader, ",") {
+		t.Errorf("Empty export should only contain the header, got %q", empty)
+	}
+}
+
+func TestShellHistoryWriters_ReadBackByImporter(t *testing.T) {
+	records := testRecords()
+
+	tests := []struct {
+		format Format
+		parse  func(string) ([]importer.Entry, error)
+	}{
+		{FormatBash, func(s string) ([]importer.Entry, error) { return importer.ParseBashHistory(strings.NewReader(s)) }},
+		{FormatZsh, func(s string) ([]importer.Entry, error) { return importer.ParseZshHistory(strings.NewReader(s)) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			entries, err := tt.parse(export(t, tt.format, records))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if len(entries) != len(records) {
+				t.Fatalf("Expected %d entries, got %d", len(records), len(entries))
+			}
+
+			for i, entry := range entries {
+				if entry.Command != records[i].Command {
+					t.Errorf("Entry %d: expected %q, got %q", i, records[i].Command, entry.Command)
+				}
+				if !entry.Timestamp.Equal(records[i].Timestamp) {
+					t.Errorf("Entry %d: expected time %v, got %v", i, records[i].Timestamp, entry.Timestamp)
+				}
+			}
+
+			if tt.format == FormatZsh && entries[0].Duration != 3*time.Second {
+				t.Errorf("Expected zsh duration to survive, got %v", entries[0].Duration)
+			}
+		})
+	}
+}
+
+func TestScriptWriter(t *testing.T) {
+	records := testRecords()
+	records[1].Directory = "/tmp/it's here"
+	records[2].Directory = records[1].Directory
+
+	want := "#!/bin/sh\nset -e\n" +
+		"\ncd '/home/dev/project'\ngit status\n" +
+		"\ncd '/tmp/it'\\''s here'\nfor f in *; do\n  echo \"$f\"\ndone\n" +
+		"Write-Host \"CAFÉ\"\n"
+	if output := export(t, FormatScript, records); output != want {
+		t.Errorf("Unexpected script:\n got  %q\n want %q", output, want)
+	}
+
+	if empty := export(t, FormatScript, nil); empty != "#!/bin/sh\nset -e\n" {
+		t.Errorf("Empty script should only contain the header, got %q", empty)
+	}
+}
+
+func TestMetafyZsh(t *testing.T) {
+	if got := metafyZsh("plain"); got != "plain" {
+		t.Errorf("Expected ASCII to pass through, got %q", got)
+	}
+	// É is 0xC3 0x89; 0x89 lies in zsh's meta range
+	if got := metafyZsh("É"); got != "\xc3\x83\xa9" {
+		t.Errorf("Unexpected metafied bytes %q", got)
+	}
+}
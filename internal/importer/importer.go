@@ -0,0 +1,201 @@
+package importer
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// DefaultBatchSize is the number of records written per BatchSaveCommands call
+const DefaultBatchSize = 500
+
+// ImportedTag marks records that came from a shell history file
+const ImportedTag = "imported"
+
+// Options controls how parsed entries become command records
+type Options struct {
+	// Directory is recorded for every imported command, since history files
+	// do not remember where a command was run
+	Directory string
+
+	// Fallback is the time assigned to the last entry without a timestamp;
+	// earlier untimestamped entries are spaced one second apart before it
+	Fallback time.Time
+
+	// BatchSize overrides DefaultBatchSize
+	BatchSize int
+
+	// Filter prepares each record before it is saved, such as masking
+	// secrets, and returns nil to leave the record out. Records keep the ID
+	// they were built with, so re-imports still find them.
+	Filter func(history.CommandRecord) (*history.CommandRecord, error)
+
+	// Progress is called after every batch
+	Progress func(Progress)
+}
+
+// Progress reports how far an import has got
+type Progress struct {
+	Total      int
+	Processed  int
+	Imported   int
+	Duplicates int
+	Excluded   int
+}
+
+// Importer writes parsed history entries to storage, skipping ones already imported
+type Importer struct {
+	storage storage.ImportStorageEngine
+}
+
+// NewImporter creates a new importer backed by the given storage
+func NewImporter(storage storage.ImportStorageEngine) *Importer {
+	return &Importer{storage: storage}
+}
+
+// Import converts entries to command records and saves those not stored yet
+func (i *Importer) Import(shell history.ShellType, entries []Entry, opts Options) (*Progress, error) {
+	if opts.Directory == "" {
+		return nil, fmt.Errorf("import directory cannot be empty")
+	}
+
+	return i.ImportRecords(BuildRecords(shell, entries, opts), opts)
+}
+
+// ImportRecords saves complete records, such as a JSON Lines export, skipping
+// IDs that are already stored. Options.Directory and Fallback are not used.
+func (i *Importer) ImportRecords(records []history.CommandRecord, opts Options) (*Progress, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	progress := &Progress{Total: len(records)}
+
+	for start := 0; start < len(records); start += batchSize {
+		end := start + batchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		batch, err := filterRecords(records[start:end], opts.Filter)
+		if err != nil {
+			return progress, err
+		}
+
+		ids := make([]string, len(batch))
+		for j, record := range batch {
+			ids[j] = record.ID
+		}
+
+		existing, err := i.storage.ExistingCommandIDs(ids)
+		if err != nil {
+			return progress, fmt.Errorf("failed to check existing commands: %w", err)
+		}
+
+		fresh := make([]history.CommandRecord, 0, len(batch))
+		for _, record := range batch {
+			if !existing[record.ID] {
+				fresh = append(fresh, record)
+				// Guard against the same ID twice in one input
+				existing[record.ID] = true
+			}
+		}
+
+		if err := i.storage.BatchSaveCommands(fresh); err != nil {
+			return progress, fmt.Errorf("failed to save imported commands: %w", err)
+		}
+
+		progress.Processed += end - start
+		progress.Imported += len(fresh)
+		progress.Duplicates += len(batch) - len(fresh)
+		progress.Excluded += end - start - len(batch)
+
+		if opts.Progress != nil {
+			opts.Progress(*progress)
+		}
+	}
+
+	return progress, nil
+}
+
+// filterRecords applies filter to every record, dropping the ones it leaves out
+func filterRecords(records []history.CommandRecord, filter func(history.CommandRecord) (*history.CommandRecord, error)) ([]history.CommandRecord, error) {
+	if filter == nil {
+		return records, nil
+	}
+
+	kept := make([]history.CommandRecord, 0, len(records))
+	for _, record := range records {
+		filtered, err := filter(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter imported command %s: %w", record.ID, err)
+		}
+		if filtered != nil {
+			kept = append(kept, *filtered)
+		}
+	}
+	return kept, nil
+}
+
+// BuildRecords converts parsed entries into command records with stable IDs,
+// so importing the same file twice yields the same IDs
+func BuildRecords(shell history.ShellType, entries []Entry, opts Options) []history.CommandRecord {
+	fallback := opts.Fallback
+	if fallback.IsZero() {
+		fallback = time.Now()
+	}
+
+	untimed := 0
+	for _, entry := range entries {
+		if entry.Timestamp.IsZero() {
+			untimed++
+		}
+	}
+
+	records := make([]history.CommandRecord, 0, len(entries))
+	seen := make(map[string]int)
+	untimedIndex := 0
+
+	for _, entry := range entries {
+		timestamp := entry.Timestamp
+		// Timestamped entries are keyed by time and command; the rest by their
+		// position in the file, which is stable while the file only grows
+		key := shell.String() + "\x00" + entry.Command + "\x00"
+		if timestamp.IsZero() {
+			untimedIndex++
+			timestamp = fallback.Add(-time.Duration(untimed-untimedIndex) * time.Second)
+			key += "line:" + strconv.Itoa(entry.Line)
+		} else {
+			key += "time:" + strconv.FormatInt(timestamp.Unix(), 10)
+		}
+
+		// The same command can be recorded twice within one second
+		seen[key]++
+		if n := seen[key]; n > 1 {
+			key += "\x00" + strconv.Itoa(n)
+		}
+
+		records = append(records, history.CommandRecord{
+			ID:        generateImportID(key),
+			Command:   entry.Command,
+			Directory: opts.Directory,
+			Timestamp: timestamp,
+			Shell:     shell,
+			Duration:  entry.Duration,
+			Tags:      []string{ImportedTag},
+		})
+	}
+
+	return records
+}
+
+// generateImportID derives a deterministic record ID from an entry key
+func generateImportID(key string) string {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return fmt.Sprintf("imp_%016x", h.Sum64())
+}
@@ -0,0 +1,256 @@
+package importer
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+func TestParseBashHistory(t *testing.T) {
+	t.Run("Plain", func(t *testing.T) {
+		entries, err := ParseBashHistory(strings.NewReader("ls -la\n\ngit status\r\ncd /tmp\n"))
+		if err != nil {
+			t.Fatalf("ParseBashHistory failed: %v", err)
+		}
+
+		assertCommands(t, entries, "ls -la", "git status", "cd /tmp")
+		if entries[1].Line != 3 {
+			t.Errorf("Expected git status on line 3, got %d", entries[1].Line)
+		}
+		if !entries[0].Timestamp.IsZero() {
+			t.Error("Plain bash history should not have timestamps")
+		}
+	})
+
+	t.Run("Timestamped", func(t *testing.T) {
+		input := "#1700000000\nmake build\n#1700000060\nfor f in *; do\n  echo $f\ndone\n#not-a-time\n"
+		entries, err := ParseBashHistory(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("ParseBashHistory failed: %v", err)
+		}
+
+		assertCommands(t, entries, "make build", "for f in *; do\n  echo $f\ndone\n#not-a-time")
+		if !entries[1].Timestamp.Equal(time.Unix(1700000060, 0)) {
+			t.Errorf("Unexpected timestamp %v", entries[1].Timestamp)
+		}
+	})
+}
+
+func TestParseZshHistory(t *testing.T) {
+	input := ": 1700000000:3;npm install\n" +
+		": 1700000010:0;echo one\\\ntwo\n" +
+		"plain command\n" +
+		": 1700000020:0;echo CAF\xc3\x83\xa9\n"
+
+	entries, err := ParseZshHistory(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseZshHistory failed: %v", err)
+	}
+
+	assertCommands(t, entries, "npm install", "echo one\ntwo", "plain command", "echo CAFÉ")
+	if entries[0].Duration != 3*time.Second {
+		t.Errorf("Expected 3s duration, got %v", entries[0].Duration)
+	}
+	if !entries[0].Timestamp.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected timestamp %v", entries[0].Timestamp)
+	}
+	if !entries[2].Timestamp.IsZero() {
+		t.Error("Plain zsh line should not have a timestamp")
+	}
+}
+
+func TestParsePowerShellHistory(t *testing.T) {
+	input := "Get-ChildItem\r\nforeach ($i in 1..3) {`\r\n  Write-Host $i`\r\n}\r\n"
+
+	entries, err := ParsePowerShellHistory(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParsePowerShellHistory failed: %v", err)
+	}
+
+	assertCommands(t, entries, "Get-ChildItem", "foreach ($i in 1..3) {\n  Write-Host $i\n}")
+}
+
+func TestParseFishHistory(t *testing.T) {
+	input := "- cmd: git status\n  when: 1700000000\n- cmd: echo a\\nb \\\\ c\n  when: 1700000005\n  paths:\n    - src\n"
+
+	entries, err := ParseFishHistory(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseFishHistory failed: %v", err)
+	}
+
+	assertCommands(t, entries, "git status", "echo a\nb \\ c")
+	if !entries[1].Timestamp.Equal(time.Unix(1700000005, 0)) {
+		t.Errorf("Unexpected timestamp %v", entries[1].Timestamp)
+	}
+}
+
+func TestParse_UnsupportedShell(t *testing.T) {
+	if _, err := Parse(history.Cmd, strings.NewReader("dir")); err == nil {
+		t.Error("Expected error for cmd history")
+	}
+}
+
+func TestBuildRecords(t *testing.T) {
+	fallback := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Command: "ls", Line: 1},
+		{Command: "ls", Line: 2},
+		{Command: "make", Timestamp: time.Unix(1700000000, 0), Line: 3},
+		{Command: "make", Timestamp: time.Unix(1700000000, 0), Line: 4},
+	}
+
+	records := BuildRecords(history.Bash, entries, Options{Directory: "/home/dev", Fallback: fallback})
+	if len(records) != len(entries) {
+		t.Fatalf("Expected %d records, got %d", len(entries), len(records))
+	}
+
+	ids := make(map[string]bool)
+	for _, record := range records {
+		if err := record.Validate(); err != nil {
+			t.Errorf("Record %q invalid: %v", record.Command, err)
+		}
+		if ids[record.ID] {
+			t.Errorf("Duplicate ID %s", record.ID)
+		}
+		ids[record.ID] = true
+		if !record.HasTag(ImportedTag) {
+			t.Errorf("Record %q missing imported tag", record.Command)
+		}
+	}
+
+	// Untimestamped entries are spaced before the fallback, keeping file order
+	if !records[1].Timestamp.Equal(fallback) || !records[0].Timestamp.Equal(fallback.Add(-time.Second)) {
+		t.Errorf("Unexpected fallback timestamps %v, %v", records[0].Timestamp, records[1].Timestamp)
+	}
+
+	again := BuildRecords(history.Bash, entries, Options{Directory: "/home/dev", Fallback: fallback.Add(time.Hour)})
+	for i := range records {
+		if records[i].ID != again[i].ID {
+			t.Errorf("ID for entry %d changed between imports", i)
+		}
+	}
+}
+
+func TestImporter_Import(t *testing.T) {
+	store := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+
+	input := ": 1700000000:1;git pull\n: 1700000005:0;go test ./...\n: 1700000009:2;git push\n"
+	entries, err := ParseZshHistory(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseZshHistory failed: %v", err)
+	}
+
+	var reports []Progress
+	opts := Options{
+		Directory: "/home/dev",
+		BatchSize: 2,
+		Progress:  func(p Progress) { reports = append(reports, p) },
+	}
+
+	imp := NewImporter(store)
+	result, err := imp.Import(history.Zsh, entries, opts)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported != 3 || result.Duplicates != 0 {
+		t.Errorf("Expected 3 imported, got %+v", result)
+	}
+	if len(reports) != 2 || reports[1].Processed != 3 {
+		t.Errorf("Expected a progress report per batch, got %+v", reports)
+	}
+
+	commands, err := store.GetCommandsByDirectory("/home/dev")
+	if err != nil {
+		t.Fatalf("GetCommandsByDirectory failed: %v", err)
+	}
+	if len(commands) != 3 || commands[0].Command != "git push" || commands[0].Duration != 2*time.Second {
+		t.Errorf("Unexpected stored commands: %+v", commands)
+	}
+
+	// Re-importing a grown file only adds the new entry
+	input += ": 1700000020:0;git log\n"
+	entries, err = ParseZshHistory(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseZshHistory failed: %v", err)
+	}
+
+	result, err = imp.Import(history.Zsh, entries, Options{Directory: "/home/dev"})
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if result.Imported != 1 || result.Duplicates != 3 {
+		t.Errorf("Expected 1 imported and 3 duplicates, got %+v", result)
+	}
+
+	if _, err := imp.Import(history.Zsh, entries, Options{}); err == nil {
+		t.Error("Expected error without an import directory")
+	}
+}
+
+func TestImporter_Filter(t *testing.T) {
+	store := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+
+	entries := []Entry{{Command: "git push", Line: 1}, {Command: "export TOKEN=abc", Line: 2}, {Command: "ls", Line: 3}}
+	opts := Options{
+		Directory: "/home/dev",
+		Filter: func(record history.CommandRecord) (*history.CommandRecord, error) {
+			if record.Command == "ls" {
+				return nil, nil
+			}
+			record.Command = strings.Replace(record.Command, "abc", "****", 1)
+			return &record, nil
+		},
+	}
+
+	result, err := NewImporter(store).Import(history.Bash, entries, opts)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Processed != 3 || result.Imported != 2 || result.Excluded != 1 {
+		t.Errorf("Expected 2 imported and 1 excluded, got %+v", result)
+	}
+
+	// The filtered record keeps the ID of the original entry
+	result, err = NewImporter(store).Import(history.Bash, entries, opts)
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if result.Imported != 0 || result.Duplicates != 2 {
+		t.Errorf("Expected filtered records to be found again, got %+v", result)
+	}
+
+	commands, err := store.GetCommandsByDirectory("/home/dev")
+	if err != nil {
+		t.Fatalf("GetCommandsByDirectory failed: %v", err)
+	}
+	for _, record := range commands {
+		if strings.Contains(record.Command, "abc") {
+			t.Errorf("Filter was not applied before saving: %+v", record)
+		}
+	}
+}
+
+func assertCommands(t *testing.T, entries []Entry, expected ...string) {
+	t.Helper()
+
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d: %+v", len(expected), len(entries), entries)
+	}
+	for i, want := range expected {
+		if entries[i].Command != want {
+			t.Errorf("Entry %d: expected %q, got %q", i, want, entries[i].Command)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// maxLineSize bounds a single history line; long pasted commands exceed bufio's default
+const maxLineSize = 1024 * 1024
+
+// Entry is a single command parsed from a shell history file
+type Entry struct {
+	Command   string
+	Timestamp time.Time     // zero when the file does not record timestamps
+	Duration  time.Duration // only recorded by zsh extended history
+	Line      int           // 1-based line the entry starts on
+}
+
+// Parse reads every command from a history file in the given shell's format
+func Parse(shell history.ShellType, r io.Reader) ([]Entry, error) {
+	switch shell {
+	case history.Bash:
+		return ParseBashHistory(r)
+	case history.Zsh:
+		return ParseZshHistory(r)
+	case history.PowerShell:
+		return ParsePowerShellHistory(r)
+	case history.Fish:
+		return ParseFishHistory(r)
+	default:
+		return nil, fmt.Errorf("importing %s history is not supported", shell)
+	}
+}
+
+// ParseBashHistory parses ~/.bash_history. When HISTTIMEFORMAT was set, each
+// entry is preceded by a "#<unix time>" line and may span several lines.
+func ParseBashHistory(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var current *Entry
+
+	flush := func() {
+		if current != nil && strings.TrimSpace(current.Command) != "" {
+			entries = append(entries, *current)
+		}
+		current = nil
+	}
+
+	err := scanLines(r, func(lineNo int, line string) {
+		if ts, ok := parseBashTimestamp(line); ok {
+			flush()
+			current = &Entry{Timestamp: ts, Line: lineNo + 1}
+			return
+		}
+
+		// Lines following a timestamp belong to the same (possibly multi-line) entry
+		if current != nil && !current.Timestamp.IsZero() {
+			if current.Command == "" {
+				current.Command = line
+			} else {
+				current.Command += "\n" + line
+			}
+			return
+		}
+
+		flush()
+		current = &Entry{Command: line, Line: lineNo}
+	})
+	if err != nil {
+		return nil, err
+	}
+	flush()
+
+	return entries, nil
+}
+
+// parseBashTimestamp recognises the "#1700000000" lines bash writes with HISTTIMEFORMAT
+func parseBashTimestamp(line string) (time.Time, bool) {
+	if len(line) < 2 || line[0] != '#' {
+		return time.Time{}, false
+	}
+	secs, err := strconv.ParseInt(line[1:], 10, 64)
+	if err != nil || secs <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0), true
+}
+
+// ParseZshHistory parses ~/.zsh_history in both the plain and the extended
+// ": <start>:<elapsed>;<command>" format. Multi-line commands are stored with
+// a trailing backslash on every line but the last.
+func ParseZshHistory(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var current *Entry
+
+	err := scanLines(r, func(lineNo int, line string) {
+		line = unmetafyZsh(line)
+
+		if current == nil {
+			current = &Entry{Line: lineNo}
+			if start, elapsed, command, ok := parseZshExtended(line); ok {
+				current.Timestamp = start
+				current.Duration = elapsed
+				line = command
+			}
+		} else {
+			current.Command += "\n"
+		}
+
+		if strings.HasSuffix(line, "\\") {
+			current.Command += strings.TrimSuffix(line, "\\")
+			return
+		}
+
+		current.Command += line
+		if strings.TrimSpace(current.Command) != "" {
+			entries = append(entries, *current)
+		}
+		current = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && strings.TrimSpace(current.Command) != "" {
+		entries = append(entries, *current)
+	}
+
+	return entries, nil
+}
+
+// parseZshExtended splits an EXTENDED_HISTORY line into its fields
+func parseZshExtended(line string) (time.Time, time.Duration, string, bool) {
+	if !strings.HasPrefix(line, ": ") {
+		return time.Time{}, 0, "", false
+	}
+
+	header, command, found := strings.Cut(line[2:], ";")
+	if !found {
+		return time.Time{}, 0, "", false
+	}
+
+	startStr, elapsedStr, found := strings.Cut(header, ":")
+	if !found {
+		return time.Time{}, 0, "", false
+	}
+
+	start, err := strconv.ParseInt(strings.TrimSpace(startStr), 10, 64)
+	if err != nil {
+		return time.Time{}, 0, "", false
+	}
+	elapsed, err := strconv.ParseInt(strings.TrimSpace(elapsedStr), 10, 64)
+	if err != nil || elapsed < 0 {
+		elapsed = 0
+	}
+
+	return time.Unix(start, 0), time.Duration(elapsed) * time.Second, command, true
+}
+
+// unmetafyZsh decodes zsh's metafied bytes: 0x83 followed by the original byte XOR 32
+func unmetafyZsh(line string) string {
+	const meta = 0x83
+	if strings.IndexByte(line, meta) < 0 {
+		return line
+	}
+
+	out := make([]byte, 0, len(line))
+	for i := 0; i < len(line); i++ {
+		if line[i] == meta && i+1 < len(line) {
+			i++
+			out = append(out, line[i]^32)
+			continue
+		}
+		out = append(out, line[i])
+	}
+	return string(out)
+}
+
+// ParsePowerShellHistory parses PSReadLine's ConsoleHost_history.txt, where
+// multi-line commands end every line but the last with a backtick
+func ParsePowerShellHistory(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var current *Entry
+
+	err := scanLines(r, func(lineNo int, line string) {
+		if current == nil {
+			current = &Entry{Line: lineNo}
+		} else {
+			current.Command += "\n"
+		}
+
+		if strings.HasSuffix(line, "`") {
+			current.Command += strings.TrimSuffix(line, "`")
+			return
+		}
+
+		current.Command += line
+		if strings.TrimSpace(current.Command) != "" {
+			entries = append(entries, *current)
+		}
+		current = nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && strings.TrimSpace(current.Command) != "" {
+		entries = append(entries, *current)
+	}
+
+	return entries, nil
+}
+
+// ParseFishHistory parses fish's YAML-like fish_history file, where each entry
+// starts with a "- cmd: <command>" line followed by indented "when:" and "paths:" fields
+func ParseFishHistory(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var current *Entry
+
+	flush := func() {
+		if current != nil && strings.TrimSpace(current.Command) != "" {
+			entries = append(entries, *current)
+		}
+		current = nil
+	}
+
+	err := scanLines(r, func(lineNo int, line string) {
+		if cmd, ok := strings.CutPrefix(line, "- cmd: "); ok {
+			flush()
+			current = &Entry{Command: unescapeFish(cmd), Line: lineNo}
+			return
+		}
+
+		if current == nil {
+			return
+		}
+
+		if when, ok := strings.CutPrefix(strings.TrimSpace(line), "when: "); ok {
+			if secs, err := strconv.ParseInt(when, 10, 64); err == nil && secs > 0 {
+				current.Timestamp = time.Unix(secs, 0)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	flush()
+
+	return entries, nil
+}
+
+// unescapeFish reverses the escaping fish applies to the cmd field
+func unescapeFish(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// ParseJSONLines reads records written by "tracker export --format jsonl".
+// Shell names are decoded by ShellType.UnmarshalJSON, so records keep their
+// IDs and every field survives the round trip.
+func ParseJSONLines(r io.Reader) ([]history.CommandRecord, error) {
+	var records []history.CommandRecord
+	var parseErr error
+
+	err := scanLines(r, func(lineNo int, line string) {
+		if parseErr != nil || strings.TrimSpace(line) == "" {
+			return
+		}
+
+		var record history.CommandRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			parseErr = fmt.Errorf("invalid JSON on line %d: %w", lineNo, err)
+			return
+		}
+		if err := record.Validate(); err != nil {
+			parseErr = fmt.Errorf("invalid record on line %d: %w", lineNo, err)
+			return
+		}
+		records = append(records, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return records, nil
+}
+
+// scanLines calls fn for every line of r with its 1-based line number
+func scanLines(r io.Reader, fn func(lineNo int, line string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fn(lineNo, strings.TrimRight(scanner.Text(), "\r"))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read history file: %w", err)
+	}
+	return nil
+}
+
+// DefaultHistoryFile returns where the given shell keeps its history by default
+func DefaultHistoryFile(shell history.ShellType) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+
+	switch shell {
+	case history.Bash:
+		return filepath.Join(homeDir, ".bash_history"), nil
+	case history.Zsh:
+		if histFile := os.Getenv("HISTFILE"); histFile != "" {
+			return histFile, nil
+		}
+		if zdotdir := os.Getenv("ZDOTDIR"); zdotdir != "" {
+			return filepath.Join(zdotdir, ".zsh_history"), nil
+		}
+		return filepath.Join(homeDir, ".zsh_history"), nil
+	case history.PowerShell:
+		if runtime.GOOS == "windows" {
+			appData := os.Getenv("APPDATA")
+			if appData == "" {
+				appData = filepath.Join(homeDir, "AppData", "Roaming")
+			}
+			return filepath.Join(appData, "Microsoft", "Windows", "PowerShell", "PSReadLine", "ConsoleHost_history.txt"), nil
+		}
+		return filepath.Join(dataHome, "powershell", "PSReadLine", "ConsoleHost_history.txt"), nil
+	case history.Fish:
+		return filepath.Join(dataHome, "fish", "fish_history"), nil
+	default:
+		return "", fmt.Errorf("importing %s history is not supported", shell)
+	}
+}
@@ -20,6 +20,7 @@ type CommandCapture struct {
 	envManager *shell.EnvironmentManager
 	config     *config.Config
 	detector   shell.ShellDetector
+	redactor   *Redactor
 }
 
 // NewCommandCapture creates a new command capture instance
@@ -29,48 +30,117 @@ func NewCommandCapture(storage history.StorageEngine, cfg *config.Config) *Comma
 		envManager: shell.NewEnvironmentManager(),
 		config:     cfg,
 		detector:   shell.NewDetector(),
+		redactor:   newConfiguredRedactor(cfg),
 	}
 }
 
+// newConfiguredRedactor builds the redactor for cfg. Config.Validate rejects
+// bad patterns, so a failure here only drops the user patterns.
+func newConfiguredRedactor(cfg *config.Config) *Redactor {
+	if cfg != nil {
+		if redactor, err := NewRedactor(cfg.RedactPatterns); err == nil {
+			return redactor
+		}
+	}
+	redactor, _ := NewRedactor(nil)
+	return redactor
+}
+
 // CaptureCommand captures a command from the current environment
 func (c *CommandCapture) CaptureCommand() error {
+	cmdRecord, err := c.PrepareCommand()
+	if err != nil || cmdRecord == nil {
+		return err
+	}
+
+	// Store the command
+	if err := c.storage.SaveCommand(*cmdRecord); err != nil {
+		return fmt.Errorf("failed to save command: %w", err)
+	}
+
+	return nil
+}
+
+// PrepareCommand builds the command record described by the current environment
+// without storing it. It returns nil when tracking is disabled or the command
+// is filtered out.
+func (c *CommandCapture) PrepareCommand() (*history.CommandRecord, error) {
 	// Check if tracking is enabled
 	if !c.envManager.IsTrackerEnabled() {
-		return nil // Silently skip if tracking is disabled
+		return nil, nil // Silently skip if tracking is disabled
 	}
 
 	// Validate environment has required variables
 	if err := c.envManager.ValidateEnvironment(); err != nil {
-		return fmt.Errorf("invalid capture environment: %w", err)
+		return nil, fmt.Errorf("invalid capture environment: %w", err)
 	}
 
 	// Extract command record from environment
 	cmdRecord, err := c.envManager.GetCommandFromEnvironment()
 	if err != nil {
-		return fmt.Errorf("failed to extract command from environment: %w", err)
+		return nil, fmt.Errorf("failed to extract command from environment: %w", err)
+	}
+
+	// The hook runs in the shell's session, so its context is the command's
+	collectSessionContext(cmdRecord, systemSessionEnv)
+
+	return c.PrepareRecord(*cmdRecord)
+}
+
+// PrepareRecord applies the project configuration, redaction, metadata and
+// exclude rules to a record built elsewhere, such as one posted to the API
+// server. It returns nil when the command is filtered out.
+func (c *CommandCapture) PrepareRecord(record history.CommandRecord) (*history.CommandRecord, error) {
+	cmdRecord := &record
+	if cmdRecord.Tags == nil {
+		cmdRecord.Tags = []string{}
+	}
+
+	// Apply the project configuration for the command's directory
+	capture, err := c.forDirectory(cmdRecord.Directory)
+	if err != nil {
+		return nil, err
+	}
+	if capture.config != nil && capture.config.DoNotRecord {
+		return nil, nil
 	}
 
 	// Enhance command record with additional metadata and directory context
-	if err := c.enhanceCommandRecord(cmdRecord); err != nil {
-		return fmt.Errorf("failed to enhance command record: %w", err)
+	if err := capture.enhanceCommandRecord(cmdRecord); err != nil {
+		return nil, fmt.Errorf("failed to enhance command record: %w", err)
 	}
 
 	// Collect additional metadata
-	if err := c.collectMetadata(cmdRecord); err != nil {
-		return fmt.Errorf("failed to collect metadata: %w", err)
+	if err := capture.collectMetadata(cmdRecord); err != nil {
+		return nil, fmt.Errorf("failed to collect metadata: %w", err)
 	}
 
 	// Validate the command record
 	if err := cmdRecord.Validate(); err != nil {
-		return fmt.Errorf("invalid command record: %w", err)
+		return nil, fmt.Errorf("invalid command record: %w", err)
 	}
 
 	// Apply filters to determine if command should be recorded
-	if c.shouldSkipCommand(cmdRecord) {
-		return nil // Skip recording this command
+	if capture.shouldSkipCommand(cmdRecord) {
+		return nil, nil // Skip recording this command
+	}
+
+	return cmdRecord, nil
+}
+
+// SaveCommand records a command run by this process, such as one re-run from
+// the browser, through the same project configuration, redaction and exclude
+// rules as commands captured by the shell hooks. It implements
+// executor.ExecutionLogger.
+func (c *CommandCapture) SaveCommand(record history.CommandRecord) error {
+	// The command ran in this process's session
+	collectSessionContext(&record, systemSessionEnv)
+
+	cmdRecord, err := c.PrepareRecord(record)
+	if err != nil || cmdRecord == nil {
+		return err
 	}
 
-	// Store the command
 	if err := c.storage.SaveCommand(*cmdRecord); err != nil {
 		return fmt.Errorf("failed to save command: %w", err)
 	}
@@ -78,6 +148,48 @@ func (c *CommandCapture) CaptureCommand() error {
 	return nil
 }
 
+// FilterRecord applies the project configuration, redaction and exclude
+// rules to a record without collecting metadata, for history imported from
+// files or exports. It returns nil when the command is filtered out.
+func (c *CommandCapture) FilterRecord(record history.CommandRecord) (*history.CommandRecord, error) {
+	capture, err := c.forDirectory(record.Directory)
+	if err != nil {
+		return nil, err
+	}
+	if capture.config != nil && capture.config.DoNotRecord {
+		return nil, nil
+	}
+
+	capture.redactor.RedactRecord(&record)
+
+	if capture.shouldSkipCommand(&record) {
+		return nil, nil
+	}
+
+	return &record, nil
+}
+
+// forDirectory returns a capture that uses the global configuration merged
+// with the project configuration found from dir
+func (c *CommandCapture) forDirectory(dir string) (*CommandCapture, error) {
+	if c.config == nil {
+		return c, nil
+	}
+
+	cfg, project, err := c.config.ForDirectory(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project config: %w", err)
+	}
+	if project == nil {
+		return c, nil
+	}
+
+	capture := *c
+	capture.config = cfg
+	capture.redactor = newConfiguredRedactor(cfg)
+	return &capture, nil
+}
+
 // CaptureCommandDirect captures a command directly with provided parameters
 func (c *CommandCapture) CaptureCommandDirect(command, directory string, shell history.ShellType, exitCode int, duration time.Duration) error {
 	// Create command record
@@ -94,13 +206,24 @@ func (c *CommandCapture) CaptureCommandDirect(command, directory string, shell h
 	// Generate ID
 	cmdRecord.ID = c.generateCommandID(cmdRecord)
 
+	collectSessionContext(cmdRecord, systemSessionEnv)
+
+	// Apply the project configuration for the command's directory
+	capture, err := c.forDirectory(directory)
+	if err != nil {
+		return err
+	}
+	if capture.config != nil && capture.config.DoNotRecord {
+		return nil
+	}
+
 	// Enhance with additional metadata
-	if err := c.enhanceCommandRecord(cmdRecord); err != nil {
+	if err := capture.enhanceCommandRecord(cmdRecord); err != nil {
 		return fmt.Errorf("failed to enhance command record: %w", err)
 	}
 
 	// Collect additional metadata
-	if err := c.collectMetadata(cmdRecord); err != nil {
+	if err := capture.collectMetadata(cmdRecord); err != nil {
 		return fmt.Errorf("failed to collect metadata: %w", err)
 	}
 
@@ -110,7 +233,7 @@ func (c *CommandCapture) CaptureCommandDirect(command, directory string, shell h
 	}
 
 	// Apply filters
-	if c.shouldSkipCommand(cmdRecord) {
+	if capture.shouldSkipCommand(cmdRecord) {
 		return nil
 	}
 
@@ -124,6 +247,9 @@ func (c *CommandCapture) CaptureCommandDirect(command, directory string, shell h
 
 // enhanceCommandRecord adds additional metadata to the command record
 func (c *CommandCapture) enhanceCommandRecord(cmdRecord *history.CommandRecord) error {
+	// Mask secrets before anything else looks at the command
+	c.redactor.RedactRecord(cmdRecord)
+
 	// Generate ID if not set
 	if cmdRecord.ID == "" {
 		cmdRecord.ID = c.generateCommandID(cmdRecord)
@@ -142,6 +268,15 @@ func (c *CommandCapture) enhanceCommandRecord(cmdRecord *history.CommandRecord)
 		cmdRecord.Timestamp = time.Now()
 	}
 
+	// Record the machine and user, so synced or shared history shows where
+	// a command ran
+	if cmdRecord.Hostname == "" {
+		cmdRecord.Hostname, _ = os.Hostname()
+	}
+	if cmdRecord.Username == "" {
+		cmdRecord.Username = currentUsername()
+	}
+
 	return nil
 }
 
@@ -300,6 +435,13 @@ func (c *CommandCapture) normalizeDirectory(dir string) (string, error) {
 func (c *CommandCapture) addAutomaticTags(cmdRecord *history.CommandRecord) {
 	command := cmdRecord.Command
 
+	// Add tags configured globally or by the project
+	if c.config != nil {
+		for _, tag := range c.config.AutoTags {
+			cmdRecord.AddTag(tag)
+		}
+	}
+
 	// Add tags based on command patterns
 	if c.isGitCommand(command) {
 		cmdRecord.AddTag("git")
@@ -341,38 +483,37 @@ func (c *CommandCapture) addAutomaticTags(cmdRecord *history.CommandRecord) {
 
 // shouldSkipCommand determines if a command should be skipped from recording
 func (c *CommandCapture) shouldSkipCommand(cmdRecord *history.CommandRecord) bool {
+	return c.SkipReason(cmdRecord) != ""
+}
+
+// SkipReason explains why a command would not be recorded, or returns an
+// empty string if it would be
+func (c *CommandCapture) SkipReason(cmdRecord *history.CommandRecord) string {
 	command := cmdRecord.Command
 
 	// Skip empty commands
-	if command == "" {
-		return true
+	if strings.TrimSpace(command) == "" {
+		return "empty command"
 	}
 
-	// Skip commands that match exclude patterns from config
+	// Skip commands that match exclude rules from config
 	if c.config != nil {
-		for _, pattern := range c.config.ExcludePatterns {
-			// Try exact match first
-			if matched, _ := filepath.Match(pattern, command); matched {
-				return true
-			}
-			// Try prefix match for commands with arguments
-			if strings.HasPrefix(command, pattern+" ") || command == pattern {
-				return true
-			}
+		if match := c.config.MatchExclude(command, cmdRecord.Directory, cmdRecord.Shell); match != nil {
+			return match.String()
 		}
 	}
 
 	// Skip internal tracker commands to avoid recursion
 	if c.isTrackerCommand(command) {
-		return true
+		return "tracker's own commands are never recorded"
 	}
 
 	// Skip common shell built-ins that don't provide value
 	if c.isSkippableBuiltin(command) {
-		return true
+		return "built-in command without arguments"
 	}
 
-	return false
+	return ""
 }
 
 // generateCommandID creates a unique identifier for the command
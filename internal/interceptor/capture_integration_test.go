@@ -328,3 +328,71 @@ func TestCommandCaptureStats(t *testing.T) {
 		t.Errorf("Expected at least 3 commands, got %d", stats.TotalCommands)
 	}
 }
+
+// TestCommandCaptureProjectConfig tests that .tracker.json settings apply to
+// commands run below the project directory
+func TestCommandCaptureProjectConfig(t *testing.T) {
+	storage, cfg := createTestStorage(t)
+	defer storage.Close()
+
+	capture := NewCommandCapture(storage, cfg)
+
+	projectDir := t.TempDir()
+	serviceDir := filepath.Join(projectDir, "services", "api")
+	privateDir := filepath.Join(projectDir, "private")
+	for _, dir := range []string{serviceDir, privateDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	projectConfig := `{"exclude_patterns": ["make*"], "auto_tags": ["monorepo"], "redact_patterns": ["vault-[0-9]+"]}`
+	if err := os.WriteFile(filepath.Join(projectDir, ".tracker.json"), []byte(projectConfig), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(privateDir, ".tracker.json"), []byte(`{"do_not_record": true}`), 0644); err != nil {
+		t.Fatalf("Failed to write project config: %v", err)
+	}
+
+	commands := []struct {
+		command   string
+		directory string
+	}{
+		{"make build", serviceDir},
+		{"unlock vault-1234", serviceDir},
+		{"git status", privateDir},
+	}
+	for _, c := range commands {
+		if err := capture.CaptureCommandDirect(c.command, c.directory, history.Bash, 0, 0); err != nil {
+			t.Fatalf("CaptureCommandDirect(%q) failed: %v", c.command, err)
+		}
+	}
+
+	recorded, err := storage.GetCommandsByDirectory(filepath.ToSlash(serviceDir))
+	if err != nil {
+		t.Fatalf("Failed to retrieve commands: %v", err)
+	}
+	if len(recorded) != 1 {
+		t.Fatalf("Expected only the unexcluded command, got %d", len(recorded))
+	}
+	if recorded[0].Command != "unlock ****" || !recorded[0].HasTag("monorepo") {
+		t.Errorf("Project redaction and tags not applied: %+v", recorded[0])
+	}
+
+	private, err := storage.GetCommandsByDirectory(filepath.ToSlash(privateDir))
+	if err != nil {
+		t.Fatalf("Failed to retrieve commands: %v", err)
+	}
+	if len(private) != 0 {
+		t.Errorf("Expected do_not_record to skip commands, got %d", len(private))
+	}
+
+	// Commands outside the project use the global configuration
+	outside := t.TempDir()
+	if err := capture.CaptureCommandDirect("make build", outside, history.Bash, 0, 0); err != nil {
+		t.Fatalf("CaptureCommandDirect failed: %v", err)
+	}
+	if recorded, _ := storage.GetCommandsByDirectory(filepath.ToSlash(outside)); len(recorded) != 1 {
+		t.Errorf("Expected command outside the project to be recorded, got %d", len(recorded))
+	}
+}
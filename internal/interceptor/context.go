@@ -0,0 +1,149 @@
+package interceptor
+
+import (
+	"bufio"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// containerIDPattern matches the 64 hex digit IDs Docker, containerd and
+// Podman give containers
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// sessionEnv describes where the context of a session is read from, so tests
+// can substitute the environment and the files
+type sessionEnv struct {
+	getenv    func(string) string
+	readFile  func(string) ([]byte, error)
+	stdinLink string // symlink naming the terminal on standard input
+}
+
+// systemSessionEnv reads the context of the calling process
+var systemSessionEnv = sessionEnv{
+	getenv:    os.Getenv,
+	readFile:  os.ReadFile,
+	stdinLink: "/proc/self/fd/0",
+}
+
+// collectSessionContext fills the session fields of a record that the
+// caller left empty: the terminal, SSH, the container, the Python
+// environment and the Kubernetes context. It describes the process that
+// captures the command, so it is only used when that is the shell's own
+// hook, not for records posted to the API server.
+func collectSessionContext(cmdRecord *history.CommandRecord, env sessionEnv) {
+	if cmdRecord.TTY == "" {
+		if tty, err := os.Readlink(env.stdinLink); err == nil && strings.HasPrefix(tty, "/dev/") {
+			cmdRecord.TTY = tty
+		}
+	}
+
+	if !cmdRecord.SSH {
+		cmdRecord.SSH = env.getenv("SSH_CONNECTION") != "" || env.getenv("SSH_CLIENT") != "" || env.getenv("SSH_TTY") != ""
+	}
+
+	if cmdRecord.ContainerID == "" {
+		cgroup, _ := env.readFile("/proc/self/cgroup")
+		mountinfo, _ := env.readFile("/proc/self/mountinfo")
+		cmdRecord.ContainerID = containerID(string(cgroup), string(mountinfo))
+	}
+
+	if cmdRecord.PythonEnv == "" {
+		cmdRecord.PythonEnv = pythonEnv(env.getenv)
+	}
+
+	if cmdRecord.KubeContext == "" {
+		cmdRecord.KubeContext = kubeContext(env)
+	}
+}
+
+// currentUsername returns the name of the user running the process
+func currentUsername() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// containerID returns the short ID of the container the process runs in,
+// found in its cgroup paths (cgroup v1, or v2 with a container manager that
+// names the cgroup) or in the source of the /etc/hostname bind mount that
+// Docker and Podman add. It is empty outside a container.
+func containerID(cgroup, mountinfo string) string {
+	if id := containerIDPattern.FindString(cgroup); id != "" {
+		return id[:12]
+	}
+
+	// A host lists the layers of every container among its own mounts, so
+	// only the mount over /etc/hostname is trusted
+	for _, line := range strings.Split(mountinfo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || fields[4] != "/etc/hostname" || !strings.Contains(fields[3], "/containers/") {
+			continue
+		}
+		if id := containerIDPattern.FindString(fields[3]); id != "" {
+			return id[:12]
+		}
+	}
+	return ""
+}
+
+// pythonEnv returns the active virtualenv, as its directory, or else the
+// active conda environment's name
+func pythonEnv(getenv func(string) string) string {
+	if venv := getenv("VIRTUAL_ENV"); venv != "" {
+		return venv
+	}
+	return getenv("CONDA_DEFAULT_ENV")
+}
+
+// kubeContext returns the current-context of the kubeconfig kubectl would
+// use: the first file in KUBECONFIG that sets one, or ~/.kube/config
+func kubeContext(env sessionEnv) string {
+	paths := filepath.SplitList(env.getenv("KUBECONFIG"))
+	if len(paths) == 0 {
+		home := env.getenv("HOME")
+		if home == "" {
+			home = env.getenv("USERPROFILE")
+		}
+		if home == "" {
+			return ""
+		}
+		paths = []string{filepath.Join(home, ".kube", "config")}
+	}
+
+	for _, path := range paths {
+		data, err := env.readFile(path)
+		if err != nil {
+			continue
+		}
+		if context := currentContext(string(data)); context != "" {
+			return context
+		}
+	}
+	return ""
+}
+
+// currentContext reads the top-level current-context key of a kubeconfig
+// without parsing the rest of it
+func currentContext(kubeconfig string) string {
+	scanner := bufio.NewScanner(strings.NewReader(kubeconfig))
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "current-context:")
+		if !ok {
+			continue
+		}
+		if i := strings.Index(value, " #"); i != -1 {
+			value = value[:i]
+		}
+		return strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package interceptor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+func TestCollectSessionContext(t *testing.T) {
+	home := t.TempDir()
+	kubeDir := filepath.Join(home, ".kube")
+	if err := os.MkdirAll(kubeDir, 0755); err != nil {
+		t.Fatalf("Failed to create kube directory: %v", err)
+	}
+	kubeconfig := "apiVersion: v1\ncontexts:\n- name: dev\n  context:\n    current-context: nested\ncurrent-context: \"prod-eu\" # active\n"
+	if err := os.WriteFile(filepath.Join(kubeDir, "config"), []byte(kubeconfig), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+
+	id := "4f2c1a9e0b7d3c5e8a6f1b2d4c9e7a3f5b8d0c2e6a4f1b9d7c3e5a8f0b2d4c6e"
+	files := map[string]string{
+		"/proc/self/cgroup": "0::/system.slice/docker-" + id + ".scope\n",
+	}
+	env := map[string]string{
+		"HOME":              home,
+		"SSH_CONNECTION":    "10.0.0.2 50022 10.0.0.1 22",
+		"CONDA_DEFAULT_ENV": "science",
+	}
+	sessionEnv := sessionEnv{
+		getenv: func(key string) string { return env[key] },
+		readFile: func(path string) ([]byte, error) {
+			if content, ok := files[path]; ok {
+				return []byte(content), nil
+			}
+			return os.ReadFile(path)
+		},
+		stdinLink: filepath.Join(home, "missing"),
+	}
+
+	record := history.CommandRecord{Command: "kubectl get pods"}
+	collectSessionContext(&record, sessionEnv)
+
+	if !record.SSH || record.ContainerID != id[:12] || record.PythonEnv != "science" || record.KubeContext != "prod-eu" || record.TTY != "" {
+		t.Errorf("Unexpected session context: %+v", record)
+	}
+
+	// A virtualenv takes precedence over conda, and fields already set are kept
+	env["VIRTUAL_ENV"] = "/srv/app/.venv"
+	delete(env, "SSH_CONNECTION")
+	record = history.CommandRecord{Command: "pytest", KubeContext: "from-client"}
+	collectSessionContext(&record, sessionEnv)
+	if record.SSH || record.PythonEnv != "/srv/app/.venv" || record.KubeContext != "from-client" {
+		t.Errorf("Unexpected session context: %+v", record)
+	}
+}
+
+func TestContainerID(t *testing.T) {
+	id := "9b1e3d5f7a2c4e6b8d0f1a3c5e7b9d2f4a6c8e0b1d3f5a7c9e2b4d6f8a0c1e3d"
+	layer := "0d8f6b4a2c0e8f6d4b2a0c8e6f4d2b0a8c6e4f2d0b8a6c4e2f0d8b6a4c2e0f8d"
+
+	tests := []struct {
+		name      string
+		cgroup    string
+		mountinfo string
+		want      string
+	}{
+		{name: "Host", cgroup: "0::/user.slice/user-1000.slice/session-2.scope\n", want: ""},
+		{name: "Cgroup v1", cgroup: "12:cpu,cpuacct:/docker/" + id + "\n", want: id[:12]},
+		{
+			name:      "Hostname bind mount",
+			cgroup:    "0::/\n",
+			mountinfo: "612 590 259:2 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw,relatime - ext4 /dev/root rw\n",
+			want:      id[:12],
+		},
+		{
+			name:      "Host with running containers",
+			cgroup:    "0::/user.slice\n",
+			mountinfo: "88 29 0:44 / /var/lib/docker/overlay2/" + layer + "/merged rw - overlay overlay rw\n",
+			want:      "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containerID(tt.cgroup, tt.mountinfo); got != tt.want {
+				t.Errorf("containerID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
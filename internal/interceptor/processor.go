@@ -78,6 +78,19 @@ func (p *CommandProcessor) ProcessCommandFromEnvironment() error {
 	return p.capture.CaptureCommand()
 }
 
+// PrepareCommandFromEnvironment builds the command record described by the
+// environment variables without storing it, or returns nil if it is skipped
+func (p *CommandProcessor) PrepareCommandFromEnvironment() (*history.CommandRecord, error) {
+	if err := p.envManager.ValidateEnvironment(); err != nil {
+		return nil, fmt.Errorf("invalid environment for command processing: %w", err)
+	}
+
+	// Missing context is filled in on a best-effort basis
+	_ = p.enhanceEnvironmentContext()
+
+	return p.capture.PrepareCommand()
+}
+
 // enhanceEnvironmentContext adds missing context to environment variables
 func (p *CommandProcessor) enhanceEnvironmentContext() error {
 	// Ensure directory is set
@@ -302,6 +315,12 @@ func (p *CommandProcessor) parseShellType(shellStr string) (history.ShellType, e
 		return history.Zsh, nil
 	case "cmd", "command":
 		return history.Cmd, nil
+	case "fish":
+		return history.Fish, nil
+	case "nushell", "nu":
+		return history.Nushell, nil
+	case "elvish":
+		return history.Elvish, nil
 	default:
 		return history.Unknown, fmt.Errorf("unknown shell type: %s", shellStr)
 	}
@@ -321,12 +340,10 @@ func (p *CommandProcessor) ValidateCommand(command string) error {
 		return fmt.Errorf("command cannot be empty")
 	}
 
-	// Check against exclude patterns
+	// Check against exclude rules
 	if p.config != nil {
-		for _, pattern := range p.config.ExcludePatterns {
-			if matched, _ := filepath.Match(pattern, command); matched {
-				return fmt.Errorf("command matches exclude pattern: %s", pattern)
-			}
+		if match := p.config.MatchExclude(command, "", history.Unknown); match != nil {
+			return fmt.Errorf("command matches exclude rule %s", match)
 		}
 	}
 
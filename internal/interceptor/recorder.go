@@ -2,6 +2,7 @@ package interceptor
 
 import (
 	"github.com/ValGrace/command-history-tracker/internal/config"
+	"github.com/ValGrace/command-history-tracker/internal/daemon"
 	"github.com/ValGrace/command-history-tracker/internal/storage"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
 	"fmt"
@@ -18,8 +19,7 @@ func NewCommandRecorder() (*CommandRecorder, error) {
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		// Use default config if loading fails
-		cfg = config.DefaultConfig()
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 
 	// Initialize storage
@@ -75,21 +75,50 @@ func (r *CommandRecorder) IsEnabled() bool {
 	return r.processor.envManager.IsTrackerEnabled()
 }
 
-// RecordCommand is a convenience function for recording a single command
-func RecordCommand() error {
-	recorder, err := NewCommandRecorder()
+// RecordCommand records the command described by the environment. With
+// useDaemon the record is handed to the recorder daemon when one is running,
+// which avoids opening the database on every prompt; otherwise, or when the
+// daemon cannot be reached, it is written directly.
+func RecordCommand(useDaemon bool) error {
+	// A broken configuration could drop exclusions, so record nothing
+	cfg, err := config.LoadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to create recorder: %w", err)
+		return fmt.Errorf("failed to load configuration: %w", err)
 	}
-	defer recorder.Close()
 
-	// Check if recording is enabled
-	if !recorder.IsEnabled() {
+	// Building the record needs no storage
+	processor := NewCommandProcessor(nil, cfg)
+	if !processor.envManager.IsTrackerEnabled() {
 		return nil // Silently skip if disabled
 	}
 
-	// Record from environment
-	return recorder.RecordFromEnvironment()
+	record, err := processor.PrepareCommandFromEnvironment()
+	if err != nil || record == nil {
+		return err
+	}
+
+	if useDaemon {
+		client := daemon.NewClient(daemon.Address(cfg.DaemonSocket))
+		if err := client.Send(*record); err == nil {
+			return nil
+		}
+	}
+
+	return saveDirect(cfg, *record)
+}
+
+// saveDirect writes a single record to the configured database
+func saveDirect(cfg *config.Config, record history.CommandRecord) error {
+	storageEngine := storage.NewSQLiteStorage(cfg.StoragePath)
+	if err := storageEngine.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer storageEngine.Close()
+
+	if err := storageEngine.SaveCommand(record); err != nil {
+		return fmt.Errorf("failed to save command: %w", err)
+	}
+	return nil
 }
 
 // RecordCommandWithArgs is a convenience function for recording a command with arguments
@@ -0,0 +1,228 @@
+package interceptor
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"unicode"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// RedactedTag marks records whose command text had secrets masked
+const RedactedTag = "redacted"
+
+// RedactionMask replaces every secret found in a command
+const RedactionMask = "****"
+
+// Detector finds one kind of secret in a command line
+type Detector interface {
+	// Name identifies the detector in reports
+	Name() string
+
+	// Find returns the [start, end) byte ranges of secrets in command
+	Find(command string) [][2]int
+}
+
+// regexDetector masks the capture groups of a pattern, or the whole match
+// when the pattern has no groups
+type regexDetector struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+func (d *regexDetector) Name() string { return d.name }
+
+func (d *regexDetector) Find(command string) [][2]int {
+	var spans [][2]int
+	for _, match := range d.pattern.FindAllStringSubmatchIndex(command, -1) {
+		if len(match) == 2 {
+			spans = append(spans, [2]int{match[0], match[1]})
+			continue
+		}
+		// Only groups that took part in the match and are not empty
+		for i := 2; i < len(match); i += 2 {
+			if match[i] >= 0 && match[i+1] > match[i] {
+				spans = append(spans, [2]int{match[i], match[i+1]})
+			}
+		}
+	}
+	return spans
+}
+
+// entropyDetector flags long random-looking words such as generated API keys
+type entropyDetector struct {
+	minLength  int
+	minEntropy float64
+}
+
+// entropyCandidate matches base64 and base64url runs; slashes split words so
+// paths are not mistaken for keys
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+_\-]{20,}={0,2}`)
+
+func (d *entropyDetector) Name() string { return "high-entropy" }
+
+func (d *entropyDetector) Find(command string) [][2]int {
+	var spans [][2]int
+	for _, loc := range entropyCandidate.FindAllStringIndex(command, -1) {
+		word := command[loc[0]:loc[1]]
+		if len(word) >= d.minLength && looksRandom(word) && shannonEntropy(word) >= d.minEntropy {
+			spans = append(spans, [2]int{loc[0], loc[1]})
+		}
+	}
+	return spans
+}
+
+// looksRandom requires a mix of upper case, lower case and digits, which
+// rules out hex digests, UUIDs and ordinary hyphenated names
+func looksRandom(word string) bool {
+	var upper, lower, digit bool
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// quotedValue captures a shell word, quoted or not, in one of three groups
+const quotedValue = `(?:"([^"]*)"|'([^']*)'|([^\s"';&|]+))`
+
+// DefaultDetectors returns the built-in secret detectors
+func DefaultDetectors() []Detector {
+	return []Detector{
+		&regexDetector{
+			name: "token",
+			pattern: regexp.MustCompile(`\b(?:` +
+				`(?:AKIA|ASIA)[0-9A-Z]{16}` + // AWS access key ID
+				`|gh[pousr]_[A-Za-z0-9]{36,}` + // GitHub
+				`|github_pat_[A-Za-z0-9_]{22,}` +
+				`|glpat-[A-Za-z0-9_\-]{20,}` + // GitLab
+				`|xox[abposr]-[A-Za-z0-9\-]{10,}` + // Slack
+				`|[rs]k_(?:live|test)_[A-Za-z0-9]{16,}` + // Stripe
+				`|AIza[0-9A-Za-z_\-]{35}` + // Google
+				`|npm_[A-Za-z0-9]{36}` +
+				`|sk-[A-Za-z0-9_\-]{20,}` +
+				`|eyJ[A-Za-z0-9_\-]{8,}\.eyJ[A-Za-z0-9_\-]{8,}\.[A-Za-z0-9_\-]{8,}` + // JWT
+				`)`),
+		},
+		&regexDetector{
+			name:    "secret-variable",
+			pattern: regexp.MustCompile(`(?i)\b[a-z0-9_]*(?:secret|token|password|passwd|passphrase|api_?key|access_?key|private_?key|credentials?)[a-z0-9_]*\s*=\s*` + quotedValue),
+		},
+		&regexDetector{
+			name: "auth-header",
+			pattern: regexp.MustCompile(`(?i)\b(?:proxy-)?authorization\s*:\s*(?:(?:bearer|basic|token|digest)\s+)?([^\s"']+)` +
+				`|\b(?:x-api-key|api-key|x-auth-token|x-access-token|private-token)\s*:\s*([^\s"']+)`),
+		},
+		&regexDetector{
+			name:    "url-credentials",
+			pattern: regexp.MustCompile(`\b[a-zA-Z][a-zA-Z0-9+.\-]*://[^\s/:@]*:([^\s/@]+)@`),
+		},
+		&regexDetector{
+			name:    "password-flag",
+			pattern: regexp.MustCompile(`(?i)(?:^|\s)--?(?:password|passwd|pass|token|secret|api-key|apikey|access-token|auth-token|client-secret)(?:=|\s+)` + quotedValue),
+		},
+		&regexDetector{
+			name: "password-option",
+			// mysql -pSECRET and sshpass -p SECRET; -p means something else to most tools
+			pattern: regexp.MustCompile(`\b(?:mysql|mysqldump|mysqladmin|mariadb|mariadb-dump)\b[^|;&\n]*?\s-p([^\s]+)` +
+				`|\bsshpass\s+-p\s*([^\s]+)`),
+		},
+		&entropyDetector{minLength: 20, minEntropy: 3.7},
+	}
+}
+
+// Redactor masks secrets in command lines before they are stored
+type Redactor struct {
+	detectors []Detector
+}
+
+// NewRedactor creates a redactor with the built-in detectors and the given
+// user patterns. A pattern with capture groups masks only the groups.
+func NewRedactor(patterns []string) (*Redactor, error) {
+	detectors := DefaultDetectors()
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		detectors = append(detectors, &regexDetector{name: "custom", pattern: re})
+	}
+
+	return &Redactor{detectors: detectors}, nil
+}
+
+// Redact returns command with every secret replaced by RedactionMask and the
+// names of the detectors that matched
+func (r *Redactor) Redact(command string) (string, []string) {
+	var spans [][2]int
+	var names []string
+	for _, detector := range r.detectors {
+		found := detector.Find(command)
+		if len(found) > 0 {
+			spans = append(spans, found...)
+			names = append(names, detector.Name())
+		}
+	}
+
+	if len(spans) == 0 {
+		return command, nil
+	}
+
+	// Merge overlapping ranges so each secret is masked once
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	redacted := make([]byte, 0, len(command))
+	last := 0
+	for i := 0; i < len(spans); i++ {
+		start, end := spans[i][0], spans[i][1]
+		for i+1 < len(spans) && spans[i+1][0] < end {
+			i++
+			if spans[i][1] > end {
+				end = spans[i][1]
+			}
+		}
+		redacted = append(redacted, command[last:start]...)
+		redacted = append(redacted, RedactionMask...)
+		last = end
+	}
+	redacted = append(redacted, command[last:]...)
+
+	return string(redacted), names
+}
+
+// RedactRecord masks secrets in record.Command and tags the record. It
+// reports whether anything was masked.
+func (r *Redactor) RedactRecord(record *history.CommandRecord) bool {
+	redacted, names := r.Redact(record.Command)
+	if len(names) == 0 || redacted == record.Command {
+		return false
+	}
+
+	record.Command = redacted
+	record.AddTag(RedactedTag)
+	return true
+}
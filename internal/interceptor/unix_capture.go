@@ -85,6 +85,15 @@ func (u *UnixCapture) detectUnixShell() (history.ShellType, error) {
 		if strings.Contains(execName, "bash") {
 			return history.Bash, nil
 		}
+		if strings.Contains(execName, "fish") {
+			return history.Fish, nil
+		}
+		if execName == "nu" || strings.Contains(execName, "nushell") {
+			return history.Nushell, nil
+		}
+		if strings.Contains(execName, "elvish") {
+			return history.Elvish, nil
+		}
 		if strings.Contains(execName, "pwsh") || strings.Contains(execName, "powershell") {
 			return history.PowerShell, nil
 		}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"fmt"
 	"github.com/ValGrace/command-history-tracker/internal/cache"
 	"github.com/ValGrace/command-history-tracker/pkg/history"
 	"time"
@@ -20,6 +21,14 @@ func NewCachedStorage(storage history.StorageEngine, maxCacheEntries int, cacheT
 	}
 }
 
+// Initialize initializes the underlying storage when it needs it
+func (cs *CachedStorage) Initialize() error {
+	if engine, ok := cs.storage.(StorageEngine); ok {
+		return engine.Initialize()
+	}
+	return nil
+}
+
 // SaveCommand saves a command and invalidates the cache for its directory
 func (cs *CachedStorage) SaveCommand(cmd history.CommandRecord) error {
 	if err := cs.storage.SaveCommand(cmd); err != nil {
@@ -31,6 +40,19 @@ func (cs *CachedStorage) SaveCommand(cmd history.CommandRecord) error {
 	return nil
 }
 
+// BatchSaveCommands saves commands in a single transaction and invalidates
+// cache
+func (cs *CachedStorage) BatchSaveCommands(commands []history.CommandRecord) error {
+	batch, ok := cs.storage.(BatchStorageEngine)
+	if !ok {
+		return fmt.Errorf("storage does not support batch saves")
+	}
+
+	err := batch.BatchSaveCommands(commands)
+	cs.cache.InvalidateAll()
+	return err
+}
+
 // GetCommandsByDirectory retrieves commands with caching
 func (cs *CachedStorage) GetCommandsByDirectory(dir string) ([]history.CommandRecord, error) {
 	// Try to get from cache first
@@ -60,6 +82,62 @@ func (cs *CachedStorage) SearchCommands(pattern string, dir string) ([]history.C
 	return cs.storage.SearchCommands(pattern, dir)
 }
 
+// GetCommandsPage delegates to underlying storage (no caching for pages)
+func (cs *CachedStorage) GetCommandsPage(filters CommandFilters, cursor string, limit int) (*CommandPage, error) {
+	paged, ok := cs.storage.(PagedStorageEngine)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support paging")
+	}
+	return paged.GetCommandsPage(filters, cursor, limit)
+}
+
+// FilterCommands delegates to underlying storage (no caching for filtered results)
+func (cs *CachedStorage) FilterCommands(filters CommandFilters) ([]history.CommandRecord, error) {
+	filterable, ok := cs.storage.(interface {
+		FilterCommands(filters CommandFilters) ([]history.CommandRecord, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("storage does not support filtering")
+	}
+	return filterable.FilterCommands(filters)
+}
+
+// GetCommandsBySession delegates to underlying storage
+func (cs *CachedStorage) GetCommandsBySession(sessionID string) ([]history.CommandRecord, error) {
+	sessions, ok := cs.storage.(SessionStorageEngine)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support session tracking")
+	}
+	return sessions.GetCommandsBySession(sessionID)
+}
+
+// GetSessions delegates to underlying storage
+func (cs *CachedStorage) GetSessions(limit int) ([]history.SessionIndex, error) {
+	sessions, ok := cs.storage.(SessionStorageEngine)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support session tracking")
+	}
+	return sessions.GetSessions(limit)
+}
+
+// GetDirectoryStats delegates to underlying storage
+func (cs *CachedStorage) GetDirectoryStats() ([]history.DirectoryIndex, error) {
+	stats, ok := cs.storage.(StatsStorageEngine)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support statistics")
+	}
+	return stats.GetDirectoryStats()
+}
+
+// GetUsageStats delegates to underlying storage
+func (cs *CachedStorage) GetUsageStats(query StatsQuery) (*UsageStats, error) {
+	stats, ok := cs.storage.(StatsStorageEngine)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support statistics")
+	}
+	return stats.GetUsageStats(query)
+}
+
 // CleanupOldCommands cleans up old commands and invalidates cache
 func (cs *CachedStorage) CleanupOldCommands(retentionDays int) error {
 	if err := cs.storage.CleanupOldCommands(retentionDays); err != nil {
@@ -71,6 +149,67 @@ func (cs *CachedStorage) CleanupOldCommands(retentionDays int) error {
 	return nil
 }
 
+// CleanupDirectoryCommands cleans up old commands in the given directories and
+// invalidates cache
+func (cs *CachedStorage) CleanupDirectoryCommands(directories []string, retentionDays int) (int64, error) {
+	retention, ok := cs.storage.(RetentionStorageEngine)
+	if !ok {
+		return 0, fmt.Errorf("storage does not support per-directory cleanup")
+	}
+
+	removed, err := retention.CleanupDirectoryCommands(directories, retentionDays)
+	cs.cache.InvalidateAll()
+	return removed, err
+}
+
+// DeleteCommand removes a command by ID and invalidates cache
+func (cs *CachedStorage) DeleteCommand(id string) error {
+	editable, ok := cs.storage.(EditableStorageEngine)
+	if !ok {
+		return fmt.Errorf("storage does not support deleting commands")
+	}
+
+	err := editable.DeleteCommand(id)
+	cs.cache.InvalidateAll()
+	return err
+}
+
+// UpdateCommand replaces a stored command and invalidates cache
+func (cs *CachedStorage) UpdateCommand(cmd history.CommandRecord) error {
+	editable, ok := cs.storage.(EditableStorageEngine)
+	if !ok {
+		return fmt.Errorf("storage does not support editing commands")
+	}
+
+	err := editable.UpdateCommand(cmd)
+	cs.cache.InvalidateAll()
+	return err
+}
+
+// DeleteCommands removes commands by ID and invalidates cache
+func (cs *CachedStorage) DeleteCommands(ids []string) (int64, error) {
+	editable, ok := cs.storage.(EditableStorageEngine)
+	if !ok {
+		return 0, fmt.Errorf("storage does not support deleting commands")
+	}
+
+	removed, err := editable.DeleteCommands(ids)
+	cs.cache.InvalidateAll()
+	return removed, err
+}
+
+// UpdateTags adds and removes tags on commands by ID and invalidates cache
+func (cs *CachedStorage) UpdateTags(ids []string, add []string, remove []string) (int64, error) {
+	editable, ok := cs.storage.(EditableStorageEngine)
+	if !ok {
+		return 0, fmt.Errorf("storage does not support editing tags")
+	}
+
+	changed, err := editable.UpdateTags(ids, add, remove)
+	cs.cache.InvalidateAll()
+	return changed, err
+}
+
 // Close closes the underlying storage
 func (cs *CachedStorage) Close() error {
 	return cs.storage.Close()
@@ -0,0 +1,574 @@
+package storage
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Environment variables that unlock an encrypted database when no KeySource
+// is set with SetKeySource
+const (
+	PassphraseEnv = "CHT_DB_PASSPHRASE"
+	KeyFileEnv    = "CHT_DB_KEY_FILE"
+)
+
+// Key derivation methods recorded for an encrypted database
+const (
+	KDFArgon2id = "argon2id"
+	KDFKeyFile  = "keyfile"
+)
+
+// encryptionSchemaVersion is the migration that adds the encryption table
+const encryptionSchemaVersion = 5
+
+// sealedPrefix starts every encrypted command value, so plaintext rows left
+// by a process that had no key are still readable
+const sealedPrefix = "enc1:"
+
+// argon2id cost parameters for new passphrase keys
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+)
+
+// keyCheckValue is sealed into the encryption settings to recognize the key
+const keyCheckValue = "command-history-tracker"
+
+// keyCheckID is the associated data of the sealed key check value
+const keyCheckID = "key-check"
+
+// KeySource supplies the key of an encrypted database: a passphrase, which is
+// stretched with argon2id, or a file holding a random key
+type KeySource struct {
+	Passphrase string
+	KeyFile    string
+}
+
+// KeySourceFromEnv reads CHT_DB_PASSPHRASE and CHT_DB_KEY_FILE
+func KeySourceFromEnv() KeySource {
+	return KeySource{
+		Passphrase: os.Getenv(PassphraseEnv),
+		KeyFile:    os.Getenv(KeyFileEnv),
+	}
+}
+
+// DefaultKeyFilePath returns the key file used when none is given: db.key in
+// the per-user configuration directory, apart from the database it unlocks
+func DefaultKeyFilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user configuration directory: %w", err)
+	}
+	return filepath.Join(dir, "command-history-tracker", "db.key"), nil
+}
+
+// EncryptionInfo describes how a database is encrypted
+type EncryptionInfo struct {
+	Enabled bool
+	KDF     string
+	KeyFile string // key file recorded when encrypting with KDFKeyFile
+}
+
+// encryptionSettings is the stored row of the encryption table
+type encryptionSettings struct {
+	kdf      string
+	salt     []byte
+	time     uint32
+	memory   uint32
+	threads  uint8
+	keyFile  string
+	keyCheck string
+}
+
+// SetKeySource sets the key used to open an encrypted database in place of
+// the CHT_DB_PASSPHRASE and CHT_DB_KEY_FILE environment variables. It must be
+// called before Initialize.
+func (s *SQLiteStorage) SetKeySource(keys KeySource) {
+	s.keys = &keys
+}
+
+// Encrypted reports whether command text is sealed before it is stored
+func (s *SQLiteStorage) Encrypted() bool {
+	return s.aead != nil
+}
+
+// EncryptionInfo returns the encryption settings of the database
+func (s *SQLiteStorage) EncryptionInfo() (*EncryptionInfo, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	settings, err := s.readEncryptionSettings(s.db)
+	if err != nil || settings == nil {
+		return &EncryptionInfo{}, err
+	}
+	return &EncryptionInfo{Enabled: true, KDF: settings.kdf, KeyFile: settings.keyFile}, nil
+}
+
+// Unlock loads the key of an encrypted database opened with Open, which
+// leaves sealed commands unreadable; Initialize unlocks by itself
+func (s *SQLiteStorage) Unlock() error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+	return s.loadEncryption()
+}
+
+// loadEncryption derives the key of an encrypted database and checks it
+func (s *SQLiteStorage) loadEncryption() error {
+	settings, err := s.readEncryptionSettings(s.db)
+	if err != nil || settings == nil {
+		return err
+	}
+	return s.openEncryption(settings)
+}
+
+// openEncryption derives the key for settings and makes it the current one
+func (s *SQLiteStorage) openEncryption(settings *encryptionSettings) error {
+	keys := KeySourceFromEnv()
+	if s.keys != nil {
+		keys = *s.keys
+	}
+
+	aead, err := settings.open(keys)
+	if err != nil {
+		return err
+	}
+	s.aead, s.keyCheck = aead, settings.keyCheck
+	return nil
+}
+
+// refreshEncryption reloads the key when another process encrypted,
+// decrypted or rekeyed the database since it was loaded, so a long-running
+// recorder does not keep sealing new commands with a retired key. Writers
+// call it inside a transaction from beginWrite, so the key cannot change
+// again before they commit.
+func (s *SQLiteStorage) refreshEncryption(q queryRower) error {
+	settings, err := s.readEncryptionSettings(q)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case settings == nil && s.aead == nil:
+		return nil
+	case settings == nil:
+		s.aead, s.keyCheck = nil, ""
+		return nil
+	case settings.keyCheck == s.keyCheck:
+		return nil
+	}
+	return s.openEncryption(settings)
+}
+
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// readEncryptionSettings returns nil when the database is not encrypted
+func (s *SQLiteStorage) readEncryptionSettings(q queryRower) (*encryptionSettings, error) {
+	var version int
+	if err := q.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		return nil, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	if version < encryptionSchemaVersion {
+		return nil, nil
+	}
+
+	var settings encryptionSettings
+	err := q.QueryRow(`SELECT kdf, salt, time_cost, memory_cost, threads, key_file, key_check FROM encryption WHERE id = 1`).
+		Scan(&settings.kdf, &settings.salt, &settings.time, &settings.memory, &settings.threads, &settings.keyFile, &settings.keyCheck)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// open derives the key from keys and verifies it against the key check value
+func (e *encryptionSettings) open(keys KeySource) (cipher.AEAD, error) {
+	var key []byte
+	switch e.kdf {
+	case KDFArgon2id:
+		if keys.Passphrase == "" {
+			return nil, fmt.Errorf("history database is encrypted with a passphrase: set %s", PassphraseEnv)
+		}
+		key = argon2.IDKey([]byte(keys.Passphrase), e.salt, e.time, e.memory, e.threads, chacha20poly1305.KeySize)
+	case KDFKeyFile:
+		path := keys.KeyFile
+		if path == "" {
+			path = e.keyFile
+		}
+		var err error
+		if key, err = readKeyFile(path); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key derivation %q", e.kdf)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	if check, err := openValue(aead, keyCheckID, e.keyCheck); err != nil || check != keyCheckValue {
+		if e.kdf == KDFArgon2id {
+			return nil, fmt.Errorf("wrong passphrase for the history database")
+		}
+		return nil, fmt.Errorf("wrong key file for the history database")
+	}
+	return aead, nil
+}
+
+// newEncryptionSettings creates a key from keys: a passphrase takes
+// precedence, otherwise the key file is read or created
+func newEncryptionSettings(keys KeySource) (*encryptionSettings, cipher.AEAD, error) {
+	settings := &encryptionSettings{}
+	var key []byte
+
+	if keys.Passphrase != "" {
+		settings.kdf = KDFArgon2id
+		settings.salt = make([]byte, 16)
+		if _, err := rand.Read(settings.salt); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		settings.time, settings.memory, settings.threads = argonTime, argonMemory, argonThreads
+		key = argon2.IDKey([]byte(keys.Passphrase), settings.salt, settings.time, settings.memory, settings.threads, chacha20poly1305.KeySize)
+	} else {
+		path := keys.KeyFile
+		if path == "" {
+			var err error
+			if path, err = DefaultKeyFilePath(); err != nil {
+				return nil, nil, err
+			}
+		}
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve key file path: %w", err)
+		}
+		if key, err = loadOrCreateKeyFile(path); err != nil {
+			return nil, nil, err
+		}
+		settings.kdf = KDFKeyFile
+		settings.keyFile = path
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	if settings.keyCheck, err = sealValue(aead, keyCheckID, keyCheckValue); err != nil {
+		return nil, nil, err
+	}
+	return settings, aead, nil
+}
+
+// readKeyFile reads a hex-encoded key
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("invalid key file %s: expected %d hex-encoded bytes", path, chacha20poly1305.KeySize)
+	}
+	return key, nil
+}
+
+// loadOrCreateKeyFile reads the key at path, writing a random one readable
+// only by the owner if the file does not exist
+func loadOrCreateKeyFile(path string) ([]byte, error) {
+	if _, err := os.Stat(path); err == nil {
+		return readKeyFile(path)
+	}
+
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+	return key, nil
+}
+
+// sealValue encrypts value, binding it to id so sealed values cannot be
+// swapped between rows
+func sealValue(aead cipher.AEAD, id, value string) (string, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(id))
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// openValue decrypts a value written by sealValue
+func openValue(aead cipher.AEAD, id, value string) (string, error) {
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return string(plain), nil
+}
+
+// sealCommand returns the value stored for a command's text
+func (s *SQLiteStorage) sealCommand(id, command string) (string, error) {
+	if s.aead == nil {
+		return command, nil
+	}
+	sealed, err := sealValue(s.aead, id, command)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt command %s: %w", id, err)
+	}
+	return sealed, nil
+}
+
+// openCommand returns the text of a stored command value
+func (s *SQLiteStorage) openCommand(id, value string) (string, error) {
+	if !strings.HasPrefix(value, sealedPrefix) {
+		return value, nil
+	}
+	if s.aead == nil {
+		return "", fmt.Errorf("command %s is encrypted and no key is loaded", id)
+	}
+	command, err := openValue(s.aead, id, value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt command %s: %w", id, err)
+	}
+	return command, nil
+}
+
+// EnableEncryption seals every stored command with a new key from keys and
+// records how to derive it. It returns the number of commands sealed.
+func (s *SQLiteStorage) EnableEncryption(keys KeySource) (int, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	if s.aead != nil {
+		return 0, fmt.Errorf("database is already encrypted")
+	}
+	if err := s.requireEncryptionSchema(); err != nil {
+		return 0, err
+	}
+
+	settings, aead, err := newEncryptionSettings(keys)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := s.resealCommands(settings, func(id, command string) (string, error) {
+		return sealValue(aead, id, command)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Rewrite the file and its write-ahead log so the replaced plaintext
+	// does not linger in free pages or old index segments
+	s.aead, s.keyCheck = aead, settings.keyCheck
+	return count, s.PurgeOldData()
+}
+
+// DisableEncryption stores every command as plaintext again and forgets the
+// key. It returns the number of commands decrypted.
+func (s *SQLiteStorage) DisableEncryption() (int, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	if s.aead == nil {
+		return 0, fmt.Errorf("database is not encrypted")
+	}
+
+	count, err := s.resealCommands(nil, func(id, command string) (string, error) {
+		return command, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.aead, s.keyCheck = nil, ""
+	return count, nil
+}
+
+// Rekey seals every command with a new key from keys, replacing the current
+// one. It returns the number of commands sealed.
+func (s *SQLiteStorage) Rekey(keys KeySource) (int, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+	if s.aead == nil {
+		return 0, fmt.Errorf("database is not encrypted")
+	}
+
+	settings, aead, err := newEncryptionSettings(keys)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := s.resealCommands(settings, func(id, command string) (string, error) {
+		return sealValue(aead, id, command)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.aead, s.keyCheck = aead, settings.keyCheck
+	return count, s.PurgeOldData()
+}
+
+// requireEncryptionSchema checks that the encryption table exists
+func (s *SQLiteStorage) requireEncryptionSchema() error {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version < encryptionSchemaVersion {
+		return fmt.Errorf("database schema version %d does not support encryption: run 'tracker db migrate' first", version)
+	}
+	return nil
+}
+
+// resealCommands rewrites every stored command with seal, given its
+// plaintext, and replaces the encryption settings (nil removes them) in a
+// single transaction. The transaction holds the write lock from the start, so
+// commands saved meanwhile by other processes are resealed too or wait for
+// the new key. The full-text index follows through its update trigger.
+func (s *SQLiteStorage) resealCommands(settings *encryptionSettings, seal func(id, command string) (string, error)) (int, error) {
+	type storedCommand struct{ id, value string }
+
+	tx, err := s.beginWrite()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	// Another process may have changed the key the stored commands use
+	if err := s.refreshEncryption(tx); err != nil {
+		return 0, err
+	}
+
+	// Read everything first: the connection cannot update rows while it is
+	// still iterating over them
+	rows, err := tx.Query(`SELECT id, command FROM commands`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read commands: %w", err)
+	}
+	var stored []storedCommand
+	for rows.Next() {
+		var cmd storedCommand
+		if err := rows.Scan(&cmd.id, &cmd.value); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan command: %w", err)
+		}
+		stored = append(stored, cmd)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read commands: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`UPDATE commands SET command = ? WHERE id = ?`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, cmd := range stored {
+		command, err := s.openCommand(cmd.id, cmd.value)
+		if err != nil {
+			return 0, err
+		}
+		value, err := seal(cmd.id, command)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt command %s: %w", cmd.id, err)
+		}
+		if _, err := stmt.Exec(value, cmd.id); err != nil {
+			return 0, fmt.Errorf("failed to rewrite command %s: %w", cmd.id, err)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM encryption`); err != nil {
+		return 0, fmt.Errorf("failed to clear encryption settings: %w", err)
+	}
+	if settings != nil {
+		_, err := tx.Exec(`
+		INSERT INTO encryption (id, kdf, salt, time_cost, memory_cost, threads, key_file, key_check)
+		VALUES (1, ?, ?, ?, ?, ?, ?, ?)`,
+			settings.kdf, settings.salt, settings.time, settings.memory, settings.threads, settings.keyFile, settings.keyCheck)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save encryption settings: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(stored), nil
+}
+
+// PlaintextBackups returns the pre-upgrade backups of the database at dbPath
+// (see LastBackupPath) that hold unencrypted commands, such as those written
+// before it was encrypted. Backups taken while the database is encrypted copy
+// its sealed commands and are not listed.
+func PlaintextBackups(dbPath string) ([]string, error) {
+	paths, err := filepath.Glob(dbPath + ".v*.bak")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var plaintext []string
+	for _, path := range paths {
+		// A backup that cannot be checked is assumed to be readable
+		if sealed, err := backupSealed(path); err != nil || !sealed {
+			plaintext = append(plaintext, path)
+		}
+	}
+	return plaintext, nil
+}
+
+// backupSealed reports whether every command in a backup is encrypted. The
+// backup is opened read-only so checking it does not change it.
+func backupSealed(path string) (bool, error) {
+	db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(path)+"?mode=ro")
+	if err != nil {
+		return false, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer db.Close()
+
+	var plaintext int
+	err = db.QueryRow(`SELECT COUNT(*) FROM commands WHERE substr(command, 1, ?) != ?`, len(sealedPrefix), sealedPrefix).Scan(&plaintext)
+	if err != nil {
+		return false, fmt.Errorf("failed to read backup: %w", err)
+	}
+	return plaintext == 0, nil
+}
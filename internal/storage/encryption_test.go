@@ -0,0 +1,444 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// rawCommandValues returns the stored command column by ID
+func rawCommandValues(t *testing.T, storage *SQLiteStorage) map[string]string {
+	t.Helper()
+	rows, err := storage.db.Query(`SELECT id, command FROM commands`)
+	if err != nil {
+		t.Fatalf("Failed to query commands: %v", err)
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var id, value string
+		if err := rows.Scan(&id, &value); err != nil {
+			t.Fatalf("Failed to scan command: %v", err)
+		}
+		values[id] = value
+	}
+	return values
+}
+
+func TestSQLiteStorage_EncryptWithKeyFile(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	t.Setenv(KeyFileEnv, "")
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+	keyFile := filepath.Join(dir, "keys", "db.key")
+
+	storage := NewSQLiteStorage(dbPath)
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	base := time.Now().Add(-time.Hour)
+	records := []history.CommandRecord{
+		createTestCommand("enc-1", "git status", "/repo", history.Bash),
+		createTestCommand("enc-2", "git push origin main", "/repo", history.Bash),
+		createTestCommand("enc-3", "docker compose up", "/repo", history.Zsh),
+		createTestCommand("enc-4", "export TOKEN=secret", "/other", history.Bash),
+	}
+	for i := range records {
+		records[i].Timestamp = base.Add(time.Duration(i) * time.Minute)
+	}
+	if err := storage.BatchSaveCommands(records); err != nil {
+		t.Fatalf("BatchSaveCommands failed: %v", err)
+	}
+
+	count, err := storage.EnableEncryption(KeySource{KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("EnableEncryption failed: %v", err)
+	}
+	if count != len(records) || !storage.Encrypted() {
+		t.Fatalf("Expected %d commands sealed, got %d (encrypted %v)", len(records), count, storage.Encrypted())
+	}
+	if _, err := storage.EnableEncryption(KeySource{KeyFile: keyFile}); err == nil {
+		t.Error("Expected an error encrypting twice")
+	}
+
+	// A command recorded afterwards is sealed too
+	later := createTestCommand("enc-5", "git log --oneline", "/repo", history.Bash)
+	later.Timestamp = base.Add(time.Hour)
+	if err := storage.SaveCommand(later); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+
+	for id, value := range rawCommandValues(t, storage) {
+		if !strings.HasPrefix(value, sealedPrefix) || strings.Contains(value, "git") || strings.Contains(value, "secret") {
+			t.Errorf("Command %s stored in plaintext: %q", id, value)
+		}
+	}
+
+	commands, err := storage.GetCommandsByDirectory("/repo")
+	if err != nil || len(commands) != 4 || commands[0].Command != "git log --oneline" {
+		t.Fatalf("Expected 4 decrypted commands, got %+v (err: %v)", commands, err)
+	}
+
+	// Searches fall back to matching decrypted text
+	found, err := storage.SearchCommands("GIT", "/repo")
+	if err != nil || len(found) != 3 {
+		t.Errorf("Expected 3 case-insensitive matches, got %d (err: %v)", len(found), err)
+	}
+	ranked, err := storage.SearchCommandsFTS(`"git push" orig*`, "", 10)
+	if err != nil || len(ranked) != 1 || ranked[0].ID != "enc-2" {
+		t.Errorf("Expected the full-text fallback to find enc-2, got %+v (err: %v)", ranked, err)
+	}
+	if _, err := storage.SearchCommandsFTS("git OR docker", "", 10); err == nil {
+		t.Error("Expected full-text operators to be rejected")
+	}
+	filtered, err := storage.FilterCommands(CommandFilters{Pattern: "git", Limit: 2})
+	if err != nil || len(filtered) != 2 || filtered[0].ID != "enc-5" || filtered[1].ID != "enc-2" {
+		t.Errorf("Expected the 2 newest git commands, got %+v (err: %v)", filtered, err)
+	}
+	page, err := storage.GetCommandsPage(CommandFilters{Pattern: "git"}, "", 2)
+	if err != nil || len(page.Commands) != 2 || page.NextCursor == "" {
+		t.Fatalf("Expected a first page of 2 with a cursor, got %+v (err: %v)", page, err)
+	}
+	page, err = storage.GetCommandsPage(CommandFilters{Pattern: "git"}, page.NextCursor, 2)
+	if err != nil || len(page.Commands) != 1 || page.Commands[0].ID != "enc-1" || page.NextCursor != "" {
+		t.Errorf("Expected enc-1 on the last page, got %+v (err: %v)", page, err)
+	}
+
+	storage.Close()
+
+	// The key file recorded in the database unlocks it on the next open
+	reopened := NewSQLiteStorage(dbPath)
+	if err := reopened.Initialize(); err != nil {
+		t.Fatalf("Reopening with the recorded key file failed: %v", err)
+	}
+	if commands, err := reopened.GetCommandsByDirectory("/other"); err != nil || commands[0].Command != "export TOKEN=secret" {
+		t.Errorf("Expected the reopened database to decrypt, got %+v (err: %v)", commands, err)
+	}
+
+	// Reverting the encryption table would lose the key settings
+	if err := reopened.MigrateTo(encryptionSchemaVersion - 1); err == nil {
+		t.Error("Expected reverting the encryption migration to fail while encrypted")
+	}
+
+	count, err = reopened.DisableEncryption()
+	if err != nil || count != 5 || reopened.Encrypted() {
+		t.Fatalf("DisableEncryption decrypted %d commands (err: %v)", count, err)
+	}
+	if value := rawCommandValues(t, reopened)["enc-4"]; value != "export TOKEN=secret" {
+		t.Errorf("Expected plaintext after decrypting, got %q", value)
+	}
+	if ranked, err := reopened.SearchCommandsFTS("docker", "", 10); err != nil || len(ranked) != 1 {
+		t.Errorf("Expected the full-text index to work after decrypting, got %d (err: %v)", len(ranked), err)
+	}
+	reopened.Close()
+}
+
+func TestSQLiteStorage_EncryptWithPassphrase(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	t.Setenv(KeyFileEnv, "")
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+
+	storage := NewSQLiteStorage(dbPath)
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if err := storage.SaveCommand(createTestCommand("pass-1", "ssh prod", "/ops", history.Bash)); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+	if _, err := storage.EnableEncryption(KeySource{Passphrase: "correct horse"}); err != nil {
+		t.Fatalf("EnableEncryption failed: %v", err)
+	}
+	info, err := storage.EncryptionInfo()
+	if err != nil || !info.Enabled || info.KDF != KDFArgon2id {
+		t.Errorf("Unexpected encryption info %+v (err: %v)", info, err)
+	}
+	storage.Close()
+
+	open := func(keys *KeySource) (*SQLiteStorage, error) {
+		s := NewSQLiteStorage(dbPath)
+		if keys != nil {
+			s.SetKeySource(*keys)
+		}
+		return s, s.Initialize()
+	}
+
+	if s, err := open(nil); err == nil || !strings.Contains(err.Error(), PassphraseEnv) {
+		t.Errorf("Expected an error naming %s without a passphrase, got %v", PassphraseEnv, err)
+		s.Close()
+	} else {
+		s.Close()
+	}
+	if s, err := open(&KeySource{Passphrase: "wrong"}); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Expected a wrong passphrase error, got %v", err)
+		s.Close()
+	} else {
+		s.Close()
+	}
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	s, err := open(nil)
+	if err != nil {
+		t.Fatalf("Opening with %s failed: %v", PassphraseEnv, err)
+	}
+
+	// Rekeying to a key file no longer needs the passphrase
+	keyFile := filepath.Join(dir, "db.key")
+	if count, err := s.Rekey(KeySource{KeyFile: keyFile}); err != nil || count != 1 {
+		t.Fatalf("Rekey sealed %d commands (err: %v)", count, err)
+	}
+	s.Close()
+
+	t.Setenv(PassphraseEnv, "")
+	s, err = open(nil)
+	if err != nil {
+		t.Fatalf("Opening after rekeying failed: %v", err)
+	}
+	defer s.Close()
+	if commands, err := s.GetCommandsByDirectory("/ops"); err != nil || commands[0].Command != "ssh prod" {
+		t.Errorf("Expected the rekeyed command to decrypt, got %+v (err: %v)", commands, err)
+	}
+}
+
+func TestSQLiteStorage_RecorderFollowsRekey(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	t.Setenv(KeyFileEnv, "")
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+
+	recorder := NewSQLiteStorage(dbPath)
+	if err := recorder.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer recorder.Close()
+
+	admin := NewSQLiteStorage(dbPath)
+	if err := admin.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer admin.Close()
+
+	// The recorder opened the database before it was encrypted, then rekeyed
+	if _, err := admin.EnableEncryption(KeySource{KeyFile: filepath.Join(dir, "first.key")}); err != nil {
+		t.Fatalf("EnableEncryption failed: %v", err)
+	}
+	if err := recorder.SaveCommand(createTestCommand("rec-1", "make", "/repo", history.Bash)); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+	if _, err := admin.Rekey(KeySource{KeyFile: filepath.Join(dir, "second.key")}); err != nil {
+		t.Fatalf("Rekey failed: %v", err)
+	}
+	if err := recorder.BatchSaveCommands([]history.CommandRecord{createTestCommand("rec-2", "make test", "/repo", history.Bash)}); err != nil {
+		t.Fatalf("BatchSaveCommands failed: %v", err)
+	}
+
+	// Edits are sealed with the new key too
+	if _, err := admin.Rekey(KeySource{KeyFile: filepath.Join(dir, "third.key")}); err != nil {
+		t.Fatalf("Rekey failed: %v", err)
+	}
+	edited := createTestCommand("rec-1", "make lint", "/repo", history.Bash)
+	if err := recorder.UpdateCommand(edited); err != nil {
+		t.Fatalf("UpdateCommand failed: %v", err)
+	}
+
+	for id, value := range rawCommandValues(t, admin) {
+		if !strings.HasPrefix(value, sealedPrefix) {
+			t.Errorf("Command %s stored in plaintext: %q", id, value)
+		}
+	}
+	commands, err := admin.GetCommandsByDirectory("/repo")
+	if err != nil || len(commands) != 2 {
+		t.Errorf("Expected both recorded commands to decrypt with the new key, got %+v (err: %v)", commands, err)
+	}
+}
+
+func TestSQLiteStorage_SaveDuringRekey(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	t.Setenv(KeyFileEnv, "")
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+
+	admin := NewSQLiteStorage(dbPath)
+	if err := admin.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer admin.Close()
+
+	recorder := NewSQLiteStorage(dbPath)
+	if err := recorder.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer recorder.Close()
+
+	var seed []history.CommandRecord
+	for i := 0; i < 100; i++ {
+		seed = append(seed, createTestCommand(fmt.Sprintf("seed-%d", i), "make build", "/seed", history.Bash))
+	}
+	if err := admin.BatchSaveCommands(seed); err != nil {
+		t.Fatalf("BatchSaveCommands failed: %v", err)
+	}
+	if _, err := admin.EnableEncryption(KeySource{KeyFile: filepath.Join(dir, "first.key")}); err != nil {
+		t.Fatalf("EnableEncryption failed: %v", err)
+	}
+
+	// The recorder keeps saving from its own handle while the admin rekeys
+	const saves = 40
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < saves; i++ {
+			if err := recorder.SaveCommand(createTestCommand(fmt.Sprintf("rec-%d", i), "go test ./...", "/repo", history.Bash)); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	for i := 0; i < 3; i++ {
+		if _, err := admin.Rekey(KeySource{KeyFile: filepath.Join(dir, fmt.Sprintf("key-%d", i))}); err != nil {
+			t.Fatalf("Rekey failed: %v", err)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("SaveCommand during rekey failed: %v", err)
+	}
+
+	for id, value := range rawCommandValues(t, admin) {
+		if !strings.HasPrefix(value, sealedPrefix) {
+			t.Errorf("Command %s stored in plaintext: %q", id, value)
+		}
+	}
+	commands, err := admin.GetCommandsByDirectory("/repo")
+	if err != nil || len(commands) != saves {
+		t.Errorf("Expected all %d saved commands to decrypt with the final key, got %d (err: %v)", saves, len(commands), err)
+	}
+}
+
+func TestPlaintextBackups(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	t.Setenv(KeyFileEnv, "")
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.db")
+
+	storage := NewSQLiteStorage(dbPath)
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	defer storage.Close()
+	if err := storage.SaveCommand(createTestCommand("bak-1", "export TOKEN=secret", "/repo", history.Bash)); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+
+	// Upgrading the plaintext database writes a plaintext backup
+	if err := storage.MigrateTo(encryptionSchemaVersion); err != nil {
+		t.Fatalf("MigrateTo(%d) failed: %v", encryptionSchemaVersion, err)
+	}
+	if err := storage.MigrateTo(LatestSchemaVersion()); err != nil {
+		t.Fatalf("MigrateTo(latest) failed: %v", err)
+	}
+	plaintextBackup := storage.LastBackupPath()
+
+	// Backups taken once it is encrypted hold only sealed commands
+	if _, err := storage.EnableEncryption(KeySource{KeyFile: filepath.Join(dir, "db.key")}); err != nil {
+		t.Fatalf("EnableEncryption failed: %v", err)
+	}
+	if err := storage.MigrateTo(encryptionSchemaVersion + 1); err != nil {
+		t.Fatalf("MigrateTo(%d) failed: %v", encryptionSchemaVersion+1, err)
+	}
+	if err := storage.MigrateTo(LatestSchemaVersion()); err != nil {
+		t.Fatalf("MigrateTo(latest) failed: %v", err)
+	}
+	if sealed, err := backupSealed(storage.LastBackupPath()); err != nil || !sealed {
+		t.Errorf("Expected the backup of the encrypted database to be sealed (err: %v)", err)
+	}
+
+	backups, err := PlaintextBackups(dbPath)
+	if err != nil {
+		t.Fatalf("PlaintextBackups failed: %v", err)
+	}
+	if !reflect.DeepEqual(backups, []string{plaintextBackup}) {
+		t.Errorf("Expected only %s to be listed, got %v", plaintextBackup, backups)
+	}
+}
+
+func TestSQLiteStorage_EncryptedUsageStats(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var records []history.CommandRecord
+	for i := 0; i < 12; i++ {
+		command := []string{"go test ./...", "go build", "make lint"}[i%3]
+		record := createTestCommand(fmt.Sprintf("usage-%d", i), command, "/repo", history.Bash)
+		record.Duration = time.Duration(i%5) * time.Second
+		record.ExitCode = i % 4
+		records = append(records, record)
+	}
+	if err := storage.BatchSaveCommands(records); err != nil {
+		t.Fatalf("BatchSaveCommands failed: %v", err)
+	}
+
+	plain, err := storage.GetUsageStats(StatsQuery{})
+	if err != nil {
+		t.Fatalf("GetUsageStats failed: %v", err)
+	}
+	if _, err := storage.EnableEncryption(KeySource{KeyFile: filepath.Join(t.TempDir(), "db.key")}); err != nil {
+		t.Fatalf("EnableEncryption failed: %v", err)
+	}
+	sealed, err := storage.GetUsageStats(StatsQuery{})
+	if err != nil {
+		t.Fatalf("GetUsageStats on the encrypted database failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(plain.TopCommands, sealed.TopCommands) ||
+		!reflect.DeepEqual(plain.TopBaseCommands, sealed.TopBaseCommands) ||
+		!reflect.DeepEqual(plain.FailingCommands, sealed.FailingCommands) ||
+		!reflect.DeepEqual(plain.SlowestCommands, sealed.SlowestCommands) {
+		t.Errorf("Encrypted rankings differ:\nplain:  %+v\nsealed: %+v", plain, sealed)
+	}
+}
+
+func TestParseFullTextQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		command string
+		match   bool
+	}{
+		{"git push", "git push origin", true},
+		{"push git", "git push origin", true},
+		{`"push git"`, "git push origin", false},
+		{`"git push"`, "git  push origin", true},
+		{"dock*", "docker ps", true},
+		{"dock", "docker ps", false},
+		{"git AND status", "git status", true},
+		{"kubectl", "git status", false},
+	}
+
+	for _, tt := range tests {
+		terms, err := parseFullTextQuery(tt.query)
+		if err != nil {
+			t.Errorf("parseFullTextQuery(%q) failed: %v", tt.query, err)
+			continue
+		}
+		if got := matchFullText(terms, tt.command); got != tt.match {
+			t.Errorf("%q matching %q = %v, want %v", tt.query, tt.command, got, tt.match)
+		}
+	}
+
+	for _, query := range []string{"", "git OR hg", "NOT git", `"open`, "col:git"} {
+		if _, err := parseFullTextQuery(query); err == nil {
+			t.Errorf("Expected parseFullTextQuery(%q) to fail", query)
+		}
+	}
+}
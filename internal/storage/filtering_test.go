@@ -162,6 +162,15 @@ func TestFilterCommands(t *testing.T) {
 			expectedCount: 2,
 			expectedFirst: "git status",
 		},
+		{
+			name: "Filter by full-text query",
+			filters: CommandFilters{
+				FullTextQuery: "npm AND (install OR test)",
+				ShellType:     history.PowerShell,
+			},
+			expectedCount: 2,
+			expectedFirst: "npm install",
+		},
 	}
 
 	for _, tt := range tests {
@@ -404,3 +413,74 @@ func TestGetCommandsByTimeRange(t *testing.T) {
 func intPtr(i int) *int {
 	return &i
 }
+
+// TestFilterCommandsBySessionContext tests the host, user and environment filters
+func TestFilterCommandsBySessionContext(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	now := time.Now()
+	testCommands := []history.CommandRecord{
+		{ID: "1", Command: "make deploy", Directory: "/srv", Timestamp: now, Shell: history.Bash, Tags: []string{},
+			Hostname: "build-01", Username: "alice", TTY: "/dev/pts/3", SSH: true, KubeContext: "prod-eu"},
+		{ID: "2", Command: "pytest", Directory: "/srv", Timestamp: now.Add(-time.Minute), Shell: history.Bash, Tags: []string{},
+			Hostname: "laptop", Username: "alice", PythonEnv: "/srv/.venv", ContainerID: "0123456789ab"},
+		{ID: "3", Command: "ls", Directory: "/srv", Timestamp: now.Add(-2 * time.Minute), Shell: history.Zsh, Tags: []string{},
+			Hostname: "laptop", Username: "bob"},
+	}
+	for _, cmd := range testCommands {
+		if err := storage.SaveCommand(cmd); err != nil {
+			t.Fatalf("Failed to save command: %v", err)
+		}
+	}
+
+	ssh, local := true, false
+	tests := []struct {
+		name     string
+		filters  CommandFilters
+		expected []string
+	}{
+		{name: "Hostname", filters: CommandFilters{Hostname: "laptop"}, expected: []string{"pytest", "ls"}},
+		{name: "Username", filters: CommandFilters{Username: "alice"}, expected: []string{"make deploy", "pytest"}},
+		{name: "Hostname and username", filters: CommandFilters{Hostname: "laptop", Username: "bob"}, expected: []string{"ls"}},
+		{name: "TTY", filters: CommandFilters{TTY: "/dev/pts/3"}, expected: []string{"make deploy"}},
+		{name: "Over SSH", filters: CommandFilters{SSH: &ssh}, expected: []string{"make deploy"}},
+		{name: "Not over SSH", filters: CommandFilters{SSH: &local}, expected: []string{"pytest", "ls"}},
+		{name: "Container", filters: CommandFilters{ContainerID: "0123456789ab"}, expected: []string{"pytest"}},
+		{name: "Python environment", filters: CommandFilters{PythonEnv: "/srv/.venv"}, expected: []string{"pytest"}},
+		{name: "Kubernetes context", filters: CommandFilters{KubeContext: "prod-eu"}, expected: []string{"make deploy"}},
+		{name: "No match", filters: CommandFilters{Hostname: "unknown"}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := storage.FilterCommands(tt.filters)
+			if err != nil {
+				t.Fatalf("FilterCommands failed: %v", err)
+			}
+
+			var commands []string
+			for _, result := range results {
+				commands = append(commands, result.Command)
+			}
+			if len(commands) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, commands)
+			}
+			for i := range commands {
+				if commands[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, commands)
+				}
+			}
+		})
+	}
+
+	// The context survives a round trip through the database
+	results, err := storage.FilterCommands(CommandFilters{Command: "make deploy"})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("FilterCommands failed: %v", err)
+	}
+	got := results[0]
+	if got.Hostname != "build-01" || got.Username != "alice" || got.TTY != "/dev/pts/3" || !got.SSH || got.KubeContext != "prod-eu" {
+		t.Errorf("Expected the session context to be stored, got %+v", got)
+	}
+}
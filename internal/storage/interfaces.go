@@ -48,12 +48,126 @@ type BatchStorageEngine interface {
 	BatchSaveCommands(commands []history.CommandRecord) error
 }
 
+// ImportStorageEngine extends BatchStorageEngine with ID lookups for deduplicated imports
+type ImportStorageEngine interface {
+	BatchStorageEngine
+
+	// ExistingCommandIDs reports which of the given IDs are already stored
+	ExistingCommandIDs(ids []string) (map[string]bool, error)
+}
+
+// StreamingStorageEngine extends StorageEngine with row-by-row filtered reads
+type StreamingStorageEngine interface {
+	StorageEngine
+
+	// StreamCommands calls fn for every command matching the filters, oldest first
+	StreamCommands(filters CommandFilters, fn func(history.CommandRecord) error) error
+}
+
+// PagedStorageEngine extends StorageEngine with keyset-paginated reads, so
+// large histories can be loaded a page at a time
+type PagedStorageEngine interface {
+	StorageEngine
+
+	// GetCommandsPage returns up to limit commands matching the filters,
+	// newest first, continuing after cursor ("" for the first page)
+	GetCommandsPage(filters CommandFilters, cursor string, limit int) (*CommandPage, error)
+}
+
+// RewriteStorageEngine extends StreamingStorageEngine with in-place rewrites of
+// stored command text, used to scrub secrets from existing history
+type RewriteStorageEngine interface {
+	StreamingStorageEngine
+
+	// RewriteCommands replaces the command text and tags of stored records,
+	// matched by ID, in a single transaction
+	RewriteCommands(commands []history.CommandRecord) error
+
+	// PurgeOldData discards previous row contents that SQLite still keeps in
+	// free pages and the full-text index
+	PurgeOldData() error
+}
+
+// EditableStorageEngine extends StorageEngine with deleting and editing
+// stored commands
+type EditableStorageEngine interface {
+	StorageEngine
+
+	// DeleteCommand removes the command with the given ID
+	DeleteCommand(id string) error
+
+	// UpdateCommand replaces the stored record with the same ID
+	UpdateCommand(cmd history.CommandRecord) error
+
+	// DeleteCommands removes the commands with the given IDs and returns how
+	// many were removed
+	DeleteCommands(ids []string) (int64, error)
+
+	// UpdateTags adds and removes tags on the commands with the given IDs and
+	// returns how many commands changed
+	UpdateTags(ids []string, add []string, remove []string) (int64, error)
+}
+
+// SyncStorageEngine extends EditableStorageEngine with the state kept between
+// history syncs with other machines
+type SyncStorageEngine interface {
+	EditableStorageEngine
+
+	// BatchSaveCommands saves multiple commands in a single transaction
+	BatchSaveCommands(commands []history.CommandRecord) error
+
+	// StreamCommands calls fn for every command matching the filters, oldest first
+	StreamCommands(filters CommandFilters, fn func(history.CommandRecord) error) error
+
+	// SyncMachineID returns the ID naming this database's change log
+	SyncMachineID() (string, error)
+
+	// SyncedCommands returns the state recorded by the last sync, by command ID
+	SyncedCommands() (map[string]SyncedCommand, error)
+
+	// SaveSyncedCommands records the state agreed on by a sync and forgets
+	// the commands with the given IDs
+	SaveSyncedCommands(synced []SyncedCommand, forget []string) error
+}
+
+// RetentionStorageEngine extends StorageEngine with cleanup limited to
+// specific directories, so projects can keep history for different periods
+type RetentionStorageEngine interface {
+	StorageEngine
+
+	// CleanupDirectoryCommands removes commands run in the given directories
+	// that are older than retentionDays and returns how many were removed
+	CleanupDirectoryCommands(directories []string, retentionDays int) (int64, error)
+}
+
+// FullTextStorageEngine extends StorageEngine with full-text search
+type FullTextStorageEngine interface {
+	StorageEngine
+
+	// SearchCommandsFTS finds commands matching an FTS5 query, ranked by relevance
+	SearchCommandsFTS(query string, dir string, limit int) ([]history.CommandRecord, error)
+}
+
+// SessionStorageEngine extends StorageEngine with shell session queries
+type SessionStorageEngine interface {
+	StorageEngine
+
+	// GetCommandsBySession retrieves all commands recorded by a shell session
+	GetCommandsBySession(sessionID string) ([]history.CommandRecord, error)
+
+	// GetSessions returns recorded shell sessions ordered by most recent activity
+	GetSessions(limit int) ([]history.SessionIndex, error)
+}
+
 // StatsStorageEngine extends StorageEngine with statistics operations
 type StatsStorageEngine interface {
 	StorageEngine
 
 	// GetDirectoryStats returns directory statistics
 	GetDirectoryStats() ([]history.DirectoryIndex, error)
+
+	// GetUsageStats aggregates command usage over a time range and directory subtree
+	GetUsageStats(query StatsQuery) (*UsageStats, error)
 }
 
 // NewStorageEngine creates a new storage engine based on the storage type
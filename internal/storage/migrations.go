@@ -0,0 +1,429 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Migration describes a single versioned schema change. Up moves the schema
+// from Version-1 to Version and Down reverts it; an empty Down marks the
+// migration as irreversible.
+type Migration struct {
+	Version     int
+	Description string
+	Up          string
+	Down        string
+}
+
+// MigrationStatus reports whether a registered migration has been applied
+type MigrationStatus struct {
+	Version     int
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+}
+
+// migrations is the ordered registry of schema migrations. Versions must be
+// contiguous starting at 1. Append new migrations to the end and never edit
+// one that has been released.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "initial schema",
+		Up: `
+		CREATE TABLE IF NOT EXISTS commands (
+			id TEXT PRIMARY KEY,
+			command TEXT NOT NULL,
+			directory TEXT NOT NULL,
+			timestamp DATETIME NOT NULL,
+			shell INTEGER NOT NULL,
+			exit_code INTEGER NOT NULL DEFAULT 0,
+			duration INTEGER NOT NULL DEFAULT 0,
+			tags TEXT DEFAULT '',
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_commands_directory ON commands(directory);
+		CREATE INDEX IF NOT EXISTS idx_commands_timestamp ON commands(timestamp);
+		CREATE INDEX IF NOT EXISTS idx_commands_dir_timestamp ON commands(directory, timestamp DESC);
+		CREATE INDEX IF NOT EXISTS idx_commands_shell ON commands(shell);
+		CREATE INDEX IF NOT EXISTS idx_commands_command ON commands(command);
+
+		CREATE TABLE IF NOT EXISTS directory_stats (
+			path TEXT PRIMARY KEY,
+			command_count INTEGER NOT NULL DEFAULT 0,
+			last_used DATETIME NOT NULL,
+			is_active BOOLEAN NOT NULL DEFAULT 1,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		`,
+	},
+	{
+		Version:     2,
+		Description: "full-text index over commands",
+		Up: `
+		CREATE VIRTUAL TABLE IF NOT EXISTS commands_fts USING fts5(
+			command,
+			content='commands',
+			content_rowid='rowid'
+		);
+
+		CREATE TRIGGER IF NOT EXISTS commands_fts_insert AFTER INSERT ON commands BEGIN
+			INSERT INTO commands_fts(rowid, command) VALUES (new.rowid, new.command);
+		END;
+
+		CREATE TRIGGER IF NOT EXISTS commands_fts_delete AFTER DELETE ON commands BEGIN
+			INSERT INTO commands_fts(commands_fts, rowid, command) VALUES ('delete', old.rowid, old.command);
+		END;
+
+		CREATE TRIGGER IF NOT EXISTS commands_fts_update AFTER UPDATE OF command ON commands BEGIN
+			INSERT INTO commands_fts(commands_fts, rowid, command) VALUES ('delete', old.rowid, old.command);
+			INSERT INTO commands_fts(rowid, command) VALUES (new.rowid, new.command);
+		END;
+
+		-- Index rows recorded before this migration
+		INSERT INTO commands_fts(commands_fts) VALUES ('rebuild');
+		`,
+		Down: `
+		DROP TRIGGER IF EXISTS commands_fts_update;
+		DROP TRIGGER IF EXISTS commands_fts_delete;
+		DROP TRIGGER IF EXISTS commands_fts_insert;
+		DROP TABLE IF EXISTS commands_fts;
+		`,
+	},
+	{
+		Version:     3,
+		Description: "shell session id and pid",
+		Up: `
+		ALTER TABLE commands ADD COLUMN session_id TEXT NOT NULL DEFAULT '';
+		ALTER TABLE commands ADD COLUMN shell_pid INTEGER NOT NULL DEFAULT 0;
+
+		CREATE INDEX IF NOT EXISTS idx_commands_session ON commands(session_id, timestamp DESC);
+		`,
+		Down: `
+		DROP INDEX IF EXISTS idx_commands_session;
+
+		ALTER TABLE commands DROP COLUMN shell_pid;
+		ALTER TABLE commands DROP COLUMN session_id;
+		`,
+	},
+	{
+		Version:     4,
+		Description: "keyset pagination indexes",
+		Up: `
+		CREATE INDEX IF NOT EXISTS idx_commands_dir_timestamp_id ON commands(directory, timestamp DESC, id DESC);
+		CREATE INDEX IF NOT EXISTS idx_commands_timestamp_id ON commands(timestamp DESC, id DESC);
+		`,
+		Down: `
+		DROP INDEX IF EXISTS idx_commands_timestamp_id;
+		DROP INDEX IF EXISTS idx_commands_dir_timestamp_id;
+		`,
+	},
+	{
+		Version:     5,
+		Description: "encryption settings",
+		Up: `
+		CREATE TABLE IF NOT EXISTS encryption (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			kdf TEXT NOT NULL,
+			salt BLOB,
+			time_cost INTEGER NOT NULL DEFAULT 0,
+			memory_cost INTEGER NOT NULL DEFAULT 0,
+			threads INTEGER NOT NULL DEFAULT 0,
+			key_file TEXT NOT NULL DEFAULT '',
+			key_check TEXT NOT NULL
+		);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS encryption;
+		`,
+	},
+	{
+		Version:     6,
+		Description: "hostname and sync state",
+		Up: `
+		ALTER TABLE commands ADD COLUMN hostname TEXT NOT NULL DEFAULT '';
+
+		CREATE TABLE IF NOT EXISTS sync_state (
+			id TEXT PRIMARY KEY,
+			tags TEXT NOT NULL DEFAULT '',
+			fingerprint TEXT NOT NULL DEFAULT '',
+			timestamp DATETIME NOT NULL,
+			deleted INTEGER NOT NULL DEFAULT 0
+		);
+
+		CREATE TABLE IF NOT EXISTS sync_meta (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS sync_meta;
+		DROP TABLE IF EXISTS sync_state;
+
+		ALTER TABLE commands DROP COLUMN hostname;
+		`,
+	},
+	{
+		Version:     7,
+		Description: "session context columns",
+		Up: `
+		ALTER TABLE commands ADD COLUMN username TEXT NOT NULL DEFAULT '';
+		ALTER TABLE commands ADD COLUMN tty TEXT NOT NULL DEFAULT '';
+		ALTER TABLE commands ADD COLUMN ssh INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE commands ADD COLUMN container_id TEXT NOT NULL DEFAULT '';
+		ALTER TABLE commands ADD COLUMN python_env TEXT NOT NULL DEFAULT '';
+		ALTER TABLE commands ADD COLUMN kube_context TEXT NOT NULL DEFAULT '';
+
+		CREATE INDEX IF NOT EXISTS idx_commands_hostname ON commands(hostname, timestamp DESC);
+		`,
+		Down: `
+		DROP INDEX IF EXISTS idx_commands_hostname;
+
+		ALTER TABLE commands DROP COLUMN kube_context;
+		ALTER TABLE commands DROP COLUMN python_env;
+		ALTER TABLE commands DROP COLUMN container_id;
+		ALTER TABLE commands DROP COLUMN ssh;
+		ALTER TABLE commands DROP COLUMN tty;
+		ALTER TABLE commands DROP COLUMN username;
+		`,
+	},
+}
+
+// LatestSchemaVersion returns the newest schema version known to this build
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrations returns a copy of the migration registry in version order
+func Migrations() []Migration {
+	result := make([]Migration, len(migrations))
+	copy(result, migrations)
+	return result
+}
+
+// ensureVersionTable creates the schema_version bookkeeping table
+func (s *SQLiteStorage) ensureVersionTable() error {
+	createVersionTable := `CREATE TABLE IF NOT EXISTS schema_version (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)`
+
+	if _, err := s.db.Exec(createVersionTable); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the highest migration version applied to the database
+func (s *SQLiteStorage) SchemaVersion() (int, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	var version int
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+// MigrationStatus returns the applied state of every registered migration
+func (s *SQLiteStorage) MigrationStatus() ([]MigrationStatus, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := s.db.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema versions: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema version: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema versions: %w", err)
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:     migration.Version,
+			Description: migration.Description,
+			Applied:     ok,
+			AppliedAt:   appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// MigrateTo upgrades or downgrades the schema to the target version. Each
+// migration runs in its own transaction together with its schema_version
+// bookkeeping. Before upgrading a database that already holds history, a
+// copy is written next to the database file (see LastBackupPath).
+func (s *SQLiteStorage) MigrateTo(target int) error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	latest := LatestSchemaVersion()
+	if target < 0 || target > latest {
+		return fmt.Errorf("target schema version %d out of range (0-%d)", target, latest)
+	}
+
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than the latest supported version %d", current, latest)
+	}
+
+	switch {
+	case target > current:
+		hasHistory, err := s.hasHistory(current)
+		if err != nil {
+			return err
+		}
+		if hasHistory {
+			if _, err := s.backupDatabase(current); err != nil {
+				return err
+			}
+		}
+
+		for _, migration := range migrations {
+			if migration.Version <= current || migration.Version > target {
+				continue
+			}
+			if err := s.applyMigration(migration, true); err != nil {
+				return err
+			}
+		}
+	case target < current:
+		// Refuse up front rather than stopping halfway at an irreversible step
+		for _, migration := range migrations {
+			if migration.Version > target && migration.Version <= current && migration.Down == "" {
+				return fmt.Errorf("migration %d (%s) cannot be reverted", migration.Version, migration.Description)
+			}
+		}
+
+		// Dropping the encryption settings would make sealed commands unreadable
+		if target < encryptionSchemaVersion && current >= encryptionSchemaVersion {
+			settings, err := s.readEncryptionSettings(s.db)
+			if err != nil {
+				return err
+			}
+			if settings != nil {
+				return fmt.Errorf("database is encrypted: run 'tracker db decrypt' before reverting migration %d", encryptionSchemaVersion)
+			}
+		}
+
+		for i := len(migrations) - 1; i >= 0; i-- {
+			migration := migrations[i]
+			if migration.Version > current || migration.Version <= target {
+				continue
+			}
+			if err := s.applyMigration(migration, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs one migration step and records it in schema_version
+func (s *SQLiteStorage) applyMigration(migration Migration, up bool) error {
+	script := migration.Up
+	if !up {
+		if migration.Down == "" {
+			return fmt.Errorf("migration %d (%s) cannot be reverted", migration.Version, migration.Description)
+		}
+		script = migration.Down
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	if _, err := tx.Exec(script); err != nil {
+		if up {
+			return fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
+		}
+		return fmt.Errorf("failed to revert migration %d: %w", migration.Version, err)
+	}
+
+	if up {
+		_, err = tx.Exec("INSERT INTO schema_version (version) VALUES (?)", migration.Version)
+	} else {
+		_, err = tx.Exec("DELETE FROM schema_version WHERE version = ?", migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+
+	return nil
+}
+
+// hasHistory reports whether the database holds data worth backing up
+func (s *SQLiteStorage) hasHistory(version int) (bool, error) {
+	if version > 0 {
+		return true, nil
+	}
+
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'commands'").Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect schema: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// backupDatabase writes a consistent copy of the database before an upgrade
+func (s *SQLiteStorage) backupDatabase(version int) (string, error) {
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", s.dbPath, version, time.Now().Format("20060102-150405"))
+
+	// VACUUM INTO refuses to overwrite, so clear out a same-second leftover
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to remove stale backup: %w", err)
+	}
+
+	if _, err := s.db.Exec("VACUUM INTO ?", backupPath); err != nil {
+		return "", fmt.Errorf("failed to back up database before migration: %w", err)
+	}
+
+	s.lastBackupPath = backupPath
+	return backupPath, nil
+}
+
+// LastBackupPath returns the file written by the most recent pre-upgrade
+// backup, or an empty string if no backup was taken
+func (s *SQLiteStorage) LastBackupPath() string {
+	return s.lastBackupPath
+}
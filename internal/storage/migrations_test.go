@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// createFixtureDatabase builds a database from a SQL fixture in testdata
+func createFixtureDatabase(t *testing.T, fixture string) string {
+	t.Helper()
+
+	script, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("Failed to read fixture %s: %v", fixture, err)
+	}
+
+	dbPath := filepath.Join(t.TempDir(), "commands.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open fixture database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(string(script)); err != nil {
+		t.Fatalf("Failed to load fixture %s: %v", fixture, err)
+	}
+
+	return dbPath
+}
+
+// tableExists reports whether a table or virtual table is present
+func tableExists(t *testing.T, s *SQLiteStorage, name string) bool {
+	t.Helper()
+
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to inspect schema: %v", err)
+	}
+	return count > 0
+}
+
+func TestMigrations_RegistryIsContiguous(t *testing.T) {
+	for i, migration := range Migrations() {
+		if migration.Version != i+1 {
+			t.Errorf("Migration at index %d has version %d, expected %d", i, migration.Version, i+1)
+		}
+		if migration.Up == "" {
+			t.Errorf("Migration %d has no up script", migration.Version)
+		}
+		if migration.Description == "" {
+			t.Errorf("Migration %d has no description", migration.Version)
+		}
+	}
+}
+
+func TestMigrations_UpgradeFixtures(t *testing.T) {
+	tests := []struct {
+		name          string
+		fixture       string
+		version       int
+		expectBackup  bool
+		expectedCount int
+	}{
+		{
+			name:          "Schema version 1",
+			fixture:       "schema_v1.sql",
+			version:       1,
+			expectBackup:  true,
+			expectedCount: 3,
+		},
+		{
+			name:          "Schema version 2",
+			fixture:       "schema_v2.sql",
+			version:       2,
+			expectBackup:  LatestSchemaVersion() > 2,
+			expectedCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbPath := createFixtureDatabase(t, tt.fixture)
+
+			storage := NewSQLiteStorage(dbPath)
+			if err := storage.Open(); err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			version, err := storage.SchemaVersion()
+			if err != nil {
+				t.Fatalf("SchemaVersion failed: %v", err)
+			}
+			if version != tt.version {
+				t.Fatalf("Expected fixture at version %d, got %d", tt.version, version)
+			}
+			storage.Close()
+
+			storage = NewSQLiteStorage(dbPath)
+			if err := storage.Initialize(); err != nil {
+				t.Fatalf("Initialize failed: %v", err)
+			}
+			defer storage.Close()
+
+			version, err = storage.SchemaVersion()
+			if err != nil {
+				t.Fatalf("SchemaVersion failed: %v", err)
+			}
+			if version != LatestSchemaVersion() {
+				t.Errorf("Expected schema version %d, got %d", LatestSchemaVersion(), version)
+			}
+
+			// Existing history survives the upgrade
+			dirs, err := storage.GetDirectoriesWithHistory()
+			if err != nil {
+				t.Fatalf("GetDirectoriesWithHistory failed: %v", err)
+			}
+			var total int
+			for _, dir := range dirs {
+				commands, err := storage.GetCommandsByDirectory(dir)
+				if err != nil {
+					t.Fatalf("GetCommandsByDirectory failed: %v", err)
+				}
+				total += len(commands)
+			}
+			if total != tt.expectedCount {
+				t.Errorf("Expected %d commands after upgrade, got %d", tt.expectedCount, total)
+			}
+
+			// Rows from the fixture are reachable through the full-text index
+			results, err := storage.SearchCommandsFTS("docker", "", 0)
+			if err != nil {
+				t.Fatalf("SearchCommandsFTS failed: %v", err)
+			}
+			if len(results) != 1 || results[0].ID != "fixture-2" {
+				t.Errorf("Expected fixture-2 from full-text search, got %v", results)
+			}
+			if len(results) == 1 && (results[0].Shell != history.Bash || len(results[0].Tags) != 1) {
+				t.Errorf("Fixture record not decoded correctly: %+v", results[0])
+			}
+
+			backup := storage.LastBackupPath()
+			if tt.expectBackup {
+				if backup == "" {
+					t.Fatal("Expected a backup before upgrading")
+				}
+				assertBackupAtVersion(t, backup, tt.version)
+			} else if backup != "" {
+				t.Errorf("Expected no backup for an up-to-date database, got %s", backup)
+			}
+		})
+	}
+}
+
+// assertBackupAtVersion checks that a backup file holds the pre-upgrade schema
+func assertBackupAtVersion(t *testing.T, path string, version int) {
+	t.Helper()
+
+	backup := NewSQLiteStorage(path)
+	if err := backup.Open(); err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer backup.Close()
+
+	backupVersion, err := backup.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion on backup failed: %v", err)
+	}
+	if backupVersion != version {
+		t.Errorf("Expected backup at version %d, got %d", version, backupVersion)
+	}
+
+	// Query with the old schema's columns only
+	var count int
+	if err := backup.db.QueryRow("SELECT COUNT(*) FROM commands WHERE directory = ?", "/home/dev/project").Scan(&count); err != nil {
+		t.Fatalf("Failed to count commands in backup: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 commands in backup, got %d", count)
+	}
+}
+
+func TestMigrations_FreshDatabaseSkipsBackup(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	if backup := storage.LastBackupPath(); backup != "" {
+		t.Errorf("Expected no backup for a new database, got %s", backup)
+	}
+}
+
+func TestMigrations_DownAndUp(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	cmd := createTestCommand("mig-1", "kubectl get pods", "/cluster", history.Zsh)
+	if err := storage.SaveCommand(cmd); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+
+	if err := storage.MigrateTo(1); err != nil {
+		t.Fatalf("MigrateTo(1) failed: %v", err)
+	}
+
+	if tableExists(t, storage, "commands_fts") {
+		t.Error("Expected commands_fts to be dropped after reverting migration 2")
+	}
+
+	statuses, err := storage.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+	for _, status := range statuses {
+		if expected := status.Version <= 1; status.Applied != expected {
+			t.Errorf("Migration %d applied = %v, expected %v", status.Version, status.Applied, expected)
+		}
+	}
+
+	// Data written by an older release is indexed after upgrading
+	insertLegacy := `INSERT INTO commands (id, command, directory, timestamp, shell) VALUES (?, ?, ?, ?, ?)`
+	if _, err := storage.db.Exec(insertLegacy, "mig-2", "kubectl logs api", "/cluster", time.Now(), int(history.Zsh)); err != nil {
+		t.Fatalf("Insert on reverted schema failed: %v", err)
+	}
+
+	if err := storage.MigrateTo(LatestSchemaVersion()); err != nil {
+		t.Fatalf("MigrateTo(latest) failed: %v", err)
+	}
+
+	results, err := storage.SearchCommandsFTS("kubectl", "/cluster", 0)
+	if err != nil {
+		t.Fatalf("SearchCommandsFTS failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 indexed commands after re-upgrade, got %d", len(results))
+	}
+
+	if storage.LastBackupPath() == "" {
+		t.Error("Expected a backup before re-upgrading a database with history")
+	}
+}
+
+func TestMigrations_InvalidTargets(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	tests := []struct {
+		name   string
+		target int
+	}{
+		{name: "Negative version", target: -1},
+		{name: "Beyond latest", target: LatestSchemaVersion() + 1},
+		{name: "Irreversible initial schema", target: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := storage.MigrateTo(tt.target); err == nil {
+				t.Errorf("Expected error migrating to %d", tt.target)
+			}
+
+			version, err := storage.SchemaVersion()
+			if err != nil {
+				t.Fatalf("SchemaVersion failed: %v", err)
+			}
+			if version != LatestSchemaVersion() {
+				t.Errorf("Expected schema to stay at %d, got %d", LatestSchemaVersion(), version)
+			}
+		})
+	}
+
+	// The irreversible step stops the downgrade before touching the schema
+	if !tableExists(t, storage, "commands") {
+		t.Error("Expected commands table to survive a rejected downgrade")
+	}
+}
+
+func TestMigrations_FailedMigrationRollsBack(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	original := migrations
+	defer func() { migrations = original }()
+
+	latest := LatestSchemaVersion()
+	migrations = append(append([]Migration{}, original...), Migration{
+		Version:     latest + 1,
+		Description: "broken migration",
+		Up: `
+		CREATE TABLE partial_migration (id INTEGER);
+		ALTER TABLE missing_table ADD COLUMN broken TEXT;
+		`,
+	})
+
+	if err := storage.MigrateTo(latest + 1); err == nil {
+		t.Fatal("Expected broken migration to fail")
+	}
+
+	version, err := storage.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if version != latest {
+		t.Errorf("Expected schema to stay at %d, got %d", latest, version)
+	}
+
+	if tableExists(t, storage, "partial_migration") {
+		t.Error("Expected partial changes to be rolled back")
+	}
+}
+
+func TestInitialize_RejectsNewerSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "commands.db")
+
+	storage := NewSQLiteStorage(dbPath)
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if _, err := storage.db.Exec("INSERT INTO schema_version (version) VALUES (?)", LatestSchemaVersion()+1); err != nil {
+		t.Fatalf("Failed to bump schema version: %v", err)
+	}
+	storage.Close()
+
+	storage = NewSQLiteStorage(dbPath)
+	defer storage.Close()
+	if err := storage.Initialize(); err == nil {
+		t.Error("Expected Initialize to reject a schema from a newer release")
+	}
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// DefaultPageSize is the number of commands GetCommandsPage returns when no
+// limit is given
+const DefaultPageSize = 500
+
+// CommandPage is one page of commands, newest first
+type CommandPage struct {
+	Commands []history.CommandRecord
+	// NextCursor continues after the last command of the page; it is empty
+	// on the last page
+	NextCursor string
+}
+
+// pageCursor is the sort key of the last command of a page. The timestamp
+// is kept as stored so it compares exactly like ORDER BY does.
+type pageCursor struct {
+	timestamp string
+	id        string
+}
+
+// encode returns the cursor as an opaque string
+func (c pageCursor) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.timestamp + "|" + c.id))
+}
+
+// parseCursor decodes a cursor returned in CommandPage.NextCursor
+func parseCursor(cursor string) (pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	timestamp, id, ok := strings.Cut(string(data), "|")
+	if !ok || timestamp == "" || id == "" {
+		return pageCursor{}, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return pageCursor{timestamp: timestamp, id: id}, nil
+}
+
+// GetCommandsPage returns up to limit commands matching the filters, newest
+// first, continuing after cursor; an empty cursor starts with the newest
+// command. Pages are keyed on (timestamp, id), so commands recorded while
+// paging do not shift later pages. filters.Limit is ignored.
+func (s *SQLiteStorage) GetCommandsPage(filters CommandFilters, cursor string, limit int) (*CommandPage, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	sqlFilters, match, err := s.splitContentFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	query, args := buildFilterWhere(sqlFilters)
+	if cursor != "" {
+		after, err := parseCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND (timestamp < ? OR (timestamp = ? AND id < ?))`
+		args = append(args, after.timestamp, after.timestamp, after.id)
+	}
+
+	// One extra row tells whether another page follows. Filters on sealed
+	// command text are matched while scanning, so SQL cannot limit the rows.
+	query += ` ORDER BY timestamp DESC, id DESC`
+	if match == nil {
+		query += ` LIMIT ?`
+		args = append(args, limit+1)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to page commands: %w", err)
+	}
+	defer rows.Close()
+
+	commands, err := s.scanMatching(rows, match, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	page := &CommandPage{Commands: commands}
+	if len(commands) > limit {
+		page.Commands = commands[:limit]
+		last := page.Commands[limit-1]
+		next, err := s.cursorFor(last.ID)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = next.encode()
+	}
+	if page.Commands == nil {
+		page.Commands = []history.CommandRecord{}
+	}
+
+	return page, nil
+}
+
+// cursorFor reads the stored sort key of a command
+func (s *SQLiteStorage) cursorFor(id string) (pageCursor, error) {
+	var timestamp string
+	err := s.db.QueryRow(`SELECT CAST(timestamp AS TEXT) FROM commands WHERE id = ?`, id).Scan(&timestamp)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pageCursor{}, fmt.Errorf("command not found: %s", id)
+	}
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("failed to read page cursor: %w", err)
+	}
+	return pageCursor{timestamp: timestamp, id: id}, nil
+}
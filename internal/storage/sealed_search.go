@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// commandMatch tests a decrypted command against the filters on command text
+type commandMatch func(cmd history.CommandRecord) bool
+
+// splitContentFilters moves the filters on command text, which SQL cannot
+// evaluate on sealed values, into an in-memory match. match is nil when the
+// database is not encrypted or no such filter is set; otherwise the returned
+// filters have no content filters and no limit, which the caller applies
+// after matching.
+func (s *SQLiteStorage) splitContentFilters(filters CommandFilters) (CommandFilters, commandMatch, error) {
+	if s.aead == nil || (filters.Command == "" && filters.Pattern == "" && filters.FullTextQuery == "") {
+		return filters, nil, nil
+	}
+
+	var terms []fullTextTerm
+	if filters.FullTextQuery != "" {
+		var err error
+		if terms, err = parseFullTextQuery(filters.FullTextQuery); err != nil {
+			return filters, nil, err
+		}
+	}
+
+	command, pattern := filters.Command, strings.ToLower(filters.Pattern)
+	match := func(cmd history.CommandRecord) bool {
+		if command != "" && cmd.Command != command {
+			return false
+		}
+		if pattern != "" && !strings.Contains(strings.ToLower(cmd.Command), pattern) {
+			return false
+		}
+		return terms == nil || matchFullText(terms, cmd.Command)
+	}
+
+	filters.Command, filters.Pattern, filters.FullTextQuery = "", "", ""
+	filters.Limit = 0
+	return filters, match, nil
+}
+
+// scanMatching scans rows like scanCommands, keeping the records that pass
+// match (all when nil) and stopping after limit of them (no limit when <= 0)
+func (s *SQLiteStorage) scanMatching(rows *sql.Rows, match commandMatch, limit int) ([]history.CommandRecord, error) {
+	var commands []history.CommandRecord
+	for rows.Next() {
+		cmd, err := s.scanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
+		if match != nil && !match(cmd) {
+			continue
+		}
+		commands = append(commands, cmd)
+		if limit > 0 && len(commands) == limit {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read commands: %w", err)
+	}
+	return commands, nil
+}
+
+// fullTextTerm is one bareword or "quoted phrase" of a full-text query
+type fullTextTerm struct {
+	tokens []string
+	prefix bool // the last token matches as a prefix (term*)
+}
+
+// parseFullTextQuery parses the subset of FTS5 query syntax evaluated in
+// memory for encrypted databases: terms that must all match, term* prefixes
+// and "quoted phrases". Other operators are rejected.
+func parseFullTextQuery(query string) ([]fullTextTerm, error) {
+	var terms []fullTextTerm
+	rest := strings.TrimSpace(query)
+
+	for rest != "" {
+		var word string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated phrase in full-text query %q", query)
+			}
+			word, rest = rest[1:end+1], rest[end+2:]
+			if strings.HasPrefix(rest, "*") {
+				word += "*"
+				rest = rest[1:]
+			}
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end == -1 {
+				end = len(rest)
+			}
+			word, rest = rest[:end], rest[end:]
+
+			switch word {
+			case "AND":
+				rest = strings.TrimSpace(rest)
+				continue
+			case "OR", "NOT", "NEAR":
+				return nil, fmt.Errorf("full-text operator %s is not supported on an encrypted database", word)
+			}
+			if strings.ContainsAny(word, "():^+") {
+				return nil, fmt.Errorf("full-text query syntax %q is not supported on an encrypted database", word)
+			}
+		}
+		rest = strings.TrimSpace(rest)
+
+		term := fullTextTerm{prefix: strings.HasSuffix(word, "*")}
+		term.tokens = tokenizeCommand(strings.TrimSuffix(word, "*"))
+		if len(term.tokens) > 0 {
+			terms = append(terms, term)
+		}
+	}
+
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("full-text query cannot be empty")
+	}
+	return terms, nil
+}
+
+// tokenizeCommand splits text into lower-case runs of letters and digits,
+// like the unicode61 tokenizer of the full-text index
+func tokenizeCommand(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchFullText reports whether every term occurs in command
+func matchFullText(terms []fullTextTerm, command string) bool {
+	tokens := tokenizeCommand(command)
+	for _, term := range terms {
+		if !containsPhrase(tokens, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsPhrase reports whether the tokens of term occur consecutively
+func containsPhrase(tokens []string, term fullTextTerm) bool {
+	last := len(term.tokens) - 1
+	for start := 0; start+last < len(tokens); start++ {
+		matched := true
+		for i, want := range term.tokens {
+			got := tokens[start+i]
+			if got != want && !(i == last && term.prefix && strings.HasPrefix(got, want)) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
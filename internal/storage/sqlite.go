@@ -1,10 +1,12 @@
 package storage
 
 import (
+	"crypto/cipher"
 	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,10 +14,22 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// commandColumns lists the commands table columns read by scanCommands, in scan order
+const commandColumns = `id, command, directory, timestamp, shell, exit_code, duration, tags, session_id, shell_pid, hostname, username, tty, ssh, container_id, python_env, kube_context`
+
+// qualifiedCommandColumns returns commandColumns prefixed with a table alias
+func qualifiedCommandColumns(alias string) string {
+	return alias + "." + strings.ReplaceAll(commandColumns, ", ", ", "+alias+".")
+}
+
 // SQLiteStorage implements the StorageEngine interface using SQLite
 type SQLiteStorage struct {
-	dbPath string
-	db     *sql.DB
+	dbPath         string
+	db             *sql.DB
+	lastBackupPath string
+	keys           *KeySource  // set by SetKeySource, otherwise read from the environment
+	aead           cipher.AEAD // seals command text; nil when the database is not encrypted
+	keyCheck       string      // key check value of aead, to notice a key changed by another process
 }
 
 // NewSQLiteStorage creates a new SQLite storage engine
@@ -25,8 +39,27 @@ func NewSQLiteStorage(dbPath string) *SQLiteStorage {
 	}
 }
 
-// Initialize opens the database connection and creates tables if needed
+// Initialize opens the database connection and upgrades the schema to the
+// latest version
 func (s *SQLiteStorage) Initialize() error {
+	if err := s.Open(); err != nil {
+		return err
+	}
+
+	// Run migrations
+	if err := s.runMigrations(); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	if err := s.loadEncryption(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Open opens the database connection without applying pending migrations
+func (s *SQLiteStorage) Open() error {
 	// Ensure directory exists (only if not using current directory)
 	if filepath.Dir(s.dbPath) != "." {
 		if err := os.MkdirAll(filepath.Dir(s.dbPath), 0755); err != nil {
@@ -63,119 +96,29 @@ func (s *SQLiteStorage) Initialize() error {
 		}
 	}
 
-	// Create tables and indexes
-	if err := s.createTables(); err != nil {
-		return fmt.Errorf("failed to create tables: %w", err)
-	}
-
-	// Run migrations
-	if err := s.runMigrations(); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	if err := s.ensureVersionTable(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-// createTables creates the necessary database tables and indexes
-func (s *SQLiteStorage) createTables() error {
-	// Create schema version table
-	createVersionTable := `CREATE TABLE IF NOT EXISTS schema_version (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)`
-
-	if _, err := s.db.Exec(createVersionTable); err != nil {
-		return fmt.Errorf("failed to create schema_version table: %w", err)
-	}
-
-	// Create commands table
-	createCommandsTable := `
-	CREATE TABLE IF NOT EXISTS commands (
-		id TEXT PRIMARY KEY,
-		command TEXT NOT NULL,
-		directory TEXT NOT NULL,
-		timestamp DATETIME NOT NULL,
-		shell INTEGER NOT NULL,
-		exit_code INTEGER NOT NULL DEFAULT 0,
-		duration INTEGER NOT NULL DEFAULT 0,
-		tags TEXT DEFAULT '',
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	if _, err := s.db.Exec(createCommandsTable); err != nil {
-		return fmt.Errorf("failed to create commands table: %w", err)
-	}
-
-	// Create indexes for performance
-	indexes := []string{
-		"CREATE INDEX IF NOT EXISTS idx_commands_directory ON commands(directory);",
-		"CREATE INDEX IF NOT EXISTS idx_commands_timestamp ON commands(timestamp);",
-		"CREATE INDEX IF NOT EXISTS idx_commands_dir_timestamp ON commands(directory, timestamp DESC);",
-		"CREATE INDEX IF NOT EXISTS idx_commands_shell ON commands(shell);",
-		"CREATE INDEX IF NOT EXISTS idx_commands_command ON commands(command);",
-	}
-
-	for _, indexSQL := range indexes {
-		if _, err := s.db.Exec(indexSQL); err != nil {
-			return fmt.Errorf("failed to create index: %w", err)
-		}
+// runMigrations applies any pending schema migrations
+func (s *SQLiteStorage) runMigrations() error {
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return err
 	}
 
-	// Create directory_stats table for optimization
-	createStatsTable := `
-	CREATE TABLE IF NOT EXISTS directory_stats (
-		path TEXT PRIMARY KEY,
-		command_count INTEGER NOT NULL DEFAULT 0,
-		last_used DATETIME NOT NULL,
-		is_active BOOLEAN NOT NULL DEFAULT 1,
-		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	if _, err := s.db.Exec(createStatsTable); err != nil {
-		return fmt.Errorf("failed to create directory_stats table: %w", err)
+	latest := LatestSchemaVersion()
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than the latest supported version %d", current, latest)
 	}
-
-	return nil
-}
-
-// runMigrations applies database schema migrations
-func (s *SQLiteStorage) runMigrations() error {
-	// Get current schema version
-	var currentVersion int
-	err := s.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&currentVersion)
-	if err != nil {
-		return fmt.Errorf("failed to get schema version: %w", err)
-	}
-
-	// Define migrations
-	migrations := []struct {
-		version int
-		sql     string
-	}{
-		{
-			version: 1,
-			sql: `
-			-- Initial schema is already created in createTables
-			-- This migration just marks the initial version
-			`,
-		},
-	}
-
-	// Apply migrations
-	for _, migration := range migrations {
-		if migration.version > currentVersion {
-			if migration.sql != "" {
-				if _, err := s.db.Exec(migration.sql); err != nil {
-					return fmt.Errorf("failed to apply migration %d: %w", migration.version, err)
-				}
-			}
-
-			// Record migration
-			_, err := s.db.Exec("INSERT INTO schema_version (version) VALUES (?)", migration.version)
-			if err != nil {
-				return fmt.Errorf("failed to record migration %d: %w", migration.version, err)
-			}
-		}
+	if current == latest {
+		return nil
 	}
 
-	return nil
+	return s.MigrateTo(latest)
 }
 
 // SaveCommand stores a command record
@@ -192,16 +135,39 @@ func (s *SQLiteStorage) SaveCommand(cmd history.CommandRecord) error {
 	// Convert tags to comma-separated string
 	tagsStr := strings.Join(cmd.Tags, ",")
 
+	tx, err := s.beginWrite()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	if err := s.refreshEncryption(tx); err != nil {
+		return err
+	}
+	command, err := s.sealCommand(cmd.ID, cmd.Command)
+	if err != nil {
+		return err
+	}
+
 	// Insert command
 	insertSQL := `
-	INSERT INTO commands (id, command, directory, timestamp, shell, exit_code, duration, tags)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	INSERT INTO commands (id, command, directory, timestamp, shell, exit_code, duration, tags, session_id, shell_pid, hostname, username, tty, ssh, container_id, python_env, kube_context)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := s.db.Exec(insertSQL, cmd.ID, cmd.Command, cmd.Directory, cmd.Timestamp, int(cmd.Shell), cmd.ExitCode, int64(cmd.Duration), tagsStr)
+	_, err = tx.Exec(insertSQL, cmd.ID, command, cmd.Directory, cmd.Timestamp, int(cmd.Shell), cmd.ExitCode, int64(cmd.Duration), tagsStr, cmd.SessionID, cmd.ShellPID, cmd.Hostname,
+		cmd.Username, cmd.TTY, cmd.SSH, cmd.ContainerID, cmd.PythonEnv, cmd.KubeContext)
 	if err != nil {
 		return fmt.Errorf("failed to save command: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	// Update directory stats
 	if err := s.updateDirectoryStats(cmd.Directory); err != nil {
 		// Log error but don't fail the save operation
@@ -218,7 +184,7 @@ func (s *SQLiteStorage) GetCommandsByDirectory(dir string) ([]history.CommandRec
 	}
 
 	query := `
-	SELECT id, command, directory, timestamp, shell, exit_code, duration, tags
+	SELECT ` + commandColumns + `
 	FROM commands
 	WHERE directory = ?
 	ORDER BY timestamp DESC`
@@ -292,19 +258,24 @@ func (s *SQLiteStorage) SearchCommands(pattern string, dir string) ([]history.Co
 		return nil, fmt.Errorf("database not initialized")
 	}
 
+	// Sealed command text is matched after decryption
+	if s.aead != nil {
+		return s.FilterCommands(CommandFilters{Directory: dir, Pattern: pattern})
+	}
+
 	var query string
 	var args []interface{}
 
 	if dir != "" {
 		query = `
-		SELECT id, command, directory, timestamp, shell, exit_code, duration, tags
+		SELECT ` + commandColumns + `
 		FROM commands
 		WHERE directory = ? AND command LIKE ?
 		ORDER BY timestamp DESC`
 		args = []interface{}{dir, "%" + pattern + "%"}
 	} else {
 		query = `
-		SELECT id, command, directory, timestamp, shell, exit_code, duration, tags
+		SELECT ` + commandColumns + `
 		FROM commands
 		WHERE command LIKE ?
 		ORDER BY timestamp DESC`
@@ -320,6 +291,119 @@ func (s *SQLiteStorage) SearchCommands(pattern string, dir string) ([]history.Co
 	return s.scanCommands(rows)
 }
 
+// SearchCommandsFTS finds commands using the FTS5 full-text index.
+// The query uses FTS5 syntax: bare tokens are ANDed together ("git push"),
+// a trailing asterisk matches a prefix ("dock*") and double quotes match a
+// phrase (`"git commit"`). Results are ordered by relevance (bm25), then by
+// recency. A limit of zero or less returns all matches.
+func (s *SQLiteStorage) SearchCommandsFTS(query string, dir string, limit int) ([]history.CommandRecord, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("full-text query cannot be empty")
+	}
+
+	// The index only holds sealed text, so match decrypted commands instead;
+	// results are ordered newest first rather than by relevance
+	if s.aead != nil {
+		return s.FilterCommands(CommandFilters{Directory: dir, FullTextQuery: query, Limit: limit})
+	}
+
+	sqlQuery := `
+	SELECT ` + qualifiedCommandColumns("c") + `
+	FROM commands_fts
+	JOIN commands c ON c.rowid = commands_fts.rowid
+	WHERE commands_fts MATCH ?`
+	args := []interface{}{query}
+
+	if dir != "" {
+		sqlQuery += ` AND c.directory = ?`
+		args = append(args, dir)
+	}
+
+	sqlQuery += ` ORDER BY bm25(commands_fts), c.timestamp DESC`
+
+	if limit > 0 {
+		sqlQuery += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run full-text search %q: %w", query, err)
+	}
+	defer rows.Close()
+
+	return s.scanCommands(rows)
+}
+
+// GetCommandsBySession retrieves all commands recorded by a shell session
+func (s *SQLiteStorage) GetCommandsBySession(sessionID string) ([]history.CommandRecord, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	query := `
+	SELECT ` + commandColumns + `
+	FROM commands
+	WHERE session_id = ?
+	ORDER BY timestamp DESC`
+
+	rows, err := s.db.Query(query, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query commands by session: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanCommands(rows)
+}
+
+// GetSessions returns recorded shell sessions ordered by most recent activity
+func (s *SQLiteStorage) GetSessions(limit int) ([]history.SessionIndex, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	// The bare directory/shell/shell_pid columns come from the row holding
+	// MAX(timestamp), i.e. the most recent command of each session
+	query := `
+	SELECT session_id, shell, shell_pid, directory, COUNT(*), MIN(timestamp), MAX(timestamp)
+	FROM commands
+	WHERE session_id != ''
+	GROUP BY session_id
+	ORDER BY MAX(timestamp) DESC`
+
+	var args []interface{}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []history.SessionIndex
+	for rows.Next() {
+		var session history.SessionIndex
+		var shellInt int
+		var firstSeen, lastSeen string
+		if err := rows.Scan(&session.ID, &shellInt, &session.ShellPID, &session.LastDirectory, &session.CommandCount, &firstSeen, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		session.Shell = history.ShellType(shellInt)
+		session.FirstSeen = parseStoredTime(firstSeen)
+		session.LastSeen = parseStoredTime(lastSeen)
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
 // BatchSaveCommands saves multiple commands in a single transaction
 func (s *SQLiteStorage) BatchSaveCommands(commands []history.CommandRecord) error {
 	if s.db == nil {
@@ -330,9 +414,9 @@ func (s *SQLiteStorage) BatchSaveCommands(commands []history.CommandRecord) erro
 		return nil
 	}
 
-	tx, err := s.db.Begin()
+	tx, err := s.beginWrite()
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return err
 	}
 	// Ensure rollback is attempted if commit is not reached. Check the
 	// returned error and ignore ErrTxDone which indicates the transaction
@@ -344,9 +428,13 @@ func (s *SQLiteStorage) BatchSaveCommands(commands []history.CommandRecord) erro
 		}
 	}()
 
+	if err := s.refreshEncryption(tx); err != nil {
+		return err
+	}
+
 	insertSQL := `
-	INSERT INTO commands (id, command, directory, timestamp, shell, exit_code, duration, tags)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	INSERT INTO commands (id, command, directory, timestamp, shell, exit_code, duration, tags, session_id, shell_pid, hostname, username, tty, ssh, container_id, python_env, kube_context)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	stmt, err := tx.Prepare(insertSQL)
 	if err != nil {
@@ -359,8 +447,14 @@ func (s *SQLiteStorage) BatchSaveCommands(commands []history.CommandRecord) erro
 			return fmt.Errorf("invalid command record: %w", err)
 		}
 
+		command, err := s.sealCommand(cmd.ID, cmd.Command)
+		if err != nil {
+			return err
+		}
+
 		tagsStr := strings.Join(cmd.Tags, ",")
-		_, err := stmt.Exec(cmd.ID, cmd.Command, cmd.Directory, cmd.Timestamp, int(cmd.Shell), cmd.ExitCode, int64(cmd.Duration), tagsStr)
+		_, err = stmt.Exec(cmd.ID, command, cmd.Directory, cmd.Timestamp, int(cmd.Shell), cmd.ExitCode, int64(cmd.Duration), tagsStr, cmd.SessionID, cmd.ShellPID, cmd.Hostname,
+			cmd.Username, cmd.TTY, cmd.SSH, cmd.ContainerID, cmd.PythonEnv, cmd.KubeContext)
 		if err != nil {
 			return fmt.Errorf("failed to save command: %w", err)
 		}
@@ -385,36 +479,518 @@ func (s *SQLiteStorage) BatchSaveCommands(commands []history.CommandRecord) erro
 	return nil
 }
 
-// scanCommands is a helper function to scan command records from SQL rows
-func (s *SQLiteStorage) scanCommands(rows *sql.Rows) ([]history.CommandRecord, error) {
+// beginWrite starts a transaction that holds the database's write lock, so
+// what it reads, such as the encryption key check, cannot change before it
+// commits. database/sql starts SQLite transactions deferred; the no-op update
+// takes the lock straight away.
+func (s *SQLiteStorage) beginWrite() (*sql.Tx, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE schema_version SET version = version WHERE 0`); err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("failed to lock database: %w", err)
+	}
+	return tx, nil
+}
+
+// existingIDsChunkSize keeps ID lookups below SQLite's bound parameter limit
+const existingIDsChunkSize = 500
+
+// ExistingCommandIDs reports which of the given IDs are already stored
+func (s *SQLiteStorage) ExistingCommandIDs(ids []string) (map[string]bool, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	existing := make(map[string]bool)
+	for start := 0; start < len(ids); start += existingIDsChunkSize {
+		end := start + existingIDsChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+
+		rows, err := s.db.Query(`SELECT id FROM commands WHERE id IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query command IDs: %w", err)
+		}
+
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan command ID: %w", err)
+			}
+			existing[id] = true
+		}
+		rows.Close()
+	}
+
+	return existing, nil
+}
+
+// GetCommandsByIDs retrieves the stored commands with the given IDs, most
+// recent first. Unknown IDs are left out.
+func (s *SQLiteStorage) GetCommandsByIDs(ids []string) ([]history.CommandRecord, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
 	var commands []history.CommandRecord
-	for rows.Next() {
-		var cmd history.CommandRecord
-		var tagsStr string
-		var shellInt int
-		var durationInt int64
+	for start := 0; start < len(ids); start += existingIDsChunkSize {
+		end := start + existingIDsChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
 
-		err := rows.Scan(&cmd.ID, &cmd.Command, &cmd.Directory, &cmd.Timestamp, &shellInt, &cmd.ExitCode, &durationInt, &tagsStr)
+		rows, err := s.db.Query(`SELECT `+commandColumns+` FROM commands WHERE id IN (`+placeholders+`)`, args...)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan command: %w", err)
+			return nil, fmt.Errorf("failed to query commands by ID: %w", err)
 		}
 
-		cmd.Shell = history.ShellType(shellInt)
-		cmd.Duration = time.Duration(durationInt)
+		found, err := s.scanCommands(rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, found...)
+	}
+
+	sort.SliceStable(commands, func(i, j int) bool {
+		return commands[i].Timestamp.After(commands[j].Timestamp)
+	})
+	return commands, nil
+}
+
+// CleanupDirectoryCommands removes commands run in the given directories that
+// are older than retentionDays
+func (s *SQLiteStorage) CleanupDirectoryCommands(directories []string, retentionDays int) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	cutoffTime := time.Now().AddDate(0, 0, -retentionDays)
+
+	var removed int64
+	for start := 0; start < len(directories); start += existingIDsChunkSize {
+		end := start + existingIDsChunkSize
+		if end > len(directories) {
+			end = len(directories)
+		}
+		chunk := directories[start:end]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		args := make([]interface{}, 0, len(chunk)+1)
+		args = append(args, cutoffTime)
+		for _, dir := range chunk {
+			args = append(args, dir)
+		}
+
+		result, err := s.db.Exec(`DELETE FROM commands WHERE timestamp < ? AND directory IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return removed, fmt.Errorf("failed to cleanup old commands: %w", err)
+		}
+		rowsAffected, _ := result.RowsAffected()
+		removed += rowsAffected
+	}
+
+	if removed > 0 {
+		if err := s.refreshDirectoryStats(); err != nil {
+			return removed, fmt.Errorf("failed to refresh directory stats: %w", err)
+		}
+	}
+
+	return removed, nil
+}
+
+// RewriteCommands replaces the command text and tags of stored records in a
+// single transaction. The full-text index follows through its update trigger.
+func (s *SQLiteStorage) RewriteCommands(commands []history.CommandRecord) error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := s.beginWrite()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	if err := s.refreshEncryption(tx); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`UPDATE commands SET command = ?, tags = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, cmd := range commands {
+		command, err := s.sealCommand(cmd.ID, cmd.Command)
+		if err != nil {
+			return err
+		}
+
+		result, err := stmt.Exec(command, strings.Join(cmd.Tags, ","), cmd.ID)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite command %s: %w", cmd.ID, err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return fmt.Errorf("command not found: %s", cmd.ID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCommand removes the command with the given ID and updates the
+// statistics of its directory
+func (s *SQLiteStorage) DeleteCommand(id string) error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
 
-		// Parse tags
+	var dir string
+	if err := s.db.QueryRow(`SELECT directory FROM commands WHERE id = ?`, id).Scan(&dir); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("command not found: %s", id)
+		}
+		return fmt.Errorf("failed to look up command %s: %w", id, err)
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM commands WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete command %s: %w", id, err)
+	}
+
+	if err := s.syncDirectoryStats(dir); err != nil {
+		return fmt.Errorf("failed to update directory stats: %w", err)
+	}
+	return nil
+}
+
+// UpdateCommand replaces every field of the stored record with the same ID
+// and updates the statistics of the directories it moved between
+func (s *SQLiteStorage) UpdateCommand(cmd history.CommandRecord) error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var oldDir string
+	if err := s.db.QueryRow(`SELECT directory FROM commands WHERE id = ?`, cmd.ID).Scan(&oldDir); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("command not found: %s", cmd.ID)
+		}
+		return fmt.Errorf("failed to look up command %s: %w", cmd.ID, err)
+	}
+
+	tx, err := s.beginWrite()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	if err := s.refreshEncryption(tx); err != nil {
+		return err
+	}
+	command, err := s.sealCommand(cmd.ID, cmd.Command)
+	if err != nil {
+		return err
+	}
+
+	updateSQL := `
+	UPDATE commands
+	SET command = ?, directory = ?, timestamp = ?, shell = ?, exit_code = ?, duration = ?, tags = ?, session_id = ?, shell_pid = ?, hostname = ?,
+		username = ?, tty = ?, ssh = ?, container_id = ?, python_env = ?, kube_context = ?
+	WHERE id = ?`
+
+	_, err = tx.Exec(updateSQL,
+		command, cmd.Directory, cmd.Timestamp, int(cmd.Shell), cmd.ExitCode, int64(cmd.Duration),
+		strings.Join(cmd.Tags, ","), cmd.SessionID, cmd.ShellPID, cmd.Hostname,
+		cmd.Username, cmd.TTY, cmd.SSH, cmd.ContainerID, cmd.PythonEnv, cmd.KubeContext, cmd.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update command %s: %w", cmd.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	dirs := []string{cmd.Directory}
+	if oldDir != cmd.Directory {
+		dirs = append(dirs, oldDir)
+	}
+	if err := s.syncDirectoryStats(dirs...); err != nil {
+		return fmt.Errorf("failed to update directory stats: %w", err)
+	}
+	return nil
+}
+
+// DeleteCommands removes the commands with the given IDs in a single
+// transaction and refreshes the directory statistics
+func (s *SQLiteStorage) DeleteCommands(ids []string) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	var removed int64
+	for start := 0; start < len(ids); start += existingIDsChunkSize {
+		end := start + existingIDsChunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[start:end]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+
+		result, err := tx.Exec(`DELETE FROM commands WHERE id IN (`+placeholders+`)`, args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete commands: %w", err)
+		}
+		rowsAffected, _ := result.RowsAffected()
+		removed += rowsAffected
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if removed > 0 {
+		if err := s.refreshDirectoryStats(); err != nil {
+			return removed, fmt.Errorf("failed to refresh directory stats: %w", err)
+		}
+	}
+
+	return removed, nil
+}
+
+// UpdateTags adds and removes tags on the commands with the given IDs in a
+// single transaction. Tags already present are not added twice.
+func (s *SQLiteStorage) UpdateTags(ids []string, add []string, remove []string) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	for _, tag := range append(append([]string{}, add...), remove...) {
+		if tag == "" || strings.Contains(tag, ",") {
+			return 0, fmt.Errorf("invalid tag %q", tag)
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	stmt, err := tx.Prepare(`UPDATE commands SET tags = ? WHERE id = ?`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	var changed int64
+	for _, id := range ids {
+		var tagsStr string
+		if err := tx.QueryRow(`SELECT tags FROM commands WHERE id = ?`, id).Scan(&tagsStr); err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			return 0, fmt.Errorf("failed to read tags of command %s: %w", id, err)
+		}
+
+		var tags []string
 		if tagsStr != "" {
-			cmd.Tags = strings.Split(tagsStr, ",")
-		} else {
-			cmd.Tags = []string{}
+			tags = strings.Split(tagsStr, ",")
+		}
+		updated := editTags(tags, add, remove)
+		if strings.Join(updated, ",") == tagsStr {
+			continue
+		}
+
+		if _, err := stmt.Exec(strings.Join(updated, ","), id); err != nil {
+			return 0, fmt.Errorf("failed to update tags of command %s: %w", id, err)
+		}
+		changed++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return changed, nil
+}
+
+// editTags returns tags without the removed ones and with the added ones
+// appended if missing
+func editTags(tags []string, add []string, remove []string) []string {
+	result := make([]string, 0, len(tags)+len(add))
+	for _, tag := range tags {
+		if !containsTag(remove, tag) {
+			result = append(result, tag)
+		}
+	}
+	for _, tag := range add {
+		if !containsTag(result, tag) {
+			result = append(result, tag)
 		}
+	}
+	return result
+}
+
+// containsTag reports whether tags contains tag
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// PurgeOldData merges the full-text index, which otherwise keeps deleted
+// terms until segments are merged, and vacuums the database and its
+// write-ahead log so overwritten text no longer exists on disk
+func (s *SQLiteStorage) PurgeOldData() error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
 
+	var ftsTables int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'commands_fts'`).Scan(&ftsTables); err != nil {
+		return fmt.Errorf("failed to check full-text index: %w", err)
+	}
+	if ftsTables > 0 {
+		if _, err := s.db.Exec(`INSERT INTO commands_fts(commands_fts) VALUES ('optimize')`); err != nil {
+			return fmt.Errorf("failed to optimize full-text index: %w", err)
+		}
+	}
+
+	if _, err := s.db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint write-ahead log: %w", err)
+	}
+
+	return nil
+}
+
+// scanCommands is a helper function to scan command records from SQL rows
+func (s *SQLiteStorage) scanCommands(rows *sql.Rows) ([]history.CommandRecord, error) {
+	var commands []history.CommandRecord
+	for rows.Next() {
+		cmd, err := s.scanCommand(rows)
+		if err != nil {
+			return nil, err
+		}
 		commands = append(commands, cmd)
 	}
 
 	return commands, nil
 }
 
+// scanCommand scans the current row, selected with commandColumns, into a
+// record, decrypting the command text
+func (s *SQLiteStorage) scanCommand(rows *sql.Rows) (history.CommandRecord, error) {
+	var cmd history.CommandRecord
+	var tagsStr string
+	var shellInt int
+	var durationInt int64
+
+	err := rows.Scan(&cmd.ID, &cmd.Command, &cmd.Directory, &cmd.Timestamp, &shellInt, &cmd.ExitCode, &durationInt, &tagsStr, &cmd.SessionID, &cmd.ShellPID, &cmd.Hostname,
+		&cmd.Username, &cmd.TTY, &cmd.SSH, &cmd.ContainerID, &cmd.PythonEnv, &cmd.KubeContext)
+	if err != nil {
+		return cmd, fmt.Errorf("failed to scan command: %w", err)
+	}
+
+	if cmd.Command, err = s.openCommand(cmd.ID, cmd.Command); err != nil {
+		return cmd, err
+	}
+
+	cmd.Shell = history.ShellType(shellInt)
+	cmd.Duration = time.Duration(durationInt)
+
+	// Parse tags
+	if tagsStr != "" {
+		cmd.Tags = strings.Split(tagsStr, ",")
+	} else {
+		cmd.Tags = []string{}
+	}
+
+	return cmd, nil
+}
+
+// storedTimeLayouts are the formats timestamps may take when read back as text,
+// e.g. from aggregates such as MAX(timestamp) that lose the DATETIME type
+var storedTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05",
+}
+
+// parseStoredTime parses a timestamp column value returned as text
+func parseStoredTime(value string) time.Time {
+	// time.Time.String appends the monotonic clock reading, which is not parseable
+	if idx := strings.Index(value, " m="); idx != -1 {
+		value = value[:idx]
+	}
+
+	for _, layout := range storedTimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed
+		}
+	}
+
+	return time.Time{}
+}
+
 // updateDirectoryStats updates statistics for a directory
 func (s *SQLiteStorage) updateDirectoryStats(dir string) error {
 	// Get current count
@@ -439,6 +1015,38 @@ func (s *SQLiteStorage) updateDirectoryStats(dir string) error {
 	return err
 }
 
+// syncDirectoryStats recalculates the statistics of the given directories from
+// their remaining commands, removing directories that have none left
+func (s *SQLiteStorage) syncDirectoryStats(dirs ...string) error {
+	for _, dir := range dirs {
+		var count int
+		var lastUsed sql.NullString
+		if err := s.db.QueryRow(`SELECT COUNT(*), MAX(timestamp) FROM commands WHERE directory = ?`, dir).Scan(&count, &lastUsed); err != nil {
+			return err
+		}
+
+		if count == 0 {
+			if _, err := s.db.Exec(`DELETE FROM directory_stats WHERE path = ?`, dir); err != nil {
+				return err
+			}
+			continue
+		}
+
+		upsertSQL := `
+		INSERT INTO directory_stats (path, command_count, last_used, is_active)
+		VALUES (?, ?, ?, 1)
+		ON CONFLICT(path) DO UPDATE SET
+			command_count = excluded.command_count,
+			last_used = excluded.last_used,
+			updated_at = CURRENT_TIMESTAMP`
+
+		if _, err := s.db.Exec(upsertSQL, dir, count, parseStoredTime(lastUsed.String)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // refreshDirectoryStats recalculates all directory statistics
 func (s *SQLiteStorage) refreshDirectoryStats() error {
 	// Clear existing stats
@@ -563,14 +1171,14 @@ func (s *SQLiteStorage) GetCommandsByTimeRange(startTime, endTime time.Time, dir
 
 	if dir != "" {
 		query = `
-		SELECT id, command, directory, timestamp, shell, exit_code, duration, tags
+		SELECT ` + commandColumns + `
 		FROM commands
 		WHERE directory = ? AND timestamp BETWEEN ? AND ?
 		ORDER BY timestamp DESC`
 		args = []interface{}{dir, startTime, endTime}
 	} else {
 		query = `
-		SELECT id, command, directory, timestamp, shell, exit_code, duration, tags
+		SELECT ` + commandColumns + `
 		FROM commands
 		WHERE timestamp BETWEEN ? AND ?
 		ORDER BY timestamp DESC`
@@ -597,14 +1205,14 @@ func (s *SQLiteStorage) GetCommandsByShell(shellType history.ShellType, dir stri
 
 	if dir != "" {
 		query = `
-		SELECT id, command, directory, timestamp, shell, exit_code, duration, tags
+		SELECT ` + commandColumns + `
 		FROM commands
 		WHERE directory = ? AND shell = ?
 		ORDER BY timestamp DESC`
 		args = []interface{}{dir, int(shellType)}
 	} else {
 		query = `
-		SELECT id, command, directory, timestamp, shell, exit_code, duration, tags
+		SELECT ` + commandColumns + `
 		FROM commands
 		WHERE shell = ?
 		ORDER BY timestamp DESC`
@@ -626,26 +1234,128 @@ func (s *SQLiteStorage) FilterCommands(filters CommandFilters) ([]history.Comman
 		return nil, fmt.Errorf("database not initialized")
 	}
 
+	sqlFilters, match, err := s.splitContentFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Order by timestamp descending (most recent first)
+	query, args := buildFilterQuery(sqlFilters, "DESC")
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter commands: %w", err)
+	}
+	defer rows.Close()
+
+	return s.scanMatching(rows, match, filters.Limit)
+}
+
+// StreamCommands calls fn for every command matching the filters, oldest
+// first, without loading the whole result set into memory
+func (s *SQLiteStorage) StreamCommands(filters CommandFilters, fn func(history.CommandRecord) error) error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	sqlFilters, match, err := s.splitContentFilters(filters)
+	if err != nil {
+		return err
+	}
+
+	query, args := buildFilterQuery(sqlFilters, "ASC")
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to filter commands: %w", err)
+	}
+	defer rows.Close()
+
+	matched := 0
+	for rows.Next() {
+		cmd, err := s.scanCommand(rows)
+		if err != nil {
+			return err
+		}
+		if match != nil && !match(cmd) {
+			continue
+		}
+		if err := fn(cmd); err != nil {
+			return err
+		}
+		matched++
+		if match != nil && filters.Limit > 0 && matched == filters.Limit {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read commands: %w", err)
+	}
+
+	return nil
+}
+
+// buildFilterQuery builds the SELECT for a set of filters, ordered by timestamp
+func buildFilterQuery(filters CommandFilters, order string) (string, []interface{}) {
+	query, args := buildFilterWhere(filters)
+
+	query += ` ORDER BY timestamp ` + order
+
+	// Apply limit if specified
+	if filters.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filters.Limit)
+	}
+
+	return query, args
+}
+
+// buildFilterWhere builds the SELECT and WHERE clause for a set of filters,
+// leaving ordering and limits to the caller
+func buildFilterWhere(filters CommandFilters) (string, []interface{}) {
 	// Build query dynamically based on filters
 	query := `
-	SELECT id, command, directory, timestamp, shell, exit_code, duration, tags
+	SELECT ` + commandColumns + `
 	FROM commands
 	WHERE 1=1`
 
 	var args []interface{}
 
 	// Directory filter
-	if filters.Directory != "" {
+	if filters.Directory != "" && filters.Recursive {
+		clause, dirArgs := subtreeClause(filters.Directory)
+		query += ` AND ` + clause
+		args = append(args, dirArgs...)
+	} else if filters.Directory != "" {
 		query += ` AND directory = ?`
 		args = append(args, filters.Directory)
 	}
 
+	// Exact command filter
+	if filters.Command != "" {
+		query += ` AND command = ?`
+		args = append(args, filters.Command)
+	}
+
 	// Text pattern filter
 	if filters.Pattern != "" {
 		query += ` AND command LIKE ?`
 		args = append(args, "%"+filters.Pattern+"%")
 	}
 
+	// Full-text filter (FTS5 query syntax)
+	if filters.FullTextQuery != "" {
+		query += ` AND rowid IN (SELECT rowid FROM commands_fts WHERE commands_fts MATCH ?)`
+		args = append(args, filters.FullTextQuery)
+	}
+
+	// Session filter
+	if filters.SessionID != "" {
+		query += ` AND session_id = ?`
+		args = append(args, filters.SessionID)
+	}
+
 	// Shell type filter
 	if filters.ShellType != history.Unknown {
 		query += ` AND shell = ?`
@@ -668,33 +1378,48 @@ func (s *SQLiteStorage) FilterCommands(filters CommandFilters) ([]history.Comman
 		args = append(args, *filters.ExitCode)
 	}
 
-	// Order by timestamp descending (most recent first)
-	query += ` ORDER BY timestamp DESC`
-
-	// Apply limit if specified
-	if filters.Limit > 0 {
-		query += ` LIMIT ?`
-		args = append(args, filters.Limit)
+	// Session context filters
+	for _, field := range []struct{ column, value string }{
+		{"hostname", filters.Hostname},
+		{"username", filters.Username},
+		{"tty", filters.TTY},
+		{"container_id", filters.ContainerID},
+		{"python_env", filters.PythonEnv},
+		{"kube_context", filters.KubeContext},
+	} {
+		if field.value != "" {
+			query += ` AND ` + field.column + ` = ?`
+			args = append(args, field.value)
+		}
 	}
-
-	rows, err := s.db.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to filter commands: %w", err)
+	if filters.SSH != nil {
+		query += ` AND ssh = ?`
+		args = append(args, *filters.SSH)
 	}
-	defer rows.Close()
 
-	return s.scanCommands(rows)
+	return query, args
 }
 
 // CommandFilters defines filter criteria for command queries
 type CommandFilters struct {
-	Directory string
-	Pattern   string
-	ShellType history.ShellType
-	StartTime time.Time
-	EndTime   time.Time
-	ExitCode  *int
-	Limit     int
+	Directory     string
+	Recursive     bool   // also match subdirectories of Directory
+	Command       string // exact command text
+	Pattern       string
+	FullTextQuery string
+	SessionID     string
+	ShellType     history.ShellType
+	StartTime     time.Time
+	EndTime       time.Time
+	ExitCode      *int
+	Hostname      string
+	Username      string
+	TTY           string
+	SSH           *bool // commands run over SSH, or not
+	ContainerID   string
+	PythonEnv     string
+	KubeContext   string
+	Limit         int
 }
 
 // OptimizeDatabase performs database optimization operations
@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -324,6 +326,107 @@ func TestSQLiteStorage_BatchSaveCommands(t *testing.T) {
 	}
 }
 
+func TestSQLiteStorage_ExistingCommandIDs(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	var commands []history.CommandRecord
+	for i := 0; i < existingIDsChunkSize+5; i++ {
+		commands = append(commands, createTestCommand(fmt.Sprintf("exist-%d", i), "echo", "/home/user", history.Bash))
+	}
+	if err := storage.BatchSaveCommands(commands); err != nil {
+		t.Fatalf("BatchSaveCommands failed: %v", err)
+	}
+
+	// Query more IDs than fit in a single chunk, including unknown ones
+	ids := []string{"missing-1"}
+	for _, cmd := range commands {
+		ids = append(ids, cmd.ID)
+	}
+	ids = append(ids, "missing-2")
+
+	existing, err := storage.ExistingCommandIDs(ids)
+	if err != nil {
+		t.Fatalf("ExistingCommandIDs failed: %v", err)
+	}
+
+	if len(existing) != len(commands) {
+		t.Errorf("Expected %d existing IDs, got %d", len(commands), len(existing))
+	}
+	if existing["missing-1"] || existing["missing-2"] {
+		t.Error("Unknown IDs should not be reported as existing")
+	}
+	if !existing[fmt.Sprintf("exist-%d", existingIDsChunkSize+4)] {
+		t.Error("Expected ID from the second chunk to exist")
+	}
+}
+
+func TestSQLiteStorage_GetCommandsByIDs(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	older := createTestCommand("by-id-1", "make build", "/home/user", history.Bash)
+	older.Timestamp = time.Now().Add(-time.Hour)
+	newer := createTestCommand("by-id-2", "make test", "/home/other", history.Zsh)
+	for _, cmd := range []history.CommandRecord{older, newer, createTestCommand("by-id-3", "ls", "/home/user", history.Bash)} {
+		if err := storage.SaveCommand(cmd); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+
+	commands, err := storage.GetCommandsByIDs([]string{"by-id-1", "missing", "by-id-2"})
+	if err != nil {
+		t.Fatalf("GetCommandsByIDs failed: %v", err)
+	}
+	if len(commands) != 2 || commands[0].ID != "by-id-2" || commands[1].Command != "make build" {
+		t.Errorf("Expected the two known commands, most recent first, got %+v", commands)
+	}
+}
+
+func TestSQLiteStorage_StreamCommands(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		cmd := createTestCommand(fmt.Sprintf("stream-%d", i), fmt.Sprintf("make step%d", i), "/home/user", history.Bash)
+		cmd.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		cmd.ExitCode = i % 2
+		if err := storage.SaveCommand(cmd); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+	if err := storage.SaveCommand(createTestCommand("stream-other", "make other", "/tmp", history.Zsh)); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+
+	var ids []string
+	exitCode := 0
+	err := storage.StreamCommands(CommandFilters{Directory: "/home/user", ExitCode: &exitCode}, func(cmd history.CommandRecord) error {
+		ids = append(ids, cmd.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamCommands failed: %v", err)
+	}
+
+	// Streamed oldest first
+	want := []string{"stream-0", "stream-2", "stream-4"}
+	if strings.Join(ids, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, ids)
+	}
+
+	stop := fmt.Errorf("stop")
+	count := 0
+	err = storage.StreamCommands(CommandFilters{}, func(cmd history.CommandRecord) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Errorf("Expected callback error to stop streaming, got %v after %d rows", err, count)
+	}
+}
+
 func TestSQLiteStorage_GetDirectoryStats(t *testing.T) {
 	storage, cleanup := setupTestStorage(t)
 	defer cleanup()
@@ -494,3 +597,664 @@ func TestSQLiteStorage_GetDatabaseSize(t *testing.T) {
 		t.Errorf("Database size should not decrease: initial=%d, new=%d", initialSize, newSize)
 	}
 }
+
+func TestSQLiteStorage_SearchCommandsFTS(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	now := time.Now()
+	commands := []history.CommandRecord{
+		createTestCommand("fts-1", "git commit -m 'fix parser'", "/home/user/project", history.Bash),
+		createTestCommand("fts-2", "git push origin main", "/home/user/project", history.Bash),
+		createTestCommand("fts-3", "docker compose up", "/home/user/project", history.Bash),
+		createTestCommand("fts-4", "dockerd --debug", "/tmp", history.Bash),
+		createTestCommand("fts-5", "commit git changes", "/tmp", history.Zsh),
+	}
+	for i := range commands {
+		commands[i].Timestamp = now.Add(-time.Duration(i) * time.Minute)
+		if err := storage.SaveCommand(commands[i]); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		dir      string
+		expected []string
+	}{
+		{"token query", "git", "", []string{"fts-1", "fts-2", "fts-5"}},
+		{"multiple tokens", "git push", "", []string{"fts-2"}},
+		{"prefix query", "dock*", "", []string{"fts-3", "fts-4"}},
+		{"phrase query", `"git commit"`, "", []string{"fts-1"}},
+		{"directory scoped", "git", "/tmp", []string{"fts-5"}},
+		{"no matches", "kubectl", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := storage.SearchCommandsFTS(tt.query, tt.dir, 0)
+			if err != nil {
+				t.Fatalf("SearchCommandsFTS failed: %v", err)
+			}
+
+			if len(results) != len(tt.expected) {
+				t.Fatalf("Expected %d results, got %d", len(tt.expected), len(results))
+			}
+
+			found := make(map[string]bool)
+			for _, r := range results {
+				found[r.ID] = true
+			}
+			for _, id := range tt.expected {
+				if !found[id] {
+					t.Errorf("Expected %s in results", id)
+				}
+			}
+		})
+	}
+
+	// Limit is applied after ranking
+	results, err := storage.SearchCommandsFTS("git", "", 2)
+	if err != nil {
+		t.Fatalf("SearchCommandsFTS with limit failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results with limit, got %d", len(results))
+	}
+
+	// Invalid FTS syntax surfaces an error
+	if _, err := storage.SearchCommandsFTS("git AND (", "", 0); err == nil {
+		t.Error("Expected error for malformed full-text query")
+	}
+
+	// Empty query is rejected
+	if _, err := storage.SearchCommandsFTS("  ", "", 0); err == nil {
+		t.Error("Expected error for empty full-text query")
+	}
+}
+
+func TestSQLiteStorage_FTSIndexStaysInSync(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	cmd := createTestCommand("sync-1", "make build", "/home/user", history.Bash)
+	if err := storage.SaveCommand(cmd); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+
+	if _, err := storage.db.Exec(`UPDATE commands SET command = 'make test' WHERE id = ?`, cmd.ID); err != nil {
+		t.Fatalf("Failed to update command: %v", err)
+	}
+
+	results, err := storage.SearchCommandsFTS("build", "", 0)
+	if err != nil {
+		t.Fatalf("SearchCommandsFTS failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected stale token to be removed from index, got %d results", len(results))
+	}
+
+	results, err = storage.SearchCommandsFTS("test", "", 0)
+	if err != nil {
+		t.Fatalf("SearchCommandsFTS failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected updated command in index, got %d results", len(results))
+	}
+
+	if err := storage.CleanupOldCommands(-1); err != nil {
+		t.Fatalf("CleanupOldCommands failed: %v", err)
+	}
+
+	results, err = storage.SearchCommandsFTS("make", "", 0)
+	if err != nil {
+		t.Fatalf("SearchCommandsFTS failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected deleted command to be removed from index, got %d results", len(results))
+	}
+}
+
+func TestSQLiteStorage_FTSMigrationIndexesExistingRows(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "test.db")
+
+	storage := NewSQLiteStorage(dbPath)
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+
+	// Roll the database back to a pre-FTS schema with existing history
+	if err := storage.MigrateTo(1); err != nil {
+		t.Fatalf("Failed to downgrade schema: %v", err)
+	}
+
+	insertLegacy := `INSERT INTO commands (id, command, directory, timestamp, shell) VALUES (?, ?, ?, ?, ?)`
+	if _, err := storage.db.Exec(insertLegacy, "legacy-1", "terraform plan", "/infra", time.Now(), int(history.Bash)); err != nil {
+		t.Fatalf("Failed to insert legacy row: %v", err)
+	}
+	storage.Close()
+
+	// Reopening runs the migration and indexes the legacy row
+	storage = NewSQLiteStorage(dbPath)
+	if err := storage.Initialize(); err != nil {
+		t.Fatalf("Initialize after downgrade failed: %v", err)
+	}
+	defer storage.Close()
+
+	results, err := storage.SearchCommandsFTS("terraform", "", 0)
+	if err != nil {
+		t.Fatalf("SearchCommandsFTS failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "legacy-1" {
+		t.Errorf("Expected legacy command to be indexed, got %v", results)
+	}
+}
+
+func TestSQLiteStorage_Sessions(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	now := time.Now()
+	commands := []history.CommandRecord{
+		{ID: "s1-1", Command: "make build", Directory: "/repo", Timestamp: now.Add(-3 * time.Hour), Shell: history.Bash, SessionID: "sess-a", ShellPID: 1001},
+		{ID: "s1-2", Command: "make test", Directory: "/repo/pkg", Timestamp: now.Add(-2 * time.Hour), Shell: history.Bash, SessionID: "sess-a", ShellPID: 1001},
+		{ID: "s2-1", Command: "htop", Directory: "/home", Timestamp: now.Add(-1 * time.Hour), Shell: history.Zsh, SessionID: "sess-b", ShellPID: 2002},
+		{ID: "none", Command: "ls", Directory: "/home", Timestamp: now, Shell: history.Zsh},
+	}
+	if err := storage.BatchSaveCommands(commands); err != nil {
+		t.Fatalf("BatchSaveCommands failed: %v", err)
+	}
+
+	t.Run("Commands by session", func(t *testing.T) {
+		results, err := storage.GetCommandsBySession("sess-a")
+		if err != nil {
+			t.Fatalf("GetCommandsBySession failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 commands, got %d", len(results))
+		}
+		if results[0].ID != "s1-2" {
+			t.Errorf("Expected most recent command first, got %s", results[0].ID)
+		}
+		if results[0].SessionID != "sess-a" || results[0].ShellPID != 1001 {
+			t.Errorf("Session fields not round-tripped: %+v", results[0])
+		}
+	})
+
+	t.Run("Session summaries", func(t *testing.T) {
+		sessions, err := storage.GetSessions(0)
+		if err != nil {
+			t.Fatalf("GetSessions failed: %v", err)
+		}
+		if len(sessions) != 2 {
+			t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+		}
+
+		latest := sessions[0]
+		if latest.ID != "sess-b" || latest.Shell != history.Zsh || latest.ShellPID != 2002 || latest.CommandCount != 1 {
+			t.Errorf("Unexpected latest session: %+v", latest)
+		}
+
+		older := sessions[1]
+		if older.ID != "sess-a" || older.CommandCount != 2 || older.LastDirectory != "/repo/pkg" {
+			t.Errorf("Unexpected older session: %+v", older)
+		}
+		if older.FirstSeen.IsZero() || !older.LastSeen.After(older.FirstSeen) {
+			t.Errorf("Expected first/last seen to span the session, got %v - %v", older.FirstSeen, older.LastSeen)
+		}
+	})
+
+	t.Run("Session limit", func(t *testing.T) {
+		sessions, err := storage.GetSessions(1)
+		if err != nil {
+			t.Fatalf("GetSessions failed: %v", err)
+		}
+		if len(sessions) != 1 || sessions[0].ID != "sess-b" {
+			t.Errorf("Expected only the latest session, got %+v", sessions)
+		}
+	})
+
+	t.Run("Filter by session", func(t *testing.T) {
+		results, err := storage.FilterCommands(CommandFilters{SessionID: "sess-a", Pattern: "test"})
+		if err != nil {
+			t.Fatalf("FilterCommands failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != "s1-2" {
+			t.Errorf("Expected s1-2, got %v", results)
+		}
+	})
+}
+
+func TestSQLiteStorage_RewriteCommands(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	record := createTestCommand("rewrite-1", "export API_TOKEN=hunter2", "/home/user", history.Bash)
+	if err := storage.SaveCommand(record); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+
+	record.Command = "export API_TOKEN=****"
+	record.Tags = []string{"test", "redacted"}
+	if err := storage.RewriteCommands([]history.CommandRecord{record}); err != nil {
+		t.Fatalf("RewriteCommands failed: %v", err)
+	}
+
+	commands, err := storage.GetCommandsByDirectory("/home/user")
+	if err != nil {
+		t.Fatalf("GetCommandsByDirectory failed: %v", err)
+	}
+	if len(commands) != 1 || commands[0].Command != "export API_TOKEN=****" {
+		t.Fatalf("Expected rewritten command, got %v", commands)
+	}
+	if !commands[0].HasTag("redacted") {
+		t.Errorf("Expected rewritten tags, got %v", commands[0].Tags)
+	}
+
+	// The full-text index follows the rewrite
+	results, err := storage.SearchCommandsFTS("hunter2", "", 0)
+	if err != nil {
+		t.Fatalf("SearchCommandsFTS failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Original text still searchable: %v", results)
+	}
+
+	missing := createTestCommand("missing", "ls", "/home/user", history.Bash)
+	if err := storage.RewriteCommands([]history.CommandRecord{missing}); err == nil {
+		t.Error("Expected error when rewriting an unknown command")
+	}
+
+	if err := storage.PurgeOldData(); err != nil {
+		t.Errorf("PurgeOldData failed: %v", err)
+	}
+}
+
+func TestSQLiteStorage_DeleteAndUpdateCommand(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	older := createTestCommand("edit-1", "mysql -p hunter2", "/repo", history.Bash)
+	older.Timestamp = time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, record := range []history.CommandRecord{
+		older,
+		createTestCommand("edit-2", "make", "/repo", history.Bash),
+		createTestCommand("edit-3", "ls", "/tmp", history.Bash),
+	} {
+		if err := storage.SaveCommand(record); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+
+	statsFor := func(path string) *history.DirectoryIndex {
+		t.Helper()
+		stats, err := storage.GetDirectoryStats()
+		if err != nil {
+			t.Fatalf("GetDirectoryStats failed: %v", err)
+		}
+		for i := range stats {
+			if stats[i].Path == path {
+				return &stats[i]
+			}
+		}
+		return nil
+	}
+
+	// Deleting the newest command moves last_used back to the one before it
+	if err := storage.DeleteCommand("edit-2"); err != nil {
+		t.Fatalf("DeleteCommand failed: %v", err)
+	}
+	if repo := statsFor("/repo"); repo == nil || repo.CommandCount != 1 || !repo.LastUsed.Equal(older.Timestamp) {
+		t.Errorf("Expected /repo with 1 command last used %v, got %+v", older.Timestamp, repo)
+	}
+	if err := storage.DeleteCommand("edit-2"); err == nil {
+		t.Error("Expected error when deleting an unknown command")
+	}
+
+	// Editing replaces every field, and moving the command updates both directories
+	edited := older
+	edited.Command = "mysql -p"
+	edited.Directory = "/tmp"
+	edited.ExitCode = 1
+	edited.Tags = []string{"edited"}
+	if err := storage.UpdateCommand(edited); err != nil {
+		t.Fatalf("UpdateCommand failed: %v", err)
+	}
+
+	commands, err := storage.GetCommandsByDirectory("/tmp")
+	if err != nil {
+		t.Fatalf("GetCommandsByDirectory failed: %v", err)
+	}
+	var found *history.CommandRecord
+	for i := range commands {
+		if commands[i].ID == "edit-1" {
+			found = &commands[i]
+		}
+	}
+	if found == nil || found.Command != "mysql -p" || found.ExitCode != 1 || !found.HasTag("edited") {
+		t.Errorf("Expected edited command in /tmp, got %+v", found)
+	}
+	if repo := statsFor("/repo"); repo != nil {
+		t.Errorf("Expected /repo to be removed from the statistics, got %+v", repo)
+	}
+	if tmp := statsFor("/tmp"); tmp == nil || tmp.CommandCount != 2 {
+		t.Errorf("Expected /tmp with 2 commands, got %+v", tmp)
+	}
+
+	// The full-text index follows the edit
+	results, err := storage.SearchCommandsFTS("hunter2", "", 0)
+	if err != nil {
+		t.Fatalf("SearchCommandsFTS failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Original text still searchable: %v", results)
+	}
+
+	missing := createTestCommand("missing", "ls", "/tmp", history.Bash)
+	if err := storage.UpdateCommand(missing); err == nil {
+		t.Error("Expected error when updating an unknown command")
+	}
+}
+
+func TestSQLiteStorage_DeleteCommands(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	for _, record := range []history.CommandRecord{
+		createTestCommand("del-1", "gti status", "/repo", history.Bash),
+		createTestCommand("del-2", "gti log", "/repo", history.Bash),
+		createTestCommand("keep-1", "git status", "/repo", history.Bash),
+		createTestCommand("del-3", "sl", "/tmp", history.Bash),
+	} {
+		if err := storage.SaveCommand(record); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+
+	removed, err := storage.DeleteCommands([]string{"del-1", "del-2", "del-3", "missing"})
+	if err != nil {
+		t.Fatalf("DeleteCommands failed: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("Expected 3 commands removed, got %d", removed)
+	}
+
+	commands, err := storage.GetCommandsByDirectory("/repo")
+	if err != nil {
+		t.Fatalf("GetCommandsByDirectory failed: %v", err)
+	}
+	if len(commands) != 1 || commands[0].ID != "keep-1" {
+		t.Errorf("Expected only keep-1 to remain, got %v", commands)
+	}
+
+	// Directory statistics follow the deletion
+	stats, err := storage.GetDirectoryStats()
+	if err != nil {
+		t.Fatalf("GetDirectoryStats failed: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Path != "/repo" || stats[0].CommandCount != 1 {
+		t.Errorf("Expected /repo with 1 command, got %+v", stats)
+	}
+}
+
+func TestSQLiteStorage_UpdateTags(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	for _, record := range []history.CommandRecord{
+		createTestCommand("tag-1", "make build", "/repo", history.Bash),
+		createTestCommand("tag-2", "make test", "/repo", history.Bash),
+	} {
+		if err := storage.SaveCommand(record); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+
+	changed, err := storage.UpdateTags([]string{"tag-1", "tag-2", "missing"}, []string{"runbook"}, []string{"test"})
+	if err != nil {
+		t.Fatalf("UpdateTags failed: %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("Expected 2 commands changed, got %d", changed)
+	}
+
+	commands, err := storage.GetCommandsByDirectory("/repo")
+	if err != nil {
+		t.Fatalf("GetCommandsByDirectory failed: %v", err)
+	}
+	for _, cmd := range commands {
+		if !cmd.HasTag("runbook") || cmd.HasTag("test") {
+			t.Errorf("Expected %s tagged runbook without test, got %v", cmd.ID, cmd.Tags)
+		}
+	}
+
+	// Adding a tag that is already present changes nothing
+	changed, err = storage.UpdateTags([]string{"tag-1"}, []string{"runbook"}, nil)
+	if err != nil || changed != 0 {
+		t.Errorf("Expected no change, got %d, %v", changed, err)
+	}
+
+	if _, err := storage.UpdateTags([]string{"tag-1"}, []string{"a,b"}, nil); err == nil {
+		t.Error("Expected error for a tag containing a comma")
+	}
+}
+
+func TestSQLiteStorage_CleanupDirectoryCommands(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	old := time.Now().AddDate(0, 0, -10)
+	for _, record := range []history.CommandRecord{
+		createTestCommand("kept-recent", "make", "/repo", history.Bash),
+		createTestCommand("old-repo", "make", "/repo", history.Bash),
+		createTestCommand("old-other", "make", "/other", history.Bash),
+	} {
+		if record.ID != "kept-recent" {
+			record.Timestamp = old
+		}
+		if err := storage.SaveCommand(record); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+
+	removed, err := storage.CleanupDirectoryCommands([]string{"/repo"}, 7)
+	if err != nil {
+		t.Fatalf("CleanupDirectoryCommands failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected 1 command removed, got %d", removed)
+	}
+
+	repo, _ := storage.GetCommandsByDirectory("/repo")
+	if len(repo) != 1 || repo[0].ID != "kept-recent" {
+		t.Errorf("Expected only the recent /repo command to remain, got %v", repo)
+	}
+	other, _ := storage.GetCommandsByDirectory("/other")
+	if len(other) != 1 {
+		t.Error("Commands in other directories should not be removed")
+	}
+}
+
+func TestSQLiteStorage_GetCommandsPage(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	// Seven commands in /repo, three sharing a timestamp, and one elsewhere
+	base := time.Now().Add(-time.Hour)
+	for i, offset := range []int{0, 1, 2, 2, 2, 3, 4} {
+		record := createTestCommand(fmt.Sprintf("page-%d", i), fmt.Sprintf("echo %d", i), "/repo", history.Bash)
+		record.Timestamp = base.Add(time.Duration(offset) * time.Minute)
+		if err := storage.SaveCommand(record); err != nil {
+			t.Fatalf("SaveCommand failed: %v", err)
+		}
+	}
+	if err := storage.SaveCommand(createTestCommand("other", "ls", "/tmp", history.Bash)); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+
+	filters := CommandFilters{Directory: "/repo"}
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("Expected paging to finish in three pages")
+		}
+		page, err := storage.GetCommandsPage(filters, cursor, 3)
+		if err != nil {
+			t.Fatalf("GetCommandsPage failed: %v", err)
+		}
+		for _, cmd := range page.Commands {
+			seen = append(seen, cmd.ID)
+		}
+
+		// A newer command recorded while paging does not shift later pages
+		if pages == 0 {
+			record := createTestCommand("newer", "echo newer", "/repo", history.Bash)
+			if err := storage.SaveCommand(record); err != nil {
+				t.Fatalf("SaveCommand failed: %v", err)
+			}
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	want := []string{"page-6", "page-5", "page-4", "page-3", "page-2", "page-1", "page-0"}
+	if strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, seen)
+	}
+
+	// Filters apply to pages, and the last page has no cursor
+	page, err := storage.GetCommandsPage(CommandFilters{Pattern: "echo 1"}, "", 10)
+	if err != nil {
+		t.Fatalf("GetCommandsPage failed: %v", err)
+	}
+	if len(page.Commands) != 1 || page.Commands[0].ID != "page-1" || page.NextCursor != "" {
+		t.Errorf("Expected only page-1, got %+v", page)
+	}
+
+	if _, err := storage.GetCommandsPage(filters, "not a cursor", 3); err == nil {
+		t.Error("Expected an error for an invalid cursor")
+	}
+}
+
+func TestSQLiteStorage_GetUsageStats(t *testing.T) {
+	storage, cleanup := setupTestStorage(t)
+	defer cleanup()
+
+	// Monday 2024-01-15 in local time
+	monday := time.Date(2024, 1, 15, 9, 30, 0, 0, time.Local)
+	var records []history.CommandRecord
+	add := func(command, directory string, hour, exitCode int, duration time.Duration) {
+		record := createTestCommand(fmt.Sprintf("stats-%d", len(records)), command, directory, history.Bash)
+		record.Timestamp = monday.Add(time.Duration(hour-9) * time.Hour)
+		record.ExitCode = exitCode
+		record.Duration = duration
+		records = append(records, record)
+	}
+
+	// 20 timed "go test" runs of 1..20 seconds, two of them failing
+	for i := 1; i <= 20; i++ {
+		exitCode := 0
+		if i <= 2 {
+			exitCode = 1
+		}
+		add("go test ./...", "/repo", 9, exitCode, time.Duration(i)*time.Second)
+	}
+	add("go build", "/repo/cmd", 14, 0, 0)
+	add("go vet", "/repo/cmd", 14, 2, 0)
+	add("ls", "/repository", 22, 0, time.Second)
+	add("git status", "/elsewhere", 22, 0, time.Second)
+
+	if err := storage.BatchSaveCommands(records); err != nil {
+		t.Fatalf("BatchSaveCommands failed: %v", err)
+	}
+
+	stats, err := storage.GetUsageStats(StatsQuery{Directory: "/repo", Limit: 5})
+	if err != nil {
+		t.Fatalf("GetUsageStats failed: %v", err)
+	}
+
+	// /repository and /elsewhere are outside the /repo subtree
+	if stats.TotalCommands != 22 || stats.FailedCommands != 3 || stats.DirectoryCount != 2 {
+		t.Errorf("Unexpected totals: %d commands, %d failed, %d directories", stats.TotalCommands, stats.FailedCommands, stats.DirectoryCount)
+	}
+
+	if len(stats.TopCommands) != 3 {
+		t.Fatalf("Expected 3 commands, got %+v", stats.TopCommands)
+	}
+	top := stats.TopCommands[0]
+	if top.Command != "go test ./..." || top.Count != 20 || top.Failures != 2 {
+		t.Errorf("Unexpected top command: %+v", top)
+	}
+	if top.FailureRate != 0.1 {
+		t.Errorf("Expected failure rate 0.1, got %v", top.FailureRate)
+	}
+	if top.AvgDuration != 10500*time.Millisecond {
+		t.Errorf("Expected average 10.5s, got %v", top.AvgDuration)
+	}
+	if top.P95Duration != 19*time.Second {
+		t.Errorf("Expected p95 19s, got %v", top.P95Duration)
+	}
+	if build := stats.TopCommands[1]; build.AvgDuration != 0 || build.P95Duration != 0 {
+		t.Errorf("Untimed commands should have no duration, got %+v", build)
+	}
+
+	if len(stats.TopBaseCommands) != 1 || stats.TopBaseCommands[0].Command != "go" || stats.TopBaseCommands[0].Count != 22 {
+		t.Errorf("Expected base command go with 22 runs, got %+v", stats.TopBaseCommands)
+	}
+
+	if len(stats.TopDirectories) != 2 || stats.TopDirectories[0].Directory != "/repo" || stats.TopDirectories[1].Failures != 1 {
+		t.Errorf("Unexpected directories: %+v", stats.TopDirectories)
+	}
+
+	if stats.ByHour[9] != 20 || stats.ByHour[14] != 2 || stats.ByHour[22] != 0 {
+		t.Errorf("Unexpected hourly activity: %v", stats.ByHour)
+	}
+	if stats.ByWeekday[time.Monday] != 22 {
+		t.Errorf("Expected all commands on Monday, got %v", stats.ByWeekday)
+	}
+	if len(stats.Daily) != 1 || stats.Daily[0].Date != "2024-01-15" || stats.Daily[0].Count != 22 {
+		t.Errorf("Unexpected daily activity: %+v", stats.Daily)
+	}
+
+	// go test failed twice, go vet once; go build never failed
+	if len(stats.FailingCommands) != 2 || stats.FailingCommands[0].Command != "go test ./..." || stats.FailingCommands[1].Command != "go vet" {
+		t.Errorf("Unexpected failing commands: %+v", stats.FailingCommands)
+	}
+	// Only go test has recorded durations in /repo
+	if len(stats.SlowestCommands) != 1 || stats.SlowestCommands[0].P95Duration != 19*time.Second {
+		t.Errorf("Unexpected slowest commands: %+v", stats.SlowestCommands)
+	}
+
+	// The same subtree and exact command filters are available to FilterCommands
+	runs, err := storage.FilterCommands(CommandFilters{Directory: "/repo", Recursive: true, Command: "go vet"})
+	if err != nil {
+		t.Fatalf("FilterCommands failed: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Directory != "/repo/cmd" {
+		t.Errorf("Expected the go vet run in /repo/cmd, got %+v", runs)
+	}
+
+	// Time range
+	stats, err = storage.GetUsageStats(StatsQuery{StartTime: monday.Add(4 * time.Hour)})
+	if err != nil {
+		t.Fatalf("GetUsageStats failed: %v", err)
+	}
+	if stats.TotalCommands != 4 || stats.ByHour[22] != 2 {
+		t.Errorf("Expected 4 commands after 13:30, got %d (%v)", stats.TotalCommands, stats.ByHour)
+	}
+
+	// Empty result
+	stats, err = storage.GetUsageStats(StatsQuery{Directory: "/nowhere"})
+	if err != nil {
+		t.Fatalf("GetUsageStats failed: %v", err)
+	}
+	if stats.TotalCommands != 0 || len(stats.TopCommands) != 0 {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
@@ -0,0 +1,487 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultStatsLimit is the number of entries in each ranked list of UsageStats
+const DefaultStatsLimit = 10
+
+// StatsQuery selects the commands summarized by GetUsageStats
+type StatsQuery struct {
+	// Directory limits the statistics to a directory and its subdirectories
+	Directory string
+	StartTime time.Time
+	EndTime   time.Time
+	// Limit is the number of entries in each ranked list
+	Limit int
+}
+
+// UsageStats summarizes command usage over a time range and directory subtree
+type UsageStats struct {
+	TotalCommands   int              `json:"total_commands"`
+	FailedCommands  int              `json:"failed_commands"`
+	FailureRate     float64          `json:"failure_rate"`
+	DirectoryCount  int              `json:"directory_count"`
+	FirstCommand    time.Time        `json:"first_command"`
+	LastCommand     time.Time        `json:"last_command"`
+	TopCommands     []CommandUsage   `json:"top_commands"`
+	TopBaseCommands []CommandUsage   `json:"top_base_commands"`
+	FailingCommands []CommandUsage   `json:"failing_commands"`
+	SlowestCommands []CommandUsage   `json:"slowest_commands"`
+	TopDirectories  []DirectoryUsage `json:"top_directories"`
+	// Daily counts commands per local calendar day, oldest first; days
+	// without commands are omitted
+	Daily []DailyCount `json:"daily"`
+	// ByHour and ByWeekday count commands per hour of the day and day of the
+	// week (Sunday first), in the local time the commands were recorded in
+	ByHour    [24]int `json:"by_hour"`
+	ByWeekday [7]int  `json:"by_weekday"`
+}
+
+// CommandUsage counts the runs of one command or base command. Durations only
+// include runs whose duration was recorded.
+type CommandUsage struct {
+	Command     string        `json:"command"`
+	Count       int           `json:"count"`
+	Failures    int           `json:"failures"`
+	FailureRate float64       `json:"failure_rate"`
+	AvgDuration time.Duration `json:"avg_duration"`
+	P95Duration time.Duration `json:"p95_duration"`
+}
+
+// DailyCount is the number of commands run on one day
+type DailyCount struct {
+	Date  string `json:"date"` // 2006-01-02
+	Count int    `json:"count"`
+}
+
+// DirectoryUsage counts the commands run in one directory
+type DirectoryUsage struct {
+	Directory string    `json:"directory"`
+	Count     int       `json:"count"`
+	Failures  int       `json:"failures"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+// usageRanking orders the entries of a command ranking. The expressions are
+// evaluated per group of runs.
+type usageRanking struct {
+	order  string
+	having string
+}
+
+var (
+	// rankByUses puts the most-run commands first
+	rankByUses = usageRanking{order: `COUNT(*) DESC, key`}
+	// rankByFailures puts the commands that failed most often first
+	rankByFailures = usageRanking{order: `SUM(exit_code != 0) DESC, COUNT(*) DESC, key`, having: `SUM(exit_code != 0) > 0`}
+	// rankBySlowest puts the commands with the longest average duration first
+	rankBySlowest = usageRanking{order: `AVG(NULLIF(duration, 0)) DESC, key`, having: `COUNT(NULLIF(duration, 0)) > 0`}
+)
+
+// baseCommandExpr is the first word of a command
+const baseCommandExpr = `CASE WHEN instr(trim(command), ' ') > 0
+	THEN substr(trim(command), 1, instr(trim(command), ' ') - 1)
+	ELSE trim(command) END`
+
+// localTimeExpr is the wall-clock time a command was recorded at. Timestamps
+// are stored as text starting with the local date and time; older rows may
+// hold Unix seconds.
+const localTimeExpr = `CASE WHEN typeof(timestamp) = 'integer'
+	THEN datetime(timestamp, 'unixepoch', 'localtime')
+	ELSE substr(timestamp, 1, 19) END`
+
+// GetUsageStats aggregates command usage in SQL
+func (s *SQLiteStorage) GetUsageStats(query StatsQuery) (*UsageStats, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = DefaultStatsLimit
+	}
+
+	where, args := statsWhere(query)
+	stats := &UsageStats{}
+
+	var failed *int
+	var first, last *string
+	totalsQuery := `
+	SELECT COUNT(*), SUM(exit_code != 0), COUNT(DISTINCT directory), MIN(timestamp), MAX(timestamp)
+	FROM commands` + where
+	if err := s.db.QueryRow(totalsQuery, args...).Scan(&stats.TotalCommands, &failed, &stats.DirectoryCount, &first, &last); err != nil {
+		return nil, fmt.Errorf("failed to query command totals: %w", err)
+	}
+	if stats.TotalCommands == 0 {
+		return stats, nil
+	}
+	stats.FailedCommands = *failed
+	stats.FailureRate = float64(stats.FailedCommands) / float64(stats.TotalCommands)
+	stats.FirstCommand = parseStoredTime(*first)
+	stats.LastCommand = parseStoredTime(*last)
+
+	var err error
+	if s.aead != nil {
+		// Every run is sealed differently, so group the decrypted text instead
+		if err = s.sealedCommandUsage(stats, where, args, query.Limit); err != nil {
+			return nil, err
+		}
+	} else {
+		if stats.TopCommands, err = s.commandUsage("command", rankByUses, where, args, query.Limit); err != nil {
+			return nil, err
+		}
+		if stats.TopBaseCommands, err = s.commandUsage(baseCommandExpr, rankByUses, where, args, query.Limit); err != nil {
+			return nil, err
+		}
+		if stats.FailingCommands, err = s.commandUsage("command", rankByFailures, where, args, query.Limit); err != nil {
+			return nil, err
+		}
+		if stats.SlowestCommands, err = s.commandUsage("command", rankBySlowest, where, args, query.Limit); err != nil {
+			return nil, err
+		}
+	}
+	if stats.TopDirectories, err = s.directoryUsage(where, args, query.Limit); err != nil {
+		return nil, err
+	}
+	if err := s.countByTime(`'%H'`, where, args, stats.ByHour[:]); err != nil {
+		return nil, err
+	}
+	if err := s.countByTime(`'%w'`, where, args, stats.ByWeekday[:]); err != nil {
+		return nil, err
+	}
+	if stats.Daily, err = s.dailyCounts(where, args); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// statsWhere builds the WHERE clause shared by the statistics queries
+func statsWhere(query StatsQuery) (string, []interface{}) {
+	where := ` WHERE 1=1`
+	var args []interface{}
+
+	if query.Directory != "" {
+		clause, dirArgs := subtreeClause(query.Directory)
+		where += ` AND ` + clause
+		args = append(args, dirArgs...)
+	}
+	if !query.StartTime.IsZero() {
+		where += ` AND timestamp >= ?`
+		args = append(args, query.StartTime)
+	}
+	if !query.EndTime.IsZero() {
+		where += ` AND timestamp <= ?`
+		args = append(args, query.EndTime)
+	}
+
+	return where, args
+}
+
+// subtreeClause matches commands run in dir or any directory below it
+func subtreeClause(dir string) (string, []interface{}) {
+	prefix := dir
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return `(directory = ? OR directory LIKE ? ESCAPE '\')`, []interface{}{dir, escapeLike(prefix) + "%"}
+}
+
+// escapeLike escapes the LIKE wildcards in s for use with ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// commandUsage ranks the values of keyExpr. The 95th percentile duration
+// uses the nearest-rank method over timed runs.
+func (s *SQLiteStorage) commandUsage(keyExpr string, ranking usageRanking, where string, args []interface{}, limit int) ([]CommandUsage, error) {
+	having := ""
+	if ranking.having != "" {
+		having = ` HAVING ` + ranking.having
+	}
+
+	query := `
+	WITH filtered AS (
+		SELECT ` + keyExpr + ` AS key, exit_code, duration
+		FROM commands` + where + `
+	),
+	usage AS (
+		SELECT key, COUNT(*) AS uses, SUM(exit_code != 0) AS failures, AVG(NULLIF(duration, 0)) AS avg_duration,
+			ROW_NUMBER() OVER (ORDER BY ` + ranking.order + `) AS place
+		FROM filtered
+		GROUP BY key` + having + `
+		ORDER BY place
+		LIMIT ?
+	),
+	ranked AS (
+		SELECT key, duration,
+			ROW_NUMBER() OVER (PARTITION BY key ORDER BY duration) AS position,
+			COUNT(*) OVER (PARTITION BY key) AS timed
+		FROM filtered
+		WHERE duration > 0 AND key IN (SELECT key FROM usage)
+	)
+	SELECT u.key, u.uses, u.failures, COALESCE(u.avg_duration, 0), COALESCE(r.duration, 0)
+	FROM usage u
+	LEFT JOIN ranked r ON r.key = u.key AND r.position = (95 * r.timed + 99) / 100
+	ORDER BY u.place`
+
+	rows, err := s.db.Query(query, append(append([]interface{}{}, args...), limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query command usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []CommandUsage
+	for rows.Next() {
+		var entry CommandUsage
+		var avg float64
+		var p95 int64
+		if err := rows.Scan(&entry.Command, &entry.Count, &entry.Failures, &avg, &p95); err != nil {
+			return nil, fmt.Errorf("failed to scan command usage: %w", err)
+		}
+		entry.FailureRate = float64(entry.Failures) / float64(entry.Count)
+		entry.AvgDuration = time.Duration(avg)
+		entry.P95Duration = time.Duration(p95)
+		usage = append(usage, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read command usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// usageGroup collects the runs of one command or base command
+type usageGroup struct {
+	key       string
+	uses      int
+	failures  int
+	durations []time.Duration // recorded durations only
+}
+
+// sealedCommandUsage fills the command rankings of stats from decrypted
+// commands, ordered and filtered like the commandUsage rankings
+func (s *SQLiteStorage) sealedCommandUsage(stats *UsageStats, where string, args []interface{}, limit int) error {
+	rows, err := s.db.Query(`SELECT id, command, exit_code, duration FROM commands`+where, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query command usage: %w", err)
+	}
+	defer rows.Close()
+
+	commands := make(map[string]*usageGroup)
+	bases := make(map[string]*usageGroup)
+	for rows.Next() {
+		var id, value string
+		var exitCode int
+		var duration int64
+		if err := rows.Scan(&id, &value, &exitCode, &duration); err != nil {
+			return fmt.Errorf("failed to scan command usage: %w", err)
+		}
+		command, err := s.openCommand(id, value)
+		if err != nil {
+			return err
+		}
+		addRun(commands, command, exitCode, time.Duration(duration))
+		addRun(bases, baseCommand(command), exitCode, time.Duration(duration))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read command usage: %w", err)
+	}
+
+	byUses := func(a, b *usageGroup) bool {
+		if a.uses != b.uses {
+			return a.uses > b.uses
+		}
+		return a.key < b.key
+	}
+	byFailures := func(a, b *usageGroup) bool {
+		if a.failures != b.failures {
+			return a.failures > b.failures
+		}
+		return byUses(a, b)
+	}
+	bySlowest := func(a, b *usageGroup) bool {
+		if avgA, avgB := averageDuration(a.durations), averageDuration(b.durations); avgA != avgB {
+			return avgA > avgB
+		}
+		return a.key < b.key
+	}
+
+	stats.TopCommands = rankGroups(commands, nil, byUses, limit)
+	stats.TopBaseCommands = rankGroups(bases, nil, byUses, limit)
+	stats.FailingCommands = rankGroups(commands, func(g *usageGroup) bool { return g.failures > 0 }, byFailures, limit)
+	stats.SlowestCommands = rankGroups(commands, func(g *usageGroup) bool { return len(g.durations) > 0 }, bySlowest, limit)
+	return nil
+}
+
+// addRun counts one run of key
+func addRun(groups map[string]*usageGroup, key string, exitCode int, duration time.Duration) {
+	group, ok := groups[key]
+	if !ok {
+		group = &usageGroup{key: key}
+		groups[key] = group
+	}
+	group.uses++
+	if exitCode != 0 {
+		group.failures++
+	}
+	if duration > 0 {
+		group.durations = append(group.durations, duration)
+	}
+}
+
+// rankGroups returns the first limit groups passing keep (all when nil) in
+// the order given by less
+func rankGroups(groups map[string]*usageGroup, keep func(*usageGroup) bool, less func(a, b *usageGroup) bool, limit int) []CommandUsage {
+	var ranked []*usageGroup
+	for _, group := range groups {
+		if keep == nil || keep(group) {
+			ranked = append(ranked, group)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool { return less(ranked[i], ranked[j]) })
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	var usage []CommandUsage
+	for _, group := range ranked {
+		entry := CommandUsage{
+			Command:     group.key,
+			Count:       group.uses,
+			Failures:    group.failures,
+			FailureRate: float64(group.failures) / float64(group.uses),
+			AvgDuration: averageDuration(group.durations),
+		}
+		if timed := len(group.durations); timed > 0 {
+			// Nearest-rank 95th percentile, as in commandUsage
+			sorted := append([]time.Duration(nil), group.durations...)
+			sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+			entry.P95Duration = sorted[(95*timed+99)/100-1]
+		}
+		usage = append(usage, entry)
+	}
+	return usage
+}
+
+// averageDuration returns the mean of durations, 0 when there are none
+func averageDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
+
+// baseCommand returns the first word of a command, as baseCommandExpr does
+func baseCommand(command string) string {
+	command = strings.Trim(command, " ")
+	if i := strings.Index(command, " "); i >= 0 {
+		return command[:i]
+	}
+	return command
+}
+
+// directoryUsage ranks directories by number of commands
+func (s *SQLiteStorage) directoryUsage(where string, args []interface{}, limit int) ([]DirectoryUsage, error) {
+	query := `
+	SELECT directory, COUNT(*), SUM(exit_code != 0), MAX(timestamp)
+	FROM commands` + where + `
+	GROUP BY directory
+	ORDER BY COUNT(*) DESC, directory
+	LIMIT ?`
+
+	rows, err := s.db.Query(query, append(append([]interface{}{}, args...), limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query directory usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []DirectoryUsage
+	for rows.Next() {
+		var entry DirectoryUsage
+		var lastUsed string
+		if err := rows.Scan(&entry.Directory, &entry.Count, &entry.Failures, &lastUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan directory usage: %w", err)
+		}
+		entry.LastUsed = parseStoredTime(lastUsed)
+		usage = append(usage, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read directory usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// dailyCounts counts commands per local calendar day
+func (s *SQLiteStorage) dailyCounts(where string, args []interface{}) ([]DailyCount, error) {
+	query := `
+	SELECT date(` + localTimeExpr + `) AS day, COUNT(*)
+	FROM commands` + where + `
+	GROUP BY day
+	HAVING day IS NOT NULL
+	ORDER BY day`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily activity: %w", err)
+	}
+	defer rows.Close()
+
+	var daily []DailyCount
+	for rows.Next() {
+		var day DailyCount
+		if err := rows.Scan(&day.Date, &day.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan daily activity: %w", err)
+		}
+		daily = append(daily, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read daily activity: %w", err)
+	}
+
+	return daily, nil
+}
+
+// countByTime fills buckets with command counts grouped by a strftime field
+// of the local time, e.g. '%H' for the hour
+func (s *SQLiteStorage) countByTime(field, where string, args []interface{}, buckets []int) error {
+	query := `
+	SELECT CAST(strftime(` + field + `, ` + localTimeExpr + `) AS INTEGER) AS bucket, COUNT(*)
+	FROM commands` + where + `
+	GROUP BY bucket`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query activity: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket *int
+		var count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return fmt.Errorf("failed to scan activity: %w", err)
+		}
+		if bucket != nil && *bucket >= 0 && *bucket < len(buckets) {
+			buckets[*bucket] += count
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read activity: %w", err)
+	}
+
+	return nil
+}
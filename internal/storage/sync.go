@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// syncMachineKey is the sync_meta key holding this database's machine ID
+const syncMachineKey = "machine_id"
+
+// SyncedCommand is the version of a command agreed on by the last sync
+type SyncedCommand struct {
+	ID   string
+	Tags []string
+	// Fingerprint identifies every field except the tags
+	Fingerprint string
+	Timestamp   time.Time
+	// Deleted marks a command deleted on some machine; it is never restored
+	Deleted bool
+}
+
+// SyncMachineID returns the random ID naming this database's change log,
+// creating it on first use
+func (s *SQLiteStorage) SyncMachineID() (string, error) {
+	if s.db == nil {
+		return "", fmt.Errorf("database not initialized")
+	}
+
+	var id string
+	err := s.db.QueryRow(`SELECT value FROM sync_meta WHERE key = ?`, syncMachineKey).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to read machine ID: %w", err)
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate machine ID: %w", err)
+	}
+	id = hex.EncodeToString(buf)
+
+	// Another process may have created the ID first; keep whichever won
+	if _, err := s.db.Exec(`INSERT OR IGNORE INTO sync_meta (key, value) VALUES (?, ?)`, syncMachineKey, id); err != nil {
+		return "", fmt.Errorf("failed to save machine ID: %w", err)
+	}
+	if err := s.db.QueryRow(`SELECT value FROM sync_meta WHERE key = ?`, syncMachineKey).Scan(&id); err != nil {
+		return "", fmt.Errorf("failed to read machine ID: %w", err)
+	}
+	return id, nil
+}
+
+// SyncedCommands returns the state recorded by the last sync, by command ID
+func (s *SQLiteStorage) SyncedCommands() (map[string]SyncedCommand, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	rows, err := s.db.Query(`SELECT id, tags, fingerprint, timestamp, deleted FROM sync_state`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	defer rows.Close()
+
+	synced := make(map[string]SyncedCommand)
+	for rows.Next() {
+		var state SyncedCommand
+		var tags string
+		if err := rows.Scan(&state.ID, &tags, &state.Fingerprint, &state.Timestamp, &state.Deleted); err != nil {
+			return nil, fmt.Errorf("failed to scan sync state: %w", err)
+		}
+		state.Tags = []string{}
+		if tags != "" {
+			state.Tags = strings.Split(tags, ",")
+		}
+		synced[state.ID] = state
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	return synced, nil
+}
+
+// SaveSyncedCommands records the state agreed on by a sync and forgets the
+// commands with the given IDs, in a single transaction
+func (s *SQLiteStorage) SaveSyncedCommands(synced []SyncedCommand, forget []string) error {
+	if s.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Printf("Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	upsert, err := tx.Prepare(`INSERT OR REPLACE INTO sync_state (id, tags, fingerprint, timestamp, deleted) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer upsert.Close()
+
+	for _, state := range synced {
+		if _, err := upsert.Exec(state.ID, strings.Join(state.Tags, ","), state.Fingerprint, state.Timestamp, state.Deleted); err != nil {
+			return fmt.Errorf("failed to save sync state of %s: %w", state.ID, err)
+		}
+	}
+
+	for _, id := range forget {
+		if _, err := tx.Exec(`DELETE FROM sync_state WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to forget sync state of %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
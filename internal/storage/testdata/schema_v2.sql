@@ -0,0 +1,59 @@
+-- Database as written by releases with the full-text index (schema version 2)
+CREATE TABLE schema_version (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP);
+
+CREATE TABLE commands (
+	id TEXT PRIMARY KEY,
+	command TEXT NOT NULL,
+	directory TEXT NOT NULL,
+	timestamp DATETIME NOT NULL,
+	shell INTEGER NOT NULL,
+	exit_code INTEGER NOT NULL DEFAULT 0,
+	duration INTEGER NOT NULL DEFAULT 0,
+	tags TEXT DEFAULT '',
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_commands_directory ON commands(directory);
+CREATE INDEX idx_commands_timestamp ON commands(timestamp);
+CREATE INDEX idx_commands_dir_timestamp ON commands(directory, timestamp DESC);
+CREATE INDEX idx_commands_shell ON commands(shell);
+CREATE INDEX idx_commands_command ON commands(command);
+
+CREATE TABLE directory_stats (
+	path TEXT PRIMARY KEY,
+	command_count INTEGER NOT NULL DEFAULT 0,
+	last_used DATETIME NOT NULL,
+	is_active BOOLEAN NOT NULL DEFAULT 1,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE VIRTUAL TABLE commands_fts USING fts5(
+	command,
+	content='commands',
+	content_rowid='rowid'
+);
+
+CREATE TRIGGER commands_fts_insert AFTER INSERT ON commands BEGIN
+	INSERT INTO commands_fts(rowid, command) VALUES (new.rowid, new.command);
+END;
+
+CREATE TRIGGER commands_fts_delete AFTER DELETE ON commands BEGIN
+	INSERT INTO commands_fts(commands_fts, rowid, command) VALUES ('delete', old.rowid, old.command);
+END;
+
+CREATE TRIGGER commands_fts_update AFTER UPDATE OF command ON commands BEGIN
+	INSERT INTO commands_fts(commands_fts, rowid, command) VALUES ('delete', old.rowid, old.command);
+	INSERT INTO commands_fts(rowid, command) VALUES (new.rowid, new.command);
+END;
+
+INSERT INTO schema_version (version) VALUES (1);
+INSERT INTO schema_version (version) VALUES (2);
+
+INSERT INTO commands (id, command, directory, timestamp, shell, exit_code, duration, tags) VALUES
+	('fixture-1', 'git status', '/home/dev/project', '2024-03-01 09:00:00 +0000 UTC', 2, 0, 120000000, ''),
+	('fixture-2', 'docker compose up -d', '/home/dev/project', '2024-03-01 09:05:00 +0000 UTC', 2, 0, 3500000000, 'docker'),
+	('fixture-3', 'Get-ChildItem -Recurse', 'C:/Users/dev', '2024-03-02 14:30:00 +0000 UTC', 1, 1, 800000000, 'ps,files');
+
+INSERT INTO directory_stats (path, command_count, last_used, is_active) VALUES
+	('/home/dev/project', 2, '2024-03-01 09:05:00 +0000 UTC', 1),
+	('C:/Users/dev', 1, '2024-03-02 14:30:00 +0000 UTC', 1);
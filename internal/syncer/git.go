@@ -0,0 +1,170 @@
+package syncer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+)
+
+// gitBranch is the branch holding the change logs in a git remote
+const gitBranch = "main"
+
+// gitPushAttempts bounds the retries when another machine pushes first
+const gitPushAttempts = 3
+
+// IsGitRemote reports whether path looks like a git repository to sync
+// through rather than a shared folder: a path ending in .git or a bare
+// repository
+func IsGitRemote(path string) bool {
+	if strings.HasSuffix(strings.TrimRight(path, `/\`), ".git") {
+		return true
+	}
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// SyncGit syncs through a git remote, usually a bare repository on a shared
+// drive or server. The change logs are kept in a clone in workDir, which is
+// created on first use; each machine commits only its own log, so merging
+// the branch never conflicts.
+func SyncGit(store storage.SyncStorageEngine, remote, workDir string, opts Options) (*Result, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git is required to sync with %s: %w", remote, err)
+	}
+
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+
+	// A local remote is resolved before git runs inside the clone
+	if _, err := os.Stat(remote); err == nil {
+		if abs, err := filepath.Abs(remote); err == nil {
+			remote = abs
+		}
+	}
+
+	repo := gitRepo{dir: workDir}
+	if err := repo.prepare(remote); err != nil {
+		return nil, err
+	}
+
+	result, err := Sync(store, workDir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.commit(fmt.Sprintf("Sync %d change(s) from %s", result.Exported, opts.Hostname)); err != nil {
+		return nil, err
+	}
+
+	// The changes are committed locally, so a failed push is sent next time
+	for attempt := 1; ; attempt++ {
+		err := repo.run("push", "--quiet", "origin", gitBranch)
+		if err == nil {
+			break
+		}
+		if attempt == gitPushAttempts {
+			return nil, fmt.Errorf("failed to push change log: %w", err)
+		}
+		if err := repo.pull(); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// gitRepo runs git in a clone of the sync remote
+type gitRepo struct {
+	dir string
+}
+
+// prepare creates the clone on first use and brings it up to date
+func (r gitRepo) prepare(remote string) error {
+	if _, err := os.Stat(filepath.Join(r.dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(r.dir, 0700); err != nil {
+			return fmt.Errorf("failed to create sync clone: %w", err)
+		}
+		if err := r.run("init", "--quiet"); err != nil {
+			return err
+		}
+		if err := r.run("symbolic-ref", "HEAD", "refs/heads/"+gitBranch); err != nil {
+			return err
+		}
+		if err := r.run("remote", "add", "origin", remote); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to open sync clone: %w", err)
+	} else {
+		if err := r.run("remote", "set-url", "origin", remote); err != nil {
+			return err
+		}
+		// A previous sync may have written its log but stopped before committing
+		if err := r.commit("Sync interrupted changes"); err != nil {
+			return err
+		}
+	}
+
+	return r.pull()
+}
+
+// pull fetches the branch and rebases local commits onto it
+func (r gitRepo) pull() error {
+	if err := r.run("fetch", "--quiet", "origin"); err != nil {
+		return fmt.Errorf("failed to fetch change logs: %w", err)
+	}
+	if r.run("rev-parse", "--quiet", "--verify", "refs/remotes/origin/"+gitBranch) != nil {
+		// Nothing has been pushed to the remote yet
+		return nil
+	}
+
+	if r.run("rev-parse", "--quiet", "--verify", "HEAD") != nil {
+		return r.run("reset", "--quiet", "--hard", "origin/"+gitBranch)
+	}
+	if err := r.run("rebase", "--quiet", "origin/"+gitBranch); err != nil {
+		_ = r.run("rebase", "--abort")
+		return fmt.Errorf("failed to merge change logs: %w", err)
+	}
+	return nil
+}
+
+// commit commits every change in the clone, if any
+func (r gitRepo) commit(message string) error {
+	if err := r.run("add", "--all"); err != nil {
+		return err
+	}
+	if r.run("diff", "--cached", "--quiet") == nil {
+		return nil
+	}
+	return r.run("commit", "--quiet", "-m", message)
+}
+
+// run runs a git command in the clone. Commits use a fixed identity, so
+// syncing works without a git configuration.
+func (r gitRepo) run(args ...string) error {
+	name := args[0]
+	args = append([]string{"-c", "user.name=command-history-tracker", "-c", "user.email=tracker@localhost", "-c", "commit.gpgsign=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("git %s: %s", name, msg)
+		}
+		return fmt.Errorf("git %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package syncer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// LogExt is the extension of the change log files in a sync directory
+const LogExt = ".jsonl"
+
+// Op is the kind of change an Entry records
+type Op string
+
+const (
+	// OpAdd introduces a command, with its tags
+	OpAdd Op = "add"
+	// OpEdit replaces every field of a command except its tags
+	OpEdit Op = "edit"
+	// OpTags adds and removes tags on a command
+	OpTags Op = "tags"
+	// OpDelete deletes a command on every machine
+	OpDelete Op = "delete"
+)
+
+// Entry is one line of a machine's change log. Every machine appends only to
+// its own log, named after its machine ID, so logs never conflict.
+type Entry struct {
+	Seq        int64                  `json:"seq"`
+	Machine    string                 `json:"machine"`
+	Host       string                 `json:"host,omitempty"`
+	Time       time.Time              `json:"time"`
+	Op         Op                     `json:"op"`
+	ID         string                 `json:"id"`
+	Record     *history.CommandRecord `json:"record,omitempty"`
+	AddTags    []string               `json:"add_tags,omitempty"`
+	RemoveTags []string               `json:"remove_tags,omitempty"`
+}
+
+// before orders entries by time, then machine and sequence number, so every
+// machine merges the same entries in the same order
+func (e Entry) before(other Entry) bool {
+	if !e.Time.Equal(other.Time) {
+		return e.Time.Before(other.Time)
+	}
+	if e.Machine != other.Machine {
+		return e.Machine < other.Machine
+	}
+	return e.Seq < other.Seq
+}
+
+// logPath returns the change log of a machine in dir
+func logPath(dir, machine string) string {
+	return filepath.Join(dir, machine+LogExt)
+}
+
+// readLogs reads every change log in dir. Lines that cannot be parsed are
+// skipped and counted, since a shared folder may still be copying a log.
+func readLogs(dir string) (entries []Entry, skipped int, err error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+LogExt))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list change logs: %w", err)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read change log: %w", err)
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+
+			var entry Entry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil || !entry.valid() {
+				skipped++
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, 0, fmt.Errorf("failed to read change log %s: %w", path, err)
+		}
+	}
+
+	return entries, skipped, nil
+}
+
+// valid reports whether an entry carries what its operation needs
+func (e Entry) valid() bool {
+	if e.ID == "" || e.Machine == "" {
+		return false
+	}
+	switch e.Op {
+	case OpAdd, OpEdit:
+		return e.Record != nil && e.Record.ID == e.ID
+	case OpTags, OpDelete:
+		return true
+	}
+	return false
+}
+
+// appendLog appends entries to a machine's change log in dir, creating it
+func appendLog(dir, machine string, entries []Entry) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to encode change: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(logPath(dir, machine), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open change log: %w", err)
+	}
+
+	// Finish a line cut short by an interrupted write, so it is skipped alone
+	data := buf.Bytes()
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write change log: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write change log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package syncer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// tagState is the latest change to one tag of a command
+type tagState struct {
+	present bool
+	change  Entry
+}
+
+// mergedCommand is a command as every change log together describes it
+type mergedCommand struct {
+	record  *history.CommandRecord
+	change  Entry // entry the fields of record come from
+	tags    map[string]tagState
+	order   []string // tags in the order they first appeared
+	deleted bool
+}
+
+// Tags returns the tags whose latest change added them
+func (m *mergedCommand) Tags() []string {
+	tags := []string{}
+	for _, tag := range m.order {
+		if m.tags[tag].present {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Record returns the merged record with its merged tags
+func (m *mergedCommand) Record() history.CommandRecord {
+	record := *m.record
+	record.Tags = m.Tags()
+	return record
+}
+
+// setTag applies a change to one tag unless a later change is already known
+func (m *mergedCommand) setTag(tag string, present bool, change Entry) {
+	current, known := m.tags[tag]
+	if !known {
+		m.order = append(m.order, tag)
+	} else if !current.change.before(change) {
+		return
+	}
+	m.tags[tag] = tagState{present: present, change: change}
+}
+
+// merge folds change log entries into the state of every command. The
+// result depends only on the set of entries, not on the order machines saw
+// them in:
+//   - the fields of a command come from its latest add or edit
+//   - each tag is present if its latest change added it, so concurrent edits
+//     of different tags both survive
+//   - a delete wins over every other change, before or after it
+func merge(entries []Entry) map[string]*mergedCommand {
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].before(sorted[j]) })
+
+	merged := make(map[string]*mergedCommand)
+	for _, entry := range sorted {
+		m := merged[entry.ID]
+		if m == nil {
+			m = &mergedCommand{tags: make(map[string]tagState)}
+			merged[entry.ID] = m
+		}
+
+		switch entry.Op {
+		case OpAdd, OpEdit:
+			if m.record == nil || m.change.before(entry) {
+				record := *entry.Record
+				record.Tags = nil
+				m.record, m.change = &record, entry
+			}
+			if entry.Op == OpAdd {
+				for _, tag := range entry.Record.Tags {
+					m.setTag(tag, true, entry)
+				}
+			}
+		case OpTags:
+			for _, tag := range entry.AddTags {
+				m.setTag(tag, true, entry)
+			}
+			for _, tag := range entry.RemoveTags {
+				m.setTag(tag, false, entry)
+			}
+		case OpDelete:
+			m.deleted = true
+		}
+	}
+
+	return merged
+}
+
+// fingerprint identifies every field of a record except its tags, so edits
+// and tag changes can be told apart
+func fingerprint(record history.CommandRecord) string {
+	record.Tags = nil
+	record.Timestamp = record.Timestamp.UTC()
+
+	data, _ := json.Marshal(record)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// sameTags reports whether two tag lists hold the same tags
+func sameTags(a, b []string) bool {
+	set := make(map[string]bool, len(a))
+	for _, tag := range a {
+		set[tag] = true
+	}
+	if len(set) != len(uniqueTags(b)) {
+		return false
+	}
+	for _, tag := range b {
+		if !set[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// uniqueTags returns tags without duplicates, keeping their order
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	unique := []string{}
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
+
+// tagDiff returns the tags added and removed going from before to now
+func tagDiff(before, now []string) (added, removed []string) {
+	had := make(map[string]bool, len(before))
+	for _, tag := range before {
+		had[tag] = true
+	}
+	has := make(map[string]bool, len(now))
+	for _, tag := range uniqueTags(now) {
+		has[tag] = true
+		if !had[tag] {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range uniqueTags(before) {
+		if !has[tag] {
+			removed = append(removed, tag)
+		}
+	}
+	return added, removed
+}
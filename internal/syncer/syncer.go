@@ -0,0 +1,250 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// Options controls a sync
+type Options struct {
+	// Hostname is recorded in this machine's change log entries
+	Hostname string
+
+	// RetentionDays matches the local retention period: commands older
+	// than it are not imported, and their local cleanup is not sent to other
+	// machines as a delete. Zero keeps every command.
+	RetentionDays int
+
+	// Now returns the time stamped on new entries; it defaults to time.Now
+	Now func() time.Time
+}
+
+// Result summarizes a sync
+type Result struct {
+	// Machines is the number of machines with changes, including this one
+	Machines int
+	// Exported is the number of local changes appended to this machine's log
+	Exported int
+	// Added, Updated and Deleted count the changes applied to the local database
+	Added   int
+	Updated int
+	Deleted int
+	// Skipped is the number of change log lines that could not be read
+	Skipped int
+}
+
+// Sync exchanges changes between the local database and the change logs in
+// dir: local changes since the last sync are appended to this machine's log,
+// then every log is merged and the result written to the database. Commands
+// changed locally while Sync runs may be overwritten by the merged version.
+func Sync(store storage.SyncStorageEngine, dir string, opts Options) (*Result, error) {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sync directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("sync path %s is not a directory", dir)
+	}
+
+	machine, err := store.SyncMachineID()
+	if err != nil {
+		return nil, err
+	}
+	synced, err := store.SyncedCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	local := make(map[string]history.CommandRecord)
+	err = store.StreamCommands(storage.CommandFilters{}, func(record history.CommandRecord) error {
+		local[record.ID] = record
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local commands: %w", err)
+	}
+
+	entries, skipped, err := readLogs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without its own log, e.g. in a new sync directory, this machine sends
+	// its whole history again
+	if _, err := os.Stat(logPath(dir, machine)); os.IsNotExist(err) {
+		synced = map[string]storage.SyncedCommand{}
+	}
+
+	var cutoff time.Time
+	if opts.RetentionDays > 0 {
+		cutoff = opts.Now().AddDate(0, 0, -opts.RetentionDays)
+	}
+
+	changes, forget := localChanges(local, synced, cutoff)
+	seq := lastSeq(entries, machine)
+	now := opts.Now().UTC()
+	for i := range changes {
+		seq++
+		changes[i].Seq, changes[i].Machine, changes[i].Host, changes[i].Time = seq, machine, opts.Hostname, now
+	}
+
+	// The log is written before the database, so an interrupted sync at
+	// worst sends the same changes again, which merging ignores. It is
+	// created even without changes to mark the directory as synced.
+	if err := appendLog(dir, machine, changes); err != nil {
+		return nil, err
+	}
+	entries = append(entries, changes...)
+
+	machines := make(map[string]bool)
+	for _, entry := range entries {
+		machines[entry.Machine] = true
+	}
+
+	result := &Result{Machines: len(machines), Exported: len(changes), Skipped: skipped}
+	if err := apply(store, merge(entries), local, synced, forget, cutoff, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// lastSeq returns the highest sequence number machine has used
+func lastSeq(entries []Entry, machine string) int64 {
+	var seq int64
+	for _, entry := range entries {
+		if entry.Machine == machine && entry.Seq > seq {
+			seq = entry.Seq
+		}
+	}
+	return seq
+}
+
+// localChanges compares the local commands with the state of the last sync
+// and returns the changes made since, without sequence numbers, and the IDs
+// to forget because local retention cleanup removed them
+func localChanges(local map[string]history.CommandRecord, synced map[string]storage.SyncedCommand, cutoff time.Time) ([]Entry, []string) {
+	var changes []Entry
+	var forget []string
+
+	for _, id := range sortedIDs(local) {
+		record := local[id]
+		state, known := synced[id]
+		switch {
+		case !known:
+			changes = append(changes, Entry{Op: OpAdd, ID: id, Record: &record})
+		case state.Deleted:
+			// Deleted elsewhere; applying the merge removes it here too
+		default:
+			if fingerprint(record) != state.Fingerprint {
+				edited := record
+				edited.Tags = nil
+				changes = append(changes, Entry{Op: OpEdit, ID: id, Record: &edited})
+			}
+			if added, removed := tagDiff(state.Tags, record.Tags); len(added) > 0 || len(removed) > 0 {
+				changes = append(changes, Entry{Op: OpTags, ID: id, AddTags: added, RemoveTags: removed})
+			}
+		}
+	}
+
+	for _, id := range sortedIDs(synced) {
+		state := synced[id]
+		if _, exists := local[id]; exists || state.Deleted {
+			continue
+		}
+		if !cutoff.IsZero() && state.Timestamp.Before(cutoff) {
+			forget = append(forget, id)
+			continue
+		}
+		changes = append(changes, Entry{Op: OpDelete, ID: id})
+	}
+
+	return changes, forget
+}
+
+// apply writes the merged commands to the local database and records them
+// as the new sync state
+func apply(store storage.SyncStorageEngine, merged map[string]*mergedCommand, local map[string]history.CommandRecord,
+	synced map[string]storage.SyncedCommand, forget []string, cutoff time.Time, result *Result) error {
+	var inserts []history.CommandRecord
+	var deletes []string
+	var states []storage.SyncedCommand
+
+	for _, id := range sortedIDs(merged) {
+		m := merged[id]
+		current, exists := local[id]
+
+		if m.deleted {
+			if exists {
+				deletes = append(deletes, id)
+			}
+			state := storage.SyncedCommand{ID: id, Tags: []string{}, Deleted: true}
+			if m.record != nil {
+				state.Timestamp = m.record.Timestamp
+			}
+			if old, known := synced[id]; !known || !old.Deleted {
+				states = append(states, state)
+			}
+			continue
+		}
+		if m.record == nil {
+			// Only tag changes have arrived so far; the add is still on its way
+			continue
+		}
+
+		record := m.Record()
+		switch {
+		case !exists && !cutoff.IsZero() && record.Timestamp.Before(cutoff):
+			continue
+		case !exists:
+			inserts = append(inserts, record)
+		case fingerprint(current) != fingerprint(record) || !sameTags(current.Tags, record.Tags):
+			if err := store.UpdateCommand(record); err != nil {
+				return fmt.Errorf("failed to update command %s: %w", id, err)
+			}
+			result.Updated++
+		}
+
+		state := storage.SyncedCommand{ID: id, Tags: record.Tags, Fingerprint: fingerprint(record), Timestamp: record.Timestamp}
+		if old, known := synced[id]; !known || old.Fingerprint != state.Fingerprint || !sameTags(old.Tags, state.Tags) {
+			states = append(states, state)
+		}
+	}
+
+	if len(inserts) > 0 {
+		if err := store.BatchSaveCommands(inserts); err != nil {
+			return fmt.Errorf("failed to add synced commands: %w", err)
+		}
+		result.Added = len(inserts)
+	}
+	if len(deletes) > 0 {
+		removed, err := store.DeleteCommands(deletes)
+		if err != nil {
+			return fmt.Errorf("failed to delete synced commands: %w", err)
+		}
+		result.Deleted = int(removed)
+	}
+
+	return store.SaveSyncedCommands(states, forget)
+}
+
+// sortedIDs returns the keys of a map keyed by command ID, sorted
+func sortedIDs[V any](m map[string]V) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,281 @@
+package syncer
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ValGrace/command-history-tracker/internal/storage"
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+// machine is one side of a sync test: a database and its options
+type machine struct {
+	store *storage.SQLiteStorage
+	opts  Options
+}
+
+// newMachine creates a database in its own temp directory, with a clock
+// shared by every machine of the test
+func newMachine(t *testing.T, host string, clock *time.Time) *machine {
+	t.Helper()
+	store := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "commands.db"))
+	if err := store.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return &machine{store: store, opts: Options{
+		Hostname: host,
+		Now: func() time.Time {
+			*clock = clock.Add(time.Second)
+			return *clock
+		},
+	}}
+}
+
+func (m *machine) record(t *testing.T, id, command string, tags ...string) {
+	t.Helper()
+	record := history.CommandRecord{
+		ID:        id,
+		Command:   command,
+		Directory: "/repo",
+		Timestamp: time.Now().Add(-time.Hour),
+		Shell:     history.Bash,
+		Tags:      append([]string{}, tags...),
+		Hostname:  m.opts.Hostname,
+	}
+	if err := m.store.SaveCommand(record); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+}
+
+func (m *machine) sync(t *testing.T, dir string) *Result {
+	t.Helper()
+	result, err := Sync(m.store, dir, m.opts)
+	if err != nil {
+		t.Fatalf("Sync failed on %s: %v", m.opts.Hostname, err)
+	}
+	return result
+}
+
+// commands returns the stored commands by ID, with sorted tags
+func (m *machine) commands(t *testing.T) map[string]history.CommandRecord {
+	t.Helper()
+	records, err := m.store.FilterCommands(storage.CommandFilters{})
+	if err != nil {
+		t.Fatalf("FilterCommands failed: %v", err)
+	}
+	byID := make(map[string]history.CommandRecord)
+	for _, record := range records {
+		sort.Strings(record.Tags)
+		record.Timestamp = record.Timestamp.UTC()
+		byID[record.ID] = record
+	}
+	return byID
+}
+
+func (m *machine) setTags(t *testing.T, id string, add, remove []string) {
+	t.Helper()
+	if _, err := m.store.UpdateTags([]string{id}, add, remove); err != nil {
+		t.Fatalf("UpdateTags failed: %v", err)
+	}
+}
+
+func TestSyncExchangesHistory(t *testing.T) {
+	shared := t.TempDir()
+	clock := time.Now()
+	laptop := newMachine(t, "laptop", &clock)
+	desktop := newMachine(t, "desktop", &clock)
+
+	laptop.record(t, "a", "git status", "git")
+	laptop.record(t, "b", "make test")
+	desktop.record(t, "c", "kubectl get pods")
+
+	if result := laptop.sync(t, shared); result.Exported != 2 || result.Added != 0 {
+		t.Errorf("Unexpected first sync result: %+v", result)
+	}
+	if result := desktop.sync(t, shared); result.Exported != 1 || result.Added != 2 || result.Machines != 2 {
+		t.Errorf("Unexpected second sync result: %+v", result)
+	}
+	if result := laptop.sync(t, shared); result.Exported != 0 || result.Added != 1 {
+		t.Errorf("Unexpected third sync result: %+v", result)
+	}
+
+	laptopCommands, desktopCommands := laptop.commands(t), desktop.commands(t)
+	if len(laptopCommands) != 3 || !reflect.DeepEqual(laptopCommands, desktopCommands) {
+		t.Fatalf("Expected identical histories:\nlaptop:  %+v\ndesktop: %+v", laptopCommands, desktopCommands)
+	}
+	if desktopCommands["a"].Hostname != "laptop" || laptopCommands["c"].Hostname != "desktop" {
+		t.Errorf("Expected records to keep the host that ran them, got %q and %q", desktopCommands["a"].Hostname, laptopCommands["c"].Hostname)
+	}
+
+	// Nothing changed, so nothing is sent again
+	if result := desktop.sync(t, shared); result.Exported != 0 || result.Added+result.Updated+result.Deleted != 0 {
+		t.Errorf("Expected an idle sync, got %+v", result)
+	}
+
+	// Edits travel as well
+	edited := desktopCommands["c"]
+	edited.Command = "kubectl get pods -A"
+	if err := desktop.store.UpdateCommand(edited); err != nil {
+		t.Fatalf("UpdateCommand failed: %v", err)
+	}
+	desktop.sync(t, shared)
+	if result := laptop.sync(t, shared); result.Updated != 1 || laptop.commands(t)["c"].Command != "kubectl get pods -A" {
+		t.Errorf("Expected the edit to reach the laptop, got %+v", result)
+	}
+
+	logs, _ := filepath.Glob(filepath.Join(shared, "*"+LogExt))
+	if len(logs) != 2 {
+		t.Errorf("Expected one change log per machine, got %v", logs)
+	}
+}
+
+func TestSyncResolvesConflicts(t *testing.T) {
+	shared := t.TempDir()
+	clock := time.Now()
+	laptop := newMachine(t, "laptop", &clock)
+	desktop := newMachine(t, "desktop", &clock)
+
+	laptop.record(t, "deploy", "./deploy.sh prod", "ops")
+	laptop.record(t, "secret", "export TOKEN=abc")
+	laptop.sync(t, shared)
+	desktop.sync(t, shared)
+
+	expectTags := func(want ...string) {
+		t.Helper()
+		for name, m := range map[string]*machine{"laptop": laptop, "desktop": desktop} {
+			if got := m.commands(t)["deploy"].Tags; !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: expected tags %v, got %v", name, want, got)
+			}
+		}
+	}
+
+	// Concurrent edits of different tags both survive
+	laptop.setTags(t, "deploy", []string{"release"}, nil)
+	desktop.setTags(t, "deploy", []string{"prod"}, []string{"ops"})
+	laptop.sync(t, shared)
+	desktop.sync(t, shared)
+	laptop.sync(t, shared)
+	expectTags("prod", "release")
+
+	// A later change to a tag replaces an earlier one
+	desktop.setTags(t, "deploy", nil, []string{"release"})
+	desktop.sync(t, shared)
+	laptop.sync(t, shared)
+	expectTags("prod")
+
+	// A delete wins over a concurrent tag edit, in either order
+	desktop.setTags(t, "secret", []string{"important"}, nil)
+	if err := laptop.store.DeleteCommand("secret"); err != nil {
+		t.Fatalf("DeleteCommand failed: %v", err)
+	}
+	desktop.sync(t, shared)
+	if result := laptop.sync(t, shared); result.Added != 0 {
+		t.Errorf("Expected the deleted command not to come back, got %+v", result)
+	}
+	if result := desktop.sync(t, shared); result.Deleted != 1 {
+		t.Errorf("Expected the delete to reach the desktop, got %+v", result)
+	}
+
+	for name, m := range map[string]*machine{"laptop": laptop, "desktop": desktop} {
+		commands := m.commands(t)
+		if _, ok := commands["secret"]; ok || len(commands) != 1 {
+			t.Errorf("%s: expected only the deploy command, got %+v", name, commands)
+		}
+	}
+
+	// A machine that imports the command again does not restore it either
+	desktop.record(t, "secret", "export TOKEN=abc")
+	desktop.sync(t, shared)
+	if _, ok := desktop.commands(t)["secret"]; ok {
+		t.Error("Expected a re-recorded deleted command to be removed again")
+	}
+}
+
+func TestSyncRetentionAndDamagedLogs(t *testing.T) {
+	shared := t.TempDir()
+	clock := time.Now()
+	laptop := newMachine(t, "laptop", &clock)
+	desktop := newMachine(t, "desktop", &clock)
+	desktop.opts.RetentionDays = 30
+
+	old := history.CommandRecord{ID: "old", Command: "make old", Directory: "/repo", Timestamp: time.Now().AddDate(0, 0, -60), Shell: history.Bash, Tags: []string{}}
+	if err := laptop.store.SaveCommand(old); err != nil {
+		t.Fatalf("SaveCommand failed: %v", err)
+	}
+	laptop.record(t, "new", "make new")
+	laptop.sync(t, shared)
+
+	// A log still being copied ends in a partial line
+	partial := filepath.Join(shared, "0123456789abcdef"+LogExt)
+	if err := os.WriteFile(partial, []byte(`{"seq":1,"machine":"0123`), 0600); err != nil {
+		t.Fatalf("Failed to write partial log: %v", err)
+	}
+
+	result := desktop.sync(t, shared)
+	if result.Added != 1 || result.Skipped != 1 {
+		t.Errorf("Expected only the command within retention and one skipped line, got %+v", result)
+	}
+	if _, ok := desktop.commands(t)["old"]; ok {
+		t.Error("Expected commands older than the retention period not to be imported")
+	}
+
+	// Local cleanup is not sent to other machines as a delete
+	laptop.opts.RetentionDays = 30
+	if err := laptop.store.CleanupOldCommands(30); err != nil {
+		t.Fatalf("CleanupOldCommands failed: %v", err)
+	}
+	if result := laptop.sync(t, shared); result.Exported != 0 {
+		t.Errorf("Expected retention cleanup not to be exported, got %+v", result)
+	}
+}
+
+func TestSyncGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	remote := filepath.Join(t.TempDir(), "history.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("Failed to create bare repository: %v\n%s", err, out)
+	}
+	if !IsGitRemote(remote) || IsGitRemote(t.TempDir()) {
+		t.Error("Expected only the bare repository to be detected as a git remote")
+	}
+
+	clock := time.Now()
+	laptop := newMachine(t, "laptop", &clock)
+	desktop := newMachine(t, "desktop", &clock)
+	laptop.record(t, "a", "git push")
+	desktop.record(t, "b", "cargo build")
+
+	clones := map[*machine]string{
+		laptop:  filepath.Join(t.TempDir(), "clone"),
+		desktop: filepath.Join(t.TempDir(), "clone"),
+	}
+	sync := func(m *machine) *Result {
+		t.Helper()
+		result, err := SyncGit(m.store, remote, clones[m], m.opts)
+		if err != nil {
+			t.Fatalf("SyncGit failed on %s: %v", m.opts.Hostname, err)
+		}
+		return result
+	}
+
+	sync(laptop)
+	if result := sync(desktop); result.Added != 1 {
+		t.Errorf("Expected the desktop to receive the laptop's command, got %+v", result)
+	}
+	if result := sync(laptop); result.Added != 1 {
+		t.Errorf("Expected the laptop to receive the desktop's command, got %+v", result)
+	}
+	if len(laptop.commands(t)) != 2 || len(desktop.commands(t)) != 2 {
+		t.Error("Expected both machines to hold both commands")
+	}
+}
@@ -13,6 +13,9 @@ const (
 	Bash
 	Zsh
 	Cmd
+	Fish
+	Nushell
+	Elvish
 )
 
 // String returns the string representation of ShellType
@@ -28,11 +31,22 @@ func (s ShellType) String() string {
 		return "zsh"
 	case Cmd:
 		return "cmd"
+	case Fish:
+		return "fish"
+	case Nushell:
+		return "nushell"
+	case Elvish:
+		return "elvish"
 	default:
 		return "unknown"
 	}
 }
 
+// IsValid reports whether s is a concrete, supported shell type
+func (s ShellType) IsValid() bool {
+	return s > Unknown && s <= Elvish
+}
+
 // MarshalJSON implements json.Marshaler
 func (s ShellType) MarshalJSON() ([]byte, error) {
 	return []byte(`"` + s.String() + `"`), nil
@@ -57,6 +71,12 @@ func (s *ShellType) UnmarshalJSON(data []byte) error {
 		*s = Zsh
 	case "cmd":
 		*s = Cmd
+	case "fish":
+		*s = Fish
+	case "nushell":
+		*s = Nushell
+	case "elvish":
+		*s = Elvish
 	default:
 		*s = Unknown
 	}
@@ -64,6 +84,16 @@ func (s *ShellType) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalText implements encoding.TextMarshaler
+func (s ShellType) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *ShellType) UnmarshalText(data []byte) error {
+	return s.UnmarshalJSON(data)
+}
+
 // CommandRecord represents a stored command with metadata
 type CommandRecord struct {
 	ID        string        `json:"id" db:"id"`
@@ -74,6 +104,17 @@ type CommandRecord struct {
 	ExitCode  int           `json:"exit_code" db:"exit_code"`
 	Duration  time.Duration `json:"duration" db:"duration"`
 	Tags      []string      `json:"tags" db:"tags"`
+	SessionID string        `json:"session_id" db:"session_id"`
+	ShellPID  int           `json:"shell_pid" db:"shell_pid"`
+	Hostname  string        `json:"hostname,omitempty" db:"hostname"`
+
+	// Context of the session the command ran in; empty when unknown
+	Username    string `json:"username,omitempty" db:"username"`
+	TTY         string `json:"tty,omitempty" db:"tty"`
+	SSH         bool   `json:"ssh,omitempty" db:"ssh"`
+	ContainerID string `json:"container_id,omitempty" db:"container_id"`
+	PythonEnv   string `json:"python_env,omitempty" db:"python_env"` // virtualenv or conda environment
+	KubeContext string `json:"kube_context,omitempty" db:"kube_context"`
 }
 
 // CommandInterceptor handles capturing commands from shell environments
@@ -153,6 +194,17 @@ type DirectoryIndex struct {
 	IsActive     bool      `json:"is_active"`
 }
 
+// SessionIndex represents a terminal session that recorded commands
+type SessionIndex struct {
+	ID            string    `json:"id"`
+	Shell         ShellType `json:"shell"`
+	ShellPID      int       `json:"shell_pid"`
+	CommandCount  int       `json:"command_count"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+	LastDirectory string    `json:"last_directory"`
+}
+
 // Validate checks if the CommandRecord has valid data
 func (c *CommandRecord) Validate() error {
 	if c.ID == "" {
@@ -167,12 +219,15 @@ func (c *CommandRecord) Validate() error {
 	if c.Timestamp.IsZero() {
 		return &ValidationError{Field: "Timestamp", Message: "Timestamp cannot be zero"}
 	}
-	if c.Shell <= Unknown || c.Shell > Cmd {
+	if !c.Shell.IsValid() {
 		return &ValidationError{Field: "Shell", Message: "Invalid shell type"}
 	}
 	if c.Duration < 0 {
 		return &ValidationError{Field: "Duration", Message: "Duration cannot be negative"}
 	}
+	if c.ShellPID < 0 {
+		return &ValidationError{Field: "ShellPID", Message: "Shell PID cannot be negative"}
+	}
 	return nil
 }
 
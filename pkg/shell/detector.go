@@ -37,6 +37,11 @@ func (d *Detector) DetectShell() (history.ShellType, error) {
 
 // detectWindowsShell detects shell on Windows systems
 func (d *Detector) detectWindowsShell() (history.ShellType, error) {
+	// Nushell inherits PSModulePath on Windows, so check it first
+	if os.Getenv("NU_VERSION") != "" {
+		return history.Nushell, nil
+	}
+
 	// Check for PowerShell specific environment variables
 	if psVersion := os.Getenv("PSVersionTable"); psVersion != "" {
 		return history.PowerShell, nil
@@ -76,6 +81,12 @@ func (d *Detector) detectUnixShell() (history.ShellType, error) {
 			return history.Bash, nil
 		case "zsh":
 			return history.Zsh, nil
+		case "fish":
+			return history.Fish, nil
+		case "nu", "nushell":
+			return history.Nushell, nil
+		case "elvish":
+			return history.Elvish, nil
 		}
 	}
 
@@ -88,6 +99,15 @@ func (d *Detector) detectUnixShell() (history.ShellType, error) {
 	if d.isRunningInZsh() {
 		return history.Zsh, nil
 	}
+	if d.isRunningInFish() {
+		return history.Fish, nil
+	}
+	if d.isRunningInNushell() {
+		return history.Nushell, nil
+	}
+	if d.isRunningInElvish() {
+		return history.Elvish, nil
+	}
 	if d.isRunningInBash() {
 		return history.Bash, nil
 	}
@@ -113,6 +133,12 @@ func (d *Detector) detectGenericShell() (history.ShellType, error) {
 			return history.Bash, nil
 		case "zsh":
 			return history.Zsh, nil
+		case "fish":
+			return history.Fish, nil
+		case "nu", "nushell":
+			return history.Nushell, nil
+		case "elvish":
+			return history.Elvish, nil
 		}
 	}
 
@@ -188,6 +214,51 @@ func (d *Detector) isRunningInZsh() bool {
 	return false
 }
 
+// isRunningInFish checks if currently running in Fish
+func (d *Detector) isRunningInFish() bool {
+	// Fish exports no version variable, but the integration hook does
+	if os.Getenv("CHT_SHELL") == "fish" {
+		return true
+	}
+
+	// Check if SHELL points to fish
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return strings.Contains(strings.ToLower(shell), "fish")
+	}
+
+	return false
+}
+
+// isRunningInNushell checks if currently running in Nushell
+func (d *Detector) isRunningInNushell() bool {
+	// Nushell exports NU_VERSION to child processes
+	if os.Getenv("NU_VERSION") != "" || os.Getenv("CHT_SHELL") == "nushell" {
+		return true
+	}
+
+	// Check if SHELL points to nu
+	if shell := os.Getenv("SHELL"); shell != "" {
+		name := strings.ToLower(filepath.Base(shell))
+		return name == "nu" || strings.Contains(name, "nushell")
+	}
+
+	return false
+}
+
+// isRunningInElvish checks if currently running in Elvish
+func (d *Detector) isRunningInElvish() bool {
+	if os.Getenv("CHT_SHELL") == "elvish" {
+		return true
+	}
+
+	// Check if SHELL points to elvish
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return strings.Contains(strings.ToLower(shell), "elvish")
+	}
+
+	return false
+}
+
 // GetShellPath returns the path to the shell executable
 func (d *Detector) GetShellPath(shell history.ShellType) (string, error) {
 	if shell == history.Unknown {
@@ -35,6 +35,33 @@ func TestDetector_DetectShell(t *testing.T) {
 			wantErr:  false,
 			skipOS:   "windows", // Skip on Windows as it has different detection logic
 		},
+		{
+			name: "detect fish from SHELL env var",
+			envVars: map[string]string{
+				"SHELL": "/usr/bin/fish",
+			},
+			expected: history.Fish,
+			wantErr:  false,
+			skipOS:   "windows", // Skip on Windows as it has different detection logic
+		},
+		{
+			name: "detect nushell from SHELL env var",
+			envVars: map[string]string{
+				"SHELL": "/usr/bin/nu",
+			},
+			expected: history.Nushell,
+			wantErr:  false,
+			skipOS:   "windows", // Skip on Windows as it has different detection logic
+		},
+		{
+			name: "detect elvish from SHELL env var",
+			envVars: map[string]string{
+				"SHELL": "/usr/local/bin/elvish",
+			},
+			expected: history.Elvish,
+			wantErr:  false,
+			skipOS:   "windows", // Skip on Windows as it has different detection logic
+		},
 		{
 			name: "detect powershell from PSModulePath",
 			envVars: map[string]string{
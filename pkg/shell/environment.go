@@ -73,6 +73,10 @@ func (e *EnvironmentManager) GetCommandFromEnvironment() (*history.CommandRecord
 	// Parse duration with validation
 	duration := e.parseDuration(os.Getenv("CHT_DURATION"))
 
+	// Session the command was typed in, as set by the shell hook
+	sessionID := os.Getenv("CHT_SESSION_ID")
+	shellPID := e.parseShellPID(os.Getenv("CHT_SHELL_PID"))
+
 	// Generate a unique ID for the command
 	id := e.GenerateCommandID(command, directory, timestamp)
 
@@ -85,9 +89,24 @@ func (e *EnvironmentManager) GetCommandFromEnvironment() (*history.CommandRecord
 		ExitCode:  exitCode,
 		Duration:  duration,
 		Tags:      []string{},
+		SessionID: sessionID,
+		ShellPID:  shellPID,
 	}, nil
 }
 
+// parseShellPID parses the shell process ID, ignoring invalid values
+func (e *EnvironmentManager) parseShellPID(pidStr string) int {
+	if pidStr == "" {
+		return 0
+	}
+
+	if pid, err := strconv.Atoi(pidStr); err == nil && pid > 0 {
+		return pid
+	}
+
+	return 0
+}
+
 // parseTimestamp parses timestamp from string with multiple format support
 func (e *EnvironmentManager) parseTimestamp(timestampStr string) time.Time {
 	if timestampStr == "" {
@@ -164,6 +183,8 @@ func (e *EnvironmentManager) ClearCaptureEnvironment() {
 		"CHT_TIMESTAMP",
 		"CHT_EXIT_CODE",
 		"CHT_DURATION",
+		"CHT_SESSION_ID",
+		"CHT_SHELL_PID",
 	}
 
 	for _, envVar := range envVars {
@@ -182,6 +203,13 @@ func (e *EnvironmentManager) SetCaptureEnvironment(cmd *history.CommandRecord) e
 		"CHT_DURATION":  strconv.FormatInt(int64(cmd.Duration/time.Millisecond), 10),
 	}
 
+	if cmd.SessionID != "" {
+		envVars["CHT_SESSION_ID"] = cmd.SessionID
+	}
+	if cmd.ShellPID > 0 {
+		envVars["CHT_SHELL_PID"] = strconv.Itoa(cmd.ShellPID)
+	}
+
 	for key, value := range envVars {
 		if err := os.Setenv(key, value); err != nil {
 			return fmt.Errorf("failed to set environment variable %s: %w", key, err)
@@ -236,6 +264,12 @@ func (e *EnvironmentManager) parseShellType(shellStr string) (history.ShellType,
 		return history.Zsh, nil
 	case "cmd":
 		return history.Cmd, nil
+	case "fish":
+		return history.Fish, nil
+	case "nushell":
+		return history.Nushell, nil
+	case "elvish":
+		return history.Elvish, nil
 	default:
 		return history.Unknown, fmt.Errorf("unknown shell type: %s", shellStr)
 	}
@@ -283,6 +317,8 @@ func (e *EnvironmentManager) GetEnvironmentInfo() map[string]string {
 		"CHT_TIMESTAMP",
 		"CHT_EXIT_CODE",
 		"CHT_DURATION",
+		"CHT_SESSION_ID",
+		"CHT_SHELL_PID",
 		"CHT_TRACKER_PATH",
 		"CHT_DISABLED",
 	}
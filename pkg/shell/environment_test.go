@@ -0,0 +1,98 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/ValGrace/command-history-tracker/pkg/history"
+)
+
+func TestEnvironmentManager_GetCommandFromEnvironment_Session(t *testing.T) {
+	tests := []struct {
+		name        string
+		sessionID   string
+		shellPID    string
+		expectedID  string
+		expectedPID int
+	}{
+		{
+			name:        "Session from hook",
+			sessionID:   "65f1c2a0-3e8-1a2b",
+			shellPID:    "4242",
+			expectedID:  "65f1c2a0-3e8-1a2b",
+			expectedPID: 4242,
+		},
+		{
+			name:        "No session variables",
+			expectedID:  "",
+			expectedPID: 0,
+		},
+		{
+			name:        "Invalid shell PID",
+			sessionID:   "abc",
+			shellPID:    "not-a-pid",
+			expectedID:  "abc",
+			expectedPID: 0,
+		},
+		{
+			name:        "Negative shell PID",
+			sessionID:   "abc",
+			shellPID:    "-1",
+			expectedID:  "abc",
+			expectedPID: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CHT_COMMAND", "git status")
+			t.Setenv("CHT_DIRECTORY", "/home/user/project")
+			t.Setenv("CHT_SHELL", "bash")
+			t.Setenv("CHT_SESSION_ID", tt.sessionID)
+			t.Setenv("CHT_SHELL_PID", tt.shellPID)
+
+			record, err := NewEnvironmentManager().GetCommandFromEnvironment()
+			if err != nil {
+				t.Fatalf("GetCommandFromEnvironment() error = %v", err)
+			}
+
+			if record.SessionID != tt.expectedID {
+				t.Errorf("SessionID = %q, want %q", record.SessionID, tt.expectedID)
+			}
+			if record.ShellPID != tt.expectedPID {
+				t.Errorf("ShellPID = %d, want %d", record.ShellPID, tt.expectedPID)
+			}
+			if record.Shell != history.Bash {
+				t.Errorf("Shell = %v, want bash", record.Shell)
+			}
+		})
+	}
+}
+
+func TestEnvironmentManager_SetCaptureEnvironment_RoundTrip(t *testing.T) {
+	t.Setenv("CHT_SESSION_ID", "")
+	t.Setenv("CHT_SHELL_PID", "")
+
+	manager := NewEnvironmentManager()
+	original := &history.CommandRecord{
+		Command:   "make test",
+		Directory: "/src",
+		Shell:     history.Zsh,
+		SessionID: "sess-1",
+		ShellPID:  77,
+	}
+
+	if err := manager.SetCaptureEnvironment(original); err != nil {
+		t.Fatalf("SetCaptureEnvironment() error = %v", err)
+	}
+	defer manager.ClearCaptureEnvironment()
+
+	record, err := manager.GetCommandFromEnvironment()
+	if err != nil {
+		t.Fatalf("GetCommandFromEnvironment() error = %v", err)
+	}
+
+	if record.SessionID != original.SessionID || record.ShellPID != original.ShellPID {
+		t.Errorf("Session round trip = (%q, %d), want (%q, %d)",
+			record.SessionID, record.ShellPID, original.SessionID, original.ShellPID)
+	}
+}
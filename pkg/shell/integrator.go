@@ -55,11 +55,35 @@ func (i *Integrator) GetIntegrationScript(shell history.ShellType) (string, erro
 		return i.getZshScript(), nil
 	case history.Cmd:
 		return i.getCmdScript(), nil
+	case history.Fish:
+		return i.getFishScript(), nil
+	case history.Nushell:
+		return i.getNushellScript(), nil
+	case history.Elvish:
+		return i.getElvishScript(), nil
 	default:
 		return "", fmt.Errorf("unsupported shell type: %s", shell.String())
 	}
 }
 
+// GetWidgetScript returns key bindings that replace Ctrl-R with the history
+// browser. The browser runs inline, starting from the text already typed, and
+// the chosen command replaces the command line for editing instead of running.
+func (i *Integrator) GetWidgetScript(shell history.ShellType) (string, error) {
+	switch shell {
+	case history.Bash:
+		return i.getBashWidget(), nil
+	case history.Zsh:
+		return i.getZshWidget(), nil
+	case history.Fish:
+		return i.getFishWidget(), nil
+	case history.PowerShell:
+		return i.getPowerShellWidget(), nil
+	default:
+		return "", fmt.Errorf("the Ctrl-R widget is not supported for %s", shell.String())
+	}
+}
+
 // IsIntegrationActive checks if integration is currently active
 func (i *Integrator) IsIntegrationActive(shell history.ShellType) (bool, error) {
 	configPath, err := i.getShellConfigPath(shell)
@@ -98,6 +122,8 @@ function Invoke-HistoryTracker {
     $env:CHT_DURATION = $Duration
     $env:CHT_SHELL = "powershell"
     $env:CHT_TIMESTAMP = (Get-Date).ToString("o")
+    $env:CHT_SESSION_ID = $global:CHTSessionId
+    $env:CHT_SHELL_PID = $PID
     
     # Call the tracker executable to record the command
     if (Get-Command "tracker" -ErrorAction SilentlyContinue) {
@@ -105,6 +131,12 @@ function Invoke-HistoryTracker {
     }
 }
 
+# Identify this terminal session so its commands can be grouped together
+if (-not $global:CHTSessionId) {
+    $global:CHTSessionId = [guid]::NewGuid().ToString("N").Substring(0, 16)
+}
+$env:CHT_SESSION_ID = $global:CHTSessionId
+
 # Override the prompt function to capture commands
 $global:OriginalPrompt = $function:prompt
 function prompt {
@@ -137,6 +169,8 @@ __cht_record_command() {
         export CHT_DURATION="$duration"
         export CHT_SHELL="bash"
         export CHT_TIMESTAMP=$(date -Iseconds)
+        export CHT_SESSION_ID="$__cht_session_id"
+        export CHT_SHELL_PID="$$"
         
         # Call the tracker executable to record the command
         if command -v tracker >/dev/null 2>&1; then
@@ -154,6 +188,12 @@ __cht_preexec() {
     __cht_start_time=$(date +%s%3N)
 }
 
+# Identify this terminal session; not exported, so child shells get their own
+if [[ -z "$__cht_session_id" ]]; then
+    __cht_session_id=$(printf '%x-%x-%04x' "$(date +%s)" "$$" "$RANDOM")
+fi
+export CHT_SESSION_ID="$__cht_session_id"
+
 # Set up command capture hooks
 if [[ -z "$__cht_installed" ]]; then
     export __cht_installed=1
@@ -193,6 +233,8 @@ __cht_record_command() {
         export CHT_DURATION="$duration"
         export CHT_SHELL="zsh"
         export CHT_TIMESTAMP=$(date -Iseconds)
+        export CHT_SESSION_ID="$__cht_session_id"
+        export CHT_SHELL_PID="$$"
         
         # Call the tracker executable to record the command
         if command -v tracker >/dev/null 2>&1; then
@@ -210,6 +252,12 @@ __cht_preexec() {
     __cht_start_time=$(date +%s%3N)
 }
 
+# Identify this terminal session; not exported, so child shells get their own
+if [[ -z "$__cht_session_id" ]]; then
+    __cht_session_id=$(printf '%x-%x-%04x' "$(date +%s)" "$$" "$RANDOM")
+fi
+export CHT_SESSION_ID="$__cht_session_id"
+
 # Set up command capture hooks
 if [[ -z "$__cht_installed" ]]; then
     export __cht_installed=1
@@ -221,6 +269,183 @@ if [[ -z "$__cht_installed" ]]; then
 fi`
 }
 
+// getFishScript returns Fish integration script
+func (i *Integrator) getFishScript() string {
+	return `# Command History Tracker Integration
+# Identify this terminal session; not exported, so child shells get their own
+if not set -q __cht_session_id
+    set -g __cht_session_id (printf '%x-%x-%04x' (date +%s) $fish_pid (random 0 65535))
+end
+set -gx CHT_SESSION_ID $__cht_session_id
+
+function __cht_preexec --on-event fish_preexec
+    set -g __cht_current_command $argv[1]
+end
+
+function __cht_postexec --on-event fish_postexec
+    set -l exit_code $status
+
+    if test -n "$__cht_current_command"
+        # Exported only to the tracker process, not to the interactive shell
+        set -lx CHT_COMMAND $__cht_current_command
+        set -lx CHT_DIRECTORY $PWD
+        set -lx CHT_EXIT_CODE $exit_code
+        set -lx CHT_DURATION $CMD_DURATION
+        set -lx CHT_SHELL fish
+        set -lx CHT_TIMESTAMP (date +%s)
+        set -lx CHT_SESSION_ID $__cht_session_id
+        set -lx CHT_SHELL_PID $fish_pid
+
+        # Call the tracker executable to record the command
+        if command -q tracker
+            tracker record 2>/dev/null
+        end
+
+        set -e __cht_current_command
+    end
+end`
+}
+
+// getNushellScript returns Nushell integration script
+func (i *Integrator) getNushellScript() string {
+	return `# Command History Tracker Integration
+# Identify this terminal session; config.nu runs once per interactive shell
+$env.CHT_SESSION_ID = (random chars --length 16)
+
+$env.config = ($env.config | upsert hooks.pre_execution (
+    ($env.config.hooks.pre_execution? | default []) | append {||
+        $env.__CHT_COMMAND = (commandline)
+    }
+))
+
+$env.config = ($env.config | upsert hooks.pre_prompt (
+    ($env.config.hooks.pre_prompt? | default []) | append {||
+        let exit_code = $env.LAST_EXIT_CODE
+        let command = ($env.__CHT_COMMAND? | default "")
+
+        if ($command | str length) > 0 and not (which tracker | is-empty) {
+            # Call the tracker executable to record the command
+            with-env {
+                CHT_COMMAND: $command
+                CHT_DIRECTORY: $env.PWD
+                CHT_EXIT_CODE: ($exit_code | into string)
+                CHT_DURATION: ($env.CMD_DURATION_MS? | default "0")
+                CHT_SHELL: "nushell"
+                CHT_TIMESTAMP: (date now | format date "%s")
+                CHT_SHELL_PID: ($nu.pid | into string)
+            } {
+                ^tracker record | complete | ignore
+            }
+        }
+
+        $env.__CHT_COMMAND = ""
+    }
+))`
+}
+
+// getElvishScript returns Elvish integration script. Elvish does not expose
+// the exit status of the last command to hooks, so it is recorded as 0.
+func (i *Integrator) getElvishScript() string {
+	return `# Command History Tracker Integration
+use os
+
+# Identify this terminal session; rc.elv runs once per interactive shell
+set-env CHT_SESSION_ID (printf '%08x%08x' (randint 0 4294967296) (randint 0 4294967296))
+
+var cht-command = ''
+
+set edit:after-readline = [$@edit:after-readline {|line|
+    set cht-command = $line
+}]
+
+set edit:before-readline = [$@edit:before-readline {
+    if (and (not-eq $cht-command '') (has-external tracker)) {
+        tmp E:CHT_COMMAND = $cht-command
+        tmp E:CHT_DIRECTORY = $pwd
+        tmp E:CHT_EXIT_CODE = 0
+        tmp E:CHT_DURATION = (printf '%.0f' (* $edit:command-duration 1000))
+        tmp E:CHT_SHELL = elvish
+        tmp E:CHT_SHELL_PID = $pid
+
+        # Call the tracker executable to record the command
+        try { tracker record 2>$os:dev-null } catch { }
+    }
+    set cht-command = ''
+}]`
+}
+
+// getBashWidget returns the Bash Ctrl-R binding, editing READLINE_LINE
+func (i *Integrator) getBashWidget() string {
+	return `# Command History Tracker Ctrl-R widget
+__cht_widget() {
+    local selected
+    selected=$(tracker browse --inline --search="$READLINE_LINE")
+    if [[ -n "$selected" ]]; then
+        READLINE_LINE="$selected"
+        READLINE_POINT=${#READLINE_LINE}
+    fi
+}
+
+bind -m emacs-standard -x '"\C-r": __cht_widget'
+bind -m vi-command -x '"\C-r": __cht_widget'
+bind -m vi-insert -x '"\C-r": __cht_widget'`
+}
+
+// getZshWidget returns the Zsh Ctrl-R binding, editing BUFFER
+func (i *Integrator) getZshWidget() string {
+	return `# Command History Tracker Ctrl-R widget
+__cht_widget() {
+    local selected
+    selected=$(tracker browse --inline --search="$BUFFER" </dev/tty)
+    if [[ -n "$selected" ]]; then
+        BUFFER="$selected"
+        CURSOR=${#BUFFER}
+    fi
+    zle reset-prompt
+}
+
+zle -N __cht_widget
+bindkey -M emacs '^R' __cht_widget
+bindkey -M viins '^R' __cht_widget
+bindkey -M vicmd '^R' __cht_widget`
+}
+
+// getFishWidget returns the Fish Ctrl-R binding, editing the commandline
+func (i *Integrator) getFishWidget() string {
+	return `# Command History Tracker Ctrl-R widget
+function __cht_widget
+    set -l query (commandline -b | string collect)
+    set -l selected (tracker browse --inline --search="$query" | string collect)
+    if test -n "$selected"
+        commandline -r -- $selected
+        commandline -C (string length -- $selected)
+    end
+    commandline -f repaint
+end
+
+bind \cr __cht_widget
+if bind -M insert >/dev/null 2>&1
+    bind -M insert \cr __cht_widget
+end`
+}
+
+// getPowerShellWidget returns the PSReadLine Ctrl-R handler, editing the
+// buffer through PSConsoleReadLine
+func (i *Integrator) getPowerShellWidget() string {
+	return `# Command History Tracker Ctrl-R widget
+Set-PSReadLineKeyHandler -Chord Ctrl+r -BriefDescription 'CommandHistoryTracker' -Description 'Search command history with tracker browse' -ScriptBlock {
+    $line = $null
+    $cursor = $null
+    [Microsoft.PowerShell.PSConsoleReadLine]::GetBufferState([ref]$line, [ref]$cursor)
+
+    $selected = (& tracker browse --inline "--search=$line") -join "` + "`" + `n"
+    if ($selected) {
+        [Microsoft.PowerShell.PSConsoleReadLine]::Replace(0, $line.Length, $selected)
+    }
+    [Microsoft.PowerShell.PSConsoleReadLine]::InvokePrompt()
+}`
+}
+
 // getCmdScript returns Windows Command Prompt integration script
 func (i *Integrator) getCmdScript() string {
 	return `@echo off
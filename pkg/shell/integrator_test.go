@@ -25,6 +25,8 @@ func TestIntegrator_GetIntegrationScript(t *testing.T) {
 			contains: []string{
 				"Invoke-HistoryTracker",
 				"$env:CHT_COMMAND",
+				"$env:CHT_SESSION_ID",
+				"$env:CHT_SHELL_PID",
 				"tracker record",
 			},
 		},
@@ -35,6 +37,8 @@ func TestIntegrator_GetIntegrationScript(t *testing.T) {
 			contains: []string{
 				"__cht_record_command",
 				"CHT_COMMAND=",
+				"CHT_SESSION_ID=",
+				"CHT_SHELL_PID=",
 				"tracker record",
 			},
 		},
@@ -46,6 +50,47 @@ func TestIntegrator_GetIntegrationScript(t *testing.T) {
 				"__cht_record_command",
 				"add-zsh-hook",
 				"CHT_COMMAND=",
+				"CHT_SESSION_ID=",
+				"CHT_SHELL_PID=",
+			},
+		},
+		{
+			name:    "Fish script",
+			shell:   history.Fish,
+			wantErr: false,
+			contains: []string{
+				"--on-event fish_preexec",
+				"--on-event fish_postexec",
+				"CHT_COMMAND",
+				"CHT_SESSION_ID",
+				"CHT_SHELL fish",
+				"tracker record",
+			},
+		},
+		{
+			name:    "Nushell script",
+			shell:   history.Nushell,
+			wantErr: false,
+			contains: []string{
+				"hooks.pre_execution",
+				"hooks.pre_prompt",
+				"CHT_COMMAND:",
+				"CHT_SESSION_ID",
+				"CHT_SHELL: \"nushell\"",
+				"tracker record",
+			},
+		},
+		{
+			name:    "Elvish script",
+			shell:   history.Elvish,
+			wantErr: false,
+			contains: []string{
+				"edit:after-readline",
+				"edit:before-readline",
+				"E:CHT_COMMAND",
+				"CHT_SESSION_ID",
+				"E:CHT_SHELL = elvish",
+				"tracker record",
 			},
 		},
 		{
@@ -102,6 +147,21 @@ func TestIntegrator_GetShellConfigPath(t *testing.T) {
 			shell:   history.PowerShell,
 			wantErr: false,
 		},
+		{
+			name:    "Fish config path",
+			shell:   history.Fish,
+			wantErr: false,
+		},
+		{
+			name:    "Nushell config path",
+			shell:   history.Nushell,
+			wantErr: false,
+		},
+		{
+			name:    "Elvish config path",
+			shell:   history.Elvish,
+			wantErr: false,
+		},
 		{
 			name:    "Cmd config path",
 			shell:   history.Cmd,
@@ -136,6 +196,18 @@ func TestIntegrator_GetShellConfigPath(t *testing.T) {
 					if !strings.Contains(path, "profile.ps1") {
 						t.Errorf("PowerShell config path should contain profile.ps1, got: %s", path)
 					}
+				case history.Fish:
+					if !strings.Contains(path, filepath.Join("fish", "conf.d")) {
+						t.Errorf("Fish config path should be in fish/conf.d, got: %s", path)
+					}
+				case history.Nushell:
+					if !strings.HasSuffix(path, filepath.Join("nushell", "config.nu")) {
+						t.Errorf("Nushell config path should end in nushell/config.nu, got: %s", path)
+					}
+				case history.Elvish:
+					if !strings.HasSuffix(path, filepath.Join("elvish", "rc.elv")) {
+						t.Errorf("Elvish config path should end in elvish/rc.elv, got: %s", path)
+					}
 				case history.Cmd:
 					if !strings.Contains(path, "cht_cmd_init.bat") {
 						t.Errorf("Cmd config path should contain cht_cmd_init.bat, got: %s", path)
@@ -231,6 +303,70 @@ func TestIntegrator_InstallAndRemoveIntegration(t *testing.T) {
 	}
 }
 
+func TestIntegrator_XDGConfigIntegration(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("XDG config shells are not supported on Windows")
+	}
+
+	tests := []struct {
+		shell    history.ShellType
+		relPath  string
+		hookText string
+	}{
+		{history.Fish, filepath.Join("fish", "conf.d", "command-history-tracker.fish"), "fish_postexec"},
+		{history.Nushell, filepath.Join("nushell", "config.nu"), "hooks.pre_prompt"},
+		{history.Elvish, filepath.Join("elvish", "rc.elv"), "edit:before-readline"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell.String(), func(t *testing.T) {
+			configHome := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", configHome)
+
+			// Existing user configuration must survive install and removal
+			confPath := filepath.Join(configHome, tt.relPath)
+			if err := os.MkdirAll(filepath.Dir(confPath), 0755); err != nil {
+				t.Fatalf("Failed to create config dir: %v", err)
+			}
+			userConfig := "# user settings\n"
+			if err := os.WriteFile(confPath, []byte(userConfig), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			integrator := NewIntegrator()
+			if err := integrator.SetupIntegration(tt.shell); err != nil {
+				t.Fatalf("SetupIntegration() failed: %v", err)
+			}
+
+			content, err := os.ReadFile(confPath)
+			if err != nil {
+				t.Fatalf("Failed to read config: %v", err)
+			}
+			if !strings.Contains(string(content), tt.hookText) {
+				t.Errorf("Hook %q not found in %s", tt.hookText, confPath)
+			}
+			if !strings.Contains(string(content), integrator.getIntegrationMarker()) {
+				t.Error("Integration marker not found after installation")
+			}
+
+			if active, err := integrator.IsIntegrationActive(tt.shell); err != nil || !active {
+				t.Errorf("Expected integration to be active, got %v (err: %v)", active, err)
+			}
+
+			if err := integrator.RemoveIntegration(tt.shell); err != nil {
+				t.Fatalf("RemoveIntegration() failed: %v", err)
+			}
+			content, err = os.ReadFile(confPath)
+			if err != nil {
+				t.Fatalf("Failed to read config: %v", err)
+			}
+			if strings.TrimSpace(string(content)) != strings.TrimSpace(userConfig) {
+				t.Errorf("Expected only user config to remain, got:\n%s", content)
+			}
+		})
+	}
+}
+
 func TestIntegrator_SetupIntegration_UnsupportedShell(t *testing.T) {
 	integrator := NewIntegrator()
 
@@ -242,3 +378,37 @@ func TestIntegrator_SetupIntegration_UnsupportedShell(t *testing.T) {
 		}
 	}
 }
+
+func TestIntegrator_GetWidgetScript(t *testing.T) {
+	integrator := NewIntegrator()
+
+	tests := []struct {
+		shell    history.ShellType
+		contains []string
+	}{
+		{history.Bash, []string{`tracker browse --inline --search="$READLINE_LINE"`, "READLINE_POINT", `bind -m emacs-standard -x '"\C-r": __cht_widget'`}},
+		{history.Zsh, []string{`--search="$BUFFER" </dev/tty`, "CURSOR=${#BUFFER}", "zle -N __cht_widget", "bindkey -M emacs '^R' __cht_widget"}},
+		{history.Fish, []string{"commandline -b", `--search="$query"`, "commandline -r -- $selected", `bind \cr __cht_widget`}},
+		{history.PowerShell, []string{"Set-PSReadLineKeyHandler -Chord Ctrl+r", "PSConsoleReadLine]::GetBufferState", "PSConsoleReadLine]::Replace(0, $line.Length, $selected)", "-join \"`n\""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell.String(), func(t *testing.T) {
+			script, err := integrator.GetWidgetScript(tt.shell)
+			if err != nil {
+				t.Fatalf("GetWidgetScript() error = %v", err)
+			}
+			for _, expected := range tt.contains {
+				if !strings.Contains(script, expected) {
+					t.Errorf("Expected widget to contain %q, got:\n%s", expected, script)
+				}
+			}
+		})
+	}
+
+	for _, unsupported := range []history.ShellType{history.Cmd, history.Nushell, history.Unknown} {
+		if _, err := integrator.GetWidgetScript(unsupported); err == nil {
+			t.Errorf("Expected no widget for %s", unsupported)
+		}
+	}
+}
@@ -110,11 +110,16 @@ func (p *platformAbstraction) GetSupportedShells() []history.ShellType {
 			history.PowerShell,
 			history.Cmd,
 			history.Bash, // Available through WSL, Git Bash, etc.
+			history.Nushell,
+			history.Elvish,
 		}
 	case PlatformLinux, PlatformDarwin, PlatformFreeBSD:
 		return []history.ShellType{
 			history.Bash,
 			history.Zsh,
+			history.Fish,
+			history.Nushell,
+			history.Elvish,
 			history.PowerShell, // PowerShell Core is available on Unix
 		}
 	default:
@@ -158,6 +163,18 @@ func (p *platformAbstraction) GetShellExecutableName(shell history.ShellType) st
 		return "zsh"
 	case history.Cmd:
 		return "cmd.exe"
+	case history.Fish:
+		return "fish"
+	case history.Nushell:
+		if p.platform == PlatformWindows {
+			return "nu.exe"
+		}
+		return "nu"
+	case history.Elvish:
+		if p.platform == PlatformWindows {
+			return "elvish.exe"
+		}
+		return "elvish"
 	default:
 		return ""
 	}
@@ -194,6 +211,46 @@ func (p *platformAbstraction) GetShellConfigPath(shell history.ShellType) (strin
 		// CMD doesn't have a standard config file, use a custom one
 		return filepath.Join(homeDir, "cht_cmd_init.bat"), nil
 
+	case history.Fish:
+		// Fish sources every file in conf.d, so the hook gets a file of its own
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(homeDir, ".config")
+		}
+		return filepath.Join(configHome, "fish", "conf.d", "command-history-tracker.fish"), nil
+
+	case history.Nushell:
+		// Nushell follows the platform config directory unless XDG_CONFIG_HOME is set
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			switch p.platform {
+			case PlatformWindows:
+				configHome = os.Getenv("APPDATA")
+				if configHome == "" {
+					configHome = filepath.Join(homeDir, "AppData", "Roaming")
+				}
+			case PlatformDarwin:
+				configHome = filepath.Join(homeDir, "Library", "Application Support")
+			default:
+				configHome = filepath.Join(homeDir, ".config")
+			}
+		}
+		return filepath.Join(configHome, "nushell", "config.nu"), nil
+
+	case history.Elvish:
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			if p.platform == PlatformWindows {
+				configHome = os.Getenv("APPDATA")
+				if configHome == "" {
+					configHome = filepath.Join(homeDir, "AppData", "Roaming")
+				}
+			} else {
+				configHome = filepath.Join(homeDir, ".config")
+			}
+		}
+		return filepath.Join(configHome, "elvish", "rc.elv"), nil
+
 	default:
 		return "", fmt.Errorf("unsupported shell type: %s", shell.String())
 	}
@@ -364,8 +421,11 @@ func (u *UnixPlatform) GetShellFromEnvironment() history.ShellType {
 	case "zsh":
 		return history.Zsh
 	case "fish":
-		// Fish shell - not currently supported but could be added
-		return history.Unknown
+		return history.Fish
+	case "nu", "nushell":
+		return history.Nushell
+	case "elvish":
+		return history.Elvish
 	default:
 		return history.Unknown
 	}
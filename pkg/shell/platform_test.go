@@ -2,6 +2,7 @@ package shell
 
 import (
 	"github.com/ValGrace/command-history-tracker/pkg/history"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -64,7 +65,7 @@ func TestPlatformAbstraction_GetSupportedShells(t *testing.T) {
 			}
 		}
 	case PlatformLinux, PlatformDarwin, PlatformFreeBSD:
-		expectedShells := []history.ShellType{history.Bash, history.Zsh, history.PowerShell}
+		expectedShells := []history.ShellType{history.Bash, history.Zsh, history.Fish, history.Nushell, history.Elvish, history.PowerShell}
 		for _, expected := range expectedShells {
 			found := false
 			for _, supported := range supportedShells {
@@ -146,6 +147,8 @@ func TestPlatformAbstraction_GetShellConfigPath(t *testing.T) {
 		history.Bash,
 		history.Zsh,
 		history.PowerShell,
+		history.Nushell,
+		history.Elvish,
 	}
 
 	// Add Cmd for Windows
@@ -153,6 +156,11 @@ func TestPlatformAbstraction_GetShellConfigPath(t *testing.T) {
 		shells = append(shells, history.Cmd)
 	}
 
+	// Fish is only supported on Unix-like systems
+	if platform.GetPlatform() != PlatformWindows {
+		shells = append(shells, history.Fish)
+	}
+
 	for _, shell := range shells {
 		t.Run(shell.String(), func(t *testing.T) {
 			configPath, err := platform.GetShellConfigPath(shell)
@@ -178,6 +186,18 @@ func TestPlatformAbstraction_GetShellConfigPath(t *testing.T) {
 				if !strings.Contains(configPath, "profile.ps1") {
 					t.Errorf("PowerShell config path should contain profile.ps1, got: %s", configPath)
 				}
+			case history.Fish:
+				if !strings.HasSuffix(configPath, "command-history-tracker.fish") {
+					t.Errorf("Fish config path should be a conf.d file, got: %s", configPath)
+				}
+			case history.Nushell:
+				if filepath.Base(configPath) != "config.nu" {
+					t.Errorf("Nushell config path should be config.nu, got: %s", configPath)
+				}
+			case history.Elvish:
+				if filepath.Base(configPath) != "rc.elv" {
+					t.Errorf("Elvish config path should be rc.elv, got: %s", configPath)
+				}
 			case history.Cmd:
 				if !strings.Contains(configPath, "cht_cmd_init.bat") {
 					t.Errorf("Cmd config path should contain cht_cmd_init.bat, got: %s", configPath)
@@ -329,7 +349,7 @@ func TestUnixPlatform_GetShellFromEnvironment(t *testing.T) {
 	shell := platform.GetShellFromEnvironment()
 
 	// Should return a valid shell type or Unknown
-	if shell < history.Unknown || shell > history.Cmd {
+	if shell != history.Unknown && !shell.IsValid() {
 		t.Errorf("Invalid shell type returned: %v", shell)
 	}
 }